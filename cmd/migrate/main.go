@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"pelico/internal/config"
+	"pelico/internal/database"
+	"github.com/joho/godotenv"
+)
+
+// migrate applies or reverts schema migrations on DATABASE_URL:
+//
+//	migrate up        apply pending migrations
+//	migrate down [n]  revert the last n migrations (default 1)
+//	migrate status    list migrations and when they were applied
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+	cfg := config.Load()
+
+	command := "up"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	db, err := database.Connect(cfg.DatabaseURL, database.PoolOptions{})
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	switch command {
+	case "up":
+		if err := database.MigrateDB(db); err != nil {
+			log.Fatal("Failed to run migrations:", err)
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps < 1 {
+				log.Fatalf("Invalid number of migrations to revert: %s", os.Args[2])
+			}
+		}
+		if err := database.Rollback(db, steps); err != nil {
+			log.Fatal("Failed to revert migrations:", err)
+		}
+	case "status":
+	default:
+		log.Fatalf("Unknown command %q; use up, down [n] or status", command)
+	}
+
+	status, err := database.Status(db)
+	if err != nil {
+		log.Fatal("Failed to read migration status:", err)
+	}
+	for _, migration := range status {
+		applied := "pending"
+		if migration.AppliedAt != nil {
+			applied = "applied " + migration.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, applied)
+	}
+}
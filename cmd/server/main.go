@@ -18,13 +18,17 @@ func main() {
 	cfg := config.Load()
 
 	// Initialize database
-	db, err := database.Connect(cfg.DatabaseURL)
+	db, err := database.Connect(cfg.DatabaseURL, database.PoolOptions{
+		MaxOpenConns:    cfg.DatabaseMaxOpenConns,
+		MaxIdleConns:    cfg.DatabaseMaxIdleConns,
+		ConnMaxLifetime: cfg.DatabaseConnMaxLifetime,
+	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
 	// Run migrations
-	if err := database.Migrate(cfg.DatabaseURL); err != nil {
+	if err := database.MigrateDB(db); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
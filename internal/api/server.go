@@ -14,11 +14,15 @@ import (
 )
 
 type Server struct {
-	router *gin.Engine
-	db     *gorm.DB
-	config *config.Config
-	cache  *services.CacheService
-	logger *services.LoggerService
+	router   *gin.Engine
+	db       *gorm.DB
+	config   *config.Config
+	cache    *services.CacheService
+	logger   *services.LoggerService
+	metadata *services.MetadataService
+	sessions *services.SessionTimer
+	images   *services.ImageService
+	search   *services.GameSearch
 }
 
 func NewServer(db *gorm.DB, cfg *config.Config) *Server {
@@ -43,17 +47,44 @@ func NewServer(db *gorm.DB, cfg *config.Config) *Server {
 	// Initialize cache service with 30-minute TTL
 	cache := services.NewCacheService(30 * time.Minute)
 	
+	// ScreenScraper identifies ROMs by hash; it stays disabled without developer credentials
+	screenScraper := services.NewScreenScraperService(cfg.ScreenScraperDevID, cfg.ScreenScraperDevPassword,
+		cfg.ScreenScraperSoftware, cfg.ScreenScraperUser, cfg.ScreenScraperPassword, cfg.ScreenScraperRegions)
+	
+	// Metadata provider responses are persisted so repeated lookups survive restarts
+	metadata := services.NewMetadataService(cfg.TwitchClientID, cfg.TwitchClientSecret, screenScraper,
+		services.NewMetadataResponseCache(db, cfg.MetadataCacheTTL), logger)
+	
+	// Artwork is stored locally so the UI works without reaching provider CDNs
+	images := services.NewImageService(db, cfg.ImageStoragePath, cfg.ImageAutoDownload, screenScraper)
+	
+	// One play session may run at a time; stale ones are ended in the background
+	sessions := services.NewSessionTimer(db, cfg.SessionConflictPolicy, cfg.SessionOverlapPolicy, cfg.SessionMaxDuration)
+	sessions.StartAutoEnd(5*time.Minute, logger)
+	
+	// Full-text search keeps its own index, updated as games and sessions change
+	search := services.NewGameSearch(db)
+	if err := search.Setup(); err != nil {
+		logger.LogError("search_index_setup_failed", err, slog.String("backend", search.Backend()))
+	}
+	
 	server := &Server{
-		router: router,
-		db:     db,
-		config: cfg,
-		cache:  cache,
-		logger: logger,
+		router:   router,
+		db:       db,
+		config:   cfg,
+		cache:    cache,
+		logger:   logger,
+		metadata: metadata,
+		sessions: sessions,
+		images:   images,
+		search:   search,
 	}
 	
 	// Log server initialization
 	logger.LogInfo("server_initialized", 
 		slog.String("cache_ttl", "30m"),
+		slog.String("metadata_cache_ttl", cfg.MetadataCacheTTL.String()),
+		slog.Bool("screenscraper_enabled", screenScraper.Enabled()),
 		slog.String("log_level", "info"))
 	
 	server.setupRoutes()
@@ -62,15 +93,23 @@ func NewServer(db *gorm.DB, cfg *config.Config) *Server {
 
 func (s *Server) setupRoutes() {
 	// Initialize handlers with cache service and logger
-	gameHandler := handlers.NewGameHandler(s.db, s.config.TwitchClientID, s.config.TwitchClientSecret, s.cache, s.logger)
-	platformHandler := handlers.NewPlatformHandler(s.db, s.cache)
-	sessionHandler := handlers.NewSessionHandler(s.db, s.cache)
-	scannerHandler := handlers.NewScannerHandler(s.db, s.config.TwitchClientID, s.config.TwitchClientSecret)
+	gameHandler := handlers.NewGameHandler(s.db, s.metadata, s.images, s.cache, s.logger)
+	platformHandler := handlers.NewPlatformHandler(s.db, s.metadata, s.cache)
+	sessionHandler := handlers.NewSessionHandler(s.db, s.cache, s.sessions)
+	scannerHandler := handlers.NewScannerHandler(s.db, s.metadata, s.images)
 	directoryHandler := handlers.NewDirectoryHandler()
-	backupHandler := handlers.NewBackupHandler(s.db, s.config)
+	backupHandler := handlers.NewBackupHandler(s.db, s.config, s.search)
 	wishlistHandler := handlers.NewWishlistHandler(s.db)
 	shortlistHandler := handlers.NewShortlistHandler(s.db)
-	statsHandler := handlers.NewStatsHandler(s.db)
+	statsHandler := handlers.NewStatsHandler(s.db, s.config)
+	imageHandler := handlers.NewImageHandler(s.db, s.images, s.cache)
+	importHandler := handlers.NewImportHandler(s.db, s.cache, s.config, s.sessions)
+	goalHandler := handlers.NewGoalHandler(s.db)
+	searchHandler := handlers.NewSearchHandler(s.db, s.search)
+	tagHandler := handlers.NewTagHandler(s.db, s.cache)
+	collectionHandler := handlers.NewCollectionHandler(s.db)
+	customFieldHandler := handlers.NewCustomFieldHandler(s.db, s.cache)
+	copyHandler := handlers.NewCopyHandler(s.db, s.cache, s.config)
 	
 	// API routes
 	api := s.router.Group("/api/v1")
@@ -79,6 +118,8 @@ func (s *Server) setupRoutes() {
 		api.GET("/games", gameHandler.GetGames)
 		api.GET("/games/recently-played", gameHandler.GetRecentlyPlayedGames)
 		api.GET("/games/genres", gameHandler.GetGenres)
+		api.GET("/games/companies", gameHandler.GetCompanies)
+		api.GET("/games/franchises", gameHandler.GetFranchises)
 		api.GET("/games/:id", gameHandler.GetGame)
 		api.POST("/games", gameHandler.CreateGame)
 		api.POST("/games/from-metadata", gameHandler.CreateGameFromMetadata)
@@ -87,12 +128,50 @@ func (s *Server) setupRoutes() {
 		api.POST("/games/search", gameHandler.SearchGames)
 		api.POST("/games/search-metadata", gameHandler.SearchMetadata)
 		
+		// Full-text search
+		api.GET("/search", searchHandler.Search)
+		api.POST("/search/reindex", searchHandler.Reindex)
+		
+		// Tags
+		api.GET("/tags", tagHandler.GetTags)
+		api.POST("/tags", tagHandler.CreateTag)
+		api.PUT("/tags/:id", tagHandler.UpdateTag)
+		api.DELETE("/tags/:id", tagHandler.DeleteTag)
+		api.POST("/tags/bulk", tagHandler.BulkTag)
+		api.PUT("/games/:id/tags", tagHandler.SetGameTags)
+		
+		// Collections
+		api.GET("/collections", collectionHandler.GetCollections)
+		api.GET("/collections/:id", collectionHandler.GetCollection)
+		api.POST("/collections", collectionHandler.CreateCollection)
+		api.POST("/collections/preview", collectionHandler.PreviewCollection)
+		api.PUT("/collections/:id", collectionHandler.UpdateCollection)
+		api.DELETE("/collections/:id", collectionHandler.DeleteCollection)
+		api.POST("/collections/:id/games", collectionHandler.AddCollectionGames)
+		api.DELETE("/collections/:id/games/:gameId", collectionHandler.RemoveCollectionGame)
+		api.PUT("/collections/:id/order", collectionHandler.ReorderCollection)
+		
+		// Custom fields
+		api.GET("/custom-fields", customFieldHandler.GetCustomFields)
+		api.POST("/custom-fields", customFieldHandler.CreateCustomField)
+		api.PUT("/custom-fields/:id", customFieldHandler.UpdateCustomField)
+		api.DELETE("/custom-fields/:id", customFieldHandler.DeleteCustomField)
+		
+		// Physical copies
+		api.GET("/copies", copyHandler.GetCopies)
+		api.GET("/games/:id/copies", copyHandler.GetGameCopies)
+		api.POST("/games/:id/copies", copyHandler.CreateCopy)
+		api.GET("/games/:id/prices", copyHandler.GetGamePrices)
+		api.PUT("/copies/:id", copyHandler.UpdateCopy)
+		api.DELETE("/copies/:id", copyHandler.DeleteCopy)
+		
 		// Platforms
 		api.GET("/platforms", platformHandler.GetPlatforms)
 		api.GET("/platforms/:id", platformHandler.GetPlatform)
 		api.POST("/platforms", platformHandler.CreatePlatform)
 		api.PUT("/platforms/:id", platformHandler.UpdatePlatform)
 		api.DELETE("/platforms/:id", platformHandler.DeletePlatform)
+		api.POST("/platforms/auto-link", platformHandler.AutoLinkPlatforms)
 		
 		// Play Sessions
 		api.GET("/games/:id/sessions", sessionHandler.GetGameSessions)
@@ -101,8 +180,22 @@ func (s *Server) setupRoutes() {
 		api.PUT("/sessions/:id", sessionHandler.UpdateSession)
 		api.DELETE("/sessions/:id", sessionHandler.DeleteSession)
 		api.GET("/sessions/active", sessionHandler.GetActiveSessions)
+		api.GET("/sessions/calendar.ics", sessionHandler.ExportCalendar)
 		api.POST("/sessions/:id/end", sessionHandler.EndSession)
 		
+		// Play session timer
+		api.GET("/sessions/timer", sessionHandler.GetTimer)
+		api.POST("/sessions/timer/start", sessionHandler.StartTimer)
+		api.POST("/sessions/timer/stop", sessionHandler.StopTimer)
+		api.POST("/sessions/timer/pause", sessionHandler.PauseTimer)
+		api.POST("/sessions/timer/resume", sessionHandler.ResumeTimer)
+		
+		// Play history import
+		api.POST("/import/retroarch", importHandler.ImportRetroArch)
+		api.POST("/import/steam", importHandler.ImportSteam)
+		api.POST("/import/ical", importHandler.ImportICalendar)
+		api.POST("/import/prices", importHandler.ImportPrices)
+		
 		// ROM Scanning
 		api.POST("/scan/directory", scannerHandler.ScanDirectory)
 		api.POST("/scan/metadata-batch", scannerHandler.UpdateMetadataBatch)
@@ -115,6 +208,14 @@ func (s *Server) setupRoutes() {
 		// Metadata
 		api.POST("/games/:id/fetch-metadata", gameHandler.FetchMetadata)
 		
+		// Images
+		api.GET("/images/:hash", imageHandler.ServeImage)
+		api.GET("/images/:hash/:size", imageHandler.ServeImage)
+		api.GET("/games/:id/images", imageHandler.GetGameImages)
+		api.POST("/games/:id/images", imageHandler.UploadGameImage)
+		api.POST("/games/:id/images/download", imageHandler.DownloadGameImages)
+		api.DELETE("/games/:id/images/:imageId", imageHandler.DeleteGameImage)
+		
 		// Completion tracking
 		api.PUT("/games/:id/completion", gameHandler.UpdateCompletionStatus)
 		api.GET("/games/stats/completion", gameHandler.GetCompletionStats)
@@ -132,6 +233,24 @@ func (s *Server) setupRoutes() {
 
 		// Statistics
 		api.GET("/stats", statsHandler.GetStats)
+		api.GET("/stats/backlog", statsHandler.GetBacklog)
+		api.GET("/stats/playtime", statsHandler.GetPlaytime)
+		api.GET("/stats/playtime/games", statsHandler.GetPlaytimeByGame)
+		api.GET("/stats/playtime/platforms", statsHandler.GetPlaytimeByPlatform)
+		api.GET("/stats/playtime/genres", statsHandler.GetPlaytimeByGenre)
+		api.GET("/stats/playtime/heatmap", statsHandler.GetPlaytimeHeatmap)
+		api.GET("/stats/year-in-review/:year", statsHandler.GetYearReview)
+		api.GET("/stats/value", statsHandler.GetCollectionValue)
+		api.GET("/stats/spending", statsHandler.GetSpending)
+		api.GET("/stats/cost-per-hour", statsHandler.GetCostPerHour)
+		
+		// Goals and streaks
+		api.GET("/goals", goalHandler.GetGoals)
+		api.GET("/goals/summary", goalHandler.GetGoalSummary)
+		api.GET("/goals/:id", goalHandler.GetGoal)
+		api.POST("/goals", goalHandler.CreateGoal)
+		api.PUT("/goals/:id", goalHandler.UpdateGoal)
+		api.DELETE("/goals/:id", goalHandler.DeleteGoal)
 		
 		// Backup/Restore
 		api.GET("/backup/export", backupHandler.ExportDatabase)
@@ -145,6 +264,7 @@ func (s *Server) setupRoutes() {
 		// Cache status
 		api.GET("/cache/stats", s.getCacheStats)
 		api.POST("/cache/clear", s.clearCache)
+		api.POST("/cache/metadata/clear", s.clearMetadataCache)
 		
 		// Health check
 		api.GET("/health", s.healthCheck)
@@ -184,6 +304,10 @@ func (s *Server) getCacheStats(c *gin.Context) {
 		"cache_stats": stats,
 		"cache_ttl": "30 minutes",
 		"cleanup_interval": "5 minutes",
+		"metadata_cache": gin.H{
+			"entries": s.metadata.ResponseCache().Count(),
+			"ttl":     s.config.MetadataCacheTTL.String(),
+		},
 	})
 }
 
@@ -196,6 +320,34 @@ func (s *Server) clearCache(c *gin.Context) {
 	})
 }
 
+// clearMetadataCache drops persisted metadata provider responses, or only expired ones with ?expired=true
+func (s *Server) clearMetadataCache(c *gin.Context) {
+	responseCache := s.metadata.ResponseCache()
+	
+	if c.Query("expired") == "true" {
+		removed, err := responseCache.PurgeExpired()
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Failed to purge metadata cache: " + err.Error()})
+			return
+		}
+		s.logger.LogWithContext(c, slog.LevelInfo, "metadata_cache_purged", slog.Int64("removed", removed))
+		c.JSON(200, gin.H{
+			"message": "Expired metadata cache entries removed",
+			"removed": removed,
+		})
+		return
+	}
+	
+	if err := responseCache.Clear(); err != nil {
+		c.JSON(500, gin.H{"error": "Failed to clear metadata cache: " + err.Error()})
+		return
+	}
+	s.logger.LogWithContext(c, slog.LevelInfo, "metadata_cache_cleared")
+	c.JSON(200, gin.H{
+		"message": "Metadata cache cleared successfully",
+	})
+}
+
 // healthCheck returns the health status of the application
 func (s *Server) healthCheck(c *gin.Context) {
 	// Test database connection
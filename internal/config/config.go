@@ -2,15 +2,51 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Port           string
 	DatabaseURL    string
 	ROMPaths       []string
+	
+	// Database connection pool (SQLite always uses a single connection)
+	DatabaseMaxOpenConns    int
+	DatabaseMaxIdleConns    int
+	DatabaseConnMaxLifetime time.Duration
 	TwitchClientID     string
 	TwitchClientSecret string
 	
+	// Metadata Configuration
+	MetadataCacheTTL time.Duration
+	
+	// ScreenScraper Configuration (hash-based ROM identification)
+	ScreenScraperDevID       string
+	ScreenScraperDevPassword string
+	ScreenScraperSoftware    string
+	ScreenScraperUser        string
+	ScreenScraperPassword    string
+	ScreenScraperRegions     []string
+	
+	// Play Session Configuration
+	SessionConflictPolicy string        // "reject" or "auto_close" an open session when another starts
+	SessionOverlapPolicy  string        // "reject" or "warn" about sessions overlapping existing ones
+	SessionMaxDuration    time.Duration // sessions open longer are ended automatically (0 disables)
+	
+	// Play History Import Configuration
+	RetroArchDir string // RetroArch config directory holding playlists/logs
+	SteamDir     string // Steam installation holding steamapps and userdata
+	SteamUserID  string // userdata account to read playtime from; empty reads all
+	
+	// Collection Value Configuration
+	Currency string // ISO 4217 code for prices given without one, and for value stats
+	
+	// Image Storage Configuration
+	ImageStoragePath  string
+	ImageAutoDownload bool
+	
 	// Backup Configuration
 	NextcloudURL      string
 	NextcloudUsername string
@@ -27,6 +63,39 @@ func Load() *Config {
 		TwitchClientID:     getEnv("TWITCH_CLIENT_ID", ""),
 		TwitchClientSecret: getEnv("TWITCH_CLIENT_SECRET", ""),
 		
+		// Database Connection Pool
+		DatabaseMaxOpenConns:    getEnvInt("DATABASE_MAX_OPEN_CONNS", 10),
+		DatabaseMaxIdleConns:    getEnvInt("DATABASE_MAX_IDLE_CONNS", 5),
+		DatabaseConnMaxLifetime: getEnvDuration("DATABASE_CONN_MAX_LIFETIME", time.Hour),
+		
+		// Metadata Configuration
+		MetadataCacheTTL: getEnvDuration("METADATA_CACHE_TTL", 7*24*time.Hour),
+		
+		// ScreenScraper Configuration
+		ScreenScraperDevID:       getEnv("SCREENSCRAPER_DEV_ID", ""),
+		ScreenScraperDevPassword: getEnv("SCREENSCRAPER_DEV_PASSWORD", ""),
+		ScreenScraperSoftware:    getEnv("SCREENSCRAPER_SOFTWARE", "pelico"),
+		ScreenScraperUser:        getEnv("SCREENSCRAPER_USER", ""),
+		ScreenScraperPassword:    getEnv("SCREENSCRAPER_PASSWORD", ""),
+		ScreenScraperRegions:     getEnvList("SCREENSCRAPER_REGIONS", []string{"us", "wor", "eu", "jp"}),
+		
+		// Play Session Configuration
+		SessionConflictPolicy: getEnv("SESSION_CONFLICT_POLICY", "reject"),
+		SessionOverlapPolicy:  getEnv("SESSION_OVERLAP_POLICY", "reject"),
+		SessionMaxDuration:    getEnvDuration("SESSION_MAX_DURATION", 12*time.Hour),
+		
+		// Play History Import Configuration
+		RetroArchDir: getEnv("RETROARCH_DIR", ""),
+		SteamDir:     getEnv("STEAM_DIR", ""),
+		SteamUserID:  getEnv("STEAM_USER_ID", ""),
+		
+		// Collection Value Configuration
+		Currency: strings.ToUpper(getEnv("CURRENCY", "USD")),
+		
+		// Image Storage Configuration
+		ImageStoragePath:  getEnv("IMAGE_STORAGE_PATH", "./data/images"),
+		ImageAutoDownload: getEnv("IMAGE_AUTO_DOWNLOAD", "true") == "true",
+		
 		// Backup Configuration
 		NextcloudURL:      getEnv("NEXTCLOUD_URL", ""),
 		NextcloudUsername: getEnv("NEXTCLOUD_USERNAME", ""),
@@ -42,4 +111,42 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvDuration parses a Go duration string (e.g. "72h", "30m"); "0" disables the feature using it
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvInt parses a whole number, falling back to the default when unset or invalid
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList parses a comma-separated list, ignoring blank entries
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return defaultValue
+	}
+	return items
+}
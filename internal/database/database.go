@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Supported dialects, as reported by gorm's Dialector.Name()
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// PoolOptions configures the connection pool. Zero values keep database/sql's
+// defaults; SQLite always uses a single connection.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Connect opens the database named by databaseURL: PostgreSQL for
+// postgres:// URLs and key=value DSNs, SQLite for sqlite:// URLs, file: URIs,
+// :memory: and plain paths
+func Connect(databaseURL string, pool PoolOptions) (*gorm.DB, error) {
+	dialect, dsn, err := ParseURL(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialector gorm.Dialector
+	switch dialect {
+	case DialectPostgres:
+		dialector = postgres.Open(dsn)
+	case DialectSQLite:
+		dialector = sqlite.Open(dsn)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", dialect, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if dialect == DialectSQLite {
+		// One writer at a time, and every caller sees the same :memory: database
+		sqlDB.SetMaxOpenConns(1)
+		return db, nil
+	}
+	if pool.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	return db, nil
+}
+
+// ParseURL picks the dialect for a DATABASE_URL and returns the DSN its driver expects
+func ParseURL(databaseURL string) (dialect, dsn string, err error) {
+	databaseURL = strings.TrimSpace(databaseURL)
+	lower := strings.ToLower(databaseURL)
+	switch {
+	case databaseURL == "":
+		return "", "", fmt.Errorf("database URL is empty")
+	case strings.HasPrefix(lower, "postgres://"), strings.HasPrefix(lower, "postgresql://"):
+		return DialectPostgres, databaseURL, nil
+	case strings.HasPrefix(lower, "sqlite://"), strings.HasPrefix(lower, "sqlite3://"):
+		return DialectSQLite, sqliteDSN(databaseURL[strings.Index(databaseURL, "://")+3:]), nil
+	case strings.HasPrefix(lower, "sqlite:"):
+		return DialectSQLite, sqliteDSN(databaseURL[len("sqlite:"):]), nil
+	case lower == ":memory:", strings.HasPrefix(lower, "file:"):
+		return DialectSQLite, sqliteDSN(databaseURL), nil
+	case strings.Contains(lower, "://"):
+		return "", "", fmt.Errorf("unsupported database URL scheme in %q", redactURL(databaseURL))
+	case strings.Contains(lower, "host=") || strings.Contains(lower, "dbname="):
+		return DialectPostgres, databaseURL, nil
+	}
+	return DialectSQLite, sqliteDSN(databaseURL), nil
+}
+
+// sqliteDSN adds a busy timeout and WAL journaling to file databases, so
+// readers don't block the writer and short lock waits don't fail requests
+func sqliteDSN(path string) string {
+	if path == ":memory:" || strings.Contains(path, "mode=memory") {
+		return path
+	}
+	params := []string{}
+	if !strings.Contains(path, "_busy_timeout") {
+		params = append(params, "_busy_timeout=5000")
+	}
+	if !strings.Contains(path, "_journal_mode") {
+		params = append(params, "_journal_mode=WAL")
+	}
+	if len(params) == 0 {
+		return path
+	}
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + strings.Join(params, "&")
+}
+
+// redactURL drops the password from a URL for error messages
+func redactURL(databaseURL string) string {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return "(unparseable URL)"
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		dialect string
+		dsn     string
+	}{
+		{"postgres://pelico:secret@db/pelico?sslmode=disable", DialectPostgres, "postgres://pelico:secret@db/pelico?sslmode=disable"},
+		{"postgresql://db/pelico", DialectPostgres, "postgresql://db/pelico"},
+		{"host=db user=pelico dbname=pelico", DialectPostgres, "host=db user=pelico dbname=pelico"},
+		{"sqlite:///var/lib/pelico/pelico.db", DialectSQLite, "/var/lib/pelico/pelico.db?_busy_timeout=5000&_journal_mode=WAL"},
+		{"sqlite://pelico.db?_journal_mode=DELETE", DialectSQLite, "pelico.db?_journal_mode=DELETE&_busy_timeout=5000"},
+		{"sqlite:data/pelico.db", DialectSQLite, "data/pelico.db?_busy_timeout=5000&_journal_mode=WAL"},
+		{"file:pelico.db?cache=shared", DialectSQLite, "file:pelico.db?cache=shared&_busy_timeout=5000&_journal_mode=WAL"},
+		{"./pelico.db", DialectSQLite, "./pelico.db?_busy_timeout=5000&_journal_mode=WAL"},
+		{":memory:", DialectSQLite, ":memory:"},
+	}
+	for _, tt := range tests {
+		dialect, dsn, err := ParseURL(tt.url)
+		require.NoError(t, err, tt.url)
+		assert.Equal(t, tt.dialect, dialect, tt.url)
+		assert.Equal(t, tt.dsn, dsn, tt.url)
+	}
+
+	_, _, err := ParseURL("")
+	assert.Error(t, err)
+	_, _, err = ParseURL("mysql://root:hunter2@db/pelico")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "hunter2")
+}
+
+func TestMigrations_SameVersionsOnEveryDialect(t *testing.T) {
+	sqlite, err := Migrations(DialectSQLite)
+	require.NoError(t, err)
+	postgres, err := Migrations(DialectPostgres)
+	require.NoError(t, err)
+	require.NotEmpty(t, sqlite)
+	require.Equal(t, len(sqlite), len(postgres))
+	for i := range sqlite {
+		assert.Equal(t, sqlite[i].Version, postgres[i].Version)
+		assert.Equal(t, sqlite[i].Name, postgres[i].Name)
+	}
+}
+
+func TestMigrateDB(t *testing.T) {
+	db, err := Connect(filepath.Join(t.TempDir(), "pelico.db"), PoolOptions{})
+	require.NoError(t, err)
+	migrations, err := Migrations(DialectSQLite)
+	require.NoError(t, err)
+
+	require.NoError(t, MigrateDB(db))
+	// Re-running is a no-op
+	require.NoError(t, MigrateDB(db))
+
+	// The migrated schema has a table and column for every model field
+	for _, model := range models.All() {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+		require.True(t, db.Migrator().HasTable(stmt.Schema.Table), stmt.Schema.Table)
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+		for _, relation := range stmt.Schema.Relationships.Relations {
+			if relation.JoinTable != nil {
+				assert.True(t, db.Migrator().HasTable(relation.JoinTable.Table), relation.JoinTable.Table)
+			}
+		}
+	}
+
+	assert.True(t, db.Migrator().HasTable("game_search_index"))
+
+	status, err := Status(db)
+	require.NoError(t, err)
+	require.Len(t, status, len(migrations))
+	for _, migration := range status {
+		assert.NotNil(t, migration.AppliedAt, migration.Name)
+	}
+
+	// Legacy genre strings are linked on migrate
+	require.NoError(t, db.Create(&models.Platform{Name: "SNES"}).Error)
+	require.NoError(t, db.Create(&models.Game{Title: "Secret of Mana", PlatformID: 1, Genre: "RPG, Action"}).Error)
+	require.NoError(t, MigrateDB(db))
+	var genres int64
+	db.Table("game_genres").Count(&genres)
+	assert.Equal(t, int64(2), genres)
+
+	// Rolling everything back leaves only the bookkeeping table
+	require.NoError(t, Rollback(db, len(migrations)))
+	tables, err := db.Migrator().GetTables()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"schema_migrations"}, withoutSQLiteInternal(tables))
+	status, err = Status(db)
+	require.NoError(t, err)
+	for _, migration := range status {
+		assert.Nil(t, migration.AppliedAt, migration.Name)
+	}
+
+	require.NoError(t, MigrateDB(db))
+	assert.True(t, db.Migrator().HasTable("games"))
+}
+
+// baselineSchema is what AutoMigrate created on SQLite before versioned
+// migrations, when the tables had fewer columns than they do now
+const baselineSchema = `
+CREATE TABLE platforms (id integer PRIMARY KEY AUTOINCREMENT, name text NOT NULL, manufacturer text, release_year integer,
+    created_at datetime, updated_at datetime, CONSTRAINT uni_platforms_name UNIQUE (name));
+CREATE TABLE games (id integer PRIMARY KEY AUTOINCREMENT, title text NOT NULL, platform_id integer, year integer, genre text,
+    rating real, description text, cover_art_url text, box_art_url text, purchase_date datetime, igdb_id integer,
+    collection_formats json, completion_status text DEFAULT 'not_started', completion_date datetime,
+    completion_percentage integer DEFAULT 0, completion_notes text, created_at datetime, updated_at datetime,
+    CONSTRAINT fk_games_platform FOREIGN KEY (platform_id) REFERENCES platforms(id));
+CREATE TABLE file_locations (id integer PRIMARY KEY AUTOINCREMENT, game_id integer, server_location text, file_path text NOT NULL,
+    file_size integer, file_hash text, created_at datetime, updated_at datetime,
+    CONSTRAINT fk_games_file_locations FOREIGN KEY (game_id) REFERENCES games(id));
+CREATE TABLE play_sessions (id integer PRIMARY KEY AUTOINCREMENT, game_id integer, start_time datetime NOT NULL, end_time datetime,
+    duration integer, notes text, rating integer, created_at datetime, updated_at datetime,
+    CONSTRAINT fk_games_play_sessions FOREIGN KEY (game_id) REFERENCES games(id));
+CREATE TABLE wishlists (id integer PRIMARY KEY AUTOINCREMENT, game_id integer, added_at datetime,
+    CONSTRAINT fk_wishlists_game FOREIGN KEY (game_id) REFERENCES games(id));
+CREATE TABLE shortlists (id integer PRIMARY KEY AUTOINCREMENT, game_id integer, added_at datetime,
+    CONSTRAINT fk_shortlists_game FOREIGN KEY (game_id) REFERENCES games(id));
+INSERT INTO platforms (name) VALUES ('PC');
+INSERT INTO games (title, platform_id, genre) VALUES ('Doom', 1, 'Shooter');
+INSERT INTO play_sessions (game_id, start_time, end_time, duration) VALUES (1, '2020-01-01 20:00:00', '2020-01-01 21:00:00', 60);
+`
+
+func TestMigrateDB_AdoptsAutoMigratedSchema(t *testing.T) {
+	db, err := Connect(":memory:", PoolOptions{})
+	require.NoError(t, err)
+	require.NoError(t, execStatements(db, baselineSchema))
+
+	require.NoError(t, MigrateDB(db))
+	for table, columns := range map[string][]string{
+		"platforms":      {"igdb_id", "the_games_db_id", "rawg_id", "screen_scraper_id"},
+		"games":          {"screen_scraper_id", "steam_app_id"},
+		"file_locations": {"file_crc", "file_sha1"},
+		"play_sessions":  {"paused_at", "paused_seconds", "auto_ended", "source"},
+	} {
+		for _, column := range columns {
+			assert.True(t, db.Migrator().HasColumn(table, column), "%s.%s", table, column)
+		}
+	}
+	assert.True(t, db.Migrator().HasIndex("games", "idx_games_steam_app_id"))
+
+	var game models.Game
+	require.NoError(t, db.Preload("Genres").First(&game).Error)
+	assert.Equal(t, "Doom", game.Title)
+	require.Len(t, game.Genres, 1)
+	assert.Equal(t, "Shooter", game.Genres[0].Name)
+	var session models.PlaySession
+	require.NoError(t, db.First(&session).Error)
+	assert.Equal(t, 60, session.Duration)
+	require.NoError(t, db.Create(&models.PlaySession{GameID: game.ID, StartTime: session.StartTime, Source: "steam"}).Error)
+
+	// Running again is a no-op
+	require.NoError(t, MigrateDB(db))
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := "-- comment\nCREATE TABLE a (\n    id integer\n);\n\nCREATE INDEX i ON a (id);\nDROP TABLE b"
+	assert.Equal(t, []string{"CREATE TABLE a (\n    id integer\n);", "CREATE INDEX i ON a (id);", "DROP TABLE b"}, splitStatements(script))
+}
+
+func withoutSQLiteInternal(tables []string) []string {
+	var user []string
+	for _, table := range tables {
+		if table != "sqlite_sequence" {
+			user = append(user, table)
+		}
+	}
+	return user
+}
@@ -0,0 +1,347 @@
+-- Initial schema, matching what GORM AutoMigrate created before versioned
+-- migrations. IF NOT EXISTS lets databases created that way adopt migrations,
+-- and the ALTER TABLE steps add columns that older releases did not have yet.
+
+CREATE TABLE IF NOT EXISTS platforms (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    manufacturer text,
+    release_year bigint,
+    igdb_id bigint,
+    the_games_db_id bigint,
+    rawg_id bigint,
+    screen_scraper_id bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT uni_platforms_name UNIQUE (name)
+);
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS igdb_id bigint;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS the_games_db_id bigint;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS rawg_id bigint;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS screen_scraper_id bigint;
+CREATE INDEX IF NOT EXISTS idx_platforms_igdb_id ON platforms (igdb_id);
+
+CREATE TABLE IF NOT EXISTS games (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    platform_id bigint,
+    year bigint,
+    genre text,
+    rating double precision,
+    description text,
+    cover_art_url text,
+    box_art_url text,
+    purchase_date timestamptz,
+    igdb_id bigint,
+    screen_scraper_id bigint,
+    steam_app_id bigint,
+    collection_formats json,
+    completion_status text DEFAULT 'not_started',
+    completion_date timestamptz,
+    completion_percentage bigint DEFAULT 0,
+    completion_notes text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_games_platform FOREIGN KEY (platform_id) REFERENCES platforms(id)
+);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS screen_scraper_id bigint;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS steam_app_id bigint;
+CREATE INDEX IF NOT EXISTS idx_games_steam_app_id ON games (steam_app_id);
+
+CREATE TABLE IF NOT EXISTS genres (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_name ON genres (name);
+
+CREATE TABLE IF NOT EXISTS game_genres (
+    game_id bigint,
+    genre_id bigint,
+    PRIMARY KEY (game_id, genre_id),
+    CONSTRAINT fk_game_genres_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_genres_genre FOREIGN KEY (genre_id) REFERENCES genres(id)
+);
+
+CREATE TABLE IF NOT EXISTS franchises (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_franchises_name ON franchises (name);
+
+CREATE TABLE IF NOT EXISTS game_franchises (
+    game_id bigint,
+    franchise_id bigint,
+    PRIMARY KEY (game_id, franchise_id),
+    CONSTRAINT fk_game_franchises_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_franchises_franchise FOREIGN KEY (franchise_id) REFERENCES franchises(id)
+);
+
+CREATE TABLE IF NOT EXISTS player_perspectives (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_player_perspectives_name ON player_perspectives (name);
+
+CREATE TABLE IF NOT EXISTS game_player_perspectives (
+    game_id bigint,
+    player_perspective_id bigint,
+    PRIMARY KEY (game_id, player_perspective_id),
+    CONSTRAINT fk_game_player_perspectives_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_player_perspectives_player_perspective FOREIGN KEY (player_perspective_id) REFERENCES player_perspectives(id)
+);
+
+CREATE TABLE IF NOT EXISTS themes (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_themes_name ON themes (name);
+
+CREATE TABLE IF NOT EXISTS game_themes (
+    game_id bigint,
+    theme_id bigint,
+    PRIMARY KEY (game_id, theme_id),
+    CONSTRAINT fk_game_themes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_themes_theme FOREIGN KEY (theme_id) REFERENCES themes(id)
+);
+
+CREATE TABLE IF NOT EXISTS game_modes (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_modes_name ON game_modes (name);
+
+CREATE TABLE IF NOT EXISTS game_game_modes (
+    game_id bigint,
+    game_mode_id bigint,
+    PRIMARY KEY (game_id, game_mode_id),
+    CONSTRAINT fk_game_game_modes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_game_modes_game_mode FOREIGN KEY (game_mode_id) REFERENCES game_modes(id)
+);
+
+CREATE TABLE IF NOT EXISTS file_locations (
+    id bigserial PRIMARY KEY,
+    game_id bigint,
+    server_location text,
+    file_path text NOT NULL,
+    file_size bigint,
+    file_hash text,
+    file_crc text,
+    file_sha1 text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_games_file_locations FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+ALTER TABLE file_locations ADD COLUMN IF NOT EXISTS file_crc text;
+ALTER TABLE file_locations ADD COLUMN IF NOT EXISTS file_sha1 text;
+
+CREATE TABLE IF NOT EXISTS play_sessions (
+    id bigserial PRIMARY KEY,
+    game_id bigint,
+    start_time timestamptz NOT NULL,
+    end_time timestamptz,
+    duration bigint,
+    notes text,
+    rating bigint,
+    paused_at timestamptz,
+    paused_seconds bigint,
+    auto_ended boolean,
+    source text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_games_play_sessions FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS paused_at timestamptz;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS paused_seconds bigint;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS auto_ended boolean;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS source text;
+CREATE INDEX IF NOT EXISTS idx_play_sessions_source ON play_sessions (source);
+
+CREATE TABLE IF NOT EXISTS wishlists (
+    id bigserial PRIMARY KEY,
+    game_id bigint,
+    added_at timestamptz,
+    CONSTRAINT fk_wishlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shortlists (
+    id bigserial PRIMARY KEY,
+    game_id bigint,
+    added_at timestamptz,
+    CONSTRAINT fk_shortlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS metadata_cache_entries (
+    id bigserial PRIMARY KEY,
+    provider text NOT NULL,
+    query text NOT NULL,
+    platform text NOT NULL DEFAULT '',
+    response text,
+    expires_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_metadata_cache_entries_expires_at ON metadata_cache_entries (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_metadata_cache_key ON metadata_cache_entries (provider, query, platform);
+
+CREATE TABLE IF NOT EXISTS game_images (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    kind text NOT NULL,
+    hash text NOT NULL,
+    source_url text,
+    content_type text,
+    width bigint,
+    height bigint,
+    file_size bigint,
+    custom boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_games_images FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_game_images_hash ON game_images (hash);
+CREATE INDEX IF NOT EXISTS idx_game_images_game_id ON game_images (game_id);
+
+CREATE TABLE IF NOT EXISTS companies (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_companies_name ON companies (name);
+
+CREATE TABLE IF NOT EXISTS game_companies (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    company_id bigint NOT NULL,
+    role text NOT NULL,
+    CONSTRAINT fk_game_companies_company FOREIGN KEY (company_id) REFERENCES companies(id),
+    CONSTRAINT fk_games_companies FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_company_role ON game_companies (game_id, company_id, role);
+
+CREATE TABLE IF NOT EXISTS game_media (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    kind text NOT NULL,
+    url text NOT NULL,
+    title text,
+    created_at timestamptz,
+    CONSTRAINT fk_games_media FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_game_media_game_id ON game_media (game_id);
+
+CREATE TABLE IF NOT EXISTS alternative_names (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    name text NOT NULL,
+    comment text,
+    CONSTRAINT fk_games_alternative_names FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_alternative_names_game_id ON alternative_names (game_id);
+
+CREATE TABLE IF NOT EXISTS release_dates (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    region text NOT NULL,
+    date timestamptz,
+    human text,
+    platform_igdb_id bigint,
+    CONSTRAINT fk_games_release_dates FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_release_dates_date ON release_dates (date);
+CREATE INDEX IF NOT EXISTS idx_release_dates_region ON release_dates (region);
+CREATE INDEX IF NOT EXISTS idx_release_dates_game_id ON release_dates (game_id);
+
+CREATE TABLE IF NOT EXISTS age_ratings (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    board text NOT NULL,
+    rating text NOT NULL,
+    minimum_age bigint,
+    CONSTRAINT fk_games_age_ratings FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_age_rating_board_rating ON age_ratings (board, rating);
+CREATE INDEX IF NOT EXISTS idx_age_ratings_game_id ON age_ratings (game_id);
+
+CREATE TABLE IF NOT EXISTS time_to_beats (
+    id bigserial PRIMARY KEY,
+    game_id bigint NOT NULL,
+    hastily_minutes bigint,
+    normally_minutes bigint,
+    completely_minutes bigint,
+    submission_count bigint,
+    updated_at timestamptz,
+    CONSTRAINT fk_games_time_to_beat FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_to_beats_game_id ON time_to_beats (game_id);
+
+CREATE TABLE IF NOT EXISTS imported_playtimes (
+    id bigserial PRIMARY KEY,
+    game_id bigint,
+    source text NOT NULL,
+    external_key text NOT NULL,
+    total_seconds bigint,
+    last_played timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_imported_playtime_source_key ON imported_playtimes (source, external_key);
+CREATE INDEX IF NOT EXISTS idx_imported_playtimes_game_id ON imported_playtimes (game_id);
+
+CREATE TABLE IF NOT EXISTS goals (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    kind text NOT NULL,
+    target double precision,
+    period text NOT NULL,
+    start_date text,
+    end_date text,
+    game_id bigint,
+    platform_id bigint,
+    archived boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_goals_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE SET NULL,
+    CONSTRAINT fk_goals_platform FOREIGN KEY (platform_id) REFERENCES platforms(id)
+);
+CREATE INDEX IF NOT EXISTS idx_goals_game_id ON goals (game_id);
+
+-- Databases adopted from AutoMigrate have these foreign keys without ON
+-- DELETE, so deleting a game would fail; declare them again
+ALTER TABLE game_genres DROP CONSTRAINT IF EXISTS fk_game_genres_game;
+ALTER TABLE game_genres ADD CONSTRAINT fk_game_genres_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_franchises DROP CONSTRAINT IF EXISTS fk_game_franchises_game;
+ALTER TABLE game_franchises ADD CONSTRAINT fk_game_franchises_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_player_perspectives DROP CONSTRAINT IF EXISTS fk_game_player_perspectives_game;
+ALTER TABLE game_player_perspectives ADD CONSTRAINT fk_game_player_perspectives_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_themes DROP CONSTRAINT IF EXISTS fk_game_themes_game;
+ALTER TABLE game_themes ADD CONSTRAINT fk_game_themes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_game_modes DROP CONSTRAINT IF EXISTS fk_game_game_modes_game;
+ALTER TABLE game_game_modes ADD CONSTRAINT fk_game_game_modes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE file_locations DROP CONSTRAINT IF EXISTS fk_games_file_locations;
+ALTER TABLE file_locations ADD CONSTRAINT fk_games_file_locations FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE play_sessions DROP CONSTRAINT IF EXISTS fk_games_play_sessions;
+ALTER TABLE play_sessions ADD CONSTRAINT fk_games_play_sessions FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE wishlists DROP CONSTRAINT IF EXISTS fk_wishlists_game;
+ALTER TABLE wishlists ADD CONSTRAINT fk_wishlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE shortlists DROP CONSTRAINT IF EXISTS fk_shortlists_game;
+ALTER TABLE shortlists ADD CONSTRAINT fk_shortlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_images DROP CONSTRAINT IF EXISTS fk_games_images;
+ALTER TABLE game_images ADD CONSTRAINT fk_games_images FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_companies DROP CONSTRAINT IF EXISTS fk_games_companies;
+ALTER TABLE game_companies ADD CONSTRAINT fk_games_companies FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE game_media DROP CONSTRAINT IF EXISTS fk_games_media;
+ALTER TABLE game_media ADD CONSTRAINT fk_games_media FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE alternative_names DROP CONSTRAINT IF EXISTS fk_games_alternative_names;
+ALTER TABLE alternative_names ADD CONSTRAINT fk_games_alternative_names FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE release_dates DROP CONSTRAINT IF EXISTS fk_games_release_dates;
+ALTER TABLE release_dates ADD CONSTRAINT fk_games_release_dates FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE age_ratings DROP CONSTRAINT IF EXISTS fk_games_age_ratings;
+ALTER TABLE age_ratings ADD CONSTRAINT fk_games_age_ratings FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE time_to_beats DROP CONSTRAINT IF EXISTS fk_games_time_to_beat;
+ALTER TABLE time_to_beats ADD CONSTRAINT fk_games_time_to_beat FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE;
+ALTER TABLE goals DROP CONSTRAINT IF EXISTS fk_goals_game;
+ALTER TABLE goals ADD CONSTRAINT fk_goals_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE SET NULL;
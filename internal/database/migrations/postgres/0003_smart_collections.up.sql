@@ -0,0 +1,3 @@
+-- Smart collections keep filter rules, as JSON, instead of items
+
+ALTER TABLE collections ADD COLUMN rules json;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS physical_copies;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS price_points;
+ALTER TABLE physical_copies DROP COLUMN currency;
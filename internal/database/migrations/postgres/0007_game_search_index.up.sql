@@ -0,0 +1,19 @@
+-- Full-text search index over games (see services.GameSearch), weighted by
+-- field: titles A, description B, completion notes C, session notes D
+
+CREATE TABLE IF NOT EXISTS game_search_index (
+    game_id bigint PRIMARY KEY,
+    title text NOT NULL DEFAULT '',
+    alt_titles text NOT NULL DEFAULT '',
+    description text NOT NULL DEFAULT '',
+    completion_notes text NOT NULL DEFAULT '',
+    session_notes text NOT NULL DEFAULT '',
+    document tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', alt_titles), 'A') ||
+        setweight(to_tsvector('simple', description), 'B') ||
+        setweight(to_tsvector('simple', completion_notes), 'C') ||
+        setweight(to_tsvector('simple', session_notes), 'D')
+    ) STORED
+);
+CREATE INDEX IF NOT EXISTS idx_game_search_document ON game_search_index USING GIN (document);
@@ -0,0 +1,27 @@
+DROP TABLE IF EXISTS goals;
+DROP TABLE IF EXISTS imported_playtimes;
+DROP TABLE IF EXISTS time_to_beats;
+DROP TABLE IF EXISTS age_ratings;
+DROP TABLE IF EXISTS release_dates;
+DROP TABLE IF EXISTS alternative_names;
+DROP TABLE IF EXISTS game_media;
+DROP TABLE IF EXISTS game_companies;
+DROP TABLE IF EXISTS companies;
+DROP TABLE IF EXISTS game_images;
+DROP TABLE IF EXISTS metadata_cache_entries;
+DROP TABLE IF EXISTS shortlists;
+DROP TABLE IF EXISTS wishlists;
+DROP TABLE IF EXISTS play_sessions;
+DROP TABLE IF EXISTS file_locations;
+DROP TABLE IF EXISTS game_game_modes;
+DROP TABLE IF EXISTS game_modes;
+DROP TABLE IF EXISTS game_themes;
+DROP TABLE IF EXISTS themes;
+DROP TABLE IF EXISTS game_player_perspectives;
+DROP TABLE IF EXISTS player_perspectives;
+DROP TABLE IF EXISTS game_franchises;
+DROP TABLE IF EXISTS franchises;
+DROP TABLE IF EXISTS game_genres;
+DROP TABLE IF EXISTS genres;
+DROP TABLE IF EXISTS games;
+DROP TABLE IF EXISTS platforms;
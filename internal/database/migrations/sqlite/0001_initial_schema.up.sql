@@ -0,0 +1,310 @@
+-- Initial schema, matching what GORM AutoMigrate created before versioned
+-- migrations. IF NOT EXISTS lets databases created that way adopt migrations,
+-- and the ALTER TABLE steps add columns that older releases did not have yet.
+
+CREATE TABLE IF NOT EXISTS platforms (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    manufacturer text,
+    release_year integer,
+    igdb_id integer,
+    the_games_db_id integer,
+    rawg_id integer,
+    screen_scraper_id integer,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT uni_platforms_name UNIQUE (name)
+);
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS igdb_id integer;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS the_games_db_id integer;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS rawg_id integer;
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS screen_scraper_id integer;
+CREATE INDEX IF NOT EXISTS idx_platforms_igdb_id ON platforms (igdb_id);
+
+CREATE TABLE IF NOT EXISTS games (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title text NOT NULL,
+    platform_id integer,
+    year integer,
+    genre text,
+    rating real,
+    description text,
+    cover_art_url text,
+    box_art_url text,
+    purchase_date datetime,
+    igdb_id integer,
+    screen_scraper_id integer,
+    steam_app_id integer,
+    collection_formats json,
+    completion_status text DEFAULT 'not_started',
+    completion_date datetime,
+    completion_percentage integer DEFAULT 0,
+    completion_notes text,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_games_platform FOREIGN KEY (platform_id) REFERENCES platforms(id)
+);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS screen_scraper_id integer;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS steam_app_id integer;
+CREATE INDEX IF NOT EXISTS idx_games_steam_app_id ON games (steam_app_id);
+
+CREATE TABLE IF NOT EXISTS genres (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_name ON genres (name);
+
+CREATE TABLE IF NOT EXISTS game_genres (
+    game_id integer,
+    genre_id integer,
+    PRIMARY KEY (game_id, genre_id),
+    CONSTRAINT fk_game_genres_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_genres_genre FOREIGN KEY (genre_id) REFERENCES genres(id)
+);
+
+CREATE TABLE IF NOT EXISTS franchises (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_franchises_name ON franchises (name);
+
+CREATE TABLE IF NOT EXISTS game_franchises (
+    game_id integer,
+    franchise_id integer,
+    PRIMARY KEY (game_id, franchise_id),
+    CONSTRAINT fk_game_franchises_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_franchises_franchise FOREIGN KEY (franchise_id) REFERENCES franchises(id)
+);
+
+CREATE TABLE IF NOT EXISTS player_perspectives (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_player_perspectives_name ON player_perspectives (name);
+
+CREATE TABLE IF NOT EXISTS game_player_perspectives (
+    game_id integer,
+    player_perspective_id integer,
+    PRIMARY KEY (game_id, player_perspective_id),
+    CONSTRAINT fk_game_player_perspectives_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_player_perspectives_player_perspective FOREIGN KEY (player_perspective_id) REFERENCES player_perspectives(id)
+);
+
+CREATE TABLE IF NOT EXISTS themes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_themes_name ON themes (name);
+
+CREATE TABLE IF NOT EXISTS game_themes (
+    game_id integer,
+    theme_id integer,
+    PRIMARY KEY (game_id, theme_id),
+    CONSTRAINT fk_game_themes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_themes_theme FOREIGN KEY (theme_id) REFERENCES themes(id)
+);
+
+CREATE TABLE IF NOT EXISTS game_modes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_modes_name ON game_modes (name);
+
+CREATE TABLE IF NOT EXISTS game_game_modes (
+    game_id integer,
+    game_mode_id integer,
+    PRIMARY KEY (game_id, game_mode_id),
+    CONSTRAINT fk_game_game_modes_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_game_modes_game_mode FOREIGN KEY (game_mode_id) REFERENCES game_modes(id)
+);
+
+CREATE TABLE IF NOT EXISTS file_locations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer,
+    server_location text,
+    file_path text NOT NULL,
+    file_size integer,
+    file_hash text,
+    file_crc text,
+    file_sha1 text,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_games_file_locations FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+ALTER TABLE file_locations ADD COLUMN IF NOT EXISTS file_crc text;
+ALTER TABLE file_locations ADD COLUMN IF NOT EXISTS file_sha1 text;
+
+CREATE TABLE IF NOT EXISTS play_sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer,
+    start_time datetime NOT NULL,
+    end_time datetime,
+    duration integer,
+    notes text,
+    rating integer,
+    paused_at datetime,
+    paused_seconds integer,
+    auto_ended numeric,
+    source text,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_games_play_sessions FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS paused_at datetime;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS paused_seconds integer;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS auto_ended numeric;
+ALTER TABLE play_sessions ADD COLUMN IF NOT EXISTS source text;
+CREATE INDEX IF NOT EXISTS idx_play_sessions_source ON play_sessions (source);
+
+CREATE TABLE IF NOT EXISTS wishlists (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer,
+    added_at datetime,
+    CONSTRAINT fk_wishlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shortlists (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer,
+    added_at datetime,
+    CONSTRAINT fk_shortlists_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS metadata_cache_entries (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    provider text NOT NULL,
+    query text NOT NULL,
+    platform text NOT NULL DEFAULT '',
+    response text,
+    expires_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_metadata_cache_entries_expires_at ON metadata_cache_entries (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_metadata_cache_key ON metadata_cache_entries (provider, query, platform);
+
+CREATE TABLE IF NOT EXISTS game_images (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    kind text NOT NULL,
+    hash text NOT NULL,
+    source_url text,
+    content_type text,
+    width integer,
+    height integer,
+    file_size integer,
+    custom numeric DEFAULT false,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_games_images FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_game_images_hash ON game_images (hash);
+CREATE INDEX IF NOT EXISTS idx_game_images_game_id ON game_images (game_id);
+
+CREATE TABLE IF NOT EXISTS companies (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_companies_name ON companies (name);
+
+CREATE TABLE IF NOT EXISTS game_companies (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    company_id integer NOT NULL,
+    role text NOT NULL,
+    CONSTRAINT fk_game_companies_company FOREIGN KEY (company_id) REFERENCES companies(id),
+    CONSTRAINT fk_games_companies FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_company_role ON game_companies (game_id, company_id, role);
+
+CREATE TABLE IF NOT EXISTS game_media (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    kind text NOT NULL,
+    url text NOT NULL,
+    title text,
+    created_at datetime,
+    CONSTRAINT fk_games_media FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_game_media_game_id ON game_media (game_id);
+
+CREATE TABLE IF NOT EXISTS alternative_names (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    name text NOT NULL,
+    comment text,
+    CONSTRAINT fk_games_alternative_names FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_alternative_names_game_id ON alternative_names (game_id);
+
+CREATE TABLE IF NOT EXISTS release_dates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    region text NOT NULL,
+    date datetime,
+    human text,
+    platform_igdb_id integer,
+    CONSTRAINT fk_games_release_dates FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_release_dates_date ON release_dates (date);
+CREATE INDEX IF NOT EXISTS idx_release_dates_region ON release_dates (region);
+CREATE INDEX IF NOT EXISTS idx_release_dates_game_id ON release_dates (game_id);
+
+CREATE TABLE IF NOT EXISTS age_ratings (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    board text NOT NULL,
+    rating text NOT NULL,
+    minimum_age integer,
+    CONSTRAINT fk_games_age_ratings FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_age_rating_board_rating ON age_ratings (board, rating);
+CREATE INDEX IF NOT EXISTS idx_age_ratings_game_id ON age_ratings (game_id);
+
+CREATE TABLE IF NOT EXISTS time_to_beats (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    hastily_minutes integer,
+    normally_minutes integer,
+    completely_minutes integer,
+    submission_count integer,
+    updated_at datetime,
+    CONSTRAINT fk_games_time_to_beat FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_to_beats_game_id ON time_to_beats (game_id);
+
+CREATE TABLE IF NOT EXISTS imported_playtimes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer,
+    source text NOT NULL,
+    external_key text NOT NULL,
+    total_seconds integer,
+    last_played datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_imported_playtime_source_key ON imported_playtimes (source, external_key);
+CREATE INDEX IF NOT EXISTS idx_imported_playtimes_game_id ON imported_playtimes (game_id);
+
+CREATE TABLE IF NOT EXISTS goals (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title text NOT NULL,
+    kind text NOT NULL,
+    target real,
+    period text NOT NULL,
+    start_date text,
+    end_date text,
+    game_id integer,
+    platform_id integer,
+    archived numeric DEFAULT false,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_goals_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE SET NULL,
+    CONSTRAINT fk_goals_platform FOREIGN KEY (platform_id) REFERENCES platforms(id)
+);
+CREATE INDEX IF NOT EXISTS idx_goals_game_id ON goals (game_id);
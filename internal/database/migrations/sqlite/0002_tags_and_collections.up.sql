@@ -0,0 +1,41 @@
+-- Tags on games and user-curated, ordered collections of games
+
+CREATE TABLE IF NOT EXISTS tags (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    color text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+
+CREATE TABLE IF NOT EXISTS game_tags (
+    game_id integer,
+    tag_id integer,
+    PRIMARY KEY (game_id, tag_id),
+    CONSTRAINT fk_game_tags_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_tags_tag FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_game_tags_tag_id ON game_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    description text,
+    cover_url text,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collections_name ON collections (name);
+
+CREATE TABLE IF NOT EXISTS collection_items (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    collection_id integer NOT NULL,
+    game_id integer NOT NULL,
+    position integer,
+    added_at datetime,
+    CONSTRAINT fk_collections_items FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    CONSTRAINT fk_collection_items_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_game ON collection_items (collection_id, game_id);
+CREATE INDEX IF NOT EXISTS idx_collection_items_game_id ON collection_items (game_id);
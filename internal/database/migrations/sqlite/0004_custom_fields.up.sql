@@ -0,0 +1,26 @@
+-- User-defined custom fields and their typed values on games
+
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    key text NOT NULL,
+    name text NOT NULL,
+    type text NOT NULL,
+    options json,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_fields_key ON custom_fields (key);
+
+CREATE TABLE IF NOT EXISTS game_custom_values (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    field_id integer NOT NULL,
+    text_value text,
+    number_value real,
+    date_value text,
+    bool_value numeric,
+    CONSTRAINT fk_games_custom_values FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE,
+    CONSTRAINT fk_game_custom_values_field FOREIGN KEY (field_id) REFERENCES custom_fields(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_game_custom_field ON game_custom_values (game_id, field_id);
+CREATE INDEX IF NOT EXISTS idx_game_custom_values_field_id ON game_custom_values (field_id);
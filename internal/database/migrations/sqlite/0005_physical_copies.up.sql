@@ -0,0 +1,19 @@
+-- Physical copies of games: condition, completeness, shelf location and purchase
+
+CREATE TABLE IF NOT EXISTS physical_copies (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    region text,
+    edition text,
+    condition text,
+    location text,
+    completeness text NOT NULL DEFAULT 'loose',
+    purchase_price real,
+    purchase_date datetime,
+    purchase_store text,
+    notes text,
+    created_at datetime,
+    updated_at datetime,
+    CONSTRAINT fk_games_physical_copies FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_physical_copies_game_id ON physical_copies (game_id);
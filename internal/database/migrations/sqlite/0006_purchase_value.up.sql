@@ -0,0 +1,16 @@
+-- Purchase currency on physical copies and imported price history
+
+ALTER TABLE physical_copies ADD COLUMN currency text;
+
+CREATE TABLE IF NOT EXISTS price_points (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    game_id integer NOT NULL,
+    grade text NOT NULL,
+    date datetime NOT NULL,
+    price real,
+    currency text NOT NULL,
+    source text,
+    created_at datetime,
+    CONSTRAINT fk_price_points_game FOREIGN KEY (game_id) REFERENCES games(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_price_point ON price_points (game_id, grade, date, currency);
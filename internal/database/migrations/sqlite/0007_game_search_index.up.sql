@@ -0,0 +1,9 @@
+-- Full-text search index over games (see services.GameSearch). FTS4 rather
+-- than FTS5 on purpose: FTS4 is in every go-sqlite3 build, while FTS5 needs
+-- the sqlite_fts5 build tag on every build and test run (see the README). An
+-- index an earlier version created with FTS5 is kept as it is.
+
+CREATE VIRTUAL TABLE IF NOT EXISTS game_search_index USING fts4(
+    title, alt_titles, description, completion_notes, session_notes,
+    tokenize=unicode61 "remove_diacritics=2", prefix="2,3"
+);
@@ -36,6 +36,32 @@ const (
 	ErrSessionNotFound       = "SESSION_NOT_FOUND"
 	ErrSessionAlreadyEnded   = "SESSION_ALREADY_ENDED"
 	ErrInvalidSessionData    = "INVALID_SESSION_DATA"
+	ErrSessionAlreadyActive  = "SESSION_ALREADY_ACTIVE"
+	ErrNoActiveSession       = "NO_ACTIVE_SESSION"
+	ErrSessionAlreadyPaused  = "SESSION_ALREADY_PAUSED"
+	ErrSessionNotPaused      = "SESSION_NOT_PAUSED"
+	ErrSessionOverlap        = "SESSION_OVERLAP"
+	
+	// Goal-specific errors
+	ErrGoalNotFound          = "GOAL_NOT_FOUND"
+	ErrInvalidGoalData       = "INVALID_GOAL_DATA"
+	
+	// Tag and collection errors
+	ErrTagNotFound              = "TAG_NOT_FOUND"
+	ErrTagAlreadyExists         = "TAG_ALREADY_EXISTS"
+	ErrInvalidTagData           = "INVALID_TAG_DATA"
+	ErrCollectionNotFound       = "COLLECTION_NOT_FOUND"
+	ErrCollectionAlreadyExists  = "COLLECTION_ALREADY_EXISTS"
+	ErrInvalidCollectionData    = "INVALID_COLLECTION_DATA"
+	
+	// Custom field errors
+	ErrCustomFieldNotFound      = "CUSTOM_FIELD_NOT_FOUND"
+	ErrCustomFieldAlreadyExists = "CUSTOM_FIELD_ALREADY_EXISTS"
+	ErrInvalidCustomField       = "INVALID_CUSTOM_FIELD"
+	
+	// Physical copy errors
+	ErrCopyNotFound             = "COPY_NOT_FOUND"
+	ErrInvalidCopyData          = "INVALID_COPY_DATA"
 	
 	// Scanner-specific errors
 	ErrScanInProgress        = "SCAN_IN_PROGRESS"
@@ -43,6 +69,10 @@ const (
 	ErrDirectoryNotFound     = "DIRECTORY_NOT_FOUND"
 	ErrPermissionDenied      = "PERMISSION_DENIED"
 	
+	// Image-specific errors
+	ErrImageNotFound         = "IMAGE_NOT_FOUND"
+	ErrInvalidImage          = "INVALID_IMAGE"
+	
 	// External service errors
 	ErrMetadataAPIError      = "METADATA_API_ERROR"
 	ErrMetadataNotFound      = "METADATA_NOT_FOUND"
@@ -79,6 +109,32 @@ var ErrorMessages = map[string]string{
 	ErrSessionNotFound:       "Play session not found",
 	ErrSessionAlreadyEnded:   "This play session has already ended",
 	ErrInvalidSessionData:    "Invalid session data provided",
+	ErrSessionAlreadyActive:  "Another play session is already active",
+	ErrNoActiveSession:       "No play session is active",
+	ErrSessionAlreadyPaused:  "This play session is already paused",
+	ErrSessionNotPaused:      "This play session is not paused",
+	ErrSessionOverlap:        "This play session overlaps an existing session",
+	
+	// Goal-specific errors
+	ErrGoalNotFound:          "Goal not found",
+	ErrInvalidGoalData:       "Invalid goal data provided",
+	
+	// Tag and collection errors
+	ErrTagNotFound:             "Tag not found",
+	ErrTagAlreadyExists:        "A tag with this name already exists",
+	ErrInvalidTagData:          "Invalid tag data provided",
+	ErrCollectionNotFound:      "Collection not found",
+	ErrCollectionAlreadyExists: "A collection with this name already exists",
+	ErrInvalidCollectionData:   "Invalid collection data provided",
+	
+	// Custom field errors
+	ErrCustomFieldNotFound:      "Custom field not found",
+	ErrCustomFieldAlreadyExists: "A custom field with this key already exists",
+	ErrInvalidCustomField:       "Invalid custom field or value",
+	
+	// Physical copy errors
+	ErrCopyNotFound:             "Physical copy not found",
+	ErrInvalidCopyData:          "Invalid physical copy data provided",
 	
 	// Scanner-specific errors
 	ErrScanInProgress:        "A directory scan is already in progress",
@@ -86,6 +142,10 @@ var ErrorMessages = map[string]string{
 	ErrDirectoryNotFound:     "Directory not found or not accessible",
 	ErrPermissionDenied:      "Permission denied to access this directory",
 	
+	// Image-specific errors
+	ErrImageNotFound:         "Image not found",
+	ErrInvalidImage:          "The uploaded file is not a supported image",
+	
 	// External service errors
 	ErrMetadataAPIError:      "Unable to fetch metadata from external service",
 	ErrMetadataNotFound:      "No metadata found for this game",
@@ -123,13 +183,17 @@ func NewAPIError(code string, details ...interface{}) *APIError {
 func getHTTPStatusForCode(code string) int {
 	switch code {
 	case ErrNotFound, ErrGameNotFound, ErrPlatformNotFound, ErrSessionNotFound, 
-		 ErrDirectoryNotFound, ErrMetadataNotFound:
+		 ErrDirectoryNotFound, ErrMetadataNotFound, ErrImageNotFound, ErrNoActiveSession,
+		 ErrGoalNotFound, ErrTagNotFound, ErrCollectionNotFound, ErrCustomFieldNotFound,
+		 ErrCopyNotFound:
 		return http.StatusNotFound
 		
 	case ErrInvalidRequest, ErrInvalidGameData, ErrInvalidPlatformData, 
 		 ErrInvalidSessionData, ErrInvalidDirectory, ErrValidationFailed,
 		 ErrMissingRequiredField, ErrInvalidFormat, ErrInvalidRange,
-		 ErrSessionAlreadyEnded, ErrPlatformHasGames:
+		 ErrSessionAlreadyEnded, ErrPlatformHasGames, ErrInvalidImage, ErrInvalidGoalData,
+		 ErrInvalidTagData, ErrInvalidCollectionData, ErrInvalidCustomField,
+		 ErrInvalidCopyData:
 		return http.StatusBadRequest
 		
 	case ErrUnauthorized:
@@ -138,7 +202,9 @@ func getHTTPStatusForCode(code string) int {
 	case ErrForbidden, ErrPermissionDenied:
 		return http.StatusForbidden
 		
-	case ErrScanInProgress:
+	case ErrScanInProgress, ErrSessionAlreadyActive, ErrSessionAlreadyPaused, ErrSessionNotPaused,
+		 ErrSessionOverlap, ErrTagAlreadyExists, ErrCollectionAlreadyExists,
+		 ErrCustomFieldAlreadyExists:
 		return http.StatusConflict
 		
 	case ErrMetadataAPIError, ErrBackupServiceError, ErrNextcloudError:
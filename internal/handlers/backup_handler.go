@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -15,12 +16,14 @@ import (
 type BackupHandler struct {
 	db              *gorm.DB
 	nextcloudBackup *services.NextcloudBackup
+	search          *services.GameSearch
 }
 
-func NewBackupHandler(db *gorm.DB, cfg *config.Config) *BackupHandler {
+func NewBackupHandler(db *gorm.DB, cfg *config.Config, search *services.GameSearch) *BackupHandler {
 	return &BackupHandler{
 		db:              db,
 		nextcloudBackup: services.NewNextcloudBackup(cfg, db),
+		search:          search,
 	}
 }
 
@@ -46,91 +49,23 @@ func (h *BackupHandler) ImportDatabase(c *gin.Context) {
 		return
 	}
 
-	// Validate backup version
-	if backup.Version != "1.0" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported backup version: " + backup.Version})
-		return
-	}
-
-	// Start transaction for atomic restore
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// Clear existing data (be very careful here)
-	if err := tx.Exec("DELETE FROM play_sessions").Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear sessions: " + err.Error()})
-		return
-	}
-
-	if err := tx.Exec("DELETE FROM file_locations").Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear file locations: " + err.Error()})
-		return
-	}
-
-	if err := tx.Exec("DELETE FROM games").Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear games: " + err.Error()})
-		return
-	}
-
-	if err := tx.Exec("DELETE FROM platforms").Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear platforms: " + err.Error()})
-		return
-	}
-
-	// Import platforms first (required for games)
-	for _, platform := range backup.Platforms {
-		if err := tx.Create(&platform).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import platform: " + err.Error()})
-			return
-		}
-	}
-
-	// Import games
-	for _, game := range backup.Games {
-		// Clear associations to avoid conflicts
-		game.FileLocations = nil
-		game.PlaySessions = nil
-		
-		if err := tx.Create(&game).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import game: " + err.Error()})
-			return
-		}
-	}
-
-	// Import file locations
-	for _, fileLocation := range backup.FileLocations {
-		if err := tx.Create(&fileLocation).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import file location: " + err.Error()})
+	if err := services.RestoreBackup(h.db, &backup); err != nil {
+		if stderrors.Is(err, services.ErrUnsupportedBackupVersion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported backup version: " + backup.Version})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore backup: " + err.Error()})
+		return
 	}
 
-	// Import sessions
-	for _, session := range backup.Sessions {
-		if err := tx.Create(&session).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import session: " + err.Error()})
+	// The restore clears tables with raw SQL the search index callbacks don't see
+	if h.search != nil {
+		if err := h.search.Rebuild(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Backup restored, but rebuilding the search index failed: " + err.Error()})
 			return
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit backup restore: " + err.Error()})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Backup restored successfully",
 		"stats": gin.H{
@@ -183,7 +118,7 @@ func (h *BackupHandler) GetBackupInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"database_stats": stats,
 		"backup_info": gin.H{
-			"supported_version": "1.0",
+			"supported_version": services.BackupVersion,
 			"last_backup":       nil, // Could be enhanced to track last backup time
 			"backup_size_mb":    0,   // Could be enhanced to calculate size
 		},
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupHandler_RestoreKeepsLibraryData(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+	if db.Dialector.Name() == "sqlite" {
+		// Enforce the foreign keys and cascades like PostgreSQL does
+		require.NoError(t, db.Exec("PRAGMA foreign_keys = ON").Error)
+	}
+
+	game := models.Game{Title: "Chrono Trigger", PlatformID: 1}
+	require.NoError(t, db.Create(&game).Error)
+	tag := models.Tag{Name: "favorite"}
+	require.NoError(t, db.Create(&tag).Error)
+	require.NoError(t, db.Model(&game).Association("Tags").Append(&tag))
+	price := 120.0
+	require.NoError(t, db.Create(&models.PhysicalCopy{GameID: game.ID, Completeness: "cib", PurchasePrice: &price, Currency: "USD"}).Error)
+	platformID := uint(1)
+	goal := models.Goal{Title: "Finish console games", Kind: "finish_games", Target: 3, Period: "year", PlatformID: &platformID}
+	require.NoError(t, db.Create(&goal).Error)
+
+	w := send(server, "GET", "/backup/export", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	backup := w.Body.Bytes()
+
+	// Changes made after the backup are undone by restoring it
+	require.NoError(t, db.Where("game_id = ?", game.ID).Delete(&models.PhysicalCopy{}).Error)
+	require.NoError(t, db.Create(&models.Tag{Name: "later"}).Error)
+
+	w = sendRaw(server, "POST", "/backup/import", "application/json", bytes.NewReader(backup))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var restored models.Game
+	require.NoError(t, db.Preload("Tags").Preload("PhysicalCopies").First(&restored, game.ID).Error)
+	require.Len(t, restored.Tags, 1)
+	assert.Equal(t, "favorite", restored.Tags[0].Name)
+	require.Len(t, restored.PhysicalCopies, 1)
+	assert.Equal(t, "cib", restored.PhysicalCopies[0].Completeness)
+	var tags int64
+	db.Model(&models.Tag{}).Count(&tags)
+	assert.Equal(t, int64(1), tags)
+	require.NoError(t, db.First(&goal, goal.ID).Error)
+	require.NotNil(t, goal.PlatformID)
+	assert.Equal(t, uint(1), *goal.PlatformID)
+
+	// New rows get IDs past the restored ones
+	require.NoError(t, db.Create(&models.Game{Title: "Secret of Mana", PlatformID: 1}).Error)
+
+	// A 1.0 backup keeps goals on the platforms it has and relinks genres
+	w = send(server, "POST", "/backup/import", map[string]interface{}{
+		"version":   "1.0",
+		"platforms": []map[string]interface{}{{"id": 1, "name": "Super Nintendo"}},
+		"games":     []map[string]interface{}{{"id": 5, "title": "EarthBound", "platform_id": 1, "genre": "RPG"}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, db.First(&goal, goal.ID).Error)
+	require.NotNil(t, goal.PlatformID)
+	assert.Equal(t, uint(1), *goal.PlatformID)
+	var earthbound models.Game
+	require.NoError(t, db.Preload("Genres").First(&earthbound, 5).Error)
+	require.Len(t, earthbound.Genres, 1)
+	assert.Equal(t, "RPG", earthbound.Genres[0].Name)
+	var copies int64
+	db.Model(&models.PhysicalCopy{}).Count(&copies)
+	assert.Equal(t, int64(0), copies)
+
+	w = send(server, "POST", "/backup/import", map[string]interface{}{"version": "3.0"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CollectionHandler manages user-curated, ordered lists of games, and smart
+// collections whose games are those matching saved rules
+type CollectionHandler struct {
+	db *gorm.DB
+}
+
+func NewCollectionHandler(db *gorm.DB) *CollectionHandler {
+	return &CollectionHandler{
+		db: db,
+	}
+}
+
+// collectionSummary is a collection in listings, without its games
+type collectionSummary struct {
+	models.Collection
+	GameCount int64 `json:"game_count"`
+}
+
+// GetCollections lists collections alphabetically with their game counts.
+// Collections without a cover of their own show their first game's cover art.
+// Smart collections are evaluated now.
+func (h *CollectionHandler) GetCollections(c *gin.Context) {
+	var collections []collectionSummary
+	err := h.db.Model(&models.Collection{}).
+		Select("collections.*, COUNT(collection_items.id) AS game_count").
+		Joins("LEFT JOIN collection_items ON collection_items.collection_id = collections.id").
+		Group("collections.id").
+		Order("LOWER(collections.name)").
+		Scan(&collections).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_collections",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	for i := range collections {
+		collection := &collections[i].Collection
+		if collection.Rules != nil {
+			query, err := collectionGames(h.db, h.db.Model(&models.Game{}), collection)
+			if err == nil {
+				err = query.Count(&collections[i].GameCount).Error
+			}
+			if err != nil {
+				errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+					"operation": "evaluate_collection_rules",
+					"error":     err.Error(),
+				})
+				return
+			}
+		}
+		if collection.CoverURL == "" {
+			collection.CoverURL = h.firstGameCover(collection)
+		}
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+
+// GetCollection returns a collection with its games in order; a smart
+// collection lists the games matching its rules, by title
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+
+	if err := h.loadGames(collection); err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_collection_games",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	var req middleware.CreateCollectionRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	collection := models.Collection{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		CoverURL:    req.CoverURL,
+		Rules:       req.Rules,
+	}
+	if !h.validateCollection(c, &collection) {
+		return
+	}
+	if collection.Rules != nil && len(req.GameIDs) > 0 {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+			"field": "game_ids",
+			"error": "a smart collection's games come from its rules",
+		})
+		return
+	}
+	var gameIDs []uint
+	if len(req.GameIDs) > 0 {
+		var ok bool
+		if gameIDs, ok = requireGames(c, h.db, req.GameIDs); !ok {
+			return
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&collection).Error; err != nil {
+			return err
+		}
+		return appendCollectionItems(tx, collection.ID, gameIDs)
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "create_collection",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.loadGames(&collection)
+	c.JSON(http.StatusCreated, collection)
+}
+
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+
+	var req middleware.UpdateCollectionRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	// Update fields if provided in request
+	if req.Name != "" {
+		collection.Name = strings.TrimSpace(req.Name)
+	}
+	if req.Description != nil {
+		collection.Description = *req.Description
+	}
+	if req.CoverURL != nil {
+		collection.CoverURL = strings.TrimSpace(*req.CoverURL)
+	}
+	becameSmart := false
+	if len(req.Rules) > 0 {
+		var rules *models.CollectionRule
+		if err := json.Unmarshal(req.Rules, &rules); err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+				"field": "rules",
+				"error": err.Error(),
+			})
+			return
+		}
+		becameSmart = collection.Rules == nil && rules != nil
+		collection.Rules = rules
+	}
+	if !h.validateCollection(c, collection) {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if becameSmart {
+			if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(collection).Error
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "update_collection",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection deletes a collection; its games stay in the library
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_collection",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
+
+// AddCollectionGames appends games to the end of a collection, in the order
+// given. Games already in the collection keep their place.
+func (h *CollectionHandler) AddCollectionGames(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok || !h.requireManual(c, collection) {
+		return
+	}
+
+	var req middleware.CollectionGamesRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	gameIDs, ok := requireGames(c, h.db, req.GameIDs)
+	if !ok {
+		return
+	}
+
+	var present []uint
+	h.db.Model(&models.CollectionItem{}).Where("collection_id = ? AND game_id IN ?", collection.ID, gameIDs).Pluck("game_id", &present)
+	inCollection := make(map[uint]bool, len(present))
+	for _, id := range present {
+		inCollection[id] = true
+	}
+	var added []uint
+	for _, id := range gameIDs {
+		if !inCollection[id] {
+			added = append(added, id)
+		}
+	}
+
+	if err := appendCollectionItems(h.db, collection.ID, added); err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "add_collection_games",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.loadGames(collection)
+	c.JSON(http.StatusOK, gin.H{
+		"added":      len(added),
+		"skipped":    len(gameIDs) - len(added),
+		"collection": collection,
+	})
+}
+
+// PreviewCollection lists the games smart collection rules match, without
+// saving them
+func (h *CollectionHandler) PreviewCollection(c *gin.Context) {
+	var req middleware.PreviewCollectionRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+	if err := services.ValidateCollectionRule(h.db, req.Rules); err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+			"field": "rules",
+			"error": err.Error(),
+		})
+		return
+	}
+
+	query, err := collectionGames(h.db, h.db.Model(&models.Game{}), &models.Collection{Rules: req.Rules})
+	var total int64
+	if err == nil {
+		err = query.Count(&total).Error
+	}
+	games := []models.Game{}
+	if err == nil {
+		err = query.Preload("Platform").Preload("Genres").Preload("Tags").
+			Order("games.title ASC").Limit(req.Limit).
+			Find(&games).Error
+	}
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "preview_collection",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"games": games,
+	})
+}
+
+// RemoveCollectionGame takes a game out of a collection
+func (h *CollectionHandler) RemoveCollectionGame(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok || !h.requireManual(c, collection) {
+		return
+	}
+
+	gameID, err := strconv.ParseUint(c.Param("gameId"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "gameId",
+			"expected":  "positive integer",
+			"received":  c.Param("gameId"),
+		})
+		return
+	}
+
+	result := h.db.Where("collection_id = ? AND game_id = ?", collection.ID, gameID).Delete(&models.CollectionItem{})
+	if result.Error != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "remove_collection_game",
+			"error":     result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+			"collection_id": collection.ID,
+			"game_id":       gameID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Game removed from collection"})
+}
+
+// ReorderCollection sets the order of a collection's games. The request must
+// list exactly the games in the collection, each once.
+func (h *CollectionHandler) ReorderCollection(c *gin.Context) {
+	collection, ok := h.findCollection(c)
+	if !ok || !h.requireManual(c, collection) {
+		return
+	}
+
+	var req middleware.CollectionGamesRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	var current []uint
+	if err := h.db.Model(&models.CollectionItem{}).Where("collection_id = ?", collection.ID).Pluck("game_id", &current).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return
+	}
+	if !sameGameSet(current, req.GameIDs) {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]interface{}{
+			"field":    "game_ids",
+			"error":    "must list every game in the collection exactly once",
+			"expected": len(current),
+			"received": len(req.GameIDs),
+		})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for position, gameID := range req.GameIDs {
+			err := tx.Model(&models.CollectionItem{}).
+				Where("collection_id = ? AND game_id = ?", collection.ID, gameID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "reorder_collection",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.loadGames(collection)
+	c.JSON(http.StatusOK, collection)
+}
+
+// findCollection loads the collection named by the :id parameter
+func (h *CollectionHandler) findCollection(c *gin.Context) (*models.Collection, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return nil, false
+	}
+
+	var collection models.Collection
+	if err := h.db.First(&collection, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrCollectionNotFound, map[string]interface{}{
+				"collection_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return &collection, true
+}
+
+// validateCollection checks the cover URL, any smart collection rules, and
+// that no other collection has the name, ignoring case
+func (h *CollectionHandler) validateCollection(c *gin.Context, collection *models.Collection) bool {
+	if collection.Name == "" {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+			"field": "name",
+			"error": "name is blank",
+		})
+		return false
+	}
+	if collection.CoverURL != "" && !strings.HasPrefix(collection.CoverURL, "http://") && !strings.HasPrefix(collection.CoverURL, "https://") {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+			"field":    "cover_url",
+			"expected": "http(s) URL",
+			"received": collection.CoverURL,
+		})
+		return false
+	}
+	if collection.Rules != nil {
+		if err := services.ValidateCollectionRule(h.db, collection.Rules); err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]string{
+				"field": "rules",
+				"error": err.Error(),
+			})
+			return false
+		}
+	}
+
+	var count int64
+	h.db.Model(&models.Collection{}).Where(services.EqualFold("name", collection.Name)).Where("id <> ?", collection.ID).Count(&count)
+	if count > 0 {
+		errors.RespondWithError(c, errors.ErrCollectionAlreadyExists, map[string]string{
+			"name": collection.Name,
+		})
+		return false
+	}
+	return true
+}
+
+// loadGames loads a collection's games: a smart collection's matching games
+// by title, or the items of others in order, numbering positions from 0 so
+// gaps left by removed games don't show
+func (h *CollectionHandler) loadGames(collection *models.Collection) error {
+	if collection.Rules != nil {
+		query, err := collectionGames(h.db, h.db.Model(&models.Game{}), collection)
+		if err != nil {
+			return err
+		}
+		return query.Preload("Platform").Preload("Genres").Preload("Tags").
+			Order("games.title ASC").
+			Find(&collection.Games).Error
+	}
+
+	err := h.db.Where("collection_id = ?", collection.ID).
+		Preload("Game").Preload("Game.Platform").Preload("Game.Genres").Preload("Game.Tags").
+		Order("position ASC, id ASC").
+		Find(&collection.Items).Error
+	if err != nil {
+		return err
+	}
+	for i := range collection.Items {
+		collection.Items[i].Position = i
+	}
+	if collection.Items == nil {
+		collection.Items = []models.CollectionItem{}
+	}
+	return nil
+}
+
+func (h *CollectionHandler) firstGameCover(collection *models.Collection) string {
+	var cover string
+	if collection.Rules != nil {
+		query, err := collectionGames(h.db, h.db.Model(&models.Game{}), collection)
+		if err == nil {
+			query.Select("games.cover_art_url").Where("games.cover_art_url <> ''").Order("games.title ASC").Limit(1).Scan(&cover)
+		}
+		return cover
+	}
+	h.db.Model(&models.CollectionItem{}).
+		Select("games.cover_art_url").
+		Joins("JOIN games ON games.id = collection_items.game_id").
+		Where("collection_items.collection_id = ? AND games.cover_art_url <> ''", collection.ID).
+		Order("collection_items.position ASC, collection_items.id ASC").
+		Limit(1).
+		Scan(&cover)
+	return cover
+}
+
+// requireManual rejects changing the games of a smart collection directly
+func (h *CollectionHandler) requireManual(c *gin.Context, collection *models.Collection) bool {
+	if collection.Rules != nil {
+		errors.RespondWithError(c, errors.ErrInvalidCollectionData, map[string]interface{}{
+			"collection_id": collection.ID,
+			"error":         "a smart collection's games come from its rules",
+		})
+		return false
+	}
+	return true
+}
+
+// collectionGames restricts a games query to a collection's games: its items,
+// or for a smart collection the games matching its rules now
+func collectionGames(db, query *gorm.DB, collection *models.Collection) (*gorm.DB, error) {
+	if collection.Rules == nil {
+		return query.Where("games.id IN (SELECT game_id FROM collection_items WHERE collection_id = ?)", collection.ID), nil
+	}
+	condition, err := services.CollectionRuleCondition(db, collection.Rules, time.Local, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return query.Where(condition), nil
+}
+
+// appendCollectionItems adds games after the collection's last position
+func appendCollectionItems(tx *gorm.DB, collectionID uint, gameIDs []uint) error {
+	if len(gameIDs) == 0 {
+		return nil
+	}
+	var last *int
+	if err := tx.Model(&models.CollectionItem{}).Where("collection_id = ?", collectionID).Select("MAX(position)").Scan(&last).Error; err != nil {
+		return err
+	}
+	next := 0
+	if last != nil {
+		next = *last + 1
+	}
+
+	items := make([]models.CollectionItem, len(gameIDs))
+	for i, gameID := range gameIDs {
+		items[i] = models.CollectionItem{CollectionID: collectionID, GameID: gameID, Position: next + i}
+	}
+	return tx.Create(&items).Error
+}
+
+func sameGameSet(current, requested []uint) bool {
+	if len(current) != len(requested) {
+		return false
+	}
+	remaining := make(map[uint]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range requested {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	games := make([]models.Game, 4)
+	for i, title := range []string{"Metroid", "Castlevania", "Hollow Knight", "Celeste"} {
+		games[i] = models.Game{Title: title, PlatformID: 1}
+		if title == "Castlevania" {
+			games[i].CoverArtURL = "https://example.com/castlevania.jpg"
+		}
+		db.Create(&games[i])
+	}
+
+	order := func(collection models.Collection) []string {
+		result := make([]string, len(collection.Items))
+		for i, item := range collection.Items {
+			require.NotNil(t, item.Game)
+			assert.Equal(t, i, item.Position)
+			result[i] = item.Game.Title
+		}
+		return result
+	}
+
+	w := send(server, "POST", "/collections", map[string]interface{}{
+		"name": "Metroidvanias", "game_ids": []uint{games[0].ID, games[1].ID},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var collection models.Collection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Equal(t, []string{"Metroid", "Castlevania"}, order(collection))
+	path := fmt.Sprintf("/collections/%d", collection.ID)
+
+	assert.Equal(t, http.StatusConflict, send(server, "POST", "/collections", map[string]interface{}{"name": "METROIDVANIAS"}).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/collections", map[string]interface{}{"name": "x", "game_ids": []uint{9999}}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", path, map[string]interface{}{"cover_url": "not a url"}).Code)
+
+	// Adding appends in order and skips games already there
+	w = send(server, "POST", path+"/games", map[string]interface{}{"game_ids": []uint{games[2].ID, games[0].ID, games[3].ID}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var added struct {
+		Added      int               `json:"added"`
+		Skipped    int               `json:"skipped"`
+		Collection models.Collection `json:"collection"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	assert.Equal(t, 2, added.Added)
+	assert.Equal(t, 1, added.Skipped)
+	assert.Equal(t, []string{"Metroid", "Castlevania", "Hollow Knight", "Celeste"}, order(added.Collection))
+
+	w = send(server, "PUT", path+"/order", map[string]interface{}{"game_ids": []uint{games[3].ID, games[2].ID, games[0].ID, games[1].ID}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Equal(t, []string{"Celeste", "Hollow Knight", "Metroid", "Castlevania"}, order(collection))
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", path+"/order", map[string]interface{}{"game_ids": []uint{games[3].ID, games[2].ID}}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", path+"/order", map[string]interface{}{
+		"game_ids": []uint{games[3].ID, games[3].ID, games[0].ID, games[1].ID},
+	}).Code)
+
+	// Removing leaves no gap in positions
+	assert.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("%s/games/%d", path, games[2].ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("%s/games/%d", path, games[2].ID), nil).Code)
+	w = send(server, "GET", path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	assert.Equal(t, []string{"Celeste", "Metroid", "Castlevania"}, order(collection))
+
+	// Deleting a game takes it out of its collections
+	assert.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/games/%d", games[3].ID), nil).Code)
+
+	w = send(server, "GET", fmt.Sprintf("/games?collection=%d", collection.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":2`)
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/games?collection=abc", nil).Code)
+
+	w = send(server, "GET", "/collections", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []struct {
+		Name      string `json:"name"`
+		CoverURL  string `json:"cover_url"`
+		GameCount int64  `json:"game_count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, int64(2), listed[0].GameCount)
+	assert.Equal(t, "https://example.com/castlevania.jpg", listed[0].CoverURL)
+
+	w = send(server, "PUT", path, map[string]interface{}{"name": "Search Action", "description": "Backtracking welcome"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"Search Action"`)
+
+	assert.Equal(t, http.StatusOK, send(server, "DELETE", path, nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", path, nil).Code)
+	var remaining int64
+	db.Model(&models.Game{}).Count(&remaining)
+	assert.Equal(t, int64(3), remaining)
+}
+
+func TestCollectionHandler_SmartCollections(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	db.Create(&models.Game{Title: "Super Metroid", PlatformID: 1, Rating: 9.5})
+	db.Create(&models.Game{Title: "Earthbound", PlatformID: 1, Rating: 9, CompletionStatus: "completed"})
+	db.Create(&models.Game{Title: "Bubsy", PlatformID: 1, Rating: 3})
+	manual := models.Game{Title: "Ys", PlatformID: 1}
+	db.Create(&manual)
+
+	titles := func(games []models.Game) []string {
+		result := make([]string, len(games))
+		for i, game := range games {
+			result[i] = game.Title
+		}
+		return result
+	}
+	get := func(path string) models.Collection {
+		w := send(server, "GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var collection models.Collection
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+		return collection
+	}
+	unplayedGems := map[string]interface{}{
+		"match": "all",
+		"rules": []map[string]interface{}{
+			{"field": "platform", "op": "eq", "value": "test console"},
+			{"field": "completion_status", "op": "eq", "value": "not_started"},
+			{"field": "rating", "op": "gte", "value": 8},
+		},
+	}
+
+	w := send(server, "POST", "/collections/preview", map[string]interface{}{"rules": unplayedGems})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var preview struct {
+		Total int64         `json:"total"`
+		Games []models.Game `json:"games"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+	assert.Equal(t, int64(1), preview.Total)
+	assert.Equal(t, []string{"Super Metroid"}, titles(preview.Games))
+
+	w = send(server, "POST", "/collections/preview", map[string]interface{}{"rules": map[string]interface{}{"field": "rating", "op": "like", "value": 8}})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "rating takes op")
+
+	w = send(server, "POST", "/collections", map[string]interface{}{"name": "Unplayed gems", "rules": unplayedGems})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var collection models.Collection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+	require.NotNil(t, collection.Rules)
+	assert.Equal(t, []string{"Super Metroid"}, titles(collection.Games))
+	path := fmt.Sprintf("/collections/%d", collection.ID)
+
+	assert.Equal(t, http.StatusBadRequest, send(server, "POST", "/collections", map[string]interface{}{
+		"name": "Both", "rules": unplayedGems, "game_ids": []uint{manual.ID},
+	}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "POST", path+"/games", map[string]interface{}{"game_ids": []uint{manual.ID}}).Code)
+
+	// Rules are evaluated on read, so the collection follows the library
+	db.Create(&models.Game{Title: "Chrono Trigger", PlatformID: 1, Rating: 10})
+	assert.Equal(t, []string{"Chrono Trigger", "Super Metroid"}, titles(get(path).Games))
+
+	w = send(server, "GET", fmt.Sprintf("/games?collection=%d&sort=title", collection.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Games []models.Game `json:"games"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"Chrono Trigger", "Super Metroid"}, titles(response.Games))
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/games?collection=9999", nil).Code)
+
+	w = send(server, "GET", "/collections", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"game_count":2`)
+
+	// Rules can change, and a smart collection can become a plain list again
+	w = send(server, "PUT", path, map[string]interface{}{"rules": map[string]interface{}{"field": "rating", "op": "lt", "value": 5}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"Bubsy", "Ys"}, titles(get(path).Games))
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", path, map[string]interface{}{"rules": map[string]interface{}{"match": "all"}}).Code)
+
+	w = send(server, "PUT", path, map[string]interface{}{"rules": nil})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusOK, send(server, "POST", path+"/games", map[string]interface{}{"game_ids": []uint{manual.ID}}).Code)
+	collection = get(path)
+	assert.Nil(t, collection.Rules)
+	require.Len(t, collection.Items, 1)
+	assert.Equal(t, "Ys", collection.Items[0].Game.Title)
+}
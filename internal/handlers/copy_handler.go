@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/config"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CopyHandler manages the physical copies owned of each game
+type CopyHandler struct {
+	db       *gorm.DB
+	cache    *services.CacheService
+	currency string // for prices given without one
+}
+
+func NewCopyHandler(db *gorm.DB, cache *services.CacheService, cfg *config.Config) *CopyHandler {
+	currency := cfg.Currency
+	if currency == "" {
+		currency = services.DefaultCurrency
+	}
+	return &CopyHandler{
+		db:       db,
+		cache:    cache,
+		currency: currency,
+	}
+}
+
+// GetCopies lists physical copies across the library by storage location,
+// optionally filtered by location, completeness, condition or region
+func (h *CopyHandler) GetCopies(c *gin.Context) {
+	query := h.db.Model(&models.PhysicalCopy{}).
+		Joins("JOIN games ON games.id = physical_copies.game_id").
+		Preload("Game.Platform")
+	if location := c.Query("location"); location != "" {
+		query = query.Where(services.EqualFold("physical_copies.location", location))
+	}
+	if completeness := c.Query("completeness"); completeness != "" {
+		query = query.Where("physical_copies.completeness = ?", completeness)
+	}
+	if condition := c.Query("condition"); condition != "" {
+		query = query.Where("physical_copies.condition = ?", condition)
+	}
+	if value := c.Query("region"); value != "" {
+		region, ok := services.NormalizeReleaseRegion(value)
+		if !ok {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "region",
+				"expected":  "region such as japan, europe, north_america (or jp, pal, na)",
+				"received":  value,
+			})
+			return
+		}
+		query = query.Where("physical_copies.region = ?", region)
+	}
+
+	var copies []models.PhysicalCopy
+	err := query.Order("LOWER(physical_copies.location), LOWER(games.title), physical_copies.id").Find(&copies).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_copies",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, copies)
+}
+
+func (h *CopyHandler) GetGameCopies(c *gin.Context) {
+	game, ok := h.findGame(c)
+	if !ok {
+		return
+	}
+
+	var copies []models.PhysicalCopy
+	if err := h.db.Where("game_id = ?", game.ID).Order("id").Find(&copies).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_copies",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, copies)
+}
+
+// GetGamePrices returns a game's imported market prices, oldest first
+func (h *CopyHandler) GetGamePrices(c *gin.Context) {
+	game, ok := h.findGame(c)
+	if !ok {
+		return
+	}
+
+	var prices []models.PricePoint
+	if err := h.db.Where("game_id = ?", game.ID).Order("date, grade, currency").Find(&prices).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_prices",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// CreateCopy adds a physical copy of a game, marking the game as owned
+// physically if it wasn't already
+func (h *CopyHandler) CreateCopy(c *gin.Context) {
+	game, ok := h.findGame(c)
+	if !ok {
+		return
+	}
+
+	var req middleware.CreatePhysicalCopyRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	physicalCopy := models.PhysicalCopy{
+		GameID:        game.ID,
+		Edition:       strings.TrimSpace(req.Edition),
+		Condition:     req.Condition,
+		Completeness:  req.Completeness,
+		Location:      strings.TrimSpace(req.Location),
+		PurchasePrice: req.PurchasePrice,
+		Currency:      strings.ToUpper(req.Currency),
+		PurchaseStore: strings.TrimSpace(req.PurchaseStore),
+		Notes:         req.Notes,
+	}
+	if physicalCopy.Completeness == "" {
+		physicalCopy.Completeness = "loose"
+	}
+	if physicalCopy.PurchasePrice != nil && physicalCopy.Currency == "" {
+		physicalCopy.Currency = h.currency
+	}
+	if physicalCopy.Region, ok = copyRegion(c, req.Region); !ok {
+		return
+	}
+	if req.PurchaseDate != "" {
+		date, _ := time.Parse("2006-01-02", req.PurchaseDate)
+		physicalCopy.PurchaseDate = &date
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&physicalCopy).Error; err != nil {
+			return err
+		}
+		if slices.Contains(game.CollectionFormats, "physical") {
+			return nil
+		}
+		formats := append(models.CollectionFormats{}, game.CollectionFormats...)
+		return tx.Model(game).Update("collection_formats", append(formats, "physical")).Error
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "create_copy",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(game.ID)
+	c.JSON(http.StatusCreated, physicalCopy)
+}
+
+func (h *CopyHandler) UpdateCopy(c *gin.Context) {
+	physicalCopy, ok := h.findCopy(c)
+	if !ok {
+		return
+	}
+
+	var req middleware.UpdatePhysicalCopyRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	// Update fields if provided in request
+	if req.Region != nil {
+		if physicalCopy.Region, ok = copyRegion(c, *req.Region); !ok {
+			return
+		}
+	}
+	if req.Edition != nil {
+		physicalCopy.Edition = strings.TrimSpace(*req.Edition)
+	}
+	if req.Condition != nil {
+		physicalCopy.Condition = *req.Condition
+	}
+	if req.Completeness != "" {
+		physicalCopy.Completeness = req.Completeness
+	}
+	if req.Location != nil {
+		physicalCopy.Location = strings.TrimSpace(*req.Location)
+	}
+	if req.PurchasePrice != nil {
+		physicalCopy.PurchasePrice = req.PurchasePrice
+	}
+	if req.Currency != nil {
+		physicalCopy.Currency = strings.ToUpper(*req.Currency)
+	}
+	if physicalCopy.PurchasePrice != nil && physicalCopy.Currency == "" {
+		physicalCopy.Currency = h.currency
+	}
+	if req.PurchaseDate != nil {
+		physicalCopy.PurchaseDate = nil
+		if *req.PurchaseDate != "" {
+			date, err := time.Parse("2006-01-02", *req.PurchaseDate)
+			if err != nil {
+				errors.RespondWithError(c, errors.ErrInvalidCopyData, map[string]string{
+					"field":    "purchase_date",
+					"expected": "YYYY-MM-DD",
+					"received": *req.PurchaseDate,
+				})
+				return
+			}
+			physicalCopy.PurchaseDate = &date
+		}
+	}
+	if req.PurchaseStore != nil {
+		physicalCopy.PurchaseStore = strings.TrimSpace(*req.PurchaseStore)
+	}
+	if req.Notes != nil {
+		physicalCopy.Notes = *req.Notes
+	}
+
+	if err := h.db.Save(physicalCopy).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "update_copy",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(physicalCopy.GameID)
+	c.JSON(http.StatusOK, physicalCopy)
+}
+
+// DeleteCopy deletes a physical copy; the game keeps its physical format
+func (h *CopyHandler) DeleteCopy(c *gin.Context) {
+	physicalCopy, ok := h.findCopy(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(physicalCopy).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_copy",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(physicalCopy.GameID)
+	c.JSON(http.StatusOK, gin.H{"message": "Physical copy deleted successfully"})
+}
+
+// findGame loads the game named by the :id parameter
+func (h *CopyHandler) findGame(c *gin.Context) (*models.Game, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return nil, false
+	}
+
+	var game models.Game
+	if err := h.db.First(&game, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+				"game_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return &game, true
+}
+
+// findCopy loads the physical copy named by the :id parameter
+func (h *CopyHandler) findCopy(c *gin.Context) (*models.PhysicalCopy, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return nil, false
+	}
+
+	var physicalCopy models.PhysicalCopy
+	if err := h.db.First(&physicalCopy, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrCopyNotFound, map[string]interface{}{
+				"copy_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return &physicalCopy, true
+}
+
+// copyRegion resolves a copy's region name or alias ("PAL", "NTSC-J");
+// empty means unknown
+func copyRegion(c *gin.Context, value string) (string, bool) {
+	if strings.TrimSpace(value) == "" {
+		return "", true
+	}
+	region, ok := services.NormalizeReleaseRegion(value)
+	if !ok {
+		errors.RespondWithError(c, errors.ErrInvalidCopyData, map[string]string{
+			"field":    "region",
+			"expected": "region such as japan, europe, north_america (or jp, pal, na)",
+			"received": value,
+		})
+		return "", false
+	}
+	return region, true
+}
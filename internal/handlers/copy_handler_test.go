@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	zelda := models.Game{Title: "Zelda", PlatformID: 1, CollectionFormats: models.CollectionFormats{"rom"}}
+	tetris := models.Game{Title: "Tetris", PlatformID: 1}
+	db.Create(&zelda)
+	db.Create(&tetris)
+
+	createCopy := func(gameID uint, body map[string]interface{}) models.PhysicalCopy {
+		w := send(server, "POST", fmt.Sprintf("/games/%d/copies", gameID), body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var physicalCopy models.PhysicalCopy
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &physicalCopy))
+		return physicalCopy
+	}
+	listCopies := func(path string) []models.PhysicalCopy {
+		w := send(server, "GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var copies []models.PhysicalCopy
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &copies))
+		return copies
+	}
+
+	cib := createCopy(zelda.ID, map[string]interface{}{
+		"region": "NTSC-U", "edition": " Gold Cartridge ", "condition": "very_good", "completeness": "cib",
+		"location": "Shelf B3", "purchase_price": 60, "purchase_date": "2023-05-14", "purchase_store": "Retro Haven",
+	})
+	assert.Equal(t, "north_america", cib.Region)
+	assert.Equal(t, "Gold Cartridge", cib.Edition)
+	require.NotNil(t, cib.PurchaseDate)
+	assert.Equal(t, "2023-05-14", cib.PurchaseDate.Format("2006-01-02"))
+	assert.Equal(t, "USD", cib.Currency, "prices default to the configured currency")
+	loose := createCopy(zelda.ID, map[string]interface{}{"region": "pal", "location": "Tote 2"})
+	assert.Equal(t, "loose", loose.Completeness)
+	assert.Empty(t, loose.Currency)
+	createCopy(tetris.ID, map[string]interface{}{"completeness": "sealed", "location": "shelf b3"})
+
+	// Adding a copy marks the game as owned physically
+	var stored models.Game
+	db.First(&stored, zelda.ID)
+	assert.Equal(t, models.CollectionFormats{"rom", "physical"}, stored.CollectionFormats)
+
+	for _, body := range []map[string]interface{}{
+		{"completeness": "boxed"},
+		{"condition": "battered"},
+		{"region": "atlantis"},
+		{"purchase_price": -5},
+		{"purchase_date": "May 2023"},
+		{"purchase_price": 10, "currency": "dollars"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, send(server, "POST", fmt.Sprintf("/games/%d/copies", zelda.ID), body).Code, body)
+	}
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/games/999/copies", map[string]interface{}{}).Code)
+
+	assert.Len(t, listCopies(fmt.Sprintf("/games/%d/copies", zelda.ID)), 2)
+	shelf := listCopies("/copies?location=SHELF%20B3")
+	require.Len(t, shelf, 2)
+	assert.Equal(t, "Tetris", shelf[0].Game.Title)
+	assert.Equal(t, "Zelda", shelf[1].Game.Title)
+	assert.Len(t, listCopies("/copies?completeness=sealed"), 1)
+	assert.Len(t, listCopies("/copies?region=europe"), 1)
+
+	// Game details include their copies
+	w := send(server, "GET", fmt.Sprintf("/games/%d", zelda.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched models.Game
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Len(t, fetched.PhysicalCopies, 2)
+
+	w = send(server, "PUT", fmt.Sprintf("/copies/%d", cib.ID), map[string]interface{}{"location": "Shelf C1", "condition": "", "purchase_date": "", "completeness": "sealed", "currency": "eur"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated models.PhysicalCopy
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, "Shelf C1", updated.Location)
+	assert.Empty(t, updated.Condition)
+	assert.Nil(t, updated.PurchaseDate)
+	assert.Equal(t, "sealed", updated.Completeness)
+	assert.Equal(t, 60.0, *updated.PurchasePrice)
+	assert.Equal(t, "EUR", updated.Currency)
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", fmt.Sprintf("/copies/%d", cib.ID), map[string]interface{}{"purchase_date": "2023-13-40"}).Code)
+
+	require.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/copies/%d", loose.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "PUT", fmt.Sprintf("/copies/%d", loose.ID), map[string]interface{}{"notes": "gone"}).Code)
+
+	require.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/games/%d", zelda.ID), nil).Code)
+	var remaining int64
+	db.Model(&models.PhysicalCopy{}).Where("game_id = ?", zelda.ID).Count(&remaining)
+	assert.Zero(t, remaining)
+}
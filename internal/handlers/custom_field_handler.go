@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CustomFieldHandler manages user-defined custom field definitions; values
+// are set through the game endpoints
+type CustomFieldHandler struct {
+	db    *gorm.DB
+	cache *services.CacheService
+}
+
+func NewCustomFieldHandler(db *gorm.DB, cache *services.CacheService) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		db:    db,
+		cache: cache,
+	}
+}
+
+// customFieldWithCount is a custom field and how many games have a value for it
+type customFieldWithCount struct {
+	models.CustomField
+	GameCount int64 `json:"game_count"`
+}
+
+// GetCustomFields lists every custom field with its number of games, by name
+func (h *CustomFieldHandler) GetCustomFields(c *gin.Context) {
+	var fields []customFieldWithCount
+	err := h.db.Model(&models.CustomField{}).
+		Select("custom_fields.*, COUNT(game_custom_values.id) AS game_count").
+		Joins("LEFT JOIN game_custom_values ON game_custom_values.field_id = custom_fields.id").
+		Group("custom_fields.id").
+		Order("LOWER(custom_fields.name)").
+		Scan(&fields).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_custom_fields",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, fields)
+}
+
+func (h *CustomFieldHandler) CreateCustomField(c *gin.Context) {
+	var req middleware.CreateCustomFieldRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	field := models.CustomField{Key: req.Key, Name: req.Name, Type: req.Type, Options: req.Options}
+	if !h.validateCustomField(c, &field) {
+		return
+	}
+
+	if err := h.db.Create(&field).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "create_custom_field",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// UpdateCustomField renames a field or changes its options. Options still
+// used by a game can't be removed, and the type can't change once games
+// have values.
+func (h *CustomFieldHandler) UpdateCustomField(c *gin.Context) {
+	field, ok := h.findCustomField(c)
+	if !ok {
+		return
+	}
+
+	var req middleware.UpdateCustomFieldRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	typeChanged := req.Type != "" && req.Type != field.Type
+	if req.Key != "" {
+		field.Key = req.Key
+	}
+	if req.Name != "" {
+		field.Name = req.Name
+	}
+	if req.Type != "" {
+		field.Type = req.Type
+	}
+	if req.Options != nil {
+		field.Options = req.Options
+	} else if typeChanged {
+		field.Options = nil
+	}
+	if !h.validateCustomField(c, field) {
+		return
+	}
+
+	gameIDs := h.gamesWithValues(field.ID)
+	if typeChanged && len(gameIDs) > 0 {
+		errors.RespondWithError(c, errors.ErrInvalidCustomField, map[string]interface{}{
+			"field":      "type",
+			"error":      "games have values for this field; clear them before changing its type",
+			"game_count": len(gameIDs),
+		})
+		return
+	}
+	if field.Type == services.CustomFieldEnum {
+		var used []string
+		h.db.Model(&models.GameCustomValue{}).Where("field_id = ? AND text_value NOT IN ?", field.ID, []string(field.Options)).
+			Distinct().Pluck("text_value", &used)
+		if len(used) > 0 {
+			errors.RespondWithError(c, errors.ErrInvalidCustomField, map[string]interface{}{
+				"field":  "options",
+				"error":  "games still use options that were removed",
+				"in_use": used,
+			})
+			return
+		}
+	}
+
+	if err := h.db.Save(field).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "update_custom_field",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	for _, id := range gameIDs {
+		h.cache.InvalidateGame(id)
+	}
+	c.JSON(http.StatusOK, field)
+}
+
+// DeleteCustomField deletes a field and every game's value for it
+func (h *CustomFieldHandler) DeleteCustomField(c *gin.Context) {
+	field, ok := h.findCustomField(c)
+	if !ok {
+		return
+	}
+
+	gameIDs := h.gamesWithValues(field.ID)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", field.ID).Delete(&models.GameCustomValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(field).Error
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_custom_field",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	for _, id := range gameIDs {
+		h.cache.InvalidateGame(id)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+// findCustomField loads the custom field named by the :id parameter
+func (h *CustomFieldHandler) findCustomField(c *gin.Context) (*models.CustomField, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return nil, false
+	}
+
+	var field models.CustomField
+	if err := h.db.First(&field, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrCustomFieldNotFound, map[string]interface{}{
+				"custom_field_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return &field, true
+}
+
+// validateCustomField checks the definition and that no other field has the key
+func (h *CustomFieldHandler) validateCustomField(c *gin.Context, field *models.CustomField) bool {
+	if err := services.ValidateCustomField(field); err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidCustomField, map[string]string{
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	var count int64
+	h.db.Model(&models.CustomField{}).Where("key = ? AND id <> ?", field.Key, field.ID).Count(&count)
+	if count > 0 {
+		errors.RespondWithError(c, errors.ErrCustomFieldAlreadyExists, map[string]string{
+			"key": field.Key,
+		})
+		return false
+	}
+	return true
+}
+
+func (h *CustomFieldHandler) gamesWithValues(fieldID uint) []uint {
+	var ids []uint
+	h.db.Model(&models.GameCustomValue{}).Where("field_id = ?", fieldID).Pluck("game_id", &ids)
+	return ids
+}
+
+// findCustomFieldByKey loads a custom field for a GetGames filter or sort,
+// responding 400 when there is none
+func findCustomFieldByKey(c *gin.Context, db *gorm.DB, parameter, key string) (models.CustomField, bool) {
+	var field models.CustomField
+	if err := db.Where("key = ?", key).First(&field).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": parameter,
+				"expected":  "custom field key",
+				"received":  key,
+			})
+			return field, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return field, false
+	}
+	return field, true
+}
+
+// applyCustomFieldFilters restricts a GetGames query by custom[key]=value,
+// custom_min[key]=value and custom_max[key]=value parameters
+func applyCustomFieldFilters(c *gin.Context, db *gorm.DB, query *gorm.DB) (*gorm.DB, bool) {
+	equal, lows, highs := c.QueryMap("custom"), c.QueryMap("custom_min"), c.QueryMap("custom_max")
+	keys := make([]string, 0, len(equal)+len(lows)+len(highs))
+	for _, params := range []map[string]string{equal, lows, highs} {
+		for key := range params {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		parameter := "custom[" + key + "]"
+		if _, ok := lows[key]; ok {
+			parameter = "custom_min[" + key + "]"
+		} else if _, ok := highs[key]; ok {
+			parameter = "custom_max[" + key + "]"
+		}
+		field, ok := findCustomFieldByKey(c, db, parameter, key)
+		if !ok {
+			return nil, false
+		}
+		var condition clause.Expression
+		var err error
+		if value, ok := equal[key]; ok {
+			condition, err = services.CustomFieldCondition(field, value)
+		} else {
+			condition, err = services.CustomFieldRangeCondition(field, lows[key], highs[key])
+		}
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": parameter,
+				"error":     err.Error(),
+			})
+			return nil, false
+		}
+		query = query.Where(condition)
+	}
+	return query, true
+}
+
+// parseGameCustomFields validates custom field values sent with a game,
+// before the game is written
+func parseGameCustomFields(c *gin.Context, db *gorm.DB, values map[string]interface{}) ([]models.GameCustomValue, bool) {
+	parsed, err := services.ParseCustomValues(db, values)
+	if err != nil {
+		if stderrors.Is(err, services.ErrInvalidCustomField) {
+			errors.RespondWithError(c, errors.ErrInvalidCustomField, map[string]string{
+				"field": "custom_fields",
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "custom_field_lookup",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return parsed, true
+}
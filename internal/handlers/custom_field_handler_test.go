@@ -0,0 +1,176 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomFieldHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	tetris := models.Game{Title: "Tetris", PlatformID: 1}
+	db.Create(&tetris)
+
+	titles := func(query string) []string {
+		w := send(server, "GET", "/games?"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Games []models.Game `json:"games"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result := make([]string, len(response.Games))
+		for i, game := range response.Games {
+			result[i] = game.Title
+		}
+		return result
+	}
+	createField := func(body map[string]interface{}) models.CustomField {
+		w := send(server, "POST", "/custom-fields", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var field models.CustomField
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &field))
+		return field
+	}
+	createGame := func(body map[string]interface{}) models.Game {
+		w := send(server, "POST", "/games", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var game models.Game
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+		return game
+	}
+
+	edition := createField(map[string]interface{}{"key": "edition", "name": "Edition", "type": "enum", "options": []string{" Standard", "Collector's"}})
+	assert.Equal(t, models.StringList{"Standard", "Collector's"}, edition.Options)
+	price := createField(map[string]interface{}{"key": "price_paid", "name": "Price paid", "type": "number"})
+	createField(map[string]interface{}{"key": "boxed", "name": "Boxed", "type": "boolean"})
+	createField(map[string]interface{}{"key": "finished_on", "name": "Finished on", "type": "date"})
+	createField(map[string]interface{}{"key": "notes", "name": "Notes", "type": "text"})
+
+	assert.Equal(t, http.StatusConflict, send(server, "POST", "/custom-fields", map[string]interface{}{"key": "boxed", "name": "Boxed again", "type": "boolean"}).Code)
+	for _, body := range []map[string]interface{}{
+		{"key": "Has Box", "name": "Has box", "type": "boolean"},
+		{"key": "region", "name": "Region", "type": "enum"},
+		{"key": "region", "name": "Region", "type": "enum", "options": []string{"PAL", "pal"}},
+		{"key": "weight", "name": "Weight", "type": "number", "options": []string{"kg"}},
+		{"key": "weight", "name": "Weight", "type": "decimal"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, send(server, "POST", "/custom-fields", body).Code, body)
+	}
+
+	// Values are validated before the game is written
+	for _, values := range []map[string]interface{}{
+		{"price_paid": "cheap"},
+		{"edition": "Deluxe"},
+		{"finished_on": "last week"},
+		{"boxed": "yes"},
+		{"region": "PAL"},
+	} {
+		w := send(server, "POST", "/games", map[string]interface{}{"title": "Rejected", "platform_id": 1, "custom_fields": values})
+		assert.Equal(t, http.StatusBadRequest, w.Code, values)
+	}
+	var rejected int64
+	db.Model(&models.Game{}).Where("title = ?", "Rejected").Count(&rejected)
+	assert.Zero(t, rejected)
+
+	zelda := createGame(map[string]interface{}{"title": "Zelda", "platform_id": 1, "custom_fields": map[string]interface{}{
+		"edition": "collector's", "price_paid": 45.5, "boxed": true, "notes": "  Gold cartridge ",
+	}})
+	assert.Equal(t, map[string]interface{}{"edition": "Collector's", "price_paid": 45.5, "boxed": true, "notes": "Gold cartridge"}, zelda.CustomFields)
+	mario := createGame(map[string]interface{}{"title": "Mario Kart", "platform_id": 1, "custom_fields": map[string]interface{}{
+		"edition": "Standard", "price_paid": 20, "boxed": false, "finished_on": "2024-02-10",
+	}})
+
+	// Updates change the values given, and null clears one
+	w := send(server, "PUT", fmt.Sprintf("/games/%d", zelda.ID), map[string]interface{}{"custom_fields": map[string]interface{}{"boxed": nil, "price_paid": 50}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = send(server, "GET", fmt.Sprintf("/games/%d", zelda.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched models.Game
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Equal(t, map[string]interface{}{"edition": "Collector's", "price_paid": 50.0, "notes": "Gold cartridge"}, fetched.CustomFields)
+
+	// Filters
+	assert.Equal(t, []string{"Zelda"}, titles("custom[edition]=COLLECTOR'S"))
+	assert.Equal(t, []string{"Mario Kart"}, titles("custom[boxed]=false"))
+	assert.Equal(t, []string{"Mario Kart", "Zelda"}, titles("custom_min[price_paid]=20"))
+	assert.Equal(t, []string{"Zelda"}, titles("custom_min[price_paid]=21&custom_max[price_paid]=60"))
+	assert.Equal(t, []string{"Mario Kart"}, titles("custom_max[finished_on]=2024-03-01"))
+	assert.Equal(t, []string{"Mario Kart"}, titles("custom[price_paid]=20"))
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/games?custom[region]=PAL", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/games?custom[boxed]=maybe", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/games?custom_min[notes]=a", nil).Code)
+
+	// Sorting puts games without a value last either way
+	assert.Equal(t, []string{"Mario Kart", "Zelda", "Tetris"}, titles("sort=custom.price_paid"))
+	assert.Equal(t, []string{"Zelda", "Mario Kart", "Tetris"}, titles("sort=-custom.price_paid"))
+	assert.Equal(t, []string{"Zelda", "Mario Kart", "Tetris"}, titles("sort=custom.edition"))
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/games?sort=custom.region", nil).Code)
+
+	// Field changes can't strand existing values
+	assert.Equal(t, http.StatusBadRequest, send(server, "PUT", fmt.Sprintf("/custom-fields/%d", price.ID), map[string]interface{}{"type": "text"}).Code)
+	w = send(server, "PUT", fmt.Sprintf("/custom-fields/%d", edition.ID), map[string]interface{}{"options": []string{"Standard", "Limited"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Collector's")
+	w = send(server, "PUT", fmt.Sprintf("/custom-fields/%d", edition.ID), map[string]interface{}{"key": "release", "options": []string{"Standard", "Collector's", "Limited"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"Zelda"}, titles("custom[release]=collector's"))
+
+	w = send(server, "GET", "/custom-fields", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fields []struct {
+		Key       string `json:"key"`
+		GameCount int64  `json:"game_count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fields))
+	require.Len(t, fields, 5)
+	assert.Equal(t, "boxed", fields[0].Key)
+	assert.Equal(t, int64(1), fields[0].GameCount)
+
+	// Deleting a field deletes its values
+	require.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/custom-fields/%d", price.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("/custom-fields/%d", price.ID), nil).Code)
+	var values int64
+	db.Model(&models.GameCustomValue{}).Where("field_id = ?", price.ID).Count(&values)
+	assert.Zero(t, values)
+	w = send(server, "GET", fmt.Sprintf("/games/%d", mario.ID), nil)
+	var marioFetched models.Game
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &marioFetched))
+	assert.Equal(t, map[string]interface{}{"release": "Standard", "boxed": false, "finished_on": "2024-02-10"}, marioFetched.CustomFields)
+
+	require.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/games/%d", mario.ID), nil).Code)
+	db.Model(&models.GameCustomValue{}).Where("game_id = ?", mario.ID).Count(&values)
+	assert.Zero(t, values)
+}
+
+func TestCustomFieldHandler_GameWritesAreAtomic(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	w := send(server, "POST", "/custom-fields", map[string]interface{}{"key": "boxed", "name": "Boxed", "type": "boolean"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	zelda := models.Game{Title: "Zelda", PlatformID: 1}
+	require.NoError(t, db.Create(&zelda).Error)
+
+	// Make saving custom values fail after the game itself is written
+	require.NoError(t, db.Migrator().DropTable(&models.GameCustomValue{}))
+
+	w = send(server, "POST", "/games", map[string]interface{}{"title": "Metroid", "platform_id": 1, "custom_fields": map[string]interface{}{"boxed": true}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "save_custom_fields")
+	var count int64
+	db.Model(&models.Game{}).Where("title = ?", "Metroid").Count(&count)
+	assert.Zero(t, count, "the game is rolled back with its custom values")
+
+	w = send(server, "PUT", fmt.Sprintf("/games/%d", zelda.ID), map[string]interface{}{"title": "Renamed", "custom_fields": map[string]interface{}{"boxed": true}})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NoError(t, db.First(&zelda, zelda.ID).Error)
+	assert.Equal(t, "Zelda", zelda.Title)
+}
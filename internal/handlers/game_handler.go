@@ -3,8 +3,9 @@ package handlers
 import (
 	"log/slog"
 	"net/http"
-	"sort"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
 	"pelico/internal/errors"
 	"pelico/internal/middleware"
@@ -12,19 +13,22 @@ import (
 	"pelico/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type GameHandler struct {
 	db              *gorm.DB
 	metadataService *services.MetadataService
+	images          *services.ImageService
 	cache           *services.CacheService
 	logger          *services.LoggerService
 }
 
-func NewGameHandler(db *gorm.DB, clientID, clientSecret string, cache *services.CacheService, logger *services.LoggerService) *GameHandler {
+func NewGameHandler(db *gorm.DB, metadataService *services.MetadataService, images *services.ImageService, cache *services.CacheService, logger *services.LoggerService) *GameHandler {
 	return &GameHandler{
 		db:              db,
-		metadataService: services.NewMetadataService(clientID, clientSecret),
+		metadataService: metadataService,
+		images:          images,
 		cache:           cache,
 		logger:          logger,
 	}
@@ -48,8 +52,19 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 		}
 	}
 	
-	if s := c.Query("sort"); s != "" {
-		allowedSorts := []string{"title", "year", "rating", "created_at"}
+	// Custom fields sort with sort=custom.<key>, or sort=-custom.<key> for descending
+	var customSort models.CustomField
+	customSortDesc := false
+	if s := c.Query("sort"); strings.HasPrefix(strings.TrimPrefix(s, "-"), "custom.") {
+		var ok bool
+		customSort, ok = findCustomFieldByKey(c, h.db, "sort", strings.TrimPrefix(strings.TrimPrefix(s, "-"), "custom."))
+		if !ok {
+			return
+		}
+		sort = "custom"
+		customSortDesc = strings.HasPrefix(s, "-")
+	} else if s != "" {
+		allowedSorts := []string{"title", "year", "rating", "created_at", "release_date", "age_rating"}
 		for _, allowed := range allowedSorts {
 			if s == allowed {
 				sort = s
@@ -61,8 +76,13 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 	// Parse filter parameters
 	platformFilter := c.Query("platform")
 	genreFilter := c.Query("genre")
+	genreFilters := parseNameFilter(c.QueryArray("genre"))
+	genreMode := c.DefaultQuery("genre_mode", "or")
 	completionFilter := c.Query("completion_status")
 	formatFilter := c.Query("collection_format")
+	tagFilters := parseNameFilter(c.QueryArray("tag"))
+	tagMode := c.DefaultQuery("tag_mode", "or")
+	collectionFilter := c.Query("collection")
 	
 	// Build base query with filters
 	baseQuery := h.db.Model(&models.Game{})
@@ -83,9 +103,28 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 		}
 	}
 	
-	// Apply genre filter
-	if genreFilter != "" && genreFilter != "all" {
-		baseQuery = baseQuery.Where("genre = ?", genreFilter)
+	// Apply genre filter (?genre=RPG&genre=Action or ?genre=RPG,Action; genre_mode=and|or)
+	baseQuery = applyGenreFilter(baseQuery, genreFilters, genreMode == "and")
+	
+	// Apply tag filter (?tag=co-op&tag=couch or ?tag=co-op,couch; tag_mode=and|or)
+	baseQuery = applyTagFilter(baseQuery, tagFilters, tagMode == "and")
+	
+	// Apply user collection filter (?collection=<collection id>); smart
+	// collections filter by their rules
+	if collectionFilter != "" {
+		collectionID, err := strconv.ParseUint(collectionFilter, 10, 32)
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "collection",
+				"expected":  "collection id",
+				"received":  collectionFilter,
+			})
+			return
+		}
+		var ok bool
+		if baseQuery, ok = h.filterByCollection(c, baseQuery, uint(collectionID)); !ok {
+			return
+		}
 	}
 	
 	// Apply completion status filter
@@ -99,7 +138,74 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 	
 	// Apply collection format filter
 	if formatFilter != "" && formatFilter != "all" {
-		baseQuery = baseQuery.Where("collection_formats::text LIKE ?", "%\""+formatFilter+"\"%")
+		baseQuery = baseQuery.Where(services.JSONArrayContains(h.db, "collection_formats", formatFilter))
+	}
+	
+	// Apply extended metadata filters (developer, publisher, franchise, ...)
+	metadataFilters := make(gin.H)
+	for param, subquery := range metadataFilterSubqueries {
+		if value := c.Query(param); value != "" && value != "all" {
+			baseQuery = baseQuery.Where("games.id IN ("+subquery+")", value)
+			metadataFilters[param] = value
+		}
+	}
+	
+	// Apply regional release filters (?release_region=japan&released_after=1995&released_before=1996-06-01)
+	releaseRegion := ""
+	if value := c.Query("release_region"); value != "" && value != "all" {
+		region, ok := services.NormalizeReleaseRegion(value)
+		if !ok {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "release_region",
+				"expected":  "region such as japan, europe, north_america, worldwide (or jp, pal, na)",
+				"received":  value,
+			})
+			return
+		}
+		releaseRegion = region
+	}
+	releasedAfter, ok := parseReleaseDateParam(c, "released_after")
+	if !ok {
+		return
+	}
+	releasedBefore, ok := parseReleaseDateParam(c, "released_before")
+	if !ok {
+		return
+	}
+	baseQuery = applyReleaseFilter(baseQuery, releaseRegion, releasedAfter, releasedBefore)
+	
+	// Apply age rating filters (?age_rating=PEGI:12, ?max_age=12)
+	ageRatingFilter := c.Query("age_rating")
+	if ageRatingFilter != "" && ageRatingFilter != "all" {
+		board, rating, ok := services.ParseAgeRatingFilter(ageRatingFilter)
+		if !ok {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "age_rating",
+				"expected":  "board:rating, e.g. PEGI:12 or ESRB:T",
+				"received":  ageRatingFilter,
+			})
+			return
+		}
+		baseQuery = baseQuery.Where("games.id IN (SELECT game_id FROM age_ratings WHERE UPPER(board) = ? AND UPPER(rating) = ?)", board, rating)
+	}
+	maxAgeFilter := c.Query("max_age")
+	if maxAgeFilter != "" {
+		maxAge, err := strconv.Atoi(maxAgeFilter)
+		if err != nil || maxAge < 0 {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "max_age",
+				"expected":  "non-negative integer",
+				"received":  maxAgeFilter,
+			})
+			return
+		}
+		// Every rating the game has must allow the age; unrated games are excluded
+		baseQuery = baseQuery.Where("games.id IN (SELECT game_id FROM age_ratings GROUP BY game_id HAVING MAX(minimum_age) <= ?)", maxAge)
+	}
+	
+	// Apply custom field filters (?custom[edition]=Collector's, ?custom_min[price_paid]=20&custom_max[price_paid]=50)
+	if baseQuery, ok = applyCustomFieldFilters(c, h.db, baseQuery); !ok {
+		return
 	}
 	
 	// Get total count with filters applied
@@ -115,7 +221,8 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 	
 	// Get paginated games with filters
 	var games []models.Game
-	query := baseQuery.Preload("Platform").Preload("FileLocations").
+	query := baseQuery.Preload("Platform").Preload("FileLocations").Preload("Images").Preload("Genres").Preload("Tags").
+		Preload("ReleaseDates").Preload("AgeRatings").Preload("TimeToBeat").Preload("CustomValues.Field").
 		Offset(offset).Limit(limit)
 	
 	// Apply sorting
@@ -126,6 +233,27 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 		query = query.Order("rating DESC")
 	case "created_at":
 		query = query.Order("created_at DESC")
+	case "release_date":
+		// Newest first by first release (in release_region when given); undated games last
+		firstRelease := "(SELECT MIN(date) FROM release_dates WHERE release_dates.game_id = games.id"
+		var vars []interface{}
+		if releaseRegion != "" {
+			firstRelease += " AND release_dates.region = ?"
+			vars = append(vars, releaseRegion, releaseRegion)
+		}
+		firstRelease += ")"
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                firstRelease + " IS NULL, " + firstRelease + " DESC, title ASC",
+			Vars:               vars,
+			WithoutParentheses: true,
+		}})
+	case "age_rating":
+		// Youngest audience first; unrated games last
+		strictest := "(SELECT MAX(minimum_age) FROM age_ratings WHERE age_ratings.game_id = games.id)"
+		query = query.Order(strictest + " IS NULL, " + strictest + " ASC, title ASC")
+	case "custom":
+		// By the field's value; games without one last
+		query = query.Order(services.CustomFieldOrder(customSort, customSortDesc))
 	default:
 		query = query.Order("title ASC")
 	}
@@ -171,13 +299,180 @@ func (h *GameHandler) GetGames(c *gin.Context) {
 			"has_prev":    page > 1,
 		},
 		"filters": gin.H{
-			"platform":   platformFilter,
-			"genre":      genreFilter,
-			"completion": completionFilter,
+			"platform":        platformFilter,
+			"genre":           genreFilter,
+			"genres":          genreFilters,
+			"genre_mode":      genreMode,
+			"tags":            tagFilters,
+			"tag_mode":        tagMode,
+			"collection":      collectionFilter,
+			"completion":      completionFilter,
+			"metadata":        metadataFilters,
+			"release_region":  releaseRegion,
+			"released_after":  c.Query("released_after"),
+			"released_before": c.Query("released_before"),
+			"age_rating":      ageRatingFilter,
+			"max_age":         maxAgeFilter,
 		},
 	})
 }
 
+// parseNameFilter flattens repeated and comma-separated genre or tag parameters, ignoring "all"
+func parseNameFilter(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" && name != "all" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// applyGenreFilter restricts games to those having any (matchAll=false) or all
+// (matchAll=true) of the given genres, compared case-insensitively
+func applyGenreFilter(query *gorm.DB, genres []string, matchAll bool) *gorm.DB {
+	return applyNameFilter(query, "game_genres", "genres", "genre_id", genres, matchAll)
+}
+
+// applyTagFilter restricts games to those having any or all of the given tags,
+// like applyGenreFilter
+func applyTagFilter(query *gorm.DB, tags []string, matchAll bool) *gorm.DB {
+	return applyNameFilter(query, "game_tags", "tags", "tag_id", tags, matchAll)
+}
+
+// applyNameFilter matches games through a many-to-many join table against the
+// names of the joined table's rows
+func applyNameFilter(query *gorm.DB, joinTable, table, key string, names []string, matchAll bool) *gorm.DB {
+	if len(names) == 0 {
+		return query
+	}
+	
+	lowered := make([]string, len(names))
+	for i, name := range names {
+		lowered[i] = strings.ToLower(name)
+	}
+	
+	subquery := "SELECT " + joinTable + ".game_id FROM " + joinTable +
+		" JOIN " + table + " ON " + table + ".id = " + joinTable + "." + key +
+		" WHERE LOWER(" + table + ".name) IN ?"
+	if matchAll {
+		subquery += " GROUP BY " + joinTable + ".game_id HAVING COUNT(DISTINCT " + table + ".id) = ?"
+		return query.Where("games.id IN ("+subquery+")", lowered, len(uniqueStrings(lowered)))
+	}
+	return query.Where("games.id IN ("+subquery+")", lowered)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// parseReleaseDateParam reads a YYYY, YYYY-MM or YYYY-MM-DD query parameter, responding
+// with an error and returning ok=false when it is malformed
+func parseReleaseDateParam(c *gin.Context, param string) (*time.Time, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed, true
+		}
+	}
+	errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+		"parameter": param,
+		"expected":  "date as YYYY, YYYY-MM or YYYY-MM-DD",
+		"received":  value,
+	})
+	return nil, false
+}
+
+// applyReleaseFilter restricts games to those released in a region and/or within a
+// date range; after is inclusive and before exclusive
+func applyReleaseFilter(query *gorm.DB, region string, after, before *time.Time) *gorm.DB {
+	if region == "" && after == nil && before == nil {
+		return query
+	}
+	
+	var conditions []string
+	var args []interface{}
+	if region != "" {
+		conditions = append(conditions, "region = ?")
+		args = append(args, region)
+	}
+	if after != nil {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, *after)
+	}
+	if before != nil {
+		conditions = append(conditions, "date < ?")
+		args = append(args, *before)
+	}
+	return query.Where("games.id IN (SELECT game_id FROM release_dates WHERE "+strings.Join(conditions, " AND ")+")", args...)
+}
+
+// filterByCollection restricts a games query to a collection's games, by its
+// rules for smart collections
+func (h *GameHandler) filterByCollection(c *gin.Context, query *gorm.DB, collectionID uint) (*gorm.DB, bool) {
+	var collection models.Collection
+	if err := h.db.First(&collection, collectionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			errors.RespondWithError(c, errors.ErrCollectionNotFound, map[string]interface{}{
+				"collection_id": collectionID,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	
+	query, err := collectionGames(h.db, query, &collection)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "evaluate_collection_rules",
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return query, true
+}
+
+// metadataFilterSubqueries maps GetGames query parameters to subqueries selecting
+// matching game IDs; names are matched case-insensitively
+var metadataFilterSubqueries = map[string]string{
+	"developer": `SELECT game_companies.game_id FROM game_companies
+		JOIN companies ON companies.id = game_companies.company_id
+		WHERE game_companies.role = 'developer' AND LOWER(companies.name) = LOWER(?)`,
+	"publisher": `SELECT game_companies.game_id FROM game_companies
+		JOIN companies ON companies.id = game_companies.company_id
+		WHERE game_companies.role = 'publisher' AND LOWER(companies.name) = LOWER(?)`,
+	"franchise": `SELECT game_franchises.game_id FROM game_franchises
+		JOIN franchises ON franchises.id = game_franchises.franchise_id
+		WHERE LOWER(franchises.name) = LOWER(?)`,
+	"game_mode": `SELECT game_game_modes.game_id FROM game_game_modes
+		JOIN game_modes ON game_modes.id = game_game_modes.game_mode_id
+		WHERE LOWER(game_modes.name) = LOWER(?)`,
+	"theme": `SELECT game_themes.game_id FROM game_themes
+		JOIN themes ON themes.id = game_themes.theme_id
+		WHERE LOWER(themes.name) = LOWER(?)`,
+	"perspective": `SELECT game_player_perspectives.game_id FROM game_player_perspectives
+		JOIN player_perspectives ON player_perspectives.id = game_player_perspectives.player_perspective_id
+		WHERE LOWER(player_perspectives.name) = LOWER(?)`,
+}
+
 func (h *GameHandler) GetGame(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -201,7 +496,11 @@ func (h *GameHandler) GetGame(c *gin.Context) {
 	h.logger.LogCacheOperation("get", "game", false, slog.Uint64("game_id", uint64(gameID)))
 	
 	var game models.Game
-	result := h.db.Preload("Platform").Preload("FileLocations").Preload("PlaySessions").First(&game, id)
+	result := h.db.Preload("Platform").Preload("FileLocations").Preload("PlaySessions").Preload("Images").Preload("PhysicalCopies").
+		Preload("Genres").Preload("Tags").Preload("CustomValues.Field").Preload("Companies.Company").Preload("Franchises").Preload("GameModes").Preload("Themes").
+		Preload("PlayerPerspectives").Preload("Media").Preload("AlternativeNames").
+		Preload("ReleaseDates").Preload("AgeRatings").Preload("TimeToBeat").
+		First(&game, id)
 	
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -245,6 +544,11 @@ func (h *GameHandler) CreateGame(c *gin.Context) {
 		return
 	}
 	
+	customValues, ok := parseGameCustomFields(c, h.db, req.CustomFields)
+	if !ok {
+		return
+	}
+	
 	// Create game from validated request
 	game := models.Game{
 		Title:             req.Title,
@@ -257,17 +561,35 @@ func (h *GameHandler) CreateGame(c *gin.Context) {
 		CollectionFormats: models.CollectionFormats(req.CollectionFormats),
 	}
 	
-	result := h.db.Create(&game)
-	if result.Error != nil {
+	// The game is only created along with its genres and custom values
+	operation := "create_game"
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+		if names := middleware.GenreNames(req.Genres, req.Genre); len(names) > 0 {
+			operation = "save_genres"
+			if err := services.SaveGameGenres(tx, game.ID, names); err != nil {
+				return err
+			}
+		}
+		if len(customValues) > 0 {
+			operation = "save_custom_fields"
+			return services.SaveGameCustomValues(tx, game.ID, customValues)
+		}
+		return nil
+	})
+	if err != nil {
 		h.logger.LogGameOperation(c, "create", 0, 
-			slog.String("error", result.Error.Error()),
+			slog.String("error", err.Error()),
 			slog.Bool("success", false))
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
-			"operation": "create_game",
-			"error": result.Error.Error(),
+			"operation": operation,
+			"error": err.Error(),
 		})
 		return
 	}
+	h.db.Preload("Genres").Preload("CustomValues.Field").First(&game, game.ID)
 	
 	// Log successful creation
 	h.logger.LogGameOperation(c, "create", game.ID, 
@@ -298,6 +620,11 @@ func (h *GameHandler) UpdateGame(c *gin.Context) {
 		return
 	}
 	
+	customValues, ok := parseGameCustomFields(c, h.db, req.CustomFields)
+	if !ok {
+		return
+	}
+	
 	var game models.Game
 	if result := h.db.First(&game, id); result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -338,9 +665,6 @@ func (h *GameHandler) UpdateGame(c *gin.Context) {
 	if req.Year != 0 {
 		game.Year = req.Year
 	}
-	if req.Genre != "" {
-		game.Genre = req.Genre
-	}
 	if req.Rating != 0 {
 		game.Rating = req.Rating
 	}
@@ -354,14 +678,32 @@ func (h *GameHandler) UpdateGame(c *gin.Context) {
 		game.CollectionFormats = models.CollectionFormats(req.CollectionFormats)
 	}
 	
-	result := h.db.Save(&game)
-	if result.Error != nil {
+	// A failed genre or custom value write leaves the game as it was
+	operation := "update_game"
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&game).Error; err != nil {
+			return err
+		}
+		if names := middleware.GenreNames(req.Genres, req.Genre); names != nil {
+			operation = "save_genres"
+			if err := services.SaveGameGenres(tx, game.ID, names); err != nil {
+				return err
+			}
+		}
+		if len(customValues) > 0 {
+			operation = "save_custom_fields"
+			return services.SaveGameCustomValues(tx, game.ID, customValues)
+		}
+		return nil
+	})
+	if err != nil {
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
-			"operation": "update_game",
-			"error": result.Error.Error(),
+			"operation": operation,
+			"error": err.Error(),
 		})
 		return
 	}
+	h.db.Preload("Genres").Preload("CustomValues.Field").First(&game, game.ID)
 	
 	// Invalidate cache for this game and related data
 	h.cache.InvalidateGame(uint(id))
@@ -382,21 +724,34 @@ func (h *GameHandler) DeleteGame(c *gin.Context) {
 		return
 	}
 	
-	result := h.db.Delete(&models.Game{}, id)
-	if result.Error != nil {
+	var found bool
+	var imageHashes []string
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Game{}).Where("id = ?", id).Count(&count).Error; err != nil || count == 0 {
+			return err
+		}
+		found = true
+		if err := tx.Model(&models.GameImage{}).Where("game_id = ?", id).Distinct().Pluck("hash", &imageHashes).Error; err != nil {
+			return err
+		}
+		return deleteGame(tx, uint(id))
+	})
+	if err != nil {
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
 			"operation": "delete_game",
-			"error": result.Error.Error(),
+			"error": err.Error(),
 		})
 		return
 	}
 	
-	if result.RowsAffected == 0 {
+	if !found {
 		errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
 			"game_id": id,
 		})
 		return
 	}
+	h.images.RemoveUnreferenced(imageHashes...)
 	
 	// Invalidate caches since game was deleted
 	h.cache.InvalidateGame(uint(id))
@@ -406,11 +761,56 @@ func (h *GameHandler) DeleteGame(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Game deleted successfully"})
 }
 
+// deleteGame deletes a game with the rows that belong to it: those of every
+// model with a game_id column and of the game's many-to-many join tables.
+// Postgres cascades these through foreign keys but SQLite doesn't enforce
+// them. Goals are kept, no longer tied to a game.
+func deleteGame(tx *gorm.DB, id uint) error {
+	game := &gorm.Statement{DB: tx}
+	if err := game.Parse(&models.Game{}); err != nil {
+		return err
+	}
+	for _, relation := range game.Schema.Relationships.Many2Many {
+		if err := tx.Table(relation.JoinTable.Table).Where("game_id = ?", id).Delete(nil).Error; err != nil {
+			return err
+		}
+	}
+	
+	for _, model := range models.All() {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		field := stmt.Schema.LookUpField("game_id")
+		if field == nil || stmt.Schema.Table == game.Schema.Table {
+			continue
+		}
+		query := tx.Model(model).Where("game_id = ?", id)
+		var err error
+		if field.FieldType.Kind() == reflect.Ptr {
+			err = query.Update("game_id", nil).Error
+		} else {
+			err = query.Delete(model).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	
+	// By primary key rather than condition so the search index drops the game
+	return tx.Delete(&models.Game{ID: id}).Error
+}
+
 func (h *GameHandler) SearchGames(c *gin.Context) {
 	var searchParams struct {
 		Title            string `json:"title"`
 		Platform         string `json:"platform"`
 		Genre            string `json:"genre"`
+		Genres           []string `json:"genres"`
+		GenreMode        string `json:"genre_mode"` // "or" (default) or "and"
+		Tags             []string `json:"tags"`
+		TagMode          string `json:"tag_mode"` // "or" (default) or "and"
+		CollectionID     uint   `json:"collection_id"`
 		Year             int    `json:"year"`
 		Format           string `json:"format"`
 		CompletionStatus string `json:"completion_status"`
@@ -421,23 +821,34 @@ func (h *GameHandler) SearchGames(c *gin.Context) {
 		return
 	}
 	
-	query := h.db.Preload("Platform").Preload("FileLocations")
+	query := h.db.Model(&models.Game{}).Preload("Platform").Preload("FileLocations").Preload("Genres").Preload("Tags").Preload("CustomValues.Field")
 	
 	if searchParams.Title != "" {
-		query = query.Where("title ILIKE ?", "%"+searchParams.Title+"%")
+		query = query.Where(services.ContainsFold(h.db, "games.title", searchParams.Title))
 	}
 	if searchParams.Genre != "" {
-		query = query.Where("genre ILIKE ?", "%"+searchParams.Genre+"%")
+		genreMatches := h.db.Table("game_genres").Select("game_genres.game_id").
+			Joins("JOIN genres ON genres.id = game_genres.genre_id").
+			Where(services.ContainsFold(h.db, "genres.name", searchParams.Genre))
+		query = query.Where("games.id IN (?)", genreMatches)
+	}
+	query = applyGenreFilter(query, searchParams.Genres, searchParams.GenreMode == "and")
+	query = applyTagFilter(query, searchParams.Tags, searchParams.TagMode == "and")
+	if searchParams.CollectionID != 0 {
+		var ok bool
+		if query, ok = h.filterByCollection(c, query, searchParams.CollectionID); !ok {
+			return
+		}
 	}
 	if searchParams.Year != 0 {
 		query = query.Where("year = ?", searchParams.Year)
 	}
 	if searchParams.Platform != "" {
 		query = query.Joins("JOIN platforms ON games.platform_id = platforms.id").
-			Where("platforms.name ILIKE ?", "%"+searchParams.Platform+"%")
+			Where(services.ContainsFold(h.db, "platforms.name", searchParams.Platform))
 	}
 	if searchParams.Format != "" && searchParams.Format != "all" {
-		query = query.Where("collection_formats::text LIKE ?", "%\""+searchParams.Format+"\"%")
+		query = query.Where(services.JSONArrayContains(h.db, "collection_formats", searchParams.Format))
 	}
 	if searchParams.CompletionStatus != "" && searchParams.CompletionStatus != "all" {
 		if searchParams.CompletionStatus == "backlog" {
@@ -466,12 +877,13 @@ func (h *GameHandler) FetchMetadata(c *gin.Context) {
 	}
 	
 	var game models.Game
-	if result := h.db.Preload("Platform").First(&game, id); result.Error != nil {
+	if result := h.db.Preload("Platform").Preload("FileLocations").First(&game, id); result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
 		return
 	}
 	
-	metadata, err := h.metadataService.FetchGameMetadata(game.Title, game.Platform.Name)
+	// ROM games are identified by file hash when possible
+	metadata, err := h.metadataService.FetchMetadataForGame(&game)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch metadata: " + err.Error()})
 		return
@@ -499,12 +911,27 @@ func (h *GameHandler) FetchMetadata(c *gin.Context) {
 	if metadata.IGDBID > 0 {
 		game.IGDBID = metadata.IGDBID
 	}
+	if metadata.ScreenScraperID > 0 {
+		game.ScreenScraperID = metadata.ScreenScraperID
+	}
 	
-	if result := h.db.Save(&game); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update game: " + result.Error.Error()})
+	// The game and its metadata details are saved together or not at all
+	failure := "Failed to update game: "
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&game).Error; err != nil {
+			return err
+		}
+		failure = "Failed to save metadata details: "
+		return services.SaveMetadataDetails(tx, game.ID, metadata)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure + err.Error()})
 		return
 	}
 	
+	h.cacheGameArt(c, &game)
+	h.cache.InvalidateGame(game.ID)
+	
 	c.JSON(http.StatusOK, game)
 }
 
@@ -514,7 +941,15 @@ func (h *GameHandler) SearchMetadata(c *gin.Context) {
 		return
 	}
 	
-	results, err := h.metadataService.SearchGames(req.Title, req.Platform)
+	platform, err := h.resolveSearchPlatform(req.PlatformID, req.Platform)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrPlatformNotFound, map[string]interface{}{
+			"platform_id": req.PlatformID,
+		})
+		return
+	}
+	
+	results, err := h.metadataService.SearchGames(req.Title, platform)
 	if err != nil {
 		errors.RespondWithError(c, errors.ErrMetadataAPIError, map[string]string{
 			"operation": "search_games",
@@ -526,6 +961,25 @@ func (h *GameHandler) SearchMetadata(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
+// resolveSearchPlatform finds the platform (and its provider IDs) for a metadata search.
+// Unknown platform names are still passed through so results can be filtered by name.
+func (h *GameHandler) resolveSearchPlatform(platformID uint, name string) (*models.Platform, error) {
+	var platform models.Platform
+	if platformID > 0 {
+		if err := h.db.First(&platform, platformID).Error; err != nil {
+			return nil, err
+		}
+		return &platform, nil
+	}
+	if name == "" {
+		return nil, nil
+	}
+	if err := h.db.Where(services.EqualFold("name", name)).First(&platform).Error; err == nil {
+		return &platform, nil
+	}
+	return &models.Platform{Name: name}, nil
+}
+
 func (h *GameHandler) CreateGameFromMetadata(c *gin.Context) {
 	var request struct {
 		PlatformID uint                          `json:"platform_id" binding:"required"`
@@ -550,18 +1004,41 @@ func (h *GameHandler) CreateGameFromMetadata(c *gin.Context) {
 		IGDBID:      request.Metadata.IGDBID,
 	}
 	
-	result := h.db.Create(&game)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	// A failure saving the details must not leave the game behind
+	failure := ""
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&game).Error; err != nil {
+			return err
+		}
+		failure = "Failed to save metadata details: "
+		return services.SaveMetadataDetails(tx, game.ID, &request.Metadata)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure + err.Error()})
 		return
 	}
 	
+	h.cacheGameArt(c, &game)
+	
 	// Reload with platform
-	h.db.Preload("Platform").Preload("FileLocations").First(&game, game.ID)
+	h.db.Preload("Platform").Preload("FileLocations").Preload("Images").First(&game, game.ID)
 	
 	c.JSON(http.StatusCreated, game)
 }
 
+// cacheGameArt stores metadata artwork locally when auto-download is enabled.
+// Failures are logged but never fail the metadata update itself.
+func (h *GameHandler) cacheGameArt(c *gin.Context, game *models.Game) {
+	if !h.images.AutoDownload() {
+		return
+	}
+	if err := h.images.CacheGameArt(game); err != nil {
+		h.logger.LogWithContext(c, slog.LevelWarn, "image_cache_failed",
+			slog.Uint64("game_id", uint64(game.ID)),
+			slog.String("error", err.Error()))
+	}
+}
+
 func (h *GameHandler) GetRecentlyPlayedGames(c *gin.Context) {
 	// Try to get from cache first
 	if cachedGames, found := h.cache.GetRecentlyPlayed(); found {
@@ -632,19 +1109,21 @@ func (h *GameHandler) UpdateCompletionStatus(c *gin.Context) {
 		return
 	}
 	
+	// Set the completion date when a game is finished or abandoned, keeping the
+	// original date when only the percentage or notes change
+	finished := req.Status == "completed" || req.Status == "100_percent" || req.Status == "abandoned"
+	if !finished {
+		game.CompletionDate = nil
+	} else if game.CompletionDate == nil || game.CompletionStatus != req.Status {
+		now := time.Now()
+		game.CompletionDate = &now
+	}
+	
 	// Update completion fields
 	game.CompletionStatus = req.Status
 	game.CompletionPercentage = req.Percentage
 	game.CompletionNotes = req.Notes
 	
-	// Set completion date when marked as completed
-	if req.Status == "completed" || req.Status == "100_percent" {
-		now := time.Now()
-		game.CompletionDate = &now
-	} else {
-		game.CompletionDate = nil
-	}
-	
 	if err := h.db.Save(&game).Error; err != nil {
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
 			"operation": "update_completion",
@@ -738,15 +1217,21 @@ func (h *GameHandler) GetGamesByCompletionStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, games)
 }
 
-// GetGenres returns all unique genres from the game collection
+// GetGenres returns all genres with the number of games in each
 func (h *GameHandler) GetGenres(c *gin.Context) {
-	var genres []string
+	type GenreCount struct {
+		ID    uint   `json:"id"`
+		Name  string `json:"name"`
+		Count int64  `json:"count"`
+	}
 	
-	// Get distinct genres from all games
-	result := h.db.Model(&models.Game{}).
-		Distinct("genre").
-		Where("genre != '' AND genre IS NOT NULL").
-		Pluck("genre", &genres)
+	var genres []GenreCount
+	result := h.db.Table("genres").
+		Select("genres.id, genres.name, COUNT(game_genres.game_id) as count").
+		Joins("JOIN game_genres ON game_genres.genre_id = genres.id").
+		Group("genres.id, genres.name").
+		Order("genres.name ASC").
+		Scan(&genres)
 	
 	if result.Error != nil {
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
@@ -756,8 +1241,62 @@ func (h *GameHandler) GetGenres(c *gin.Context) {
 		return
 	}
 	
-	// Sort genres alphabetically
-	sort.Strings(genres)
-	
 	c.JSON(http.StatusOK, genres)
+}
+
+// GetCompanies returns companies with their game counts, optionally filtered by ?role=developer|publisher
+func (h *GameHandler) GetCompanies(c *gin.Context) {
+	type CompanyCount struct {
+		ID    uint   `json:"id"`
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+		Games int64  `json:"games"`
+	}
+	
+	query := h.db.Table("companies").
+		Select("companies.id, companies.name, game_companies.role, COUNT(DISTINCT game_companies.game_id) as games").
+		Joins("JOIN game_companies ON game_companies.company_id = companies.id").
+		Group("companies.id, companies.name, game_companies.role").
+		Order("companies.name ASC")
+	
+	if role := c.Query("role"); role != "" {
+		query = query.Where("game_companies.role = ?", role)
+	}
+	
+	var companies []CompanyCount
+	if err := query.Scan(&companies).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_companies",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, companies)
+}
+
+// GetFranchises returns franchises with their game counts
+func (h *GameHandler) GetFranchises(c *gin.Context) {
+	type FranchiseCount struct {
+		ID    uint   `json:"id"`
+		Name  string `json:"name"`
+		Games int64  `json:"games"`
+	}
+	
+	var franchises []FranchiseCount
+	err := h.db.Table("franchises").
+		Select("franchises.id, franchises.name, COUNT(game_franchises.game_id) as games").
+		Joins("JOIN game_franchises ON game_franchises.franchise_id = franchises.id").
+		Group("franchises.id, franchises.name").
+		Order("franchises.name ASC").
+		Scan(&franchises).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_franchises",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, franchises)
 }
\ No newline at end of file
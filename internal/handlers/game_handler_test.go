@@ -3,13 +3,17 @@ package handlers_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"pelico/internal/config"
+	"pelico/internal/database"
 	"pelico/internal/handlers"
 	"pelico/internal/models"
 	"pelico/internal/services"
@@ -17,22 +21,38 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// setupTestDB opens in-memory SQLite, or the PostgreSQL database named by
+// PELICO_TEST_POSTGRES_URL (which is wiped) to run the suite against Postgres
 func setupTestDB(t *testing.T) *gorm.DB {
-	// Use in-memory SQLite for testing
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	require.NoError(t, err)
+	var db *gorm.DB
+	var err error
+	if dsn := os.Getenv("PELICO_TEST_POSTGRES_URL"); dsn != "" {
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		require.NoError(t, err)
+		require.NoError(t, db.Exec("DROP SCHEMA public CASCADE").Error)
+		require.NoError(t, db.Exec("CREATE SCHEMA public").Error)
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+	} else {
+		// CI sets PELICO_TEST_REQUIRE_POSTGRES so a missing URL fails the run
+		// rather than quietly testing SQLite only
+		if os.Getenv("PELICO_TEST_REQUIRE_POSTGRES") != "" {
+			t.Fatal("PELICO_TEST_REQUIRE_POSTGRES is set but PELICO_TEST_POSTGRES_URL is not")
+		}
+		db, err = gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		require.NoError(t, err)
+	}
 
-	// Auto-migrate the schema
-	err = db.AutoMigrate(
-		&models.Platform{},
-		&models.Game{},
-		&models.FileLocation{},
-		&models.PlaySession{},
-	)
+	// Create the schema with the same migrations the server runs
+	err = database.MigrateDB(db)
 	require.NoError(t, err)
 
 	// Create test platform
@@ -62,24 +82,74 @@ func setupTestServer(db *gorm.DB) http.Handler {
 	logger := services.NewLoggerService(slog.LevelInfo)
 	
 	// Initialize handlers
-	gameHandler := handlers.NewGameHandler(db, cfg.TwitchClientID, cfg.TwitchClientSecret, cache, logger)
-	platformHandler := handlers.NewPlatformHandler(db, cache)
-	sessionHandler := handlers.NewSessionHandler(db, cache)
+	metadataService := services.NewMetadataService(cfg.TwitchClientID, cfg.TwitchClientSecret, nil, nil, logger)
+	gameHandler := handlers.NewGameHandler(db, metadataService, nil, cache, logger)
+	platformHandler := handlers.NewPlatformHandler(db, metadataService, cache)
+	sessions := services.NewSessionTimer(db, services.SessionConflictReject, services.SessionOverlapReject, 12*time.Hour)
+	sessionHandler := handlers.NewSessionHandler(db, cache, sessions)
 	wishlistHandler := handlers.NewWishlistHandler(db)
 	shortlistHandler := handlers.NewShortlistHandler(db)
-	statsHandler := handlers.NewStatsHandler(db)
+	statsHandler := handlers.NewStatsHandler(db, cfg)
+	importHandler := handlers.NewImportHandler(db, cache, cfg, sessions)
+	goalHandler := handlers.NewGoalHandler(db)
+	search := services.NewGameSearch(db)
+	if err := search.Setup(); err != nil {
+		panic(err)
+	}
+	searchHandler := handlers.NewSearchHandler(db, search)
+	backupHandler := handlers.NewBackupHandler(db, cfg, search)
+	tagHandler := handlers.NewTagHandler(db, cache)
+	collectionHandler := handlers.NewCollectionHandler(db)
+	customFieldHandler := handlers.NewCustomFieldHandler(db, cache)
+	copyHandler := handlers.NewCopyHandler(db, cache, cfg)
 	
 	// Setup API routes only (skip web routes that need templates)
 	api := router.Group("/api/v1")
 	{
 		// Games
 		api.GET("/games", gameHandler.GetGames)
+		api.GET("/games/genres", gameHandler.GetGenres)
 		api.GET("/games/:id", gameHandler.GetGame)
 		api.POST("/games", gameHandler.CreateGame)
+		api.POST("/games/from-metadata", gameHandler.CreateGameFromMetadata)
 		api.PUT("/games/:id", gameHandler.UpdateGame)
 		api.DELETE("/games/:id", gameHandler.DeleteGame)
+		api.POST("/games/search", gameHandler.SearchGames)
 		api.PUT("/games/:id/completion", gameHandler.UpdateCompletionStatus)
 		
+		// Tags
+		api.GET("/tags", tagHandler.GetTags)
+		api.POST("/tags", tagHandler.CreateTag)
+		api.PUT("/tags/:id", tagHandler.UpdateTag)
+		api.DELETE("/tags/:id", tagHandler.DeleteTag)
+		api.POST("/tags/bulk", tagHandler.BulkTag)
+		api.PUT("/games/:id/tags", tagHandler.SetGameTags)
+		
+		// Collections
+		api.GET("/collections", collectionHandler.GetCollections)
+		api.GET("/collections/:id", collectionHandler.GetCollection)
+		api.POST("/collections", collectionHandler.CreateCollection)
+		api.POST("/collections/preview", collectionHandler.PreviewCollection)
+		api.PUT("/collections/:id", collectionHandler.UpdateCollection)
+		api.DELETE("/collections/:id", collectionHandler.DeleteCollection)
+		api.POST("/collections/:id/games", collectionHandler.AddCollectionGames)
+		api.DELETE("/collections/:id/games/:gameId", collectionHandler.RemoveCollectionGame)
+		api.PUT("/collections/:id/order", collectionHandler.ReorderCollection)
+		
+		// Custom fields
+		api.GET("/custom-fields", customFieldHandler.GetCustomFields)
+		api.POST("/custom-fields", customFieldHandler.CreateCustomField)
+		api.PUT("/custom-fields/:id", customFieldHandler.UpdateCustomField)
+		api.DELETE("/custom-fields/:id", customFieldHandler.DeleteCustomField)
+		
+		// Physical copies
+		api.GET("/copies", copyHandler.GetCopies)
+		api.GET("/games/:id/copies", copyHandler.GetGameCopies)
+		api.POST("/games/:id/copies", copyHandler.CreateCopy)
+		api.PUT("/copies/:id", copyHandler.UpdateCopy)
+		api.DELETE("/copies/:id", copyHandler.DeleteCopy)
+		api.GET("/games/:id/prices", copyHandler.GetGamePrices)
+		
 		// Platforms
 		api.GET("/platforms", platformHandler.GetPlatforms)
 		api.POST("/platforms", platformHandler.CreatePlatform)
@@ -87,6 +157,23 @@ func setupTestServer(db *gorm.DB) http.Handler {
 		// Sessions
 		api.GET("/games/:id/sessions", sessionHandler.GetGameSessions)
 		api.POST("/games/:id/sessions", sessionHandler.CreateSession)
+		api.DELETE("/sessions/:id", sessionHandler.DeleteSession)
+		api.PUT("/sessions/:id", sessionHandler.UpdateSession)
+		api.POST("/sessions/:id/end", sessionHandler.EndSession)
+		api.GET("/sessions/timer", sessionHandler.GetTimer)
+		api.POST("/sessions/timer/start", sessionHandler.StartTimer)
+		api.POST("/sessions/timer/stop", sessionHandler.StopTimer)
+		api.POST("/sessions/timer/pause", sessionHandler.PauseTimer)
+		api.POST("/sessions/timer/resume", sessionHandler.ResumeTimer)
+		api.GET("/sessions/calendar.ics", sessionHandler.ExportCalendar)
+		api.POST("/import/ical", importHandler.ImportICalendar)
+		api.POST("/import/prices", importHandler.ImportPrices)
+		
+		// Full-text search
+		api.GET("/search", searchHandler.Search)
+		api.POST("/search/reindex", searchHandler.Reindex)
+		api.GET("/backup/export", backupHandler.ExportDatabase)
+		api.POST("/backup/import", backupHandler.ImportDatabase)
 		
 		// Wishlist & Shortlist
 		api.GET("/wishlist", wishlistHandler.GetWishlist)
@@ -94,6 +181,24 @@ func setupTestServer(db *gorm.DB) http.Handler {
 		
 		// Stats
 		api.GET("/stats", statsHandler.GetStats)
+		api.GET("/stats/backlog", statsHandler.GetBacklog)
+		api.GET("/stats/playtime", statsHandler.GetPlaytime)
+		api.GET("/stats/playtime/games", statsHandler.GetPlaytimeByGame)
+		api.GET("/stats/playtime/platforms", statsHandler.GetPlaytimeByPlatform)
+		api.GET("/stats/playtime/genres", statsHandler.GetPlaytimeByGenre)
+		api.GET("/stats/playtime/heatmap", statsHandler.GetPlaytimeHeatmap)
+		api.GET("/stats/year-in-review/:year", statsHandler.GetYearReview)
+		api.GET("/stats/value", statsHandler.GetCollectionValue)
+		api.GET("/stats/spending", statsHandler.GetSpending)
+		api.GET("/stats/cost-per-hour", statsHandler.GetCostPerHour)
+		
+		// Goals
+		api.GET("/goals", goalHandler.GetGoals)
+		api.GET("/goals/summary", goalHandler.GetGoalSummary)
+		api.GET("/goals/:id", goalHandler.GetGoal)
+		api.POST("/goals", goalHandler.CreateGoal)
+		api.PUT("/goals/:id", goalHandler.UpdateGoal)
+		api.DELETE("/goals/:id", goalHandler.DeleteGoal)
 		
 		// Health check and cache stats
 		api.GET("/health", func(c *gin.Context) {
@@ -119,6 +224,29 @@ func setupTestServer(db *gorm.DB) http.Handler {
 	return router
 }
 
+// send makes a request to the test server under /api/v1, with body encoded
+// as JSON unless it is nil
+func send(server http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	return sendRaw(server, method, path, "application/json", reader)
+}
+
+// sendRaw makes a request to the test server under /api/v1 with a body of
+// the given content type
+func sendRaw(server http.Handler, method, path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1"+path, body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
 func TestGameHandler_CreateGame(t *testing.T) {
 	db := setupTestDB(t)
 	server := setupTestServer(db)
@@ -311,6 +439,53 @@ func TestGameHandler_GetGames(t *testing.T) {
 	}
 }
 
+func TestGameHandler_CaseAndFormatFilters(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	rpg := models.Genre{Name: "Role-Playing"}
+	db.Create(&rpg)
+	db.Create(&models.Game{Title: "Final Fantasy VI", PlatformID: 1, Genres: []models.Genre{rpg},
+		CollectionFormats: models.CollectionFormats{"physical", "digital"}})
+	db.Create(&models.Game{Title: "FINAL FIGHT", PlatformID: 1, CollectionFormats: models.CollectionFormats{"rom"}})
+	db.Create(&models.Game{Title: "100% Orange Juice", PlatformID: 1, CollectionFormats: models.CollectionFormats{"digital_deluxe"}})
+
+	search := func(body map[string]interface{}) []string {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/api/v1/games/search", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var games []models.Game
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &games))
+		titles := make([]string, len(games))
+		for i, game := range games {
+			titles[i] = game.Title
+		}
+		return titles
+	}
+
+	assert.ElementsMatch(t, []string{"Final Fantasy VI", "FINAL FIGHT"}, search(map[string]interface{}{"title": "final f"}))
+	assert.Equal(t, []string{"100% Orange Juice"}, search(map[string]interface{}{"title": "0% o"}))
+	assert.Empty(t, search(map[string]interface{}{"title": "_"}))
+	assert.Len(t, search(map[string]interface{}{"platform": "test CONSOLE"}), 3)
+	assert.Equal(t, []string{"Final Fantasy VI"}, search(map[string]interface{}{"genre": "role-play"}))
+	assert.Equal(t, []string{"Final Fantasy VI"}, search(map[string]interface{}{"format": "digital"}))
+	assert.Equal(t, []string{"FINAL FIGHT"}, search(map[string]interface{}{"title": "final", "format": "rom"}))
+
+	req := httptest.NewRequest("GET", "/api/v1/games?collection_format=physical", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Games []models.Game `json:"games"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Games, 1)
+	assert.Equal(t, "Final Fantasy VI", response.Games[0].Title)
+}
+
 func TestGameHandler_UpdateCompletionStatus(t *testing.T) {
 	db := setupTestDB(t)
 	server := setupTestServer(db)
@@ -380,6 +555,61 @@ func TestGameHandler_UpdateCompletionStatus(t *testing.T) {
 	}
 }
 
+func TestGameHandler_DeleteGame(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	core := "mgba"
+	tag := models.Tag{Name: "Favourites"}
+	genre := models.Genre{Name: "Platformer"}
+	field := models.CustomField{Key: "emulator", Name: "Emulator", Type: "text"}
+	collection := models.Collection{Name: "Shelf"}
+	for _, row := range []interface{}{&tag, &genre, &field, &collection} {
+		require.NoError(t, db.Create(row).Error)
+	}
+	games := make([]models.Game, 2)
+	for i, title := range []string{"Celeste", "Towerfall"} {
+		games[i] = models.Game{Title: title, PlatformID: 1, Genres: []models.Genre{genre}, Tags: []models.Tag{tag}}
+		require.NoError(t, db.Create(&games[i]).Error)
+		start := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+		gameID := games[i].ID
+		for _, row := range []interface{}{
+			&models.PlaySession{GameID: gameID, StartTime: start},
+			&models.FileLocation{GameID: gameID, FilePath: fmt.Sprintf("/roms/%d.bin", gameID)},
+			&models.Wishlist{GameID: gameID},
+			&models.GameImage{GameID: gameID, Kind: "cover", Hash: fmt.Sprintf("%064d", gameID)},
+			&models.ImportedPlaytime{GameID: gameID, Source: "steam", ExternalKey: fmt.Sprint(gameID)},
+			&models.CollectionItem{CollectionID: collection.ID, GameID: gameID},
+			&models.PhysicalCopy{GameID: gameID},
+			&models.PricePoint{GameID: gameID, Grade: "loose", Date: start, Price: 10, Currency: "USD"},
+			&models.GameCustomValue{GameID: gameID, FieldID: field.ID, TextValue: &core},
+			&models.Goal{Title: "Finish " + games[i].Title, Kind: "finish", Period: "once", GameID: &gameID},
+		} {
+			require.NoError(t, db.Create(row).Error)
+		}
+	}
+	celeste, towerfall := games[0].ID, games[1].ID
+
+	w := send(server, "DELETE", fmt.Sprintf("/games/%d", celeste), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("/games/%d", celeste), nil).Code)
+
+	for _, table := range []string{"game_genres", "game_tags", "play_sessions", "file_locations", "wishlists", "game_images",
+		"imported_playtimes", "collection_items", "physical_copies", "price_points", "game_custom_values"} {
+		var deleted, kept int64
+		require.NoError(t, db.Table(table).Where("game_id = ?", celeste).Count(&deleted).Error)
+		require.NoError(t, db.Table(table).Where("game_id = ?", towerfall).Count(&kept).Error)
+		assert.Zero(t, deleted, table)
+		assert.Equal(t, int64(1), kept, table)
+	}
+	var goals []models.Goal
+	require.NoError(t, db.Order("id").Find(&goals).Error)
+	require.Len(t, goals, 2)
+	assert.Nil(t, goals[0].GameID, "goal outlives its game")
+	require.NotNil(t, goals[1].GameID)
+	assert.Equal(t, towerfall, *goals[1].GameID)
+}
+
 func TestHealthCheck(t *testing.T) {
 	db := setupTestDB(t)
 	server := setupTestServer(db)
@@ -435,4 +665,272 @@ func TestCacheStats(t *testing.T) {
 
 	assert.Equal(t, "30 minutes", response.CacheTTL)
 	assert.Equal(t, "5 minutes", response.CleanupInterval)
-}
\ No newline at end of file
+}
+func TestGameHandler_GetGamesMetadataFilters(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	chrono := models.Game{Title: "Chrono Trigger", PlatformID: 1}
+	db.Create(&chrono)
+	mana := models.Game{Title: "Secret of Mana", PlatformID: 1}
+	db.Create(&mana)
+	db.Create(&models.Game{Title: "Unrelated", PlatformID: 1})
+
+	require.NoError(t, services.SaveMetadataDetails(db, chrono.ID, &services.GameMetadata{
+		Developers: []string{"Square"},
+		Publishers: []string{"Square", "Nintendo"},
+		Franchises: []string{"Chrono"},
+		Themes:     []string{"Fantasy"},
+	}))
+	require.NoError(t, services.SaveMetadataDetails(db, mana.ID, &services.GameMetadata{
+		Developers: []string{"Square"},
+		Franchises: []string{"Mana"},
+		Themes:     []string{"Fantasy"},
+	}))
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+	}{
+		{name: "by developer", query: "?developer=square", wantCount: 2},
+		{name: "by publisher", query: "?publisher=Nintendo", wantCount: 1},
+		{name: "by franchise", query: "?franchise=Chrono", wantCount: 1},
+		{name: "combined filters", query: "?theme=Fantasy&franchise=Mana", wantCount: 1},
+		{name: "no match", query: "?developer=Capcom", wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/games"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Games []models.Game `json:"games"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Len(t, response.Games, tt.wantCount)
+		})
+	}
+
+	// Re-saving replaces the links for the roles given and keeps the others
+	require.NoError(t, services.SaveMetadataDetails(db, chrono.ID, &services.GameMetadata{
+		Developers: []string{"Square", "Enix"},
+	}))
+	var links int64
+	db.Model(&models.GameCompany{}).Where("game_id = ? AND role = ?", chrono.ID, "developer").Count(&links)
+	assert.Equal(t, int64(2), links)
+	db.Model(&models.GameCompany{}).Where("game_id = ? AND role = ?", chrono.ID, "publisher").Count(&links)
+	assert.Equal(t, int64(2), links)
+
+	require.NoError(t, services.SaveMetadataDetails(db, chrono.ID, &services.GameMetadata{
+		Publishers: []string{"Nintendo"},
+	}))
+	db.Model(&models.GameCompany{}).Where("game_id = ? AND role = ?", chrono.ID, "developer").Count(&links)
+	assert.Equal(t, int64(2), links)
+	db.Model(&models.GameCompany{}).Where("game_id = ? AND role = ?", chrono.ID, "publisher").Count(&links)
+	assert.Equal(t, int64(1), links)
+}
+
+func TestGameHandler_ReleaseDatesAndAgeRatings(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	date := func(value string) *time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		require.NoError(t, err)
+		return &parsed
+	}
+
+	earthbound := models.Game{Title: "EarthBound", PlatformID: 1}
+	db.Create(&earthbound)
+	mario := models.Game{Title: "Super Mario World", PlatformID: 1}
+	db.Create(&mario)
+	doom := models.Game{Title: "Doom", PlatformID: 1}
+	db.Create(&doom)
+	db.Create(&models.Game{Title: "Unreleased", PlatformID: 1})
+
+	require.NoError(t, services.SaveMetadataDetails(db, earthbound.ID, &services.GameMetadata{
+		ReleaseDates: []services.MetadataReleaseDate{
+			{Region: "japan", Date: date("1994-08-27")},
+			{Region: "north_america", Date: date("1995-06-05")},
+		},
+		AgeRatings: []services.MetadataAgeRating{{Board: "ESRB", Rating: "T", MinimumAge: 13}},
+	}))
+	require.NoError(t, services.SaveMetadataDetails(db, mario.ID, &services.GameMetadata{
+		ReleaseDates: []services.MetadataReleaseDate{
+			{Region: "japan", Date: date("1990-11-21")},
+			{Region: "europe", Date: date("1992-04-11")},
+		},
+		AgeRatings: []services.MetadataAgeRating{
+			{Board: "ESRB", Rating: "E", MinimumAge: 6},
+			{Board: "PEGI", Rating: "3", MinimumAge: 3},
+		},
+	}))
+	require.NoError(t, services.SaveMetadataDetails(db, doom.ID, &services.GameMetadata{
+		ReleaseDates: []services.MetadataReleaseDate{{Region: "europe", Date: date("1996-01-01")}},
+		AgeRatings:   []services.MetadataAgeRating{{Board: "PEGI", Rating: "18", MinimumAge: 18}},
+	}))
+
+	fetch := func(t *testing.T, query string) (int, []string) {
+		req := httptest.NewRequest("GET", "/api/v1/games"+query, nil)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+
+		var response struct {
+			Games []models.Game `json:"games"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		titles := make([]string, len(response.Games))
+		for i, game := range response.Games {
+			titles[i] = game.Title
+		}
+		return w.Code, titles
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		ordered bool
+	}{
+		{name: "released in region", query: "?release_region=jp", want: []string{"EarthBound", "Super Mario World"}},
+		{name: "PAL alias", query: "?release_region=PAL", want: []string{"Doom", "Super Mario World"}},
+		{name: "region and date range", query: "?release_region=japan&released_after=1994&released_before=1995", want: []string{"EarthBound"}},
+		{name: "any region after date", query: "?released_after=1995-06-01", want: []string{"Doom", "EarthBound"}},
+		{name: "exact age rating", query: "?age_rating=esrb:t", want: []string{"EarthBound"}},
+		{name: "maximum age", query: "?max_age=12", want: []string{"Super Mario World"}},
+		{name: "sort by release date", query: "?sort=release_date", want: []string{"Doom", "EarthBound", "Super Mario World", "Unreleased"}, ordered: true},
+		{name: "sort by regional release date", query: "?sort=release_date&release_region=europe", want: []string{"Doom", "Super Mario World"}, ordered: true},
+		{name: "sort by age rating", query: "?sort=age_rating", want: []string{"Super Mario World", "EarthBound", "Doom", "Unreleased"}, ordered: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, titles := fetch(t, tt.query)
+			require.Equal(t, http.StatusOK, code)
+			if tt.ordered {
+				assert.Equal(t, tt.want, titles)
+			} else {
+				assert.ElementsMatch(t, tt.want, titles)
+			}
+		})
+	}
+
+	for _, query := range []string{"?release_region=atlantis", "?released_after=yesterday", "?age_rating=PEGI", "?max_age=-1"} {
+		code, _ := fetch(t, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+
+	// Release dates and ratings are included with the game
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/games/%d", mario.ID), nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var game models.Game
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	assert.Len(t, game.ReleaseDates, 2)
+	assert.Len(t, game.AgeRatings, 2)
+}
+
+func TestGameHandler_MultiGenre(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	create := func(payload map[string]interface{}) {
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/api/v1/games", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	create(map[string]interface{}{"title": "Zelda", "platform_id": 1, "genres": []string{"Adventure", "RPG"}})
+	create(map[string]interface{}{"title": "Doom", "platform_id": 1, "genres": []string{"Shooter"}})
+	create(map[string]interface{}{"title": "Secret of Mana", "platform_id": 1, "genre": "RPG"})
+
+	var zelda models.Game
+	require.NoError(t, db.Preload("Genres").Where("title = ?", "Zelda").First(&zelda).Error)
+	assert.Equal(t, "Adventure", zelda.Genre)
+	assert.Len(t, zelda.Genres, 2)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+	}{
+		{name: "single genre", query: "?genre=rpg", wantCount: 2},
+		{name: "any of", query: "?genre=RPG,Shooter", wantCount: 3},
+		{name: "all of", query: "?genre=RPG&genre=Adventure&genre_mode=and", wantCount: 1},
+		{name: "all", query: "?genre=all", wantCount: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/games"+tt.query, nil)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response struct {
+				Games []models.Game `json:"games"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Len(t, response.Games, tt.wantCount)
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/games/genres", nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var genres []struct {
+		Name  string `json:"name"`
+		Count int64  `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &genres))
+	counts := make(map[string]int64)
+	for _, genre := range genres {
+		counts[genre.Name] = genre.Count
+	}
+	assert.Equal(t, map[string]int64{"Adventure": 1, "RPG": 2, "Shooter": 1}, counts)
+}
+
+func TestMigrateLegacyGenres(t *testing.T) {
+	db := setupTestDB(t)
+
+	legacy := models.Game{Title: "Legacy", PlatformID: 1, Genre: "Action, Platformer"}
+	db.Create(&legacy)
+
+	require.NoError(t, models.MigrateLegacyGenres(db))
+	require.NoError(t, models.MigrateLegacyGenres(db))
+
+	var game models.Game
+	require.NoError(t, db.Preload("Genres").First(&game, legacy.ID).Error)
+	assert.Len(t, game.Genres, 2)
+}
+
+func TestGameHandler_CreateGameFromMetadataIsAtomic(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	metadata := map[string]interface{}{"title": "Chrono Trigger", "developers": []string{"Square"}}
+	w := send(server, "POST", "/games/from-metadata", map[string]interface{}{"platform_id": 1, "metadata": metadata})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Make saving the details fail after the game itself is written
+	require.NoError(t, db.Migrator().DropTable(&models.GameCompany{}))
+	metadata["title"] = "Secret of Mana"
+	w = send(server, "POST", "/games/from-metadata", map[string]interface{}{"platform_id": 1, "metadata": metadata})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to save metadata details")
+	var count int64
+	db.Model(&models.Game{}).Where("title = ?", "Secret of Mana").Count(&count)
+	assert.Zero(t, count, "the game is rolled back with its details")
+}
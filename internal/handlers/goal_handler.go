@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GoalHandler manages play goals and reports their progress
+type GoalHandler struct {
+	db    *gorm.DB
+	goals *services.GoalTracker
+}
+
+func NewGoalHandler(db *gorm.DB) *GoalHandler {
+	return &GoalHandler{
+		db:    db,
+		goals: services.NewGoalTracker(db),
+	}
+}
+
+// GetGoals returns goals with their current progress; archived goals are
+// included with ?include_archived=true
+func (h *GoalHandler) GetGoals(c *gin.Context) {
+	loc, ok := h.timezone(c)
+	if !ok {
+		return
+	}
+	
+	query := h.db.Preload("Game").Preload("Platform").Order("id")
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+	var goals []models.Goal
+	if err := query.Find(&goals).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_goals",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	progress := make([]services.GoalProgress, 0, len(goals))
+	for _, goal := range goals {
+		p, err := h.goals.Progress(goal, loc)
+		if err != nil {
+			h.respondProgressError(c, err)
+			return
+		}
+		progress = append(progress, *p)
+	}
+	
+	c.JSON(http.StatusOK, progress)
+}
+
+// GetGoalSummary returns the dashboard summary: progress of active goals,
+// the play streak and reminders for goals falling behind
+func (h *GoalHandler) GetGoalSummary(c *gin.Context) {
+	loc, ok := h.timezone(c)
+	if !ok {
+		return
+	}
+	
+	summary, err := h.goals.Summary(loc)
+	if err != nil {
+		h.respondProgressError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *GoalHandler) GetGoal(c *gin.Context) {
+	goal, ok := h.findGoal(c)
+	if !ok {
+		return
+	}
+	loc, ok := h.timezone(c)
+	if !ok {
+		return
+	}
+	
+	progress, err := h.goals.Progress(*goal, loc)
+	if err != nil {
+		h.respondProgressError(c, err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, progress)
+}
+
+func (h *GoalHandler) CreateGoal(c *gin.Context) {
+	var req middleware.CreateGoalRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	loc, ok := h.timezone(c)
+	if !ok {
+		return
+	}
+	
+	goal := models.Goal{
+		Title:      req.Title,
+		Kind:       req.Kind,
+		Target:     req.Target,
+		Period:     req.Period,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		GameID:     req.GameID,
+		PlatformID: req.PlatformID,
+	}
+	if !h.validateGoal(c, &goal) {
+		return
+	}
+	
+	if err := h.db.Create(&goal).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "create_goal",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	h.respondWithGoal(c, http.StatusCreated, goal.ID, loc)
+}
+
+func (h *GoalHandler) UpdateGoal(c *gin.Context) {
+	goal, ok := h.findGoal(c)
+	if !ok {
+		return
+	}
+	
+	var req middleware.UpdateGoalRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	loc, ok := h.timezone(c)
+	if !ok {
+		return
+	}
+	
+	// Update fields if provided in request
+	if req.Title != "" {
+		goal.Title = req.Title
+	}
+	if req.Kind != "" {
+		goal.Kind = req.Kind
+	}
+	if req.Target != 0 {
+		goal.Target = req.Target
+	}
+	if req.Period != "" {
+		goal.Period = req.Period
+	}
+	if req.Period != "" && req.Period != services.GoalPeriodCustom {
+		goal.StartDate, goal.EndDate = "", ""
+	}
+	if req.StartDate != nil {
+		goal.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		goal.EndDate = *req.EndDate
+	}
+	if req.GameID != nil {
+		goal.GameID = req.GameID
+		if *req.GameID == 0 {
+			goal.GameID = nil
+		}
+	}
+	if req.PlatformID != nil {
+		goal.PlatformID = req.PlatformID
+		if *req.PlatformID == 0 {
+			goal.PlatformID = nil
+		}
+	}
+	if req.Archived != nil {
+		goal.Archived = *req.Archived
+	}
+	if !h.validateGoal(c, goal) {
+		return
+	}
+	
+	// Associations were preloaded; save the IDs only
+	goal.Game, goal.Platform = nil, nil
+	if err := h.db.Save(goal).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "update_goal",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	h.respondWithGoal(c, http.StatusOK, goal.ID, loc)
+}
+
+func (h *GoalHandler) DeleteGoal(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected": "positive integer",
+			"received": c.Param("id"),
+		})
+		return
+	}
+	
+	result := h.db.Delete(&models.Goal{}, id)
+	if result.Error != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_goal",
+			"error": result.Error.Error(),
+		})
+		return
+	}
+	
+	if result.RowsAffected == 0 {
+		errors.RespondWithError(c, errors.ErrGoalNotFound, map[string]interface{}{
+			"goal_id": id,
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{"message": "Goal deleted successfully"})
+}
+
+// findGoal loads the goal named by the :id parameter with its game and platform
+func (h *GoalHandler) findGoal(c *gin.Context) (*models.Goal, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected": "positive integer",
+			"received": c.Param("id"),
+		})
+		return nil, false
+	}
+	
+	var goal models.Goal
+	if err := h.db.Preload("Game").Preload("Platform").First(&goal, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrGoalNotFound, map[string]interface{}{
+				"goal_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return &goal, true
+}
+
+// validateGoal checks the goal's settings and that its game and platform exist
+func (h *GoalHandler) validateGoal(c *gin.Context, goal *models.Goal) bool {
+	if err := services.ValidateGoal(goal); err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidGoalData, map[string]string{
+			"error": err.Error(),
+		})
+		return false
+	}
+	
+	var count int64
+	if goal.GameID != nil {
+		h.db.Model(&models.Game{}).Where("id = ?", *goal.GameID).Count(&count)
+		if count == 0 {
+			errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+				"game_id": *goal.GameID,
+			})
+			return false
+		}
+	}
+	if goal.PlatformID != nil {
+		h.db.Model(&models.Platform{}).Where("id = ?", *goal.PlatformID).Count(&count)
+		if count == 0 {
+			errors.RespondWithError(c, errors.ErrPlatformNotFound, map[string]interface{}{
+				"platform_id": *goal.PlatformID,
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// respondWithGoal reloads a saved goal and responds with its progress
+func (h *GoalHandler) respondWithGoal(c *gin.Context, status int, id uint, loc *time.Location) {
+	var goal models.Goal
+	if err := h.db.Preload("Game").Preload("Platform").First(&goal, id).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	progress, err := h.goals.Progress(goal, loc)
+	if err != nil {
+		h.respondProgressError(c, err)
+		return
+	}
+	
+	c.JSON(status, progress)
+}
+
+// timezone reads ?timezone=, the zone periods and streak days are counted in
+func (h *GoalHandler) timezone(c *gin.Context) (*time.Location, bool) {
+	return parseTimezoneParam(c, c.Query("timezone"))
+}
+
+func (h *GoalHandler) respondProgressError(c *gin.Context, err error) {
+	errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+		"operation": "goal_progress",
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+	"pelico/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoalHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	game := models.Game{Title: "Persona 5", PlatformID: 1}
+	db.Create(&game)
+	// Played today, so it always falls in the current week
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 5, 0, 0, time.Local)
+	end := start.Add(90 * time.Minute)
+	db.Create(&models.PlaySession{GameID: game.ID, StartTime: start, EndTime: &end, Duration: 90})
+
+	w := send(server, "POST", "/goals", map[string]interface{}{
+		"title": "Persona weekly", "kind": "play_time", "target": 1, "period": "week", "game_id": game.ID,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var progress services.GoalProgress
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	assert.Equal(t, "Persona weekly", progress.Goal.Title)
+	require.NotNil(t, progress.Goal.Game)
+	assert.Equal(t, "Persona 5", progress.Goal.Game.Title)
+	assert.Equal(t, 1.5, progress.Current)
+	assert.Equal(t, services.GoalCompleted, progress.Status)
+	goalID := progress.Goal.ID
+
+	for name, tt := range map[string]struct {
+		body map[string]interface{}
+		code int
+	}{
+		"missing kind":       {map[string]interface{}{"title": "x", "target": 1, "period": "week"}, http.StatusBadRequest},
+		"fractional games":   {map[string]interface{}{"title": "x", "kind": "finish_games", "target": 1.5, "period": "year"}, http.StatusBadRequest},
+		"custom needs dates": {map[string]interface{}{"title": "x", "kind": "play_days", "target": 3, "period": "custom"}, http.StatusBadRequest},
+		"unknown game":       {map[string]interface{}{"title": "x", "kind": "play_time", "target": 1, "period": "week", "game_id": 9999}, http.StatusNotFound},
+	} {
+		assert.Equal(t, tt.code, send(server, "POST", "/goals", tt.body).Code, name)
+	}
+
+	w = send(server, "GET", "/goals/summary", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var summary services.GoalSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	require.Len(t, summary.Goals, 1)
+	assert.Equal(t, 1, summary.Streak.Current)
+	assert.Equal(t, 1, summary.Statuses[services.GoalCompleted])
+
+	w = send(server, "PUT", fmt.Sprintf("/goals/%d", goalID), map[string]interface{}{"target": 5, "archived": true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &progress))
+	assert.Equal(t, 5.0, progress.Target)
+	assert.True(t, progress.Goal.Archived)
+
+	w = send(server, "GET", "/goals", nil)
+	assert.Equal(t, "[]", w.Body.String())
+	w = send(server, "GET", "/goals?include_archived=true", nil)
+	assert.Contains(t, w.Body.String(), `"title":"Persona weekly"`)
+
+	assert.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/goals/%d", goalID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", fmt.Sprintf("/goals/%d", goalID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("/goals/%d", goalID), nil).Code)
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"pelico/internal/errors"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxUploadSize limits custom cover uploads
+const maxUploadSize = 10 << 20
+
+type ImageHandler struct {
+	db     *gorm.DB
+	images *services.ImageService
+	cache  *services.CacheService
+}
+
+func NewImageHandler(db *gorm.DB, images *services.ImageService, cache *services.CacheService) *ImageHandler {
+	return &ImageHandler{
+		db:     db,
+		images: images,
+		cache:  cache,
+	}
+}
+
+// ServeImage serves a stored image or one of its thumbnails. Images are
+// content-addressed, so responses can be cached indefinitely.
+func (h *ImageHandler) ServeImage(c *gin.Context) {
+	hash := c.Param("hash")
+	size := c.Param("size")
+
+	path, err := h.images.FilePath(hash, size)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrImageNotFound, map[string]string{
+			"hash": hash,
+			"size": size,
+		})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s"`, hash, size)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.File(path)
+}
+
+// GetGameImages lists the locally stored images for a game
+func (h *ImageHandler) GetGameImages(c *gin.Context) {
+	gameID, ok := h.parseGameID(c)
+	if !ok {
+		return
+	}
+
+	var images []models.GameImage
+	if err := h.db.Where("game_id = ?", gameID).Order("kind ASC").Find(&images).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_images",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, images)
+}
+
+// DownloadGameImages fetches the game's cover and box art URLs into local storage
+func (h *ImageHandler) DownloadGameImages(c *gin.Context) {
+	gameID, ok := h.parseGameID(c)
+	if !ok {
+		return
+	}
+
+	var game models.Game
+	if err := h.db.First(&game, gameID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+				"game_id": gameID,
+			})
+			return
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	if err := h.images.CacheGameArt(&game); err != nil {
+		errors.RespondWithError(c, errors.ErrMetadataAPIError, map[string]string{
+			"operation": "download_images",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(gameID)
+
+	var images []models.GameImage
+	h.db.Where("game_id = ?", gameID).Order("kind ASC").Find(&images)
+
+	c.JSON(http.StatusOK, images)
+}
+
+// UploadGameImage stores a user-provided image (multipart field "image") for a game.
+// Custom images are never overwritten by metadata updates.
+func (h *ImageHandler) UploadGameImage(c *gin.Context) {
+	gameID, ok := h.parseGameID(c)
+	if !ok {
+		return
+	}
+
+	kind := c.DefaultPostForm("kind", "cover")
+	if kind != "cover" && kind != "box_art" {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "kind",
+			"expected":  "cover or box_art",
+			"received":  kind,
+		})
+		return
+	}
+
+	var game models.Game
+	if err := h.db.First(&game, gameID).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+			"game_id": gameID,
+		})
+		return
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrMissingRequiredField, map[string]string{
+			"field": "image",
+		})
+		return
+	}
+	if fileHeader.Size > maxUploadSize {
+		errors.RespondWithError(c, errors.ErrInvalidRange, map[string]interface{}{
+			"field":     "image",
+			"max_bytes": maxUploadSize,
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidImage, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize))
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidImage, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	image, err := h.images.StoreGameImage(gameID, kind, data, "", true)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidImage, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(gameID)
+
+	c.JSON(http.StatusCreated, image)
+}
+
+// DeleteGameImage removes a stored image; deleting a custom cover lets metadata art be used again
+func (h *ImageHandler) DeleteGameImage(c *gin.Context) {
+	gameID, ok := h.parseGameID(c)
+	if !ok {
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("imageId"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "imageId",
+			"expected":  "positive integer",
+			"received":  c.Param("imageId"),
+		})
+		return
+	}
+
+	var image models.GameImage
+	if err := h.db.Where("id = ? AND game_id = ?", imageID, gameID).First(&image).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrImageNotFound, map[string]interface{}{
+			"image_id": imageID,
+			"game_id":  gameID,
+		})
+		return
+	}
+
+	if err := h.images.DeleteGameImage(&image); err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_image",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.cache.InvalidateGame(gameID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted successfully"})
+}
+
+func (h *ImageHandler) parseGameID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
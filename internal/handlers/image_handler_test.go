@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pelico/internal/handlers"
+	"pelico/internal/models"
+	"pelico/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupImageServer serves the image routes and game deletion with images
+// stored under a temporary directory
+func setupImageServer(t *testing.T, db *gorm.DB) http.Handler {
+	cache := services.NewCacheService(30 * time.Minute)
+	images := services.NewImageService(db, t.TempDir(), false, nil)
+	imageHandler := handlers.NewImageHandler(db, images, cache)
+	gameHandler := handlers.NewGameHandler(db, nil, images, cache, nil)
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.GET("/images/:hash", imageHandler.ServeImage)
+	api.GET("/images/:hash/:size", imageHandler.ServeImage)
+	api.GET("/games/:id/images", imageHandler.GetGameImages)
+	api.POST("/games/:id/images", imageHandler.UploadGameImage)
+	api.DELETE("/games/:id/images/:imageId", imageHandler.DeleteGameImage)
+	api.DELETE("/games/:id", gameHandler.DeleteGame)
+	return router
+}
+
+// imageForm builds a multipart upload with the image under field "image"
+func imageForm(t *testing.T, kind string, data []byte) (string, io.Reader) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if kind != "" {
+		require.NoError(t, form.WriteField("kind", kind))
+	}
+	if data != nil {
+		part, err := form.CreateFormFile("image", "cover.png")
+		require.NoError(t, err)
+		part.Write(data)
+	}
+	require.NoError(t, form.Close())
+	return form.FormDataContentType(), &body
+}
+
+func TestImageHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupImageServer(t, db)
+
+	game := models.Game{Title: "Okami", PlatformID: 1}
+	require.NoError(t, db.Create(&game).Error)
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		img.Set(x, 100, color.White)
+	}
+	var data bytes.Buffer
+	require.NoError(t, png.Encode(&data, img))
+
+	upload := func(gameID uint, kind string, data []byte) *httptest.ResponseRecorder {
+		contentType, body := imageForm(t, kind, data)
+		return sendRaw(server, "POST", fmt.Sprintf("/games/%d/images", gameID), contentType, body)
+	}
+
+	w := upload(game.ID, "box_art", data.Bytes())
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var stored models.GameImage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stored))
+	assert.Equal(t, "box_art", stored.Kind)
+	assert.True(t, stored.Custom)
+	assert.Equal(t, 400, stored.Width)
+	assert.Equal(t, "/api/v1/images/"+stored.Hash, stored.URLs["original"])
+
+	assert.Equal(t, http.StatusBadRequest, upload(game.ID, "banner", data.Bytes()).Code)
+	assert.Equal(t, http.StatusBadRequest, upload(game.ID, "cover", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, upload(game.ID, "cover", []byte("GIF89a but not really")).Code)
+	assert.Equal(t, http.StatusNotFound, upload(9999, "cover", data.Bytes()).Code)
+
+	w = send(server, "GET", fmt.Sprintf("/games/%d/images", game.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []models.GameImage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, stored.Hash, listed[0].Hash)
+
+	// Originals are served as uploaded, thumbnails as JPEG, both cacheable
+	w = send(server, "GET", "/images/"+stored.Hash, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, data.Bytes(), w.Body.Bytes())
+	assert.Contains(t, w.Header().Get("Cache-Control"), "immutable")
+	w = send(server, "GET", "/images/"+stored.Hash+"/small", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	thumb, format, err := image.DecodeConfig(w.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, models.ThumbnailSizes["small"], thumb.Width)
+
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	req := httptest.NewRequest("GET", "/api/v1/images/"+stored.Hash+"/small", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/images/not-a-hash", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/images/"+stored.Hash+"/huge", nil).Code)
+
+	w = send(server, "DELETE", fmt.Sprintf("/games/%d/images/%d", game.ID, stored.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/images/"+stored.Hash, nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("/games/%d/images/%d", game.ID, stored.ID), nil).Code)
+
+	// Deleting the game removes its image files once the deletion commits
+	w = upload(game.ID, "cover", data.Bytes())
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Equal(t, http.StatusOK, send(server, "GET", "/images/"+stored.Hash, nil).Code)
+	require.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/games/%d", game.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/images/"+stored.Hash, nil).Code)
+}
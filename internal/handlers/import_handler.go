@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"pelico/internal/config"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImportHandler imports play history from other launchers and emulators
+type ImportHandler struct {
+	cache     *services.CacheService
+	retroArch *services.RetroArchImporter
+	steam     *services.SteamImporter
+	calendar  *services.ICalendarImporter
+	prices    *services.PriceImporter
+}
+
+// maxCalendarSize bounds uploaded .ics files
+const maxCalendarSize = 10 << 20
+
+// maxPriceFileSize bounds uploaded price guide exports
+const maxPriceFileSize = 50 << 20
+
+func NewImportHandler(db *gorm.DB, cache *services.CacheService, cfg *config.Config, sessions *services.SessionTimer) *ImportHandler {
+	return &ImportHandler{
+		cache:     cache,
+		retroArch: services.NewRetroArchImporter(db, cfg.RetroArchDir, sessions),
+		steam:     services.NewSteamImporter(db, cfg.SteamDir, cfg.SteamUserID, sessions),
+		calendar:  services.NewICalendarImporter(db, sessions),
+		prices:    services.NewPriceImporter(db, cfg.Currency),
+	}
+}
+
+// ImportRetroArch imports play time from RetroArch runtime logs. The first
+// import records each log's runtime as a baseline; re-running it only adds
+// time played since.
+func (h *ImportHandler) ImportRetroArch(c *gin.Context) {
+	var req middleware.ImportRetroArchRequest
+	if c.Request.ContentLength != 0 && !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	
+	loc, ok := parseTimezoneParam(c, req.Timezone)
+	if !ok {
+		return
+	}
+	
+	result, err := h.retroArch.Import(req.Directory, loc)
+	if err != nil {
+		h.respondImportError(c, err, req.Directory)
+		return
+	}
+	
+	if result.SessionsCreated > 0 {
+		h.cache.InvalidateRecentlyPlayed()
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportSteam adds installed Steam games on the PC platform and imports their
+// playtime from the Steam installation's local files. The first import records
+// each game's playtime as a baseline; later ones add the time played since.
+func (h *ImportHandler) ImportSteam(c *gin.Context) {
+	var req middleware.ImportSteamRequest
+	if c.Request.ContentLength != 0 && !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	
+	result, err := h.steam.Import(req.Directory, req.UserID)
+	if err != nil {
+		h.respondImportError(c, err, req.Directory)
+		return
+	}
+	
+	if result.SessionsCreated > 0 {
+		h.cache.InvalidateRecentlyPlayed()
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportICalendar logs calendar events as play sessions. The .ics file is sent
+// as a multipart "file" field or as the raw request body; ?timezone= is used
+// for events with floating times. Events already imported are skipped.
+func (h *ImportHandler) ImportICalendar(c *gin.Context) {
+	loc, ok := parseTimezoneParam(c, c.Query("timezone"))
+	if !ok {
+		return
+	}
+	
+	data, ok := readImportUpload(c, maxCalendarSize)
+	if !ok {
+		return
+	}
+	
+	result, err := h.calendar.Import(bytes.NewReader(data), loc)
+	if err != nil {
+		if stderrors.Is(err, services.ErrInvalidCalendar) {
+			errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+				"field": "file",
+				"expected": "iCalendar (.ics) data",
+				"error": err.Error(),
+			})
+			return
+		}
+		h.respondImportError(c, err, "")
+		return
+	}
+	
+	if result.SessionsCreated > 0 {
+		h.cache.InvalidateRecentlyPlayed()
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportPrices records market prices from a PriceCharting-style CSV export,
+// sent as the body or a multipart "file", for ?date= (default today) in
+// ?currency= (default the configured one). They value physical copies in the
+// collection value stats.
+func (h *ImportHandler) ImportPrices(c *gin.Context) {
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+				"parameter": "date",
+				"expected": "YYYY-MM-DD",
+				"received": value,
+			})
+			return
+		}
+		date = parsed
+	}
+	currency, ok := services.NormalizeCurrency(c.Query("currency"))
+	if currency != "" && !ok {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "currency",
+			"expected": "ISO 4217 currency code, e.g. USD",
+			"received": c.Query("currency"),
+		})
+		return
+	}
+	
+	data, ok := readImportUpload(c, maxPriceFileSize)
+	if !ok {
+		return
+	}
+	
+	result, err := h.prices.ImportPriceCharting(bytes.NewReader(data), date, currency)
+	if err != nil {
+		if stderrors.Is(err, services.ErrInvalidPriceCSV) {
+			errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+				"field": "file",
+				"expected": "CSV with product-name, console-name and loose-price, cib-price or new-price columns",
+				"error": err.Error(),
+			})
+			return
+		}
+		h.respondImportError(c, err, "")
+		return
+	}
+	
+	c.JSON(http.StatusOK, result)
+}
+
+// readImportUpload reads an uploaded file, sent as a multipart "file" or as
+// the request body, of at most maxSize bytes
+func readImportUpload(c *gin.Context, maxSize int64) ([]byte, bool) {
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrMissingRequiredField, map[string]string{
+				"field": "file",
+			})
+			return nil, false
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+				"field": "file",
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		defer file.Close()
+		body = file
+	}
+	
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil || int64(len(data)) > maxSize {
+		errors.RespondWithError(c, errors.ErrInvalidRange, map[string]interface{}{
+			"field": "file",
+			"max_bytes": maxSize,
+		})
+		return nil, false
+	}
+	return data, true
+}
+
+// respondImportError maps importer errors to API errors
+func (h *ImportHandler) respondImportError(c *gin.Context, err error, directory string) {
+	switch {
+	case stderrors.Is(err, services.ErrRetroArchDirNotSet):
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "directory",
+			"expected": "a directory, or RETROARCH_DIR set on the server",
+		})
+	case stderrors.Is(err, services.ErrSteamDirNotSet):
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "directory",
+			"expected": "a directory, or STEAM_DIR set on the server",
+		})
+	case os.IsNotExist(err):
+		errors.RespondWithError(c, errors.ErrDirectoryNotFound, map[string]string{
+			"directory": directory,
+		})
+	case os.IsPermission(err):
+		errors.RespondWithError(c, errors.ErrPermissionDenied, map[string]string{
+			"directory": directory,
+		})
+	default:
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "import_play_history",
+			"error": err.Error(),
+		})
+	}
+}
@@ -12,14 +12,16 @@ import (
 )
 
 type PlatformHandler struct {
-	db    *gorm.DB
-	cache *services.CacheService
+	db              *gorm.DB
+	metadataService *services.MetadataService
+	cache           *services.CacheService
 }
 
-func NewPlatformHandler(db *gorm.DB, cache *services.CacheService) *PlatformHandler {
+func NewPlatformHandler(db *gorm.DB, metadataService *services.MetadataService, cache *services.CacheService) *PlatformHandler {
 	return &PlatformHandler{
-		db:    db,
-		cache: cache,
+		db:              db,
+		metadataService: metadataService,
+		cache:           cache,
 	}
 }
 
@@ -85,9 +87,13 @@ func (h *PlatformHandler) CreatePlatform(c *gin.Context) {
 	}
 	
 	platform := models.Platform{
-		Name:         req.Name,
-		Manufacturer: req.Manufacturer,
-		ReleaseYear:  req.ReleaseYear,
+		Name:            req.Name,
+		Manufacturer:    req.Manufacturer,
+		ReleaseYear:     req.ReleaseYear,
+		IGDBID:          req.IGDBID,
+		TheGamesDBID:    req.TheGamesDBID,
+		RAWGID:          req.RAWGID,
+		ScreenScraperID: req.ScreenScraperID,
 	}
 	
 	result := h.db.Create(&platform)
@@ -146,6 +152,18 @@ func (h *PlatformHandler) UpdatePlatform(c *gin.Context) {
 	if req.ReleaseYear != 0 {
 		platform.ReleaseYear = req.ReleaseYear
 	}
+	if req.IGDBID != 0 {
+		platform.IGDBID = req.IGDBID
+	}
+	if req.TheGamesDBID != 0 {
+		platform.TheGamesDBID = req.TheGamesDBID
+	}
+	if req.RAWGID != 0 {
+		platform.RAWGID = req.RAWGID
+	}
+	if req.ScreenScraperID != 0 {
+		platform.ScreenScraperID = req.ScreenScraperID
+	}
 	
 	result := h.db.Save(&platform)
 	if result.Error != nil {
@@ -205,4 +223,31 @@ func (h *PlatformHandler) DeletePlatform(c *gin.Context) {
 	h.cache.InvalidatePlatforms()
 	
 	c.JSON(http.StatusOK, gin.H{"message": "Platform deleted successfully"})
-}
\ No newline at end of file
+}
+
+// AutoLinkPlatforms links local platforms to IGDB platform IDs by matching names
+// against IGDB's platform list. Pass ?overwrite=true to re-link linked platforms.
+func (h *PlatformHandler) AutoLinkPlatforms(c *gin.Context) {
+	igdbPlatforms, err := h.metadataService.ListIGDBPlatforms()
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrMetadataAPIError, map[string]string{
+			"operation": "list_igdb_platforms",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	result, err := services.LinkPlatforms(h.db, igdbPlatforms, c.Query("overwrite") == "true")
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "link_platforms",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	// Invalidate platforms cache
+	h.cache.InvalidatePlatforms()
+	
+	c.JSON(http.StatusOK, result)
+}
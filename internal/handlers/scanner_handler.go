@@ -16,13 +16,15 @@ type ScannerHandler struct {
 	db              *gorm.DB
 	scanner         *services.ROMScanner
 	metadataService *services.MetadataService
+	images          *services.ImageService
 }
 
-func NewScannerHandler(db *gorm.DB, clientID, clientSecret string) *ScannerHandler {
+func NewScannerHandler(db *gorm.DB, metadataService *services.MetadataService, images *services.ImageService) *ScannerHandler {
 	return &ScannerHandler{
 		db:              db,
 		scanner:         services.NewROMScanner(db),
-		metadataService: services.NewMetadataService(clientID, clientSecret),
+		metadataService: metadataService,
+		images:          images,
 	}
 }
 
@@ -151,15 +153,15 @@ func (h *ScannerHandler) processBatchMetadataUpdate(gameIDs []uint, batchSize, d
 		batch := gameIDs[i:end]
 		
 		for _, gameID := range batch {
-			// Fetch game with platform
+			// Fetch game with platform and files
 			var game models.Game
-			if err := h.db.Preload("Platform").First(&game, gameID).Error; err != nil {
+			if err := h.db.Preload("Platform").Preload("FileLocations").First(&game, gameID).Error; err != nil {
 				errors = append(errors, fmt.Sprintf("Game %d: Failed to fetch - %v", gameID, err))
 				continue
 			}
 			
-			// Fetch metadata
-			metadata, err := h.metadataService.FetchGameMetadata(game.Title, game.Platform.Name)
+			// Fetch metadata, by ROM hash when the game has files
+			metadata, err := h.metadataService.FetchMetadataForGame(&game)
 			if err != nil {
 				errors = append(errors, fmt.Sprintf("Game %d (%s): Failed to fetch metadata - %v", gameID, game.Title, err))
 				continue
@@ -188,6 +190,9 @@ func (h *ScannerHandler) processBatchMetadataUpdate(gameIDs []uint, batchSize, d
 			if metadata.IGDBID > 0 {
 				updateData["igdb_id"] = metadata.IGDBID
 			}
+			if metadata.ScreenScraperID > 0 {
+				updateData["screen_scraper_id"] = metadata.ScreenScraperID
+			}
 			
 			if len(updateData) > 0 {
 				if err := h.db.Model(&game).Updates(updateData).Error; err != nil {
@@ -195,6 +200,17 @@ func (h *ScannerHandler) processBatchMetadataUpdate(gameIDs []uint, batchSize, d
 					continue
 				}
 				updated++
+				
+				if err := services.SaveMetadataDetails(h.db, game.ID, metadata); err != nil {
+					errors = append(errors, fmt.Sprintf("Game %d (%s): Failed to save metadata details - %v", gameID, game.Title, err))
+				}
+				
+				// Store artwork locally so the library works offline
+				if h.images.AutoDownload() {
+					if err := h.images.CacheGameArt(&game); err != nil {
+						errors = append(errors, fmt.Sprintf("Game %d (%s): Failed to cache art - %v", gameID, game.Title, err))
+					}
+				}
 			}
 		}
 		
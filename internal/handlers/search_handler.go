@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"pelico/internal/errors"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchHandler serves full-text search across the collection
+type SearchHandler struct {
+	db     *gorm.DB
+	search *services.GameSearch
+}
+
+func NewSearchHandler(db *gorm.DB, search *services.GameSearch) *SearchHandler {
+	return &SearchHandler{
+		db:     db,
+		search: search,
+	}
+}
+
+// Search finds games by title, alternative title, description, completion
+// notes and session notes. Every word must match, as a word prefix.
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := c.Query("q")
+	
+	opts := services.SearchOptions{Limit: 20}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			opts.Limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			opts.Offset = parsed
+		}
+	}
+	if p := c.Query("platform_id"); p != "" {
+		parsed, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "platform_id",
+				"expected": "positive integer",
+				"received": p,
+			})
+			return
+		}
+		opts.PlatformID = uint(parsed)
+	}
+	
+	results, err := h.search.Search(query, opts)
+	if err != nil {
+		if stderrors.Is(err, services.ErrEmptySearchQuery) {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "q",
+				"expected": "at least one word to search for",
+				"received": query,
+			})
+			return
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "search_games",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"query": results.Query,
+		"total": results.Total,
+		"limit": opts.Limit,
+		"offset": opts.Offset,
+		"hits": results.Hits,
+	})
+}
+
+// Reindex rebuilds the search index. Writes through GORM keep it current one
+// game at a time; this covers edits it can't see, such as SQL run against the
+// database directly or bulk updates by condition that carry no game IDs.
+func (h *SearchHandler) Reindex(c *gin.Context) {
+	if err := h.search.Rebuild(); err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "rebuild_search_index",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	var indexed int64
+	h.db.Table("games").Count(&indexed)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Search index rebuilt",
+		"backend": h.search.Backend(),
+		"games_indexed": indexed,
+	})
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	celeste := models.Game{Title: "Celeste", PlatformID: 1, Description: "Climb the mountain."}
+	db.Create(&celeste)
+	db.Create(&models.Game{Title: "Mountain Climber", PlatformID: 1})
+	end := time.Date(2024, 2, 1, 20, 0, 0, 0, time.UTC)
+	session := models.PlaySession{GameID: celeste.ID, StartTime: end.Add(-time.Hour), EndTime: &end, Duration: 60, Notes: "Golden strawberry on chapter 7"}
+	db.Create(&session)
+
+	search := func(query string) (int, map[string]interface{}) {
+		w := send(server, "GET", "/search?"+query, nil)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+		return w.Code, body
+	}
+
+	code, body := search("q=mount")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, float64(2), body["total"])
+	hits := body["hits"].([]interface{})
+	require.Len(t, hits, 2)
+	first := hits[0].(map[string]interface{})
+	assert.Equal(t, "<mark>Mountain</mark> Climber", first["title"])
+	assert.Equal(t, "Mountain Climber", first["game"].(map[string]interface{})["title"])
+	second := hits[1].(map[string]interface{})
+	assert.Equal(t, "Climb the <mark>mountain</mark>.", second["snippet"])
+
+	code, body = search("q=golden+straw")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, body["hits"], 1)
+	assert.Equal(t, "session_notes", body["hits"].([]interface{})[0].(map[string]interface{})["snippet_field"])
+
+	// Deleting through the API keeps the index current
+	w := send(server, "DELETE", fmt.Sprintf("/sessions/%d", session.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	_, body = search("q=golden")
+	assert.Empty(t, body["hits"])
+
+	w = send(server, "DELETE", fmt.Sprintf("/games/%d", celeste.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	_, body = search("q=mountain")
+	assert.Equal(t, float64(1), body["total"])
+
+	code, _ = search("q=%20%2A")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = search("q=mountain&platform_id=abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	w = send(server, "POST", "/search/reindex", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"games_indexed":1`)
+}
+
+func TestSearchHandler_BackupRestoreReindexes(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+	require.NoError(t, db.Create(&models.Game{Title: "Okami", PlatformID: 1}).Error)
+
+	// The restore clears games with raw SQL the index callbacks don't see
+	w := send(server, "POST", "/backup/import", map[string]interface{}{
+		"version":   "1.0",
+		"platforms": []map[string]interface{}{{"id": 1, "name": "PlayStation 2"}},
+		"games":     []map[string]interface{}{{"id": 7, "title": "Shadow of the Colossus", "platform_id": 1}},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = send(server, "GET", "/search?q=okami", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":0`)
+	w = send(server, "GET", "/search?q=colossus", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+}
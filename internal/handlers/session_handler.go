@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"bytes"
+	stderrors "errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,15 +14,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// sessionResponse is a session plus the sessions it overlaps, reported when the
+// overlap policy only warns
+type sessionResponse struct {
+	*models.PlaySession
+	OverlappingSessions []services.SessionOverlap `json:"overlapping_sessions,omitempty"`
+}
+
 type SessionHandler struct {
 	db    *gorm.DB
 	cache *services.CacheService
+	timer *services.SessionTimer
 }
 
-func NewSessionHandler(db *gorm.DB, cache *services.CacheService) *SessionHandler {
+func NewSessionHandler(db *gorm.DB, cache *services.CacheService, timer *services.SessionTimer) *SessionHandler {
 	return &SessionHandler{
 		db:    db,
 		cache: cache,
+		timer: timer,
 	}
 }
 
@@ -65,47 +76,69 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 		return
 	}
 	
-	// Parse start time
-	startTime, err := time.Parse(time.RFC3339, req.StartTime)
-	if err != nil {
-		startTime = time.Now()
+	loc, ok := parseTimezoneParam(c, req.Timezone)
+	if !ok {
+		return
+	}
+	startTime, ok := h.parseSessionTime(c, "start_time", req.StartTime, loc)
+	if !ok {
+		return
 	}
-	
-	// Parse end time if provided
 	var endTime *time.Time
 	if req.EndTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, req.EndTime); err == nil {
-			endTime = &parsed
+		parsed, ok := h.parseSessionTime(c, "end_time", req.EndTime, loc)
+		if !ok {
+			return
 		}
+		endTime = &parsed
+	}
+	
+	if !h.validateSessionTimes(c, startTime, endTime) {
+		return
+	}
+	
+	// Sessions without an end time are running timers; only one may be open
+	if endTime == nil {
+		session, closed, overlaps, err := h.timer.StartChecked(uint(gameID), startTime, req.Notes, req.Rating)
+		if stderrors.Is(err, services.ErrSessionOverlap) {
+			h.respondOverlap(c, overlaps)
+			return
+		}
+		if err != nil {
+			h.respondTimerError(c, err, closed)
+			return
+		}
+		h.cache.InvalidateRecentlyPlayed()
+		c.JSON(http.StatusCreated, sessionResponse{session, overlaps})
+		return
 	}
 	
 	session := models.PlaySession{
 		GameID:    uint(gameID),
 		StartTime: startTime,
-		EndTime:   endTime,
 		Notes:     req.Notes,
 		Rating:    req.Rating,
 	}
-	
-	// Calculate duration if end time is provided
-	if endTime != nil {
-		duration := endTime.Sub(startTime)
-		session.Duration = int(duration.Minutes())
-	}
-	
-	result := h.db.Create(&session)
-	if result.Error != nil {
-		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
-			"operation": "create_session",
-			"error": result.Error.Error(),
-		})
+	services.EndSessionAt(&session, *endTime)
+	
+	// Checked under the timer's lock so a concurrent write can't slip in between
+	var overlaps []services.SessionOverlap
+	err = h.timer.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if overlaps, err = h.timer.CheckOverlapTx(tx, startTime, endTime, 0); err != nil {
+			return err
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		h.respondWriteError(c, "create_session", overlaps, err)
 		return
 	}
 	
 	// Invalidate recently played cache since session affects it
 	h.cache.InvalidateRecentlyPlayed()
 	
-	c.JSON(http.StatusCreated, session)
+	c.JSON(http.StatusCreated, sessionResponse{&session, overlaps})
 }
 
 func (h *SessionHandler) UpdateSession(c *gin.Context) {
@@ -139,18 +172,35 @@ func (h *SessionHandler) UpdateSession(c *gin.Context) {
 		return
 	}
 	
-	// Update fields if provided
-	if req.StartTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, req.StartTime); err == nil {
-			session.StartTime = parsed
+	// Update times if provided, recalculating the duration when either end moves
+	timesChanged := req.StartTime != "" || req.EndTime != ""
+	if timesChanged {
+		loc, ok := parseTimezoneParam(c, req.Timezone)
+		if !ok {
+			return
 		}
-	}
-	if req.EndTime != "" {
-		if parsed, err := time.Parse(time.RFC3339, req.EndTime); err == nil {
-			session.EndTime = &parsed
-			// Recalculate duration
-			duration := parsed.Sub(session.StartTime)
-			session.Duration = int(duration.Minutes())
+		startTime := session.StartTime
+		endTime := session.EndTime
+		if req.StartTime != "" {
+			if startTime, ok = h.parseSessionTime(c, "start_time", req.StartTime, loc); !ok {
+				return
+			}
+		}
+		if req.EndTime != "" {
+			parsed, ok := h.parseSessionTime(c, "end_time", req.EndTime, loc)
+			if !ok {
+				return
+			}
+			endTime = &parsed
+		}
+		
+		if !h.validateSessionTimes(c, startTime, endTime) {
+			return
+		}
+		
+		session.StartTime = startTime
+		if endTime != nil {
+			services.EndSessionAt(&session, *endTime)
 		}
 	}
 	if req.Notes != "" {
@@ -160,19 +210,25 @@ func (h *SessionHandler) UpdateSession(c *gin.Context) {
 		session.Rating = req.Rating
 	}
 	
-	result := h.db.Save(&session)
-	if result.Error != nil {
-		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
-			"operation": "update_session",
-			"error": result.Error.Error(),
-		})
+	var overlaps []services.SessionOverlap
+	err = h.timer.Transaction(func(tx *gorm.DB) error {
+		if timesChanged {
+			var err error
+			if overlaps, err = h.timer.CheckOverlapTx(tx, session.StartTime, session.EndTime, session.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&session).Error
+	})
+	if err != nil {
+		h.respondWriteError(c, "update_session", overlaps, err)
 		return
 	}
 	
 	// Invalidate recently played cache since session affects it
 	h.cache.InvalidateRecentlyPlayed()
 	
-	c.JSON(http.StatusOK, session)
+	c.JSON(http.StatusOK, sessionResponse{&session, overlaps})
 }
 
 func (h *SessionHandler) DeleteSession(c *gin.Context) {
@@ -186,7 +242,10 @@ func (h *SessionHandler) DeleteSession(c *gin.Context) {
 		return
 	}
 	
-	result := h.db.Delete(&models.PlaySession{}, id)
+	// Load the game ID first so the search index drops the session's notes
+	session := models.PlaySession{ID: uint(id)}
+	h.db.Select("id", "game_id").Limit(1).Find(&session, id)
+	result := h.db.Delete(&session)
 	if result.Error != nil {
 		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
 			"operation": "delete_session",
@@ -229,8 +288,95 @@ func (h *SessionHandler) GetAllSessions(c *gin.Context) {
 	c.JSON(http.StatusOK, sessions)
 }
 
+// ExportCalendar serves ended sessions as an iCalendar feed that calendar apps
+// can subscribe to. Optional ?game_id= limits it to one game and ?from=&to=
+// (inclusive YYYY-MM-DD, read in ?timezone=) to a date range.
+func (h *SessionHandler) ExportCalendar(c *gin.Context) {
+	query := h.db.Where("end_time IS NOT NULL").
+		Preload("Game").
+		Preload("Game.Platform").
+		Order("start_time")
+	name := "Pelico play sessions"
+	
+	if value := c.Query("game_id"); value != "" {
+		gameID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+				"parameter": "game_id",
+				"expected": "positive integer",
+				"received": value,
+			})
+			return
+		}
+		var game models.Game
+		if err := h.db.Select("id", "title").First(&game, gameID).Error; err != nil {
+			errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+				"game_id": gameID,
+			})
+			return
+		}
+		query = query.Where("game_id = ?", gameID)
+		name = game.Title + " play sessions"
+	}
+	
+	var r *services.PlaytimeRange
+	if c.Query("from") != "" || c.Query("to") != "" {
+		loc, ok := parseTimezoneParam(c, c.Query("timezone"))
+		if !ok {
+			return
+		}
+		parsed, err := services.NewPlaytimeRange(c.Query("from"), c.Query("to"), loc, time.Now())
+		if err != nil {
+			errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+				"parameter": "from/to",
+				"expected": "YYYY-MM-DD dates with from on or before to",
+				"error": err.Error(),
+			})
+			return
+		}
+		r = &parsed
+		// Widened by a day and filtered below, as SQLite compares times as text
+		query = query.Where("start_time >= ? AND start_time < ?", r.From.AddDate(0, 0, -1), r.To.AddDate(0, 0, 1))
+	}
+	
+	var sessions []models.PlaySession
+	if err := query.Find(&sessions).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "export_sessions_calendar",
+			"error": err.Error(),
+		})
+		return
+	}
+	if r != nil {
+		inRange := sessions[:0]
+		for _, session := range sessions {
+			if !session.StartTime.Before(r.From) && session.StartTime.Before(r.To) {
+				inRange = append(inRange, session)
+			}
+		}
+		sessions = inRange
+	}
+	
+	var body bytes.Buffer
+	if err := services.WriteSessionCalendar(&body, name, sessions, time.Now()); err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "export_sessions_calendar",
+			"error": err.Error(),
+		})
+		return
+	}
+	c.Header("Content-Disposition", "inline; filename=pelico-sessions.ics")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body.Bytes())
+}
+
 // GetActiveSessions returns all sessions without end_time (currently active)
 func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
+	// Close sessions that ran past the auto-end limit first
+	if _, err := h.timer.AutoEndStale(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	
 	var sessions []models.PlaySession
 	
 	// Find all sessions without end_time
@@ -248,24 +394,24 @@ func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
 	// Calculate current duration for each active session
 	type ActiveSession struct {
 		models.PlaySession
-		CurrentDuration int `json:"current_duration"` // minutes
+		CurrentDuration int  `json:"current_duration"` // minutes, excluding paused time
+		Paused          bool `json:"paused"`
 	}
 	
 	activeSessions := make([]ActiveSession, len(sessions))
-	now := time.Now()
 	
-	for i, session := range sessions {
-		duration := int(now.Sub(session.StartTime).Minutes())
+	for i := range sessions {
 		activeSessions[i] = ActiveSession{
-			PlaySession:     session,
-			CurrentDuration: duration,
+			PlaySession:     sessions[i],
+			CurrentDuration: int(h.timer.Elapsed(&sessions[i]).Minutes()),
+			Paused:          sessions[i].PausedAt != nil,
 		}
 	}
 	
 	c.JSON(http.StatusOK, activeSessions)
 }
 
-// EndSession sets the end_time for an active session
+// EndSession sets the end_time for an active session, excluding any paused time from its duration
 func (h *SessionHandler) EndSession(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -277,11 +423,56 @@ func (h *SessionHandler) EndSession(c *gin.Context) {
 		return
 	}
 	
-	var session models.PlaySession
-	if err := h.db.First(&session, id).Error; err != nil {
+	session, err := h.timer.Stop(uint(id), "", 0)
+	if err != nil {
+		h.respondTimerError(c, err, session)
+		return
+	}
+	
+	// Invalidate recently played cache since session affects it
+	h.cache.InvalidateRecentlyPlayed()
+	
+	c.JSON(http.StatusOK, session)
+}
+
+// GetTimer returns the running session with its elapsed play time, or null when idle
+func (h *SessionHandler) GetTimer(c *gin.Context) {
+	session, err := h.timer.Active()
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_active_session",
+			"error": err.Error(),
+		})
+		return
+	}
+	
+	response := gin.H{
+		"session":         session,
+		"running":         session != nil && session.PausedAt == nil,
+		"paused":          session != nil && session.PausedAt != nil,
+		"elapsed_minutes": 0,
+		"conflict_policy": h.timer.ConflictPolicy(),
+		"max_duration":    h.timer.MaxDuration().String(),
+	}
+	if session != nil {
+		response["elapsed_minutes"] = int(h.timer.Elapsed(session).Minutes())
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// StartTimer opens a session for a game now. An open session is refused or
+// auto-closed according to SESSION_CONFLICT_POLICY.
+func (h *SessionHandler) StartTimer(c *gin.Context) {
+	var req middleware.StartTimerRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	
+	var game models.Game
+	if err := h.db.First(&game, req.GameID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			errors.RespondWithError(c, errors.ErrSessionNotFound, map[string]interface{}{
-				"session_id": id,
+			errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+				"game_id": req.GameID,
 			})
 			return
 		}
@@ -292,30 +483,163 @@ func (h *SessionHandler) EndSession(c *gin.Context) {
 		return
 	}
 	
-	// Check if session is already ended
-	if session.EndTime != nil {
-		errors.RespondWithError(c, errors.ErrSessionAlreadyEnded, map[string]interface{}{
-			"session_id": id,
-			"end_time": session.EndTime,
-		})
+	session, closed, err := h.timer.Start(game.ID, time.Now(), req.Notes)
+	if err != nil {
+		h.respondTimerError(c, err, closed)
 		return
 	}
 	
-	// Update end_time to now and calculate duration
-	now := time.Now()
-	session.EndTime = &now
-	session.Duration = int(now.Sub(session.StartTime).Minutes())
+	h.cache.InvalidateRecentlyPlayed()
 	
-	if err := h.db.Save(&session).Error; err != nil {
-		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
-			"operation": "end_session",
-			"error": err.Error(),
-		})
+	c.JSON(http.StatusCreated, gin.H{
+		"session":        session,
+		"closed_session": closed,
+	})
+}
+
+// StopTimer ends the running session, optionally recording notes and a rating
+func (h *SessionHandler) StopTimer(c *gin.Context) {
+	var req middleware.StopTimerRequest
+	if c.Request.ContentLength != 0 && !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	
+	session, err := h.timer.Stop(0, req.Notes, req.Rating)
+	if err != nil {
+		h.respondTimerError(c, err, session)
 		return
 	}
 	
-	// Invalidate recently played cache since session affects it
 	h.cache.InvalidateRecentlyPlayed()
 	
 	c.JSON(http.StatusOK, session)
+}
+
+// PauseTimer pauses the running session; paused time doesn't count towards its duration
+func (h *SessionHandler) PauseTimer(c *gin.Context) {
+	session, err := h.timer.Pause(0)
+	if err != nil {
+		h.respondTimerError(c, err, session)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// ResumeTimer resumes a paused session
+func (h *SessionHandler) ResumeTimer(c *gin.Context) {
+	session, err := h.timer.Resume(0)
+	if err != nil {
+		h.respondTimerError(c, err, session)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// respondTimerError maps session timer errors to API errors; session is the
+// session the error refers to, when known
+func (h *SessionHandler) respondTimerError(c *gin.Context, err error, session *models.PlaySession) {
+	details := map[string]interface{}{}
+	if session != nil {
+		details["session_id"] = session.ID
+		details["game_id"] = session.GameID
+	}
+	
+	switch {
+	case stderrors.Is(err, services.ErrSessionActive):
+		details["conflict_policy"] = h.timer.ConflictPolicy()
+		errors.RespondWithError(c, errors.ErrSessionAlreadyActive, details)
+	case stderrors.Is(err, services.ErrNoActiveSession):
+		errors.RespondWithError(c, errors.ErrNoActiveSession)
+	case stderrors.Is(err, services.ErrSessionEnded):
+		if session != nil {
+			details["end_time"] = session.EndTime
+		}
+		errors.RespondWithError(c, errors.ErrSessionAlreadyEnded, details)
+	case stderrors.Is(err, services.ErrSessionPaused):
+		errors.RespondWithError(c, errors.ErrSessionAlreadyPaused, details)
+	case stderrors.Is(err, services.ErrSessionNotPaused):
+		errors.RespondWithError(c, errors.ErrSessionNotPaused, details)
+	case stderrors.Is(err, gorm.ErrRecordNotFound):
+		errors.RespondWithError(c, errors.ErrSessionNotFound, map[string]interface{}{
+			"session_id": c.Param("id"),
+		})
+	default:
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "session_timer",
+			"error": err.Error(),
+		})
+	}
+}
+
+
+// parseTimezoneParam resolves a timezone parameter (an empty one is the
+// server's zone), responding with an error and returning ok=false when it is
+// not a known IANA name
+func parseTimezoneParam(c *gin.Context, value string) (*time.Location, bool) {
+	loc, err := services.LoadSessionTimezone(value)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "timezone",
+			"expected": "IANA timezone name, e.g. Europe/Warsaw",
+			"received": value,
+		})
+		return nil, false
+	}
+	return loc, true
+}
+
+// parseSessionTime parses a request time, responding with ErrInvalidSessionData when it can't
+func (h *SessionHandler) parseSessionTime(c *gin.Context, field, value string, loc *time.Location) (time.Time, bool) {
+	parsed, err := services.ParseSessionTime(value, loc)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidSessionData, map[string]interface{}{
+			"field": field,
+			"value": value,
+			"expected": services.SessionTimeFormats,
+		})
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+// validateSessionTimes rejects sessions ending before they start or lying in the future
+func (h *SessionHandler) validateSessionTimes(c *gin.Context, startTime time.Time, endTime *time.Time) bool {
+	err := services.ValidateSessionTimes(startTime, endTime, h.timer.Now())
+	if err == nil {
+		return true
+	}
+	
+	details := map[string]interface{}{
+		"start_time": startTime,
+		"end_time": endTime,
+	}
+	if stderrors.Is(err, services.ErrSessionEndBeforeStart) {
+		details["field"] = "end_time"
+		details["reason"] = "end_time must not be before start_time"
+	} else {
+		details["reason"] = "session times must not be in the future"
+	}
+	errors.RespondWithError(c, errors.ErrInvalidSessionData, details)
+	return false
+}
+
+// respondWriteError reports a failed session write: overlaps rejected under
+// SESSION_OVERLAP_POLICY, or a database error
+func (h *SessionHandler) respondWriteError(c *gin.Context, operation string, overlaps []services.SessionOverlap, err error) {
+	if stderrors.Is(err, services.ErrSessionOverlap) {
+		h.respondOverlap(c, overlaps)
+		return
+	}
+	errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+		"operation": operation,
+		"error": err.Error(),
+	})
+}
+
+// respondOverlap rejects a session overlapping the given ones
+func (h *SessionHandler) respondOverlap(c *gin.Context, overlaps []services.SessionOverlap) {
+	errors.RespondWithError(c, errors.ErrSessionOverlap, map[string]interface{}{
+		"overlap_policy": h.timer.OverlapPolicy(),
+		"overlapping_sessions": overlaps,
+	})
 }
\ No newline at end of file
@@ -0,0 +1,227 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+	"pelico/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandler_Timer(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	zelda := models.Game{Title: "Ocarina of Time", PlatformID: 1}
+	db.Create(&zelda)
+	mario := models.Game{Title: "Super Mario 64", PlatformID: 1}
+	db.Create(&mario)
+
+	// Nothing to stop or pause yet
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/sessions/timer/stop", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/sessions/timer/pause", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/sessions/timer/start", map[string]interface{}{"game_id": 999}).Code)
+
+	w := send(server, "POST", "/sessions/timer/start", map[string]interface{}{"game_id": zelda.ID, "notes": "Water Temple"})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// A second timer, or an open manual session, is refused under the reject policy
+	w = send(server, "POST", "/sessions/timer/start", map[string]interface{}{"game_id": mario.ID})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "SESSION_ALREADY_ACTIVE")
+	w = send(server, "POST", fmt.Sprintf("/games/%d/sessions", mario.ID), map[string]interface{}{"start_time": time.Now().Format(time.RFC3339)})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Completed sessions can still be logged while the timer runs
+	w = send(server, "POST", fmt.Sprintf("/games/%d/sessions", mario.ID), map[string]interface{}{
+		"start_time": time.Now().Add(-3 * time.Hour).Format(time.RFC3339),
+		"end_time":   time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	require.Equal(t, http.StatusOK, send(server, "POST", "/sessions/timer/pause", nil).Code)
+	assert.Equal(t, http.StatusConflict, send(server, "POST", "/sessions/timer/pause", nil).Code)
+
+	w = send(server, "GET", "/sessions/timer", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var timer struct {
+		Session *models.PlaySession `json:"session"`
+		Running bool                `json:"running"`
+		Paused  bool                `json:"paused"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &timer))
+	require.NotNil(t, timer.Session)
+	assert.Equal(t, zelda.ID, timer.Session.GameID)
+	assert.True(t, timer.Paused)
+	assert.False(t, timer.Running)
+
+	require.Equal(t, http.StatusOK, send(server, "POST", "/sessions/timer/resume", nil).Code)
+	assert.Equal(t, http.StatusConflict, send(server, "POST", "/sessions/timer/resume", nil).Code)
+
+	w = send(server, "POST", "/sessions/timer/stop", map[string]interface{}{"rating": 9})
+	require.Equal(t, http.StatusOK, w.Code)
+	var stopped models.PlaySession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stopped))
+	assert.NotNil(t, stopped.EndTime)
+	assert.Equal(t, 9, stopped.Rating)
+	assert.Equal(t, "Water Temple", stopped.Notes)
+
+	// Ending it again reports it as already ended
+	w = send(server, "POST", fmt.Sprintf("/sessions/%d/end", stopped.ID), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "SESSION_ALREADY_ENDED")
+
+	// With the timer stopped a new one can start
+	assert.Equal(t, http.StatusCreated, send(server, "POST", "/sessions/timer/start", map[string]interface{}{"game_id": mario.ID}).Code)
+}
+
+func TestSessionHandler_ValidatesTimes(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	game := models.Game{Title: "Metroid Prime", PlatformID: 1}
+	db.Create(&game)
+
+	sessionsPath := fmt.Sprintf("/games/%d/sessions", game.ID)
+
+	// Unparseable times and reversed ranges are rejected instead of defaulting to now
+	w := send(server, "POST", sessionsPath, map[string]interface{}{"start_time": "yesterday evening"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_SESSION_DATA")
+	assert.Contains(t, w.Body.String(), "start_time")
+
+	w = send(server, "POST", sessionsPath, map[string]interface{}{
+		"start_time": "2024-03-01T20:00:00Z",
+		"end_time":   "2024-03-01T19:00:00Z",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "end_time must not be before start_time")
+
+	w = send(server, "POST", sessionsPath, map[string]interface{}{
+		"start_time": time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send(server, "POST", sessionsPath, map[string]interface{}{"start_time": "2024-03-01 18:00", "timezone": "Mars/Olympus_Mons"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "timezone")
+
+	// Local times are read in the request timezone; duration is rounded to the minute
+	w = send(server, "POST", sessionsPath, map[string]interface{}{
+		"start_time": "2024-03-01 18:00",
+		"end_time":   "2024-03-01 19:30:40",
+		"timezone":   "Europe/Warsaw",
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var session models.PlaySession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.True(t, session.StartTime.Equal(time.Date(2024, 3, 1, 17, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 91, session.Duration)
+
+	// Overlapping another session is refused, touching it is fine
+	w = send(server, "POST", sessionsPath, map[string]interface{}{
+		"start_time": "2024-03-01T19:00:00+01:00",
+		"end_time":   "2024-03-01T21:00:00+01:00",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "SESSION_OVERLAP")
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"session_id":%d`, session.ID))
+
+	w = send(server, "POST", sessionsPath, map[string]interface{}{
+		"start_time": "2024-03-01T16:00:00Z",
+		"end_time":   "2024-03-01T17:00:00Z",
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var earlier models.PlaySession
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &earlier))
+
+	// Moving either end recalculates the duration, and updates are checked too
+	sessionPath := fmt.Sprintf("/sessions/%d", session.ID)
+	w = send(server, "PUT", sessionPath, map[string]interface{}{"start_time": "2024-03-01T17:30:00Z"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.Equal(t, 61, session.Duration)
+
+	w = send(server, "PUT", sessionPath, map[string]interface{}{"end_time": "2024-03-01T18:00:00Z"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &session))
+	assert.Equal(t, 30, session.Duration)
+
+	w = send(server, "PUT", sessionPath, map[string]interface{}{"end_time": "2024-03-01T17:00:00Z"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send(server, "PUT", sessionPath, map[string]interface{}{"start_time": "2024-03-01T16:30:00Z"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = send(server, "PUT", fmt.Sprintf("/sessions/%d", earlier.ID), map[string]interface{}{"notes": "Chozo ruins"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSessionHandler_Calendar(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	game := models.Game{Title: "Chrono Trigger", PlatformID: 1}
+	other := models.Game{Title: "Hollow Knight", PlatformID: 1}
+	db.Create(&game)
+	db.Create(&other)
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	for i, gameID := range []uint{game.ID, other.ID, game.ID} {
+		sessionStart := start.AddDate(0, 0, i*10)
+		end := sessionStart.Add(time.Hour)
+		db.Create(&models.PlaySession{GameID: gameID, StartTime: sessionStart, EndTime: &end, Duration: 60})
+	}
+
+	w := send(server, "GET", "/sessions/calendar.ics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/calendar")
+	assert.Equal(t, 3, strings.Count(w.Body.String(), "BEGIN:VEVENT"))
+	feed := w.Body.String()
+
+	w = send(server, "GET", fmt.Sprintf("/sessions/calendar.ics?game_id=%d&from=2024-03-01&to=2024-03-10&timezone=UTC", game.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, strings.Count(w.Body.String(), "BEGIN:VEVENT"))
+	assert.Contains(t, w.Body.String(), "X-WR-CALNAME:Chrono Trigger play sessions")
+
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/sessions/calendar.ics?game_id=abc", nil).Code)
+	assert.Equal(t, http.StatusNotFound, send(server, "GET", "/sessions/calendar.ics?game_id=9999", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/sessions/calendar.ics?from=March", nil).Code)
+
+	// Re-importing our own feed changes nothing
+	w = sendRaw(server, "POST", "/import/ical?timezone=UTC", "text/calendar", strings.NewReader(feed))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result services.PlaytimeImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Unchanged)
+	assert.Equal(t, 0, result.SessionsCreated)
+
+	// A calendar event uploaded as a file becomes a session
+	var upload bytes.Buffer
+	form := multipart.NewWriter(&upload)
+	part, err := form.CreateFormFile("file", "games.ics")
+	require.NoError(t, err)
+	part.Write([]byte("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:evening\r\nSUMMARY:Hollow Knight\r\n" +
+		"DTSTART:20240320T190000\r\nDTEND:20240320T213000\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	form.Close()
+	w = sendRaw(server, "POST", "/import/ical?timezone=UTC", form.FormDataContentType(), &upload)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.SessionsCreated)
+	assert.Equal(t, 150, result.MinutesImported)
+
+	var count int64
+	db.Model(&models.PlaySession{}).Where("game_id = ? AND source = ?", other.ID, services.SourceICalendar).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w = sendRaw(server, "POST", "/import/ical?timezone=UTC", "text/calendar", strings.NewReader("hello"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
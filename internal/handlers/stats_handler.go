@@ -1,20 +1,38 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"pelico/internal/config"
+	"pelico/internal/errors"
+	"pelico/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type StatsHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	playtime *services.PlaytimeStats
+	value    *services.ValueStats
 }
 
-func NewStatsHandler(db *gorm.DB) *StatsHandler {
-	return &StatsHandler{DB: db}
+func NewStatsHandler(db *gorm.DB, cfg *config.Config) *StatsHandler {
+	return &StatsHandler{
+		DB:       db,
+		playtime: services.NewPlaytimeStats(db),
+		value:    services.NewValueStats(db, cfg.Currency),
+	}
 }
 
+// maxPlaytimeRangeDays bounds date ranges for the playtime endpoints
+const maxPlaytimeRangeDays = 3660
+
 // GetStats retrieves various statistics about the game collection
 func (h *StatsHandler) GetStats(c *gin.Context) {
 	var totalGames int64
@@ -39,11 +57,19 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 	h.DB.Table("games").Where("completion_status = ? OR completion_status = ? OR completion_status IS NULL", "not_started", "").Count(&notStarted)
 	h.DB.Table("games").Where("completion_status = ?", "abandoned").Count(&abandoned)
 
-	// Playtime statistics
-	var totalHours, avgHours float64
-	h.DB.Table("play_sessions").Select("COALESCE(SUM(play_time_minutes), 0) / 60.0").Scan(&totalHours)
-	if totalSessions > 0 {
-		avgHours = totalHours / float64(totalSessions)
+	// Playtime statistics, over completed sessions only since open ones have no duration yet
+	var playtime struct {
+		Minutes  int64
+		Sessions int64
+	}
+	h.DB.Table("play_sessions").
+		Select("COALESCE(SUM(duration), 0) AS minutes, COUNT(*) AS sessions").
+		Where("end_time IS NOT NULL").
+		Scan(&playtime)
+	totalHours := float64(playtime.Minutes) / 60
+	var avgHours float64
+	if playtime.Sessions > 0 {
+		avgHours = totalHours / float64(playtime.Sessions)
 	}
 
 	// Platform breakdown
@@ -71,9 +97,388 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 			"abandoned":   abandoned,
 		},
 		"playtime_stats": gin.H{
-			"total_hours":   totalHours,
-			"average_hours": avgHours,
+			"total_minutes":      playtime.Minutes,
+			"total_hours":        math.Round(totalHours*10) / 10,
+			"average_hours":      math.Round(avgHours*100) / 100,
+			"completed_sessions": playtime.Sessions,
 		},
 		"platform_breakdown": platformBreakdown,
 	})
 }
+
+// backlogEstimateColumns maps the ?estimate= parameter to time_to_beats columns
+var backlogEstimateColumns = map[string]string{
+	"hastily":    "hastily_minutes",
+	"normally":   "normally_minutes",
+	"completely": "completely_minutes",
+}
+
+// BacklogGame is one unfinished game with its estimate and logged play time
+type BacklogGame struct {
+	GameID               uint   `json:"game_id"`
+	Title                string `json:"title"`
+	Platform             string `json:"platform"`
+	CompletionStatus     string `json:"completion_status"`
+	CompletionPercentage int    `json:"completion_percentage"`
+	EstimateMinutes      int    `json:"estimate_minutes"` // 0 when no estimate is known
+	PlayedMinutes        int    `json:"played_minutes"`
+	RemainingMinutes     int    `json:"remaining_minutes"`
+	OverEstimate         bool   `json:"over_estimate"` // played longer than the estimate
+}
+
+// GetBacklog totals the estimated time left on not_started/in_progress games by
+// comparing time-to-beat estimates (?estimate=hastily|normally|completely) with
+// logged play session time
+func (h *StatsHandler) GetBacklog(c *gin.Context) {
+	estimate := c.DefaultQuery("estimate", "normally")
+	column, ok := backlogEstimateColumns[estimate]
+	if !ok {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "estimate",
+			"expected":  "hastily, normally or completely",
+			"received":  estimate,
+		})
+		return
+	}
+
+	var games []BacklogGame
+	err := h.DB.Table("games").
+		Select("games.id AS game_id, games.title, platforms.name AS platform, "+
+			"games.completion_status, games.completion_percentage, "+
+			"COALESCE(time_to_beats."+column+", 0) AS estimate_minutes, "+
+			"COALESCE((SELECT SUM(duration) FROM play_sessions WHERE play_sessions.game_id = games.id), 0) AS played_minutes").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Joins("LEFT JOIN time_to_beats ON time_to_beats.game_id = games.id").
+		Where("games.completion_status IN ? OR games.completion_status IS NULL OR games.completion_status = ''", []string{"not_started", "in_progress"}).
+		Order("games.title ASC").
+		Scan(&games).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "backlog_query",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	var estimatedGames, estimatedMinutes, playedMinutes, remainingMinutes int
+	for i := range games {
+		game := &games[i]
+		if game.CompletionStatus == "" {
+			game.CompletionStatus = "not_started"
+		}
+		playedMinutes += game.PlayedMinutes
+		if game.EstimateMinutes == 0 {
+			continue
+		}
+		estimatedGames++
+		estimatedMinutes += game.EstimateMinutes
+		game.RemainingMinutes = game.EstimateMinutes - game.PlayedMinutes
+		if game.RemainingMinutes < 0 {
+			game.RemainingMinutes = 0
+			game.OverEstimate = true
+		}
+		remainingMinutes += game.RemainingMinutes
+	}
+	if games == nil {
+		games = []BacklogGame{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"estimate": estimate,
+		"games":    games,
+		"totals": gin.H{
+			"games":             len(games),
+			"estimated_games":   estimatedGames,
+			"unestimated_games": len(games) - estimatedGames,
+			"estimated_minutes": estimatedMinutes,
+			"played_minutes":    playedMinutes,
+			"remaining_minutes": remainingMinutes,
+			"remaining_hours":   math.Round(float64(remainingMinutes)/6) / 10,
+		},
+	})
+}
+
+// playtimeRange reads the ?from=&to= dates (inclusive, YYYY-MM-DD) and
+// ?timezone= shared by the playtime endpoints. Defaults to the last year.
+func (h *StatsHandler) playtimeRange(c *gin.Context) (services.PlaytimeRange, bool) {
+	loc, ok := parseTimezoneParam(c, c.Query("timezone"))
+	if !ok {
+		return services.PlaytimeRange{}, false
+	}
+
+	r, err := services.NewPlaytimeRange(c.Query("from"), c.Query("to"), loc, time.Now())
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidFormat, map[string]string{
+			"parameter": "from/to",
+			"expected":  "YYYY-MM-DD dates with from on or before to",
+			"error":     err.Error(),
+		})
+		return services.PlaytimeRange{}, false
+	}
+	if r.Days() > maxPlaytimeRangeDays {
+		errors.RespondWithError(c, errors.ErrInvalidRange, map[string]interface{}{
+			"parameter": "from/to",
+			"max_days":  maxPlaytimeRangeDays,
+		})
+		return services.PlaytimeRange{}, false
+	}
+	return r, true
+}
+
+func (h *StatsHandler) respondPlaytimeError(c *gin.Context, operation string, err error) {
+	errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+		"operation": operation,
+		"error":     err.Error(),
+	})
+}
+
+// GetPlaytime returns play time per day, week or month (?interval=) over a date range
+func (h *StatsHandler) GetPlaytime(c *gin.Context) {
+	interval := c.DefaultQuery("interval", services.PlaytimeByDay)
+	if interval != services.PlaytimeByDay && interval != services.PlaytimeByWeek && interval != services.PlaytimeByMonth {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "interval",
+			"expected":  "day, week or month",
+			"received":  interval,
+		})
+		return
+	}
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.playtime.Series(r, interval)
+	if err != nil {
+		h.respondPlaytimeError(c, "playtime_series", err)
+		return
+	}
+
+	var minutes, sessions int
+	for _, bucket := range series {
+		minutes += bucket.Minutes
+		sessions += bucket.Sessions
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":          r.FirstDay(),
+		"to":            r.LastDay(),
+		"timezone":      r.Location.String(),
+		"interval":      interval,
+		"total_minutes": minutes,
+		"total_hours":   math.Round(float64(minutes)/6) / 10,
+		"sessions":      sessions,
+		"series":        series,
+	})
+}
+
+// GetPlaytimeByGame returns the most played games in a date range (?limit=, default 20, 0 for all)
+func (h *StatsHandler) GetPlaytimeByGame(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "limit",
+			"expected":  "non-negative integer",
+			"received":  c.Query("limit"),
+		})
+		return
+	}
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	games, err := h.playtime.ByGame(r, limit)
+	if err != nil {
+		h.respondPlaytimeError(c, "playtime_by_game", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":  r.FirstDay(),
+		"to":    r.LastDay(),
+		"games": games,
+	})
+}
+
+// GetPlaytimeByPlatform returns play time per platform in a date range
+func (h *StatsHandler) GetPlaytimeByPlatform(c *gin.Context) {
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	platforms, err := h.playtime.ByPlatform(r)
+	if err != nil {
+		h.respondPlaytimeError(c, "playtime_by_platform", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":      r.FirstDay(),
+		"to":        r.LastDay(),
+		"platforms": platforms,
+	})
+}
+
+// GetPlaytimeByGenre returns play time per genre in a date range
+func (h *StatsHandler) GetPlaytimeByGenre(c *gin.Context) {
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	genres, err := h.playtime.ByGenre(r)
+	if err != nil {
+		h.respondPlaytimeError(c, "playtime_by_genre", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":   r.FirstDay(),
+		"to":     r.LastDay(),
+		"genres": genres,
+	})
+}
+
+// GetPlaytimeHeatmap returns daily play time for a contribution-calendar view
+func (h *StatsHandler) GetPlaytimeHeatmap(c *gin.Context) {
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	heatmap, err := h.playtime.Heatmap(r)
+	if err != nil {
+		h.respondPlaytimeError(c, "playtime_heatmap", err)
+		return
+	}
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// GetYearReview returns a year-in-review report as JSON, or as a Markdown or
+// HTML download with ?format=markdown|html
+func (h *StatsHandler) GetYearReview(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 1970 || year > time.Now().Year()+1 {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "year",
+			"expected":  "four-digit year from 1970 to next year",
+			"received":  c.Param("year"),
+		})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" && format != "html" {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "format",
+			"expected":  "json, markdown or html",
+			"received":  format,
+		})
+		return
+	}
+	loc, ok := parseTimezoneParam(c, c.Query("timezone"))
+	if !ok {
+		return
+	}
+
+	review, err := h.playtime.YearReview(year, loc)
+	if err != nil {
+		h.respondPlaytimeError(c, "year_review", err)
+		return
+	}
+
+	var body bytes.Buffer
+	var contentType, extension string
+	switch format {
+	case "markdown":
+		err = services.RenderYearReviewMarkdown(&body, review)
+		contentType, extension = "text/markdown; charset=utf-8", "md"
+	case "html":
+		err = services.RenderYearReviewHTML(&body, review)
+		contentType, extension = "text/html; charset=utf-8", "html"
+	default:
+		c.JSON(http.StatusOK, review)
+		return
+	}
+	if err != nil {
+		h.respondPlaytimeError(c, "year_review_render", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=pelico-year-in-review-%d.%s", year, extension))
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// GetCollectionValue returns the physical collection's market value at the end
+// of each day, week or month (?interval=, default month) over a date range,
+// from imported price history
+func (h *StatsHandler) GetCollectionValue(c *gin.Context) {
+	interval := c.DefaultQuery("interval", services.PlaytimeByMonth)
+	if interval != services.PlaytimeByDay && interval != services.PlaytimeByWeek && interval != services.PlaytimeByMonth {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "interval",
+			"expected":  "day, week or month",
+			"received":  interval,
+		})
+		return
+	}
+	r, ok := h.playtimeRange(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.value.ValueSeries(r, interval)
+	if err != nil {
+		h.respondPlaytimeError(c, "collection_value", err)
+		return
+	}
+	current, err := h.value.Value(time.Now().In(r.Location))
+	if err != nil {
+		h.respondPlaytimeError(c, "collection_value", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":     r.FirstDay(),
+		"to":       r.LastDay(),
+		"timezone": r.Location.String(),
+		"interval": interval,
+		"currency": h.value.Currency(),
+		"current":  current,
+		"series":   series,
+	})
+}
+
+// GetSpending returns money spent on physical copies per purchase year and platform
+func (h *StatsHandler) GetSpending(c *gin.Context) {
+	loc, ok := parseTimezoneParam(c, c.Query("timezone"))
+	if !ok {
+		return
+	}
+
+	spending, err := h.value.Spending(loc)
+	if err != nil {
+		h.respondPlaytimeError(c, "spending", err)
+		return
+	}
+	c.JSON(http.StatusOK, spending)
+}
+
+// GetCostPerHour returns what games' physical copies cost per hour played,
+// cheapest first (?limit=, default 20, 0 for all)
+func (h *StatsHandler) GetCostPerHour(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "limit",
+			"expected":  "non-negative integer",
+			"received":  c.Query("limit"),
+		})
+		return
+	}
+
+	games, err := h.value.CostPerHour(limit)
+	if err != nil {
+		h.respondPlaytimeError(c, "cost_per_hour", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"currency": h.value.Currency(),
+		"games":    games,
+	})
+}
@@ -0,0 +1,303 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+	"pelico/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler_Playtime(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	game := models.Game{Title: "Chrono Trigger", PlatformID: 1}
+	db.Create(&game)
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	for _, minutes := range []int{90, 30} {
+		end := start.Add(time.Duration(minutes) * time.Minute)
+		db.Create(&models.PlaySession{GameID: game.ID, StartTime: start, EndTime: &end, Duration: minutes})
+		start = start.AddDate(0, 0, 1)
+	}
+	db.Create(&models.PlaySession{GameID: game.ID, StartTime: time.Now().Add(-time.Hour)})
+
+	// Totals come from session durations; the open session has none yet
+	w := send(server, "GET", "/stats", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		PlaytimeStats struct {
+			TotalMinutes      int     `json:"total_minutes"`
+			TotalHours        float64 `json:"total_hours"`
+			AverageHours      float64 `json:"average_hours"`
+			CompletedSessions int     `json:"completed_sessions"`
+		} `json:"playtime_stats"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 120, stats.PlaytimeStats.TotalMinutes)
+	assert.Equal(t, 2.0, stats.PlaytimeStats.TotalHours)
+	assert.Equal(t, 1.0, stats.PlaytimeStats.AverageHours)
+	assert.Equal(t, 2, stats.PlaytimeStats.CompletedSessions)
+
+	w = send(server, "GET", "/stats/playtime?from=2024-03-01&to=2024-03-31&interval=week&timezone=UTC", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var series struct {
+		Interval     string                    `json:"interval"`
+		TotalMinutes int                       `json:"total_minutes"`
+		Series       []services.PlaytimeBucket `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+	assert.Equal(t, "week", series.Interval)
+	assert.Equal(t, 120, series.TotalMinutes)
+	require.Len(t, series.Series, 5)
+	assert.Equal(t, "2024-W10", series.Series[1].Period)
+	assert.Equal(t, 120, series.Series[1].Minutes)
+
+	w = send(server, "GET", "/stats/playtime/games?from=2024-03-01&to=2024-03-31", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Chrono Trigger"`)
+
+	w = send(server, "GET", "/stats/playtime/heatmap?from=2024-03-04&to=2024-03-10", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var heatmap services.PlaytimeHeatmap
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &heatmap))
+	assert.Len(t, heatmap.Days, 7)
+	assert.Equal(t, 2, heatmap.ActiveDays)
+
+	for _, path := range []string{
+		"/stats/playtime?interval=year",
+		"/stats/playtime?from=2024-03-31&to=2024-03-01",
+		"/stats/playtime?from=1990-01-01&to=2024-01-01",
+		"/stats/playtime/platforms?timezone=Nowhere/Special",
+		"/stats/playtime/games?limit=-1",
+	} {
+		assert.Equal(t, http.StatusBadRequest, send(server, "GET", path, nil).Code, path)
+	}
+}
+
+func TestStatsHandler_YearReview(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	game := models.Game{Title: "Chrono Trigger", PlatformID: 1}
+	db.Create(&game)
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+	db.Create(&models.PlaySession{GameID: game.ID, StartTime: start, EndTime: &end, Duration: 90})
+
+	w := send(server, "GET", "/stats/year-in-review/2024?timezone=UTC", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var review services.YearReview
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &review))
+	assert.Equal(t, 2024, review.Year)
+	assert.Equal(t, 90, review.TotalMinutes)
+	require.Len(t, review.GamesStarted, 1)
+	assert.Equal(t, "Chrono Trigger", review.GamesStarted[0].Title)
+
+	w = send(server, "GET", "/stats/year-in-review/2024?format=markdown", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "attachment; filename=pelico-year-in-review-2024.md", w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/markdown")
+	assert.Contains(t, w.Body.String(), "# 2024 in review")
+
+	w = send(server, "GET", "/stats/year-in-review/2024?format=html", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "attachment; filename=pelico-year-in-review-2024.html", w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), "<h1>2024 in review</h1>")
+
+	for _, path := range []string{
+		"/stats/year-in-review/last",
+		"/stats/year-in-review/1800",
+		"/stats/year-in-review/2024?format=pdf",
+		"/stats/year-in-review/2024?timezone=Nowhere/Special",
+	} {
+		assert.Equal(t, http.StatusBadRequest, send(server, "GET", path, nil).Code, path)
+	}
+
+	// Abandoning a game records when, so it shows up in that year's review
+	w = send(server, "PUT", fmt.Sprintf("/games/%d/completion", game.ID), map[string]interface{}{"status": "abandoned"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated models.Game
+	require.NoError(t, db.First(&updated, game.ID).Error)
+	require.NotNil(t, updated.CompletionDate)
+}
+
+func TestCollectionValueStats(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	zelda := models.Game{Title: "The Legend of Zelda", PlatformID: 1}
+	tetris := models.Game{Title: "Tetris", PlatformID: 1}
+	db.Create(&zelda)
+	db.Create(&tetris)
+
+	createCopy := func(gameID uint, body string) {
+		w := sendRaw(server, "POST", fmt.Sprintf("/games/%d/copies", gameID), "application/json", strings.NewReader(body))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+	createCopy(zelda.ID, `{"completeness": "cib", "purchase_price": 40, "purchase_date": "2023-02-10"}`)
+	createCopy(tetris.ID, `{"purchase_price": 12.5, "purchase_date": "2024-06-01"}`)
+	createCopy(tetris.ID, `{"purchase_price": 30, "currency": "JPY", "purchase_date": "2024-06-02"}`)
+	start := time.Date(2024, 1, 5, 18, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+	db.Create(&models.PlaySession{GameID: zelda.ID, StartTime: start, EndTime: &end, Duration: 240})
+
+	csv := "product-name,console-name,loose-price,cib-price,new-price\n" +
+		"The Legend of Zelda,Test Console,$20.00,$55.00,$400.00\n" +
+		"Tetris,Test Console,$8.00,,\n" +
+		"Metroid,NES,$15.00,$60.00,\n"
+	w := sendRaw(server, "POST", "/import/prices?date=2024-01-15", "text/csv", strings.NewReader(csv))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var imported services.PriceImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &imported))
+	assert.Equal(t, 3, imported.Rows)
+	assert.Equal(t, 2, imported.GamesPriced)
+	assert.Equal(t, 4, imported.PricesImported)
+	assert.Equal(t, []string{"Metroid (NES)"}, imported.Unmatched)
+	assert.Equal(t, "USD", imported.Currency)
+
+	// A later export updates the value from its date on
+	w = sendRaw(server, "POST", "/import/prices?date=2024-03-01", "text/csv", strings.NewReader("product-name,cib-price\nThe Legend of Zelda,70\n"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, sendRaw(server, "POST", "/import/prices", "text/csv", strings.NewReader("title,price\nTetris,5\n")).Code)
+	assert.Equal(t, http.StatusBadRequest, sendRaw(server, "POST", "/import/prices?date=March", "text/csv", strings.NewReader(csv)).Code)
+
+	w = send(server, "GET", fmt.Sprintf("/games/%d/prices", zelda.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var prices []models.PricePoint
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prices))
+	assert.Len(t, prices, 4)
+
+	w = send(server, "GET", "/stats/value?from=2024-01-01&to=2024-03-31&timezone=UTC", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var value struct {
+		Currency string                `json:"currency"`
+		Series   []services.ValuePoint `json:"series"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &value))
+	assert.Equal(t, "USD", value.Currency)
+	require.Len(t, value.Series, 3)
+	assert.Equal(t, 55.0, value.Series[0].Value, "only Zelda was owned in January")
+	assert.Equal(t, 1, value.Series[0].Copies)
+	assert.Equal(t, 70.0, value.Series[2].Value)
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/stats/value?interval=year", nil).Code)
+
+	w = send(server, "GET", "/stats/spending?timezone=UTC", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var spending services.Spending
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spending))
+	assert.Equal(t, 52.5, spending.Total)
+	assert.Equal(t, 1, spending.Skipped)
+	assert.Equal(t, []services.SpendingBreakdown{{Name: "2023", Amount: 40, Copies: 1}, {Name: "2024", Amount: 12.5, Copies: 1}}, spending.ByYear)
+	assert.Equal(t, []services.SpendingBreakdown{{Name: "Test Console", Amount: 52.5, Copies: 2}}, spending.ByPlatform)
+
+	w = send(server, "GET", "/stats/cost-per-hour", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var costs struct {
+		Games []services.GameCostPerHour `json:"games"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &costs))
+	require.Len(t, costs.Games, 2)
+	assert.Equal(t, zelda.ID, costs.Games[0].GameID)
+	require.NotNil(t, costs.Games[0].CostPerHour)
+	assert.Equal(t, 10.0, *costs.Games[0].CostPerHour)
+	assert.Nil(t, costs.Games[1].CostPerHour, "Tetris was never played")
+	assert.Equal(t, http.StatusBadRequest, send(server, "GET", "/stats/cost-per-hour?limit=-1", nil).Code)
+}
+
+func TestStatsHandler_GetBacklog(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	ff6 := models.Game{Title: "Final Fantasy VI", PlatformID: 1, CompletionStatus: "in_progress"}
+	db.Create(&ff6)
+	zelda := models.Game{Title: "A Link to the Past", PlatformID: 1, CompletionStatus: "not_started"}
+	db.Create(&zelda)
+	tetris := models.Game{Title: "Tetris", PlatformID: 1, CompletionStatus: "in_progress"}
+	db.Create(&tetris)
+	unknown := models.Game{Title: "Obscure Homebrew", PlatformID: 1, CompletionStatus: "not_started"}
+	db.Create(&unknown)
+	db.Create(&models.Game{Title: "Finished", PlatformID: 1, CompletionStatus: "completed"})
+
+	require.NoError(t, services.SaveMetadataDetails(db, ff6.ID, &services.GameMetadata{
+		TimeToBeat: &services.MetadataTimeToBeat{HastilyMinutes: 1800, NormallyMinutes: 2400, CompletelyMinutes: 3600},
+	}))
+	require.NoError(t, services.SaveMetadataDetails(db, zelda.ID, &services.GameMetadata{
+		TimeToBeat: &services.MetadataTimeToBeat{HastilyMinutes: 600, NormallyMinutes: 900, CompletelyMinutes: 1200},
+	}))
+	require.NoError(t, services.SaveMetadataDetails(db, tetris.ID, &services.GameMetadata{
+		TimeToBeat: &services.MetadataTimeToBeat{HastilyMinutes: 60, NormallyMinutes: 120},
+	}))
+
+	// Saving again updates the estimate rather than adding a row
+	require.NoError(t, services.SaveMetadataDetails(db, zelda.ID, &services.GameMetadata{
+		TimeToBeat: &services.MetadataTimeToBeat{HastilyMinutes: 600, NormallyMinutes: 960, CompletelyMinutes: 1200},
+	}))
+	var estimates int64
+	db.Model(&models.TimeToBeat{}).Where("game_id = ?", zelda.ID).Count(&estimates)
+	assert.Equal(t, int64(1), estimates)
+
+	start := time.Now().Add(-10 * time.Hour)
+	db.Create(&models.PlaySession{GameID: ff6.ID, StartTime: start, Duration: 400})
+	db.Create(&models.PlaySession{GameID: ff6.ID, StartTime: start.Add(time.Hour), Duration: 200})
+	db.Create(&models.PlaySession{GameID: tetris.ID, StartTime: start, Duration: 300})
+
+	type backlogResponse struct {
+		Estimate string `json:"estimate"`
+		Games    []struct {
+			Title            string `json:"title"`
+			EstimateMinutes  int    `json:"estimate_minutes"`
+			PlayedMinutes    int    `json:"played_minutes"`
+			RemainingMinutes int    `json:"remaining_minutes"`
+			OverEstimate     bool   `json:"over_estimate"`
+		} `json:"games"`
+		Totals struct {
+			Games            int     `json:"games"`
+			EstimatedGames   int     `json:"estimated_games"`
+			UnestimatedGames int     `json:"unestimated_games"`
+			EstimatedMinutes int     `json:"estimated_minutes"`
+			PlayedMinutes    int     `json:"played_minutes"`
+			RemainingMinutes int     `json:"remaining_minutes"`
+			RemainingHours   float64 `json:"remaining_hours"`
+		} `json:"totals"`
+	}
+
+	w := send(server, "GET", "/stats/backlog", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response backlogResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "normally", response.Estimate)
+	require.Len(t, response.Games, 4)
+	assert.Equal(t, "A Link to the Past", response.Games[0].Title)
+	assert.Equal(t, 960, response.Games[0].RemainingMinutes)
+	assert.Equal(t, "Final Fantasy VI", response.Games[1].Title)
+	assert.Equal(t, 600, response.Games[1].PlayedMinutes)
+	assert.Equal(t, 1800, response.Games[1].RemainingMinutes)
+	assert.Equal(t, "Tetris", response.Games[3].Title)
+	assert.True(t, response.Games[3].OverEstimate)
+	assert.Equal(t, 0, response.Games[3].RemainingMinutes)
+
+	assert.Equal(t, 4, response.Totals.Games)
+	assert.Equal(t, 3, response.Totals.EstimatedGames)
+	assert.Equal(t, 1, response.Totals.UnestimatedGames)
+	assert.Equal(t, 3480, response.Totals.EstimatedMinutes)
+	assert.Equal(t, 900, response.Totals.PlayedMinutes)
+	assert.Equal(t, 2760, response.Totals.RemainingMinutes)
+	assert.Equal(t, 46.0, response.Totals.RemainingHours)
+
+	w = send(server, "GET", "/stats/backlog?estimate=hastily", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1800+600+60, response.Totals.EstimatedMinutes)
+
+	w = send(server, "GET", "/stats/backlog?estimate=forever", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
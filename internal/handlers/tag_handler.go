@@ -0,0 +1,433 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"pelico/internal/errors"
+	"pelico/internal/middleware"
+	"pelico/internal/models"
+	"pelico/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// TagHandler manages free-form tags and tagging games
+type TagHandler struct {
+	db    *gorm.DB
+	cache *services.CacheService
+}
+
+func NewTagHandler(db *gorm.DB, cache *services.CacheService) *TagHandler {
+	return &TagHandler{
+		db:    db,
+		cache: cache,
+	}
+}
+
+// tagWithCount is a tag and how many games carry it
+type tagWithCount struct {
+	models.Tag
+	GameCount int64 `json:"game_count"`
+}
+
+// GetTags lists every tag with its number of games, alphabetically
+func (h *TagHandler) GetTags(c *gin.Context) {
+	var tags []tagWithCount
+	err := h.db.Model(&models.Tag{}).
+		Select("tags.*, COUNT(game_tags.game_id) AS game_count").
+		Joins("LEFT JOIN game_tags ON game_tags.tag_id = tags.id").
+		Group("tags.id").
+		Order("LOWER(tags.name)").
+		Scan(&tags).Error
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "fetch_tags",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req middleware.CreateTagRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	tag := models.Tag{Name: normalizeTagName(req.Name), Color: strings.ToLower(req.Color)}
+	if !h.validateTag(c, &tag) {
+		return
+	}
+
+	if err := h.db.Create(&tag).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "create_tag",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	tag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	var req middleware.UpdateTagRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+
+	// Update fields if provided in request
+	if req.Name != "" {
+		tag.Name = normalizeTagName(req.Name)
+	}
+	if req.Color != nil {
+		tag.Color = strings.ToLower(*req.Color)
+	}
+	if !h.validateTag(c, tag) {
+		return
+	}
+
+	if err := h.db.Save(tag).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "update_tag",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	h.invalidateTaggedGames(tag.ID)
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag and removes it from its games
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	tag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	gameIDs := h.taggedGames(tag.ID)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM game_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "delete_tag",
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	for _, id := range gameIDs {
+		h.cache.InvalidateGame(id)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// BulkTag adds and removes tags on many games at once. Added tags are
+// created when missing; removing a tag a game doesn't have is not an error.
+func (h *TagHandler) BulkTag(c *gin.Context) {
+	var req middleware.BulkTagRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		errors.RespondWithError(c, errors.ErrInvalidTagData, map[string]string{
+			"error": "give tags to add or remove",
+		})
+		return
+	}
+	gameIDs, ok := requireGames(c, h.db, req.GameIDs)
+	if !ok {
+		return
+	}
+
+	var added, removed []models.Tag
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if added, err = resolveTags(tx, req.Add, true); err != nil {
+			return err
+		}
+		if removed, err = resolveTags(tx, req.Remove, false); err != nil {
+			return err
+		}
+		if err := addGameTags(tx, gameIDs, added); err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		return tx.Exec("DELETE FROM game_tags WHERE game_id IN ? AND tag_id IN ?", gameIDs, tagIDs(removed)).Error
+	})
+	if err != nil {
+		h.respondTagWriteError(c, "bulk_tag", err)
+		return
+	}
+
+	for _, id := range gameIDs {
+		h.cache.InvalidateGame(id)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"games_updated": len(gameIDs),
+		"added":         nonNilTags(added),
+		"removed":       nonNilTags(removed),
+	})
+}
+
+// SetGameTags replaces a game's tags, creating missing ones
+func (h *TagHandler) SetGameTags(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return
+	}
+
+	var req middleware.SetGameTagsRequest
+	if !middleware.ValidateAndBind(c, &req) {
+		return
+	}
+	gameIDs, ok := requireGames(c, h.db, []uint{uint(id)})
+	if !ok {
+		return
+	}
+
+	var tags []models.Tag
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if tags, err = resolveTags(tx, req.Tags, true); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM game_tags WHERE game_id = ?", gameIDs[0]).Error; err != nil {
+			return err
+		}
+		return addGameTags(tx, gameIDs, tags)
+	})
+	if err != nil {
+		h.respondTagWriteError(c, "set_game_tags", err)
+		return
+	}
+
+	h.cache.InvalidateGame(gameIDs[0])
+	c.JSON(http.StatusOK, gin.H{
+		"game_id": gameIDs[0],
+		"tags":    nonNilTags(tags),
+	})
+}
+
+// findTag loads the tag named by the :id parameter
+func (h *TagHandler) findTag(c *gin.Context) (*models.Tag, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		errors.RespondWithError(c, errors.ErrInvalidRequest, map[string]string{
+			"parameter": "id",
+			"expected":  "positive integer",
+			"received":  c.Param("id"),
+		})
+		return nil, false
+	}
+
+	var tag models.Tag
+	if err := h.db.First(&tag, id).Error; err != nil {
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			errors.RespondWithError(c, errors.ErrTagNotFound, map[string]interface{}{
+				"tag_id": id,
+			})
+			return nil, false
+		}
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return &tag, true
+}
+
+// validateTag checks the color and that no other tag has the name, ignoring case
+func (h *TagHandler) validateTag(c *gin.Context, tag *models.Tag) bool {
+	if tag.Name == "" {
+		errors.RespondWithError(c, errors.ErrInvalidTagData, map[string]string{
+			"field": "name",
+			"error": "name is blank",
+		})
+		return false
+	}
+	if tag.Color != "" && !tagColorPattern.MatchString(tag.Color) {
+		errors.RespondWithError(c, errors.ErrInvalidTagData, map[string]string{
+			"field":    "color",
+			"expected": "#rrggbb",
+			"received": tag.Color,
+		})
+		return false
+	}
+
+	var count int64
+	h.db.Model(&models.Tag{}).Where(services.EqualFold("name", tag.Name)).Where("id <> ?", tag.ID).Count(&count)
+	if count > 0 {
+		errors.RespondWithError(c, errors.ErrTagAlreadyExists, map[string]string{
+			"name": tag.Name,
+		})
+		return false
+	}
+	return true
+}
+
+func (h *TagHandler) taggedGames(tagID uint) []uint {
+	var ids []uint
+	h.db.Table("game_tags").Where("tag_id = ?", tagID).Pluck("game_id", &ids)
+	return ids
+}
+
+func (h *TagHandler) invalidateTaggedGames(tagID uint) {
+	for _, id := range h.taggedGames(tagID) {
+		h.cache.InvalidateGame(id)
+	}
+}
+
+func (h *TagHandler) respondTagWriteError(c *gin.Context, operation string, err error) {
+	var invalid invalidTagError
+	if stderrors.As(err, &invalid) {
+		errors.RespondWithError(c, errors.ErrInvalidTagData, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+		"operation": operation,
+		"error":     err.Error(),
+	})
+}
+
+// invalidTagError rejects a tag name given for tagging
+type invalidTagError string
+
+func (e invalidTagError) Error() string {
+	return string(e)
+}
+
+// normalizeTagName trims a tag name and collapses runs of whitespace
+func normalizeTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// resolveTags finds tags by name, ignoring case and duplicates. Missing tags
+// are created when create is set and skipped otherwise.
+func resolveTags(tx *gorm.DB, names []string, create bool) ([]models.Tag, error) {
+	var tags []models.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" {
+			return nil, invalidTagError("tag names can't be blank")
+		}
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		var tag models.Tag
+		err := tx.Where(services.EqualFold("name", name)).First(&tag).Error
+		if stderrors.Is(err, gorm.ErrRecordNotFound) {
+			if !create {
+				continue
+			}
+			tag = models.Tag{Name: name}
+			err = tx.Create(&tag).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// addGameTags tags every game with every tag, keeping tags they already have
+func addGameTags(tx *gorm.DB, gameIDs []uint, tags []models.Tag) error {
+	if len(gameIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, 0, len(gameIDs)*len(tags))
+	for _, gameID := range gameIDs {
+		for _, tag := range tags {
+			rows = append(rows, map[string]interface{}{"game_id": gameID, "tag_id": tag.ID})
+		}
+	}
+	return tx.Table("game_tags").Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 500).Error
+}
+
+// requireGames checks that every game exists, responding with the missing IDs
+// when not. It returns the IDs without duplicates.
+func requireGames(c *gin.Context, db *gorm.DB, ids []uint) ([]uint, bool) {
+	unique := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	var found []uint
+	if err := db.Model(&models.Game{}).Where("id IN ?", unique).Pluck("id", &found).Error; err != nil {
+		errors.RespondWithError(c, errors.ErrInternalServer, map[string]string{
+			"operation": "database_query",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	if len(found) == len(unique) {
+		return unique, true
+	}
+
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	var missing []uint
+	for _, id := range unique {
+		if !exists[id] {
+			missing = append(missing, id)
+		}
+	}
+	errors.RespondWithError(c, errors.ErrGameNotFound, map[string]interface{}{
+		"game_ids": missing,
+	})
+	return nil, false
+}
+
+func tagIDs(tags []models.Tag) []uint {
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+	return ids
+}
+
+// nonNilTags keeps empty tag lists as [] rather than null in responses
+func nonNilTags(tags []models.Tag) []models.Tag {
+	if tags == nil {
+		return []models.Tag{}
+	}
+	return tags
+}
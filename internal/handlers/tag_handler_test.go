@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagHandler(t *testing.T) {
+	db := setupTestDB(t)
+	server := setupTestServer(db)
+
+	zelda := models.Game{Title: "Zelda", PlatformID: 1}
+	mario := models.Game{Title: "Mario Kart", PlatformID: 1}
+	tetris := models.Game{Title: "Tetris", PlatformID: 1}
+	db.Create(&zelda)
+	db.Create(&mario)
+	db.Create(&tetris)
+
+	titles := func(query string) []string {
+		w := send(server, "GET", "/games?"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response struct {
+			Games []models.Game `json:"games"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result := make([]string, len(response.Games))
+		for i, game := range response.Games {
+			result[i] = game.Title
+		}
+		return result
+	}
+
+	w := send(server, "POST", "/tags", map[string]interface{}{"name": "  Couch   Co-op ", "color": "#FF8800"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var couch models.Tag
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &couch))
+	assert.Equal(t, "Couch Co-op", couch.Name)
+	assert.Equal(t, "#ff8800", couch.Color)
+
+	assert.Equal(t, http.StatusConflict, send(server, "POST", "/tags", map[string]interface{}{"name": "couch co-op"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "POST", "/tags", map[string]interface{}{"name": "x", "color": "orange"}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "POST", "/tags", map[string]interface{}{"name": "   "}).Code)
+
+	// Bulk tagging creates missing tags and skips tags already present
+	w = send(server, "POST", "/tags/bulk", map[string]interface{}{
+		"game_ids": []uint{zelda.ID, mario.ID}, "add": []string{"couch co-op", "Comfort"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = send(server, "POST", "/tags/bulk", map[string]interface{}{
+		"game_ids": []uint{mario.ID, tetris.ID}, "add": []string{"comfort"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusNotFound, send(server, "POST", "/tags/bulk", map[string]interface{}{
+		"game_ids": []uint{zelda.ID, 9999}, "add": []string{"comfort"},
+	}).Code)
+	assert.Equal(t, http.StatusBadRequest, send(server, "POST", "/tags/bulk", map[string]interface{}{
+		"game_ids": []uint{zelda.ID},
+	}).Code)
+
+	assert.ElementsMatch(t, []string{"Zelda", "Mario Kart", "Tetris"}, titles("tag=comfort,couch+co-op"))
+	assert.ElementsMatch(t, []string{"Zelda", "Mario Kart"}, titles("tag=Comfort&tag=Couch+Co-op&tag_mode=and"))
+
+	w = send(server, "GET", "/tags", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed []struct {
+		Name      string `json:"name"`
+		GameCount int64  `json:"game_count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Equal(t, "Comfort", listed[0].Name)
+	assert.Equal(t, int64(3), listed[0].GameCount)
+	assert.Equal(t, int64(2), listed[1].GameCount)
+
+	w = send(server, "POST", "/tags/bulk", map[string]interface{}{
+		"game_ids": []uint{mario.ID}, "remove": []string{"COMFORT", "never-created"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []string{"Zelda", "Tetris"}, titles("tag=comfort"))
+
+	w = send(server, "PUT", fmt.Sprintf("/games/%d/tags", tetris.ID), map[string]interface{}{"tags": []string{"Puzzle", "puzzle"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = send(server, "GET", fmt.Sprintf("/games/%d", tetris.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var game models.Game
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &game))
+	require.Len(t, game.Tags, 1)
+	assert.Equal(t, "Puzzle", game.Tags[0].Name)
+
+	w = send(server, "PUT", fmt.Sprintf("/tags/%d", couch.ID), map[string]interface{}{"name": "Local Co-op", "color": ""})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.ElementsMatch(t, []string{"Zelda", "Mario Kart"}, titles("tag=local+co-op"))
+	assert.Equal(t, http.StatusConflict, send(server, "PUT", fmt.Sprintf("/tags/%d", couch.ID), map[string]interface{}{"name": "puzzle"}).Code)
+
+	assert.Equal(t, http.StatusOK, send(server, "DELETE", fmt.Sprintf("/tags/%d", couch.ID), nil).Code)
+	assert.Empty(t, titles("tag=local+co-op"))
+	assert.Equal(t, http.StatusNotFound, send(server, "DELETE", fmt.Sprintf("/tags/%d", couch.ID), nil).Code)
+	var joins int64
+	db.Table("game_tags").Where("tag_id = ?", couch.ID).Count(&joins)
+	assert.Zero(t, joins)
+}
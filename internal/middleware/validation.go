@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"encoding/json"
 	"strings"
 	"pelico/internal/errors"
+	"pelico/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -84,10 +86,13 @@ type CreateGameRequest struct {
 	PlatformID        uint     `json:"platform_id" binding:"required,gt=0"`
 	Year              int      `json:"year" binding:"omitempty,gte=1970,lte=2030"`
 	Genre             string   `json:"genre" binding:"omitempty,max=100"`
+	Genres            []string `json:"genres" binding:"omitempty,max=20,dive,min=1,max=100"`
 	Rating            float32  `json:"rating" binding:"omitempty,gte=0,lte=10"`
 	Description       string   `json:"description" binding:"omitempty,max=2000"`
 	CoverArtURL       string   `json:"cover_art_url" binding:"omitempty,url"`
 	CollectionFormats []string `json:"collection_formats" binding:"omitempty,dive,oneof=physical digital rom"`
+	// Custom field values by field key; null clears a field
+	CustomFields map[string]interface{} `json:"custom_fields" binding:"omitempty,max=100"`
 }
 
 // UpdateGameRequest represents the request to update a game
@@ -96,24 +101,35 @@ type UpdateGameRequest struct {
 	PlatformID        uint     `json:"platform_id" binding:"omitempty,gt=0"`
 	Year              int      `json:"year" binding:"omitempty,gte=1970,lte=2030"`
 	Genre             string   `json:"genre" binding:"omitempty,max=100"`
+	Genres            []string `json:"genres" binding:"omitempty,max=20,dive,min=1,max=100"`
 	Rating            float32  `json:"rating" binding:"omitempty,gte=0,lte=10"`
 	Description       string   `json:"description" binding:"omitempty,max=2000"`
 	CoverArtURL       string   `json:"cover_art_url" binding:"omitempty,url"`
 	CollectionFormats []string `json:"collection_formats" binding:"omitempty,dive,oneof=physical digital rom"`
+	// Custom field values by field key; null clears a field
+	CustomFields map[string]interface{} `json:"custom_fields" binding:"omitempty,max=100"`
 }
 
 // CreatePlatformRequest represents the request to create a platform
 type CreatePlatformRequest struct {
-	Name         string `json:"name" binding:"required,min=1,max=100"`
-	Manufacturer string `json:"manufacturer" binding:"omitempty,max=100"`
-	ReleaseYear  int    `json:"release_year" binding:"omitempty,gte=1970,lte=2030"`
+	Name            string `json:"name" binding:"required,min=1,max=100"`
+	Manufacturer    string `json:"manufacturer" binding:"omitempty,max=100"`
+	ReleaseYear     int    `json:"release_year" binding:"omitempty,gte=1970,lte=2030"`
+	IGDBID          int    `json:"igdb_id" binding:"omitempty,gte=0"`
+	TheGamesDBID    int    `json:"thegamesdb_id" binding:"omitempty,gte=0"`
+	RAWGID          int    `json:"rawg_id" binding:"omitempty,gte=0"`
+	ScreenScraperID int    `json:"screenscraper_id" binding:"omitempty,gte=0"`
 }
 
 // UpdatePlatformRequest represents the request to update a platform
 type UpdatePlatformRequest struct {
-	Name         string `json:"name" binding:"omitempty,min=1,max=100"`
-	Manufacturer string `json:"manufacturer" binding:"omitempty,max=100"`
-	ReleaseYear  int    `json:"release_year" binding:"omitempty,gte=1970,lte=2030"`
+	Name            string `json:"name" binding:"omitempty,min=1,max=100"`
+	Manufacturer    string `json:"manufacturer" binding:"omitempty,max=100"`
+	ReleaseYear     int    `json:"release_year" binding:"omitempty,gte=1970,lte=2030"`
+	IGDBID          int    `json:"igdb_id" binding:"omitempty,gte=0"`
+	TheGamesDBID    int    `json:"thegamesdb_id" binding:"omitempty,gte=0"`
+	RAWGID          int    `json:"rawg_id" binding:"omitempty,gte=0"`
+	ScreenScraperID int    `json:"screenscraper_id" binding:"omitempty,gte=0"`
 }
 
 // CreateSessionRequest represents the request to create a play session
@@ -122,6 +138,7 @@ type CreateSessionRequest struct {
 	EndTime   string `json:"end_time" binding:"omitempty"`
 	Notes     string `json:"notes" binding:"omitempty,max=1000"`
 	Rating    int    `json:"rating" binding:"omitempty,gte=1,lte=10"`
+	Timezone  string `json:"timezone" binding:"omitempty"` // IANA name for times without an offset
 }
 
 // UpdateSessionRequest represents the request to update a play session
@@ -130,6 +147,160 @@ type UpdateSessionRequest struct {
 	EndTime   string `json:"end_time" binding:"omitempty"`
 	Notes     string `json:"notes" binding:"omitempty,max=1000"`
 	Rating    int    `json:"rating" binding:"omitempty,gte=1,lte=10"`
+	Timezone  string `json:"timezone" binding:"omitempty"` // IANA name for times without an offset
+}
+
+// StartTimerRequest represents the request to start the play session timer
+type StartTimerRequest struct {
+	GameID uint   `json:"game_id" binding:"required,gt=0"`
+	Notes  string `json:"notes" binding:"omitempty,max=1000"`
+}
+
+// StopTimerRequest represents the optional body when stopping the play session timer
+type StopTimerRequest struct {
+	Notes  string `json:"notes" binding:"omitempty,max=1000"`
+	Rating int    `json:"rating" binding:"omitempty,gte=1,lte=10"`
+}
+
+// CreateGoalRequest represents the request to create a goal
+type CreateGoalRequest struct {
+	Title      string  `json:"title" binding:"required,min=1,max=200"`
+	Kind       string  `json:"kind" binding:"required,oneof=finish_games play_time play_days"`
+	Target     float64 `json:"target" binding:"required,gt=0,lte=100000"`
+	Period     string  `json:"period" binding:"required,oneof=week month year custom"`
+	StartDate  string  `json:"start_date" binding:"omitempty,datetime=2006-01-02"`
+	EndDate    string  `json:"end_date" binding:"omitempty,datetime=2006-01-02"`
+	GameID     *uint   `json:"game_id" binding:"omitempty,gt=0"`
+	PlatformID *uint   `json:"platform_id" binding:"omitempty,gt=0"`
+}
+
+// UpdateGoalRequest represents the request to update a goal; a game_id or
+// platform_id of 0 removes that filter
+type UpdateGoalRequest struct {
+	Title      string  `json:"title" binding:"omitempty,min=1,max=200"`
+	Kind       string  `json:"kind" binding:"omitempty,oneof=finish_games play_time play_days"`
+	Target     float64 `json:"target" binding:"omitempty,gt=0,lte=100000"`
+	Period     string  `json:"period" binding:"omitempty,oneof=week month year custom"`
+	StartDate  *string `json:"start_date" binding:"omitempty"`
+	EndDate    *string `json:"end_date" binding:"omitempty"`
+	GameID     *uint   `json:"game_id" binding:"omitempty"`
+	PlatformID *uint   `json:"platform_id" binding:"omitempty"`
+	Archived   *bool   `json:"archived" binding:"omitempty"`
+}
+
+// CreateTagRequest represents the request to create a tag
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required,min=1,max=50"`
+	Color string `json:"color" binding:"omitempty"` // #rrggbb
+}
+
+// UpdateTagRequest represents the request to update a tag; an empty color clears it
+type UpdateTagRequest struct {
+	Name  string  `json:"name" binding:"omitempty,min=1,max=50"`
+	Color *string `json:"color" binding:"omitempty"`
+}
+
+// BulkTagRequest adds and removes tags, by name, on several games at once.
+// Tags added by name are created when missing.
+type BulkTagRequest struct {
+	GameIDs []uint   `json:"game_ids" binding:"required,min=1,max=1000,dive,gt=0"`
+	Add     []string `json:"add" binding:"omitempty,max=50,dive,min=1,max=50"`
+	Remove  []string `json:"remove" binding:"omitempty,max=50,dive,min=1,max=50"`
+}
+
+// SetGameTagsRequest replaces a game's tags; missing tags are created
+type SetGameTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,max=50,dive,min=1,max=50"` // empty removes every tag
+}
+
+// CreateCollectionRequest represents the request to create a collection
+type CreateCollectionRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description" binding:"omitempty,max=5000"`
+	CoverURL    string `json:"cover_url" binding:"omitempty,url"`
+	GameIDs     []uint `json:"game_ids" binding:"omitempty,dive,gt=0"` // initial games, in order
+	// Rules make a smart collection; they can't be combined with game_ids
+	Rules *models.CollectionRule `json:"rules"`
+}
+
+// UpdateCollectionRequest represents the request to update a collection
+type UpdateCollectionRequest struct {
+	Name        string  `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=5000"`
+	CoverURL    *string `json:"cover_url" binding:"omitempty"` // empty falls back to the first game's cover
+	// Rules replace a smart collection's rules, or turn a collection into a
+	// smart one, dropping its games; null turns it back into an empty list
+	Rules json.RawMessage `json:"rules"`
+}
+
+// PreviewCollectionRequest asks which games smart collection rules match
+type PreviewCollectionRequest struct {
+	Rules *models.CollectionRule `json:"rules" binding:"required"`
+	Limit int                    `json:"limit" binding:"omitempty,gte=1,lte=100"`
+}
+
+// CollectionGamesRequest lists games to add to a collection, or every game
+// of the collection in its new order
+type CollectionGamesRequest struct {
+	GameIDs []uint `json:"game_ids" binding:"required,min=1,dive,gt=0"`
+}
+
+// CreateCustomFieldRequest represents the request to define a custom field
+type CreateCustomFieldRequest struct {
+	Key     string   `json:"key" binding:"required,min=1,max=50"`
+	Name    string   `json:"name" binding:"required,min=1,max=100"`
+	Type    string   `json:"type" binding:"required,oneof=text number date boolean enum"`
+	Options []string `json:"options" binding:"omitempty,max=50,dive,min=1,max=100"` // enum fields only
+}
+
+// UpdateCustomFieldRequest represents the request to update a custom field.
+// The type can only change while no game has a value for the field.
+type UpdateCustomFieldRequest struct {
+	Key     string   `json:"key" binding:"omitempty,min=1,max=50"`
+	Name    string   `json:"name" binding:"omitempty,min=1,max=100"`
+	Type    string   `json:"type" binding:"omitempty,oneof=text number date boolean enum"`
+	Options []string `json:"options" binding:"omitempty,max=50,dive,min=1,max=100"` // replaces an enum's options
+}
+
+// CreatePhysicalCopyRequest represents the request to add a physical copy of a game
+type CreatePhysicalCopyRequest struct {
+	Region        string   `json:"region" binding:"omitempty,max=50"`
+	Edition       string   `json:"edition" binding:"omitempty,max=100"`
+	Condition     string   `json:"condition" binding:"omitempty,oneof=mint near_mint very_good good fair poor"`
+	Completeness  string   `json:"completeness" binding:"omitempty,oneof=loose box manual cib sealed"` // defaults to loose
+	Location      string   `json:"location" binding:"omitempty,max=100"`
+	PurchasePrice *float64 `json:"purchase_price" binding:"omitempty,gte=0"`
+	Currency      string   `json:"currency" binding:"omitempty,len=3,alpha"` // ISO 4217; defaults to CURRENCY with a price
+	PurchaseDate  string   `json:"purchase_date" binding:"omitempty,datetime=2006-01-02"`
+	PurchaseStore string   `json:"purchase_store" binding:"omitempty,max=100"`
+	Notes         string   `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// UpdatePhysicalCopyRequest represents the request to update a physical copy;
+// empty strings clear text fields and the purchase date
+type UpdatePhysicalCopyRequest struct {
+	Region        *string  `json:"region" binding:"omitempty,max=50"`
+	Edition       *string  `json:"edition" binding:"omitempty,max=100"`
+	Condition     *string  `json:"condition" binding:"omitempty,oneof='' mint near_mint very_good good fair poor"`
+	Completeness  string   `json:"completeness" binding:"omitempty,oneof=loose box manual cib sealed"`
+	Location      *string  `json:"location" binding:"omitempty,max=100"`
+	PurchasePrice *float64 `json:"purchase_price" binding:"omitempty,gte=0"`
+	Currency      *string  `json:"currency" binding:"omitempty,len=3,alpha"`
+	PurchaseDate  *string  `json:"purchase_date" binding:"omitempty"`
+	PurchaseStore *string  `json:"purchase_store" binding:"omitempty,max=100"`
+	Notes         *string  `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// ImportRetroArchRequest represents the request to import RetroArch runtime logs
+type ImportRetroArchRequest struct {
+	Directory string `json:"directory" binding:"omitempty"` // defaults to RETROARCH_DIR
+	Timezone  string `json:"timezone" binding:"omitempty"`  // IANA name the logs' local times are in
+}
+
+// ImportSteamRequest represents the request to import a Steam library from local files
+type ImportSteamRequest struct {
+	Directory string `json:"directory" binding:"omitempty"`       // defaults to STEAM_DIR
+	UserID    string `json:"user_id" binding:"omitempty,numeric"` // userdata account; defaults to STEAM_USER_ID, or all
 }
 
 // ScanDirectoryRequest represents the request to scan a directory
@@ -149,8 +320,9 @@ type CompletionStatusRequest struct {
 
 // SearchGamesRequest represents the request to search for games
 type SearchGamesRequest struct {
-	Title    string `json:"title" binding:"required,min=1,max=255"`
-	Platform string `json:"platform" binding:"omitempty,max=100"`
+	Title      string `json:"title" binding:"required,min=1,max=255"`
+	Platform   string `json:"platform" binding:"omitempty,max=100"`
+	PlatformID uint   `json:"platform_id" binding:"omitempty,gt=0"`
 }
 
 // ListGamesQuery represents query parameters for listing games
@@ -160,4 +332,16 @@ type ListGamesQuery struct {
 	Sort     string `form:"sort" binding:"omitempty,oneof=title year rating created_at"`
 	Platform uint   `form:"platform" binding:"omitempty,gt=0"`
 	Genre    string `form:"genre" binding:"omitempty,max=100"`
+}
+
+// GenreNames returns the genres given in a request, falling back to the single
+// legacy genre field. A nil result means genres weren't provided at all.
+func GenreNames(genres []string, genre string) []string {
+	if genres != nil {
+		return genres
+	}
+	if genre != "" {
+		return []string{genre}
+	}
+	return nil
 }
\ No newline at end of file
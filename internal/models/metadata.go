@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+)
+
+// Company is a developer or publisher, shared across games
+type Company struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GameCompany links a game to a company in a specific role
+type GameCompany struct {
+	ID        uint    `json:"id" gorm:"primaryKey"`
+	GameID    uint    `json:"game_id" gorm:"not null;uniqueIndex:idx_game_company_role"`
+	CompanyID uint    `json:"company_id" gorm:"not null;uniqueIndex:idx_game_company_role"`
+	Company   Company `json:"company" gorm:"foreignKey:CompanyID"`
+	Role      string  `json:"role" gorm:"not null;uniqueIndex:idx_game_company_role"` // developer, publisher, porting, supporting
+}
+
+// Franchise groups related games; IGDB franchises and collections (series) are both stored here
+type Franchise struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GameMode is a play mode such as "Single player" or "Co-operative"
+type GameMode struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"uniqueIndex;not null"`
+}
+
+// Theme is a thematic classification such as "Fantasy" or "Horror"
+type Theme struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"uniqueIndex;not null"`
+}
+
+// PlayerPerspective is a camera perspective such as "Side view" or "First person"
+type PlayerPerspective struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"uniqueIndex;not null"`
+}
+
+// GameMedia is a screenshot, artwork, wheel logo or video reference from a metadata provider
+type GameMedia struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	GameID    uint      `json:"game_id" gorm:"index;not null"`
+	Kind      string    `json:"kind" gorm:"not null"` // screenshot, artwork, wheel, video
+	URL       string    `json:"url" gorm:"not null"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlternativeName is another title a game is known by (regional names, abbreviations)
+type AlternativeName struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	GameID  uint   `json:"game_id" gorm:"index;not null"`
+	Name    string `json:"name" gorm:"not null"`
+	Comment string `json:"comment"`
+}
+
+// ReleaseDate is a game's release in one region, e.g. the Japanese or PAL launch
+type ReleaseDate struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	GameID         uint       `json:"game_id" gorm:"index;not null"`
+	Region         string     `json:"region" gorm:"index;not null"` // europe, north_america, japan, worldwide, ...
+	Date           *time.Time `json:"date" gorm:"index"`            // nil when only announced (TBD)
+	Human          string     `json:"human"`                         // provider's display form, e.g. "Q4 2024"
+	PlatformIGDBID int        `json:"platform_igdb_id"`
+}
+
+// AgeRating is a classification by a rating board (ESRB, PEGI, CERO)
+type AgeRating struct {
+	ID         uint   `json:"id" gorm:"primaryKey"`
+	GameID     uint   `json:"game_id" gorm:"index;not null"`
+	Board      string `json:"board" gorm:"not null;index:idx_age_rating_board_rating"`
+	Rating     string `json:"rating" gorm:"not null;index:idx_age_rating_board_rating"` // e.g. "E10+", "12", "B"
+	MinimumAge int    `json:"minimum_age"`
+}
+
+// TimeToBeat holds completion time estimates in minutes; 0 means no estimate
+type TimeToBeat struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	GameID            uint      `json:"game_id" gorm:"uniqueIndex;not null"`
+	HastilyMinutes    int       `json:"hastily_minutes"`    // main story, rushed
+	NormallyMinutes   int       `json:"normally_minutes"`   // main story plus some extras
+	CompletelyMinutes int       `json:"completely_minutes"` // completionist
+	SubmissionCount   int       `json:"submission_count"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 	"gorm.io/gorm"
 )
@@ -36,11 +38,42 @@ func (cf CollectionFormats) Value() (driver.Value, error) {
 	return json.Marshal(cf)
 }
 
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+// Scan implements the sql.Scanner interface for database reads
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	*l = nil
+	return nil
+}
+
+// Value implements the driver.Valuer interface for database writes
+func (l StringList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
 type Platform struct {
 	ID           uint   `json:"id" gorm:"primaryKey"`
 	Name         string `json:"name" gorm:"unique;not null"`
 	Manufacturer string `json:"manufacturer"`
 	ReleaseYear  int    `json:"release_year"`
+	
+	// External metadata provider IDs (0 = not linked)
+	IGDBID          int `json:"igdb_id" gorm:"index"`
+	TheGamesDBID    int `json:"thegamesdb_id"`
+	RAWGID          int `json:"rawg_id"`
+	ScreenScraperID int `json:"screenscraper_id"`
+	
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -51,20 +84,24 @@ type Game struct {
 	PlatformID  uint      `json:"platform_id"`
 	Platform    Platform  `json:"platform" gorm:"foreignKey:PlatformID"`
 	Year        int       `json:"year"`
-	Genre       string    `json:"genre"`
+	Genre       string    `json:"genre"` // primary genre, kept in sync with the first entry of Genres
+	Genres      []Genre   `json:"genres" gorm:"many2many:game_genres"`
+	Tags        []Tag     `json:"tags" gorm:"many2many:game_tags"`
 	Rating      float32   `json:"rating"`
 	Description string    `json:"description" gorm:"type:text"`
 	CoverArtURL string    `json:"cover_art_url"`
 	BoxArtURL   string    `json:"box_art_url"`
 	PurchaseDate *time.Time `json:"purchase_date"`
 	IGDBID      int       `json:"igdb_id"`
+	ScreenScraperID int   `json:"screenscraper_id"`
+	SteamAppID  int       `json:"steam_app_id" gorm:"index"`
 	
 	// Collection formats (physical, digital, rom)
 	CollectionFormats CollectionFormats `json:"collection_formats" gorm:"type:json"`
 	
 	// Completion tracking
 	CompletionStatus     string     `json:"completion_status" gorm:"default:not_started"`
-	CompletionDate       *time.Time `json:"completion_date"`
+	CompletionDate       *time.Time `json:"completion_date"` // when it was completed or abandoned
 	CompletionPercentage int        `json:"completion_percentage" gorm:"default:0"`
 	CompletionNotes      string     `json:"completion_notes" gorm:"type:text"`
 	
@@ -72,8 +109,41 @@ type Game struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 	
 	// Relationships
-	FileLocations []FileLocation `json:"file_locations" gorm:"foreignKey:GameID"`
-	PlaySessions  []PlaySession  `json:"play_sessions" gorm:"foreignKey:GameID"`
+	FileLocations  []FileLocation `json:"file_locations" gorm:"foreignKey:GameID"`
+	PlaySessions   []PlaySession  `json:"play_sessions" gorm:"foreignKey:GameID"`
+	Images         []GameImage    `json:"images,omitempty" gorm:"foreignKey:GameID"`
+	PhysicalCopies []PhysicalCopy `json:"physical_copies,omitempty" gorm:"foreignKey:GameID"`
+	
+	// Extended metadata
+	Companies          []GameCompany       `json:"companies,omitempty" gorm:"foreignKey:GameID"`
+	Franchises         []Franchise         `json:"franchises,omitempty" gorm:"many2many:game_franchises"`
+	GameModes          []GameMode          `json:"game_modes,omitempty" gorm:"many2many:game_game_modes"`
+	Themes             []Theme             `json:"themes,omitempty" gorm:"many2many:game_themes"`
+	PlayerPerspectives []PlayerPerspective `json:"player_perspectives,omitempty" gorm:"many2many:game_player_perspectives"`
+	Media              []GameMedia         `json:"media,omitempty" gorm:"foreignKey:GameID"`
+	AlternativeNames   []AlternativeName   `json:"alternative_names,omitempty" gorm:"foreignKey:GameID"`
+	ReleaseDates       []ReleaseDate       `json:"release_dates,omitempty" gorm:"foreignKey:GameID"`
+	AgeRatings         []AgeRating         `json:"age_ratings,omitempty" gorm:"foreignKey:GameID"`
+	TimeToBeat         *TimeToBeat         `json:"time_to_beat,omitempty" gorm:"foreignKey:GameID"`
+	
+	// User-defined custom fields; CustomFields is filled from CustomValues,
+	// when preloaded with their Field, keyed by field key
+	CustomValues []GameCustomValue      `json:"-" gorm:"foreignKey:GameID"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty" gorm:"-"`
+}
+
+// AfterFind keys preloaded custom field values by field
+func (g *Game) AfterFind(tx *gorm.DB) error {
+	if len(g.CustomValues) == 0 {
+		return nil
+	}
+	g.CustomFields = make(map[string]interface{}, len(g.CustomValues))
+	for _, value := range g.CustomValues {
+		if value.Field.Key != "" {
+			g.CustomFields[value.Field.Key] = value.Typed()
+		}
+	}
+	return nil
 }
 
 type FileLocation struct {
@@ -83,7 +153,9 @@ type FileLocation struct {
 	ServerLocation string `json:"server_location"`
 	FilePath       string `json:"file_path" gorm:"not null"`
 	FileSize       int64  `json:"file_size"`
-	FileHash       string `json:"file_hash"`
+	FileHash       string `json:"file_hash"` // MD5
+	FileCRC        string `json:"file_crc"`
+	FileSHA1       string `json:"file_sha1"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -94,13 +166,60 @@ type PlaySession struct {
 	Game      Game       `json:"game" gorm:"foreignKey:GameID"`
 	StartTime time.Time  `json:"start_time" gorm:"not null"`
 	EndTime   *time.Time `json:"end_time"`
-	Duration  int        `json:"duration"` // in minutes
+	Duration  int        `json:"duration"` // in minutes, excluding paused time
 	Notes     string     `json:"notes" gorm:"type:text"`
 	Rating    int        `json:"rating"` // 1-10 scale
+	
+	// Timer state
+	PausedAt      *time.Time `json:"paused_at"`      // set while the session is paused
+	PausedSeconds int        `json:"paused_seconds"` // total time spent paused
+	AutoEnded     bool       `json:"auto_ended"`     // closed by the auto-end policy
+	
+	// Source is empty for sessions logged in Pelico, or the importer that created it
+	Source string `json:"source,omitempty" gorm:"index"`
+	
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// ImportedPlaytime is the total play time last imported for one item from an
+// external source (a RetroArch runtime log, a Steam app). Re-imports only add
+// sessions for time played since.
+type ImportedPlaytime struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	GameID       uint       `json:"game_id" gorm:"index"`
+	Source       string     `json:"source" gorm:"not null;uniqueIndex:idx_imported_playtime_source_key"`
+	ExternalKey  string     `json:"external_key" gorm:"not null;uniqueIndex:idx_imported_playtime_source_key"`
+	TotalSeconds int64      `json:"total_seconds"`
+	LastPlayed   *time.Time `json:"last_played"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Goal is a target for finishing games, play time or days played, measured
+// over each week, month or year, or once over a custom date range
+type Goal struct {
+	ID     uint    `json:"id" gorm:"primaryKey"`
+	Title  string  `json:"title" gorm:"not null"`
+	Kind   string  `json:"kind" gorm:"not null"`   // finish_games, play_time, play_days
+	Target float64 `json:"target"`                 // games, hours or days, depending on Kind
+	Period string  `json:"period" gorm:"not null"` // week, month, year, custom
+	
+	// Inclusive YYYY-MM-DD dates, only for custom periods
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	
+	// Optional filters: count only one game or platform
+	GameID     *uint     `json:"game_id" gorm:"index"`
+	Game       *Game     `json:"game,omitempty" gorm:"foreignKey:GameID"`
+	PlatformID *uint     `json:"platform_id"`
+	Platform   *Platform `json:"platform,omitempty" gorm:"foreignKey:PlatformID"`
+	
+	Archived  bool      `json:"archived" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 type Wishlist struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	GameID    uint      `json:"game_id"`
@@ -115,6 +234,259 @@ type Shortlist struct {
 	AddedAt   time.Time `json:"added_at"`
 }
 
+// Tag is a free-form label on games, e.g. "co-op" or "comfort game"
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	Color     string    `json:"color"` // #rrggbb; empty leaves it to the UI
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Collection is a named, manually ordered list of games
+type Collection struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	Name        string           `json:"name" gorm:"uniqueIndex;not null"`
+	Description string           `json:"description" gorm:"type:text"`
+	CoverURL    string           `json:"cover_url"` // empty uses the first game's cover art
+	Items       []CollectionItem `json:"items,omitempty" gorm:"foreignKey:CollectionID"`
+	
+	// Smart collections have rules instead of items; their games are the ones
+	// matching the rules when read
+	Rules     *CollectionRule `json:"rules,omitempty" gorm:"type:json"`
+	Games     []Game          `json:"games,omitempty" gorm:"-"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// CollectionRule is a smart collection filter: either a group joining nested
+// rules with all (AND) or any (OR), or a condition comparing a game field
+type CollectionRule struct {
+	Match   string           `json:"match,omitempty"` // all or any; set on groups only
+	Rules   []CollectionRule `json:"rules,omitempty"`
+	Field   string           `json:"field,omitempty"`
+	Op      string           `json:"op,omitempty"`
+	Operand interface{}      `json:"value,omitempty"` // string, number or a list of them
+}
+
+// Scan implements the sql.Scanner interface for database reads
+func (r *CollectionRule) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return fmt.Errorf("cannot scan %T into a collection rule", value)
+}
+
+// Value implements the driver.Valuer interface for database writes
+func (r CollectionRule) Value() (driver.Value, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// CollectionItem places a game at a position in a collection
+type CollectionItem struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	CollectionID uint      `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_game"`
+	GameID       uint      `json:"game_id" gorm:"not null;uniqueIndex:idx_collection_game;index"`
+	Game         *Game     `json:"game,omitempty" gorm:"foreignKey:GameID"`
+	Position     int       `json:"position"` // 0-based
+	AddedAt      time.Time `json:"added_at" gorm:"autoCreateTime"`
+}
+
+// PhysicalCopy is one owned physical copy of a game; a game can have several,
+// e.g. a loose cartridge and a sealed collector's edition
+type PhysicalCopy struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	GameID    uint   `json:"game_id" gorm:"not null;index"`
+	Game      *Game  `json:"game,omitempty" gorm:"foreignKey:GameID"`
+	Region    string `json:"region"`    // release region, e.g. japan, europe, north_america
+	Edition   string `json:"edition"`   // e.g. Collector's Edition, Player's Choice
+	Condition string `json:"condition"` // mint, near_mint, very_good, good, fair, poor
+	Location  string `json:"location"`  // where it's kept, e.g. "Shelf B3" or "Tote 2"
+	
+	// What the copy includes: loose (cart/disc only), box, manual, cib or sealed
+	Completeness string `json:"completeness" gorm:"not null;default:loose"`
+	
+	PurchasePrice *float64   `json:"purchase_price"`
+	Currency      string     `json:"currency"` // ISO 4217 code of the purchase price
+	PurchaseDate  *time.Time `json:"purchase_date"`
+	PurchaseStore string     `json:"purchase_store"`
+	Notes         string     `json:"notes" gorm:"type:text"`
+	
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PricePoint is a game's market price on a day for one grade of copy,
+// imported from price guide exports such as PriceCharting's
+type PricePoint struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	GameID    uint      `json:"game_id" gorm:"not null;uniqueIndex:idx_price_point"`
+	Grade     string    `json:"grade" gorm:"not null;uniqueIndex:idx_price_point"` // loose, cib or new
+	Date      time.Time `json:"date" gorm:"not null;uniqueIndex:idx_price_point"`
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency" gorm:"not null;uniqueIndex:idx_price_point"`
+	Source    string    `json:"source"` // e.g. pricecharting
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CustomField defines a user-defined game attribute, e.g. the controller used
+// or a boxed copy's condition
+type CustomField struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Key       string     `json:"key" gorm:"uniqueIndex;not null"` // in game JSON and filters, e.g. emulator_core
+	Name      string     `json:"name" gorm:"not null"`
+	Type      string     `json:"type" gorm:"not null"` // text, number, date, boolean or enum
+	Options   StringList `json:"options" gorm:"type:json"` // the values an enum field allows
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// GameCustomValue is a game's value for a custom field, kept in the column
+// for the field's type so it can be filtered and sorted on
+type GameCustomValue struct {
+	ID          uint        `json:"id" gorm:"primaryKey"`
+	GameID      uint        `json:"game_id" gorm:"not null;uniqueIndex:idx_game_custom_field"`
+	FieldID     uint        `json:"field_id" gorm:"not null;uniqueIndex:idx_game_custom_field;index"`
+	Field       CustomField `json:"-" gorm:"foreignKey:FieldID"`
+	TextValue   *string     `json:"text_value,omitempty"` // text and enum fields
+	NumberValue *float64    `json:"number_value,omitempty"`
+	DateValue   *string     `json:"date_value,omitempty"` // YYYY-MM-DD
+	BoolValue   *bool       `json:"bool_value,omitempty"`
+}
+
+// Typed returns the value as its field type's JSON value
+func (v GameCustomValue) Typed() interface{} {
+	switch {
+	case v.NumberValue != nil:
+		return *v.NumberValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.DateValue != nil:
+		return *v.DateValue
+	case v.TextValue != nil:
+		return *v.TextValue
+	}
+	return nil
+}
+
+// Genre is a game genre shared across the collection
+type Genre struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ThumbnailSizes lists the generated thumbnail variants and their maximum width in pixels
+var ThumbnailSizes = map[string]int{
+	"small":  128,
+	"medium": 264,
+	"large":  512,
+}
+
+// GameImage is a locally stored copy of a game's artwork, addressed by content hash
+type GameImage struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	GameID      uint      `json:"game_id" gorm:"index;not null"`
+	Kind        string    `json:"kind" gorm:"not null"` // cover, box_art
+	Hash        string    `json:"hash" gorm:"not null;index"`
+	SourceURL   string    `json:"source_url"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	FileSize    int64     `json:"file_size"`
+	Custom      bool      `json:"custom" gorm:"default:false"` // user upload, never replaced by metadata
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	
+	// URLs maps "original" and each thumbnail size to the local serving path
+	URLs map[string]string `json:"urls" gorm:"-"`
+}
+
+// AfterFind fills in the serving URLs for the stored image
+func (i *GameImage) AfterFind(tx *gorm.DB) error {
+	i.SetURLs()
+	return nil
+}
+
+// SetURLs populates URLs from the content hash
+func (i *GameImage) SetURLs() {
+	i.URLs = map[string]string{
+		"original": fmt.Sprintf("/api/v1/images/%s", i.Hash),
+	}
+	for size := range ThumbnailSizes {
+		i.URLs[size] = fmt.Sprintf("/api/v1/images/%s/%s", i.Hash, size)
+	}
+}
+
+// MetadataCacheEntry stores a raw metadata provider response so repeated lookups
+// don't hit the external API again until ExpiresAt
+type MetadataCacheEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_metadata_cache_key"`
+	Query     string    `json:"query" gorm:"not null;uniqueIndex:idx_metadata_cache_key"`
+	Platform  string    `json:"platform" gorm:"not null;default:'';uniqueIndex:idx_metadata_cache_key"`
+	Response  string    `json:"response" gorm:"type:text"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// All lists every persisted model. The schema itself comes from the SQL
+// migrations in internal/database; their tests check it covers these models.
+func All() []interface{} {
+	return []interface{}{&Platform{}, &Game{}, &FileLocation{}, &PlaySession{}, &Wishlist{}, &Shortlist{}, &MetadataCacheEntry{}, &GameImage{},
+		&Company{}, &GameCompany{}, &Franchise{}, &GameMode{}, &Theme{}, &PlayerPerspective{}, &GameMedia{}, &AlternativeName{},
+		&Genre{}, &ReleaseDate{}, &AgeRating{}, &TimeToBeat{}, &ImportedPlaytime{}, &Goal{},
+		&Tag{}, &Collection{}, &CollectionItem{}, &CustomField{}, &GameCustomValue{}, &PhysicalCopy{},
+		&PricePoint{}}
+}
+
+// AutoMigrate creates the schema straight from the models, for tests that
+// don't need the versioned migrations
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Platform{}, &Game{}, &FileLocation{}, &PlaySession{}, &Wishlist{}, &Shortlist{})
+	if err := db.AutoMigrate(All()...); err != nil {
+		return err
+	}
+	return MigrateLegacyGenres(db)
+}
+
+// MigrateLegacyGenres links games that only have the legacy single genre string
+// to Genre rows. Comma-separated values become multiple genres. Safe to re-run.
+func MigrateLegacyGenres(db *gorm.DB) error {
+	var games []Game
+	err := db.Where("genre IS NOT NULL AND genre != ''").
+		Where("id NOT IN (SELECT game_id FROM game_genres)").
+		Find(&games).Error
+	if err != nil {
+		return err
+	}
+	
+	for _, game := range games {
+		var genres []Genre
+		for _, name := range strings.Split(game.Genre, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			var genre Genre
+			if err := db.Where("name = ?", name).Attrs(Genre{Name: name}).FirstOrCreate(&genre).Error; err != nil {
+				return err
+			}
+			genres = append(genres, genre)
+		}
+		if len(genres) == 0 {
+			continue
+		}
+		if err := db.Model(&Game{ID: game.ID}).Association("Genres").Append(genres); err != nil {
+			return err
+		}
+	}
+	return nil
 }
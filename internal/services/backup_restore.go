@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Backup format versions. 1.0 holds platforms, games, sessions and file
+// locations only; 2.0 adds everything else hanging off games.
+const (
+	BackupVersionLegacy = "1.0"
+	BackupVersion       = "2.0"
+)
+
+var ErrUnsupportedBackupVersion = errors.New("unsupported backup version")
+
+// BackupLink is a row of a many-to-many table between games and a lookup
+// table such as genres or tags
+type BackupLink struct {
+	GameID uint `json:"game_id"`
+	ID     uint `json:"id"`
+}
+
+// backupLinkTables are the game many-to-many tables and their lookup column
+var backupLinkTables = []struct{ table, column string }{
+	{"game_genres", "genre_id"},
+	{"game_tags", "tag_id"},
+	{"game_franchises", "franchise_id"},
+	{"game_game_modes", "game_mode_id"},
+	{"game_themes", "theme_id"},
+	{"game_player_perspectives", "player_perspective_id"},
+}
+
+// backupGameTables hold rows owned by games. A restore clears them before
+// deleting games rather than relying on ON DELETE CASCADE, which SQLite only
+// applies with foreign keys enabled.
+var backupGameTables = []string{
+	"play_sessions", "file_locations", "game_genres", "game_tags", "game_franchises",
+	"game_game_modes", "game_themes", "game_player_perspectives", "game_companies",
+	"game_media", "alternative_names", "release_dates", "age_ratings", "time_to_beats",
+	"game_images", "collection_items", "game_custom_values", "physical_copies",
+	"price_points", "wishlists", "shortlists", "imported_playtimes",
+}
+
+// backupLookupTables are only replaced by 2.0 backups, in deletion order
+var backupLookupTables = []string{
+	"goals", "collections", "custom_fields", "tags", "genres", "companies",
+	"franchises", "game_modes", "themes", "player_perspectives",
+}
+
+// backupRows lists the 2.0 additions of backup, in restore order
+func backupRows(backup *BackupData) []struct {
+	name string
+	rows interface{}
+} {
+	return []struct {
+		name string
+		rows interface{}
+	}{
+		{"genres", &backup.Genres},
+		{"tags", &backup.Tags},
+		{"companies", &backup.Companies},
+		{"franchises", &backup.Franchises},
+		{"game modes", &backup.GameModes},
+		{"themes", &backup.Themes},
+		{"player perspectives", &backup.PlayerPerspectives},
+		{"game companies", &backup.GameCompanies},
+		{"media", &backup.Media},
+		{"alternative names", &backup.AlternativeNames},
+		{"release dates", &backup.ReleaseDates},
+		{"age ratings", &backup.AgeRatings},
+		{"time to beat", &backup.TimesToBeat},
+		{"images", &backup.Images},
+		{"collections", &backup.Collections},
+		{"collection items", &backup.CollectionItems},
+		{"custom fields", &backup.CustomFields},
+		{"custom values", &backup.CustomValues},
+		{"physical copies", &backup.PhysicalCopies},
+		{"price points", &backup.PricePoints},
+		{"goals", &backup.Goals},
+		{"wishlist", &backup.Wishlist},
+		{"shortlist", &backup.Shortlist},
+		{"imported playtime", &backup.ImportedPlaytime},
+	}
+}
+
+// exportBackupTables fills the 2.0 additions of backup from db
+func exportBackupTables(db *gorm.DB, backup *BackupData) error {
+	for _, export := range backupRows(backup) {
+		if err := db.Order("id").Find(export.rows).Error; err != nil {
+			return fmt.Errorf("failed to export %s: %w", export.name, err)
+		}
+	}
+
+	backup.GameLinks = make(map[string][]BackupLink, len(backupLinkTables))
+	for _, link := range backupLinkTables {
+		var links []BackupLink
+		err := db.Table(link.table).Select("game_id, " + link.column + " AS id").Order("game_id").Scan(&links).Error
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", link.table, err)
+		}
+		backup.GameLinks[link.table] = links
+	}
+	return nil
+}
+
+// RestoreBackup replaces the library with the backup's contents in one
+// transaction. Rows keep their IDs. A 1.0 backup only carries platforms, games,
+// sessions and file locations: tags, collections, custom fields and goals are
+// kept, goals tied to a game or platform missing from the backup are removed,
+// and genres are relinked from each game's genre column.
+func RestoreBackup(db *gorm.DB, backup *BackupData) error {
+	if backup.Version != BackupVersion && backup.Version != BackupVersionLegacy {
+		return fmt.Errorf("%w: %s", ErrUnsupportedBackupVersion, backup.Version)
+	}
+	legacy := backup.Version == BackupVersionLegacy
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// A 1.0 backup has no goals to replace the existing ones with. Detach
+		// them first: the platform foreign key has no ON DELETE.
+		var goals []models.Goal
+		if legacy {
+			if err := tx.Where("game_id IS NOT NULL OR platform_id IS NOT NULL").Find(&goals).Error; err != nil {
+				return fmt.Errorf("failed to load goals: %w", err)
+			}
+			err := tx.Model(&models.Goal{}).Where("game_id IS NOT NULL OR platform_id IS NOT NULL").
+				Updates(map[string]interface{}{"game_id": nil, "platform_id": nil}).Error
+			if err != nil {
+				return fmt.Errorf("failed to detach goals: %w", err)
+			}
+		}
+
+		tables := append([]string{}, backupGameTables...)
+		if !legacy {
+			tables = append(tables, backupLookupTables...)
+		}
+		tables = append(tables, "games", "platforms")
+		for _, table := range tables {
+			if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+				return fmt.Errorf("failed to clear %s: %w", table, err)
+			}
+		}
+
+		if err := restoreRows(tx, "platforms", &backup.Platforms); err != nil {
+			return err
+		}
+		if err := restoreRows(tx, "games", &backup.Games); err != nil {
+			return err
+		}
+		if err := restoreRows(tx, "file locations", &backup.FileLocations); err != nil {
+			return err
+		}
+		if err := restoreRows(tx, "sessions", &backup.Sessions); err != nil {
+			return err
+		}
+
+		if legacy {
+			if err := reattachGoals(tx, backup, goals); err != nil {
+				return err
+			}
+			if err := models.MigrateLegacyGenres(tx); err != nil {
+				return fmt.Errorf("failed to link genres: %w", err)
+			}
+			return resetSequences(tx, tables)
+		}
+
+		for _, restore := range backupRows(backup) {
+			if err := restoreRows(tx, restore.name, restore.rows); err != nil {
+				return err
+			}
+		}
+
+		for _, link := range backupLinkTables {
+			rows := make([]map[string]interface{}, len(backup.GameLinks[link.table]))
+			for i, row := range backup.GameLinks[link.table] {
+				rows[i] = map[string]interface{}{"game_id": row.GameID, link.column: row.ID}
+			}
+			if len(rows) == 0 {
+				continue
+			}
+			if err := tx.Table(link.table).Create(&rows).Error; err != nil {
+				return fmt.Errorf("failed to restore %s: %w", link.table, err)
+			}
+		}
+		return resetSequences(tx, tables)
+	})
+}
+
+// restoreRows inserts rows, a pointer to a slice of models, as they are and
+// without their associations
+func restoreRows(tx *gorm.DB, name string, rows interface{}) error {
+	if reflect.ValueOf(rows).Elem().Len() == 0 {
+		return nil
+	}
+	if err := tx.Omit(clause.Associations).CreateInBatches(rows, 100).Error; err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	return nil
+}
+
+// reattachGoals points goals detached for a 1.0 restore back at their game and
+// platform, removing those whose game or platform the backup doesn't have
+func reattachGoals(tx *gorm.DB, backup *BackupData, goals []models.Goal) error {
+	games := make(map[uint]bool, len(backup.Games))
+	for _, game := range backup.Games {
+		games[game.ID] = true
+	}
+	platforms := make(map[uint]bool, len(backup.Platforms))
+	for _, platform := range backup.Platforms {
+		platforms[platform.ID] = true
+	}
+
+	for _, goal := range goals {
+		if (goal.GameID != nil && !games[*goal.GameID]) || (goal.PlatformID != nil && !platforms[*goal.PlatformID]) {
+			if err := tx.Delete(&models.Goal{}, goal.ID).Error; err != nil {
+				return fmt.Errorf("failed to remove goal %d: %w", goal.ID, err)
+			}
+			continue
+		}
+		err := tx.Model(&models.Goal{ID: goal.ID}).
+			Updates(map[string]interface{}{"game_id": goal.GameID, "platform_id": goal.PlatformID}).Error
+		if err != nil {
+			return fmt.Errorf("failed to reattach goal %d: %w", goal.ID, err)
+		}
+	}
+	return nil
+}
+
+// resetSequences moves PostgreSQL id sequences past the restored IDs, which
+// were inserted explicitly; SQLite tracks them by itself
+func resetSequences(tx *gorm.DB, tables []string) error {
+	if !IsPostgres(tx) {
+		return nil
+	}
+	for _, table := range tables {
+		if !tx.Migrator().HasColumn(table, "id") {
+			continue
+		}
+		err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "+table, table).Error
+		if err != nil {
+			return fmt.Errorf("failed to reset the %s id sequence: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Smart collection rules are stored as a tree of models.CollectionRule and
+// turned into a WHERE condition on games each time the collection is read,
+// so the collection follows the library as it changes. A rule is either a
+// group, {"match": "all"|"any", "rules": [...]}, or a condition,
+// {"field": "rating", "op": "gte", "value": 8}.
+
+// Rule groups
+const (
+	RuleMatchAll = "all"
+	RuleMatchAny = "any"
+)
+
+var ErrInvalidCollectionRule = errors.New("invalid collection rule")
+
+// Limits on rule trees, so a stored collection can't grow an unbounded query
+const (
+	maxRuleDepth      = 5
+	maxRuleConditions = 50
+)
+
+// ruleFieldKind groups fields by the operators they accept
+type ruleFieldKind int
+
+const (
+	ruleText ruleFieldKind = iota
+	ruleNumber
+	ruleDate
+	ruleChoice // one value per game, matched against a list
+	ruleSet    // many values per game, e.g. genres
+)
+
+// ruleField is a game field rules can compare. For ruleChoice and ruleSet
+// fields, sql selects the matching game IDs given a lowercased name list.
+type ruleField struct {
+	kind   ruleFieldKind
+	column string
+	sql    string
+}
+
+// collectionRuleFields are the fields GetGames filters on, plus tags, play
+// time and dates
+var collectionRuleFields = map[string]ruleField{
+	"title":                 {kind: ruleText, column: "games.title"},
+	"platform":              {kind: ruleChoice, sql: "SELECT games.id FROM games JOIN platforms ON platforms.id = games.platform_id WHERE LOWER(platforms.name) IN ? OR CAST(platforms.id AS TEXT) IN ?"},
+	"completion_status":     {kind: ruleChoice, column: "games.completion_status"},
+	"collection_format":     {kind: ruleSet, column: "games.collection_formats"},
+	"genre":                 {kind: ruleSet, sql: nameSubquery("game_genres", "genres", "genre_id")},
+	"tag":                   {kind: ruleSet, sql: nameSubquery("game_tags", "tags", "tag_id")},
+	"developer":             {kind: ruleSet, sql: companySubquery("developer")},
+	"publisher":             {kind: ruleSet, sql: companySubquery("publisher")},
+	"franchise":             {kind: ruleSet, sql: nameSubquery("game_franchises", "franchises", "franchise_id")},
+	"year":                  {kind: ruleNumber, column: "games.year"},
+	"rating":                {kind: ruleNumber, column: "games.rating"},
+	"completion_percentage": {kind: ruleNumber, column: "games.completion_percentage"},
+	"playtime_hours":        {kind: ruleNumber, column: "(SELECT COALESCE(SUM(duration), 0) / 60.0 FROM play_sessions WHERE play_sessions.game_id = games.id)"},
+	"session_count":         {kind: ruleNumber, column: "(SELECT COUNT(*) FROM play_sessions WHERE play_sessions.game_id = games.id)"},
+	"created_at":            {kind: ruleDate, column: "games.created_at"},
+	"purchase_date":         {kind: ruleDate, column: "games.purchase_date"},
+	"completion_date":       {kind: ruleDate, column: "games.completion_date"},
+	"last_played":           {kind: ruleDate, column: "(SELECT MAX(start_time) FROM play_sessions WHERE play_sessions.game_id = games.id)"},
+	"release_date":          {kind: ruleDate, column: "(SELECT MIN(date) FROM release_dates WHERE release_dates.game_id = games.id)"},
+}
+
+// ruleOps lists the operators each kind of field accepts
+var ruleOps = map[ruleFieldKind][]string{
+	ruleText:   {"eq", "neq", "contains", "not_contains"},
+	ruleNumber: {"eq", "neq", "gt", "gte", "lt", "lte", "between"},
+	ruleDate:   {"before", "after", "between", "within_days", "is_set", "not_set"},
+	ruleChoice: {"eq", "neq", "in", "not_in"},
+	ruleSet:    {"has_any", "has_all", "has_none"},
+}
+
+// completionStatuses are the values completion_status rules accept; backlog
+// stands for not_started and in_progress, as in GetGames
+var completionStatuses = map[string][]string{
+	"not_started": {"not_started"},
+	"in_progress": {"in_progress"},
+	"completed":   {"completed"},
+	"abandoned":   {"abandoned"},
+	"100_percent": {"100_percent"},
+	"backlog":     {"not_started", "in_progress"},
+}
+
+func nameSubquery(joinTable, table, key string) string {
+	return "SELECT " + joinTable + ".game_id FROM " + joinTable +
+		" JOIN " + table + " ON " + table + ".id = " + joinTable + "." + key +
+		" WHERE LOWER(" + table + ".name) IN ?"
+}
+
+func companySubquery(role string) string {
+	return "SELECT game_companies.game_id FROM game_companies" +
+		" JOIN companies ON companies.id = game_companies.company_id" +
+		" WHERE game_companies.role = '" + role + "' AND LOWER(companies.name) IN ?"
+}
+
+// ValidateCollectionRule checks a rule tree. Errors name the offending rule
+// by its path, e.g. rules[1].rules[0].
+func ValidateCollectionRule(db *gorm.DB, rule *models.CollectionRule) error {
+	_, err := CollectionRuleCondition(db, rule, time.Local, time.Now())
+	return err
+}
+
+// CollectionRuleCondition turns a rule tree into a condition on games. Dates
+// in rules are days in loc; within_days counts back from now.
+func CollectionRuleCondition(db *gorm.DB, rule *models.CollectionRule, loc *time.Location, now time.Time) (clause.Expression, error) {
+	if rule == nil {
+		return nil, fmt.Errorf("%w: rules are empty", ErrInvalidCollectionRule)
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	b := &ruleBuilder{db: db, loc: loc, now: now}
+	if rule.Match == "" && rule.Field != "" {
+		// A lone condition is a group of one
+		rule = &models.CollectionRule{Match: RuleMatchAll, Rules: []models.CollectionRule{*rule}}
+	}
+	return b.build(rule, "rules", 1)
+}
+
+type ruleBuilder struct {
+	db         *gorm.DB
+	loc        *time.Location
+	now        time.Time
+	conditions int
+}
+
+func (b *ruleBuilder) build(rule *models.CollectionRule, path string, depth int) (clause.Expression, error) {
+	if rule.Match == "" {
+		return b.condition(rule, path)
+	}
+
+	if rule.Field != "" || rule.Op != "" || rule.Operand != nil {
+		return nil, fmt.Errorf("%w: %s: a group has match and rules, not field, op or value", ErrInvalidCollectionRule, path)
+	}
+	if rule.Match != RuleMatchAll && rule.Match != RuleMatchAny {
+		return nil, fmt.Errorf("%w: %s: match must be all or any, not %q", ErrInvalidCollectionRule, path, rule.Match)
+	}
+	if depth > maxRuleDepth {
+		return nil, fmt.Errorf("%w: %s: groups nest at most %d deep", ErrInvalidCollectionRule, path, maxRuleDepth)
+	}
+	if len(rule.Rules) == 0 {
+		return nil, fmt.Errorf("%w: %s: group has no rules", ErrInvalidCollectionRule, path)
+	}
+
+	exprs := make([]clause.Expression, len(rule.Rules))
+	for i := range rule.Rules {
+		expr, err := b.build(&rule.Rules[i], fmt.Sprintf("%s[%d]", path, i), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = expr
+	}
+	if rule.Match == RuleMatchAny {
+		return clause.Or(exprs...), nil
+	}
+	return clause.And(exprs...), nil
+}
+
+func (b *ruleBuilder) condition(rule *models.CollectionRule, path string) (clause.Expression, error) {
+	b.conditions++
+	if b.conditions > maxRuleConditions {
+		return nil, fmt.Errorf("%w: at most %d conditions", ErrInvalidCollectionRule, maxRuleConditions)
+	}
+	if len(rule.Rules) > 0 {
+		return nil, fmt.Errorf("%w: %s: nested rules need match all or any", ErrInvalidCollectionRule, path)
+	}
+	field, ok := collectionRuleFields[rule.Field]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: unknown field %q", ErrInvalidCollectionRule, path, rule.Field)
+	}
+	if !containsString(ruleOps[field.kind], rule.Op) {
+		return nil, fmt.Errorf("%w: %s: %s takes op %s, not %q", ErrInvalidCollectionRule, path, rule.Field, strings.Join(ruleOps[field.kind], ", "), rule.Op)
+	}
+
+	var expr clause.Expression
+	var err error
+	switch field.kind {
+	case ruleText:
+		expr, err = b.text(field, rule)
+	case ruleNumber:
+		expr, err = b.number(field, rule)
+	case ruleDate:
+		expr, err = b.date(field, rule)
+	case ruleChoice:
+		expr, err = b.choice(field, rule)
+	case ruleSet:
+		expr, err = b.set(field, rule)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidCollectionRule, path, err)
+	}
+	return expr, nil
+}
+
+func (b *ruleBuilder) text(field ruleField, rule *models.CollectionRule) (clause.Expression, error) {
+	value, ok := rule.Operand.(string)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("%s needs a text value", rule.Field)
+	}
+	switch rule.Op {
+	case "eq":
+		return EqualFold(field.column, value), nil
+	case "neq":
+		return clause.Not(EqualFold(field.column, value)), nil
+	case "contains":
+		return ContainsFold(b.db, field.column, value), nil
+	default: // not_contains
+		return clause.Not(ContainsFold(b.db, field.column, value)), nil
+	}
+}
+
+func (b *ruleBuilder) number(field ruleField, rule *models.CollectionRule) (clause.Expression, error) {
+	if rule.Op == "between" {
+		bounds, ok := ruleNumbers(rule.Operand)
+		if !ok || len(bounds) != 2 || bounds[0] > bounds[1] {
+			return nil, fmt.Errorf("between needs [low, high]")
+		}
+		return gorm.Expr(field.column+" BETWEEN ? AND ?", bounds[0], bounds[1]), nil
+	}
+	value, ok := rule.Operand.(float64)
+	if !ok || math.IsNaN(value) {
+		return nil, fmt.Errorf("%s needs a number", rule.Field)
+	}
+	operator := map[string]string{"eq": "=", "neq": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}[rule.Op]
+	return gorm.Expr(field.column+" "+operator+" ?", value), nil
+}
+
+func (b *ruleBuilder) date(field ruleField, rule *models.CollectionRule) (clause.Expression, error) {
+	switch rule.Op {
+	case "is_set", "not_set":
+		if rule.Operand != nil {
+			return nil, fmt.Errorf("%s takes no value", rule.Op)
+		}
+		if rule.Op == "is_set" {
+			return gorm.Expr(field.column + " IS NOT NULL"), nil
+		}
+		return gorm.Expr(field.column + " IS NULL"), nil
+	case "within_days":
+		days, ok := rule.Operand.(float64)
+		if !ok || days < 1 || days != math.Trunc(days) {
+			return nil, fmt.Errorf("within_days needs a whole number of days")
+		}
+		today := b.now.In(b.loc)
+		from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, b.loc).AddDate(0, 0, 1-int(days))
+		return gorm.Expr(field.column+" >= ?", from), nil
+	case "between":
+		values, ok := rule.Operand.([]interface{})
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("between needs [from, to] dates")
+		}
+		from, err := b.day(values[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := b.day(values[1])
+		if err != nil {
+			return nil, err
+		}
+		if to.Before(from) {
+			return nil, fmt.Errorf("between needs from before to")
+		}
+		return gorm.Expr("("+field.column+" >= ? AND "+field.column+" < ?)", from, to.AddDate(0, 0, 1)), nil
+	}
+
+	day, err := b.day(rule.Operand)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Op == "after" {
+		return gorm.Expr(field.column+" >= ?", day), nil
+	}
+	return gorm.Expr(field.column+" < ?", day), nil
+}
+
+// day parses a YYYY-MM-DD date to midnight in the builder's timezone
+func (b *ruleBuilder) day(value interface{}) (time.Time, error) {
+	text, _ := value.(string)
+	day, err := time.ParseInLocation("2006-01-02", text, b.loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("dates must be YYYY-MM-DD")
+	}
+	return day, nil
+}
+
+func (b *ruleBuilder) choice(field ruleField, rule *models.CollectionRule) (clause.Expression, error) {
+	values, ok := ruleStrings(rule.Operand)
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("%s needs a value or list of values", rule.Field)
+	}
+	if (rule.Op == "eq" || rule.Op == "neq") && len(values) > 1 {
+		return nil, fmt.Errorf("%s takes one value; use in or not_in for a list", rule.Op)
+	}
+
+	var expr clause.Expression
+	if field.sql != "" {
+		expr = gorm.Expr("games.id IN ("+field.sql+")", lowerStrings(values), values)
+	} else {
+		var statuses []string
+		for _, value := range values {
+			expanded, ok := completionStatuses[value]
+			if !ok {
+				return nil, fmt.Errorf("unknown %s %q", rule.Field, value)
+			}
+			statuses = append(statuses, expanded...)
+		}
+		expr = gorm.Expr(field.column+" IN ?", statuses)
+	}
+	if rule.Op == "neq" || rule.Op == "not_in" {
+		return clause.Not(expr), nil
+	}
+	return expr, nil
+}
+
+func (b *ruleBuilder) set(field ruleField, rule *models.CollectionRule) (clause.Expression, error) {
+	values, ok := ruleStrings(rule.Operand)
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("%s needs a name or list of names", rule.Field)
+	}
+
+	exprs := make([]clause.Expression, 0, len(values))
+	if field.sql == "" {
+		// collection_format is a JSON array on the game itself
+		for _, value := range values {
+			exprs = append(exprs, JSONArrayContains(b.db, field.column, value))
+		}
+	} else if rule.Op == "has_all" {
+		for _, value := range values {
+			exprs = append(exprs, gorm.Expr("games.id IN ("+field.sql+")", []string{strings.ToLower(value)}))
+		}
+	} else {
+		exprs = append(exprs, gorm.Expr("games.id IN ("+field.sql+")", lowerStrings(values)))
+	}
+
+	switch rule.Op {
+	case "has_all":
+		return clause.And(exprs...), nil
+	case "has_none":
+		return clause.Not(clause.Or(exprs...)), nil
+	default:
+		return clause.Or(exprs...), nil
+	}
+}
+
+// ruleStrings reads a string, number or list of them as strings
+func ruleStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, strings.TrimSpace(v) != ""
+	case float64:
+		return []string{fmt.Sprint(v)}, true
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			strs, ok := ruleStrings(item)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, strs...)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+func ruleNumbers(value interface{}) ([]float64, bool) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	numbers := make([]float64, len(list))
+	for i, item := range list {
+		if numbers[i], ok = item.(float64); !ok {
+			return nil, false
+		}
+	}
+	return numbers, true
+}
+
+func lowerStrings(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseRule(t *testing.T, rule string) *models.CollectionRule {
+	var parsed models.CollectionRule
+	require.NoError(t, json.Unmarshal([]byte(rule), &parsed))
+	return &parsed
+}
+
+func TestCollectionRuleCondition(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 1).Updates(map[string]interface{}{"rating": 9, "year": 1990}).Error)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 2).Updates(map[string]interface{}{"rating": 10, "year": 1995, "completion_status": "completed"}).Error)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 3).Updates(map[string]interface{}{"rating": 9, "year": 2017, "completion_status": "in_progress"}).Error)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 4).
+		Update("collection_formats", models.CollectionFormats{"physical", "rom"}).Error)
+	comfort := models.Tag{Name: "Comfort"}
+	require.NoError(t, db.Create(&comfort).Error)
+	require.NoError(t, db.Model(&models.Game{ID: 4}).Association("Tags").Append(&comfort))
+	addPlayedSession(t, db, 3, time.Date(2024, 3, 18, 20, 0, 0, 0, time.UTC), 150)
+	addPlayedSession(t, db, 1, time.Date(2024, 1, 5, 20, 0, 0, 0, time.UTC), 30)
+
+	match := func(rule string) []string {
+		condition, err := CollectionRuleCondition(db, parseRule(t, rule), time.UTC, now)
+		require.NoError(t, err, rule)
+		var titles []string
+		require.NoError(t, db.Model(&models.Game{}).Where(condition).Order("id").Pluck("title", &titles).Error)
+		return titles
+	}
+
+	assert.Equal(t, []string{"Super Mario World"}, match(`{"match": "all", "rules": [
+		{"field": "platform", "op": "eq", "value": "snes"},
+		{"field": "completion_status", "op": "eq", "value": "not_started"},
+		{"field": "rating", "op": "gte", "value": 8}]}`))
+	assert.Equal(t, []string{"Chrono Trigger", "Hollow Knight", "Tetris"}, match(`{"match": "any", "rules": [
+		{"field": "genre", "op": "has_any", "value": "rpg"},
+		{"field": "tag", "op": "has_any", "value": ["Comfort"]}]}`))
+	assert.Equal(t, []string{"Hollow Knight", "Tetris"}, match(`{"match": "all", "rules": [
+		{"field": "platform", "op": "eq", "value": 2},
+		{"match": "any", "rules": [
+			{"field": "playtime_hours", "op": "gte", "value": 2.5},
+			{"field": "title", "op": "contains", "value": "TET"}]}]}`))
+
+	// A lone condition needs no group
+	assert.Equal(t, []string{"Hollow Knight"}, match(`{"field": "genre", "op": "has_all", "value": ["Platform", "RPG"]}`))
+	assert.Equal(t, []string{"Tetris"}, match(`{"field": "genre", "op": "has_none", "value": ["Platform", "RPG"]}`))
+	assert.Equal(t, []string{"Hollow Knight", "Tetris"}, match(`{"field": "platform", "op": "neq", "value": "SNES"}`))
+	assert.Equal(t, []string{"Super Mario World", "Hollow Knight", "Tetris"}, match(`{"field": "completion_status", "op": "in", "value": ["backlog"]}`))
+	assert.Equal(t, []string{"Tetris"}, match(`{"field": "collection_format", "op": "has_all", "value": ["rom", "physical"]}`))
+	assert.Equal(t, []string{"Chrono Trigger", "Hollow Knight"}, match(`{"field": "year", "op": "between", "value": [1991, 2020]}`))
+	assert.Equal(t, []string{"Super Mario World", "Chrono Trigger", "Tetris"}, match(`{"field": "title", "op": "not_contains", "value": "knight"}`))
+
+	// Dates
+	assert.Equal(t, []string{"Hollow Knight"}, match(`{"field": "last_played", "op": "within_days", "value": 7}`))
+	assert.Equal(t, []string{"Super Mario World"}, match(`{"field": "last_played", "op": "before", "value": "2024-03-01"}`))
+	assert.Equal(t, []string{"Super Mario World", "Hollow Knight"}, match(`{"field": "last_played", "op": "between", "value": ["2024-01-05", "2024-03-18"]}`))
+	assert.Equal(t, []string{"Chrono Trigger", "Tetris"}, match(`{"field": "last_played", "op": "not_set"}`))
+	assert.Empty(t, match(`{"field": "completion_date", "op": "is_set"}`))
+}
+
+func TestValidateCollectionRule(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	for _, tt := range []struct{ rule, message string }{
+		{`{"match": "all", "rules": []}`, "rules: group has no rules"},
+		{`{"match": "most", "rules": [{"field": "year", "op": "eq", "value": 1}]}`, `match must be all or any, not "most"`},
+		{`{"match": "all", "rules": [{"field": "price", "op": "eq", "value": 1}]}`, `rules[0]: unknown field "price"`},
+		{`{"match": "all", "rules": [{"field": "title", "op": "gt", "value": "a"}]}`, "rules[0]: title takes op eq, neq, contains, not_contains"},
+		{`{"match": "any", "rules": [{"field": "year", "op": "eq", "value": 1}, {"field": "year", "op": "gt", "value": "1990"}]}`, "rules[1]: year needs a number"},
+		{`{"field": "year", "op": "between", "value": [2000, 1990]}`, "between needs [low, high]"},
+		{`{"field": "created_at", "op": "after", "value": "last week"}`, "dates must be YYYY-MM-DD"},
+		{`{"field": "created_at", "op": "within_days", "value": 0.5}`, "within_days needs a whole number of days"},
+		{`{"field": "completion_status", "op": "eq", "value": "finished"}`, `unknown completion_status "finished"`},
+		{`{"field": "platform", "op": "eq", "value": ["SNES", "PC"]}`, "use in or not_in for a list"},
+		{`{"field": "tag", "op": "has_any", "value": []}`, "tag needs a name or list of names"},
+		{`{"match": "all", "field": "year", "rules": [{"field": "year", "op": "eq", "value": 1}]}`, "a group has match and rules"},
+	} {
+		err := ValidateCollectionRule(db, parseRule(t, tt.rule))
+		if assert.ErrorIs(t, err, ErrInvalidCollectionRule, tt.rule) {
+			assert.Contains(t, err.Error(), tt.message, tt.rule)
+		}
+	}
+	nested := `{"field": "year", "op": "eq", "value": 1}`
+	for i := 0; i < 6; i++ {
+		nested = `{"match": "all", "rules": [` + nested + `]}`
+	}
+	assert.ErrorContains(t, ValidateCollectionRule(db, parseRule(t, nested)), "groups nest at most 5 deep")
+	assert.ErrorIs(t, ValidateCollectionRule(db, nil), ErrInvalidCollectionRule)
+	assert.NoError(t, ValidateCollectionRule(db, parseRule(t, `{"field": "rating", "op": "gte", "value": 0}`)))
+
+	// The same rules render for PostgreSQL
+	pg := newPostgresStandIn(t)
+	condition, err := CollectionRuleCondition(pg, parseRule(t, `{"match": "any", "rules": [
+		{"field": "title", "op": "not_contains", "value": "demo"},
+		{"field": "collection_format", "op": "has_none", "value": ["rom"]}]}`), time.UTC, time.Now())
+	require.NoError(t, err)
+	stmt := pg.Where(condition).Find(&[]models.Game{}).Statement
+	assert.Equal(t, `SELECT * FROM "games" WHERE (NOT games.title ILIKE $1 ESCAPE '\' OR NOT CAST(games.collection_formats AS jsonb) @> CAST($2 AS jsonb))`, stmt.SQL.String())
+}
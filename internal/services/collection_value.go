@@ -0,0 +1,332 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// ValueStats reports what the physical collection cost and what it is worth,
+// from copies' purchase prices and imported price history. Amounts are in one
+// currency: copies bought and prices recorded in another are left out, while
+// copies without a currency are taken to be in it.
+type ValueStats struct {
+	db       *gorm.DB
+	currency string
+}
+
+func NewValueStats(db *gorm.DB, currency string) *ValueStats {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return &ValueStats{db: db, currency: currency}
+}
+
+// Currency is the currency amounts are reported in
+func (s *ValueStats) Currency() string {
+	return s.currency
+}
+
+// ValuePoint is the collection's market value at the end of a period: each
+// copy owned by then at the latest price known by then for its grade
+type ValuePoint struct {
+	Period         string    `json:"period"`
+	Start          time.Time `json:"start"`
+	Value          float64   `json:"value"`
+	Copies         int       `json:"copies"`
+	UnpricedCopies int       `json:"unpriced_copies"`
+}
+
+// SpendingBreakdown is money spent on copies, per year or platform
+type SpendingBreakdown struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+	Copies int     `json:"copies"`
+}
+
+// Spending sums copies' purchase prices
+type Spending struct {
+	Currency   string              `json:"currency"`
+	Total      float64             `json:"total"`
+	Copies     int                 `json:"copies"`
+	Skipped    int                 `json:"other_currency_copies"` // priced in another currency
+	ByYear     []SpendingBreakdown `json:"by_year"`               // oldest first; undated copies under "unknown"
+	ByPlatform []SpendingBreakdown `json:"by_platform"`           // most spent first
+}
+
+// GameCostPerHour is what a game's copies cost per hour played
+type GameCostPerHour struct {
+	GameID      uint     `json:"game_id"`
+	Title       string   `json:"title"`
+	Platform    string   `json:"platform"`
+	Spent       float64  `json:"spent"`
+	Hours       float64  `json:"hours"`
+	CostPerHour *float64 `json:"cost_per_hour"` // nil until the game is played
+}
+
+// valuedCopy is a copy with what's needed to price and total it
+type valuedCopy struct {
+	ID            uint
+	GameID        uint
+	Title         string
+	Platform      string
+	Completeness  string
+	PurchasePrice *float64
+	Currency      string
+	PurchaseDate  *time.Time
+	CreatedAt     time.Time
+}
+
+// ownedSince is when the copy joined the collection
+func (c valuedCopy) ownedSince() time.Time {
+	if c.PurchaseDate != nil {
+		return *c.PurchaseDate
+	}
+	return c.CreatedAt
+}
+
+func (s *ValueStats) copies() ([]valuedCopy, error) {
+	var copies []valuedCopy
+	err := s.db.Table("physical_copies").
+		Select("physical_copies.id, physical_copies.game_id, games.title, platforms.name AS platform, physical_copies.completeness, " +
+			"physical_copies.purchase_price, physical_copies.currency, physical_copies.purchase_date, physical_copies.created_at").
+		Joins("JOIN games ON games.id = physical_copies.game_id").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Order("physical_copies.id").
+		Scan(&copies).Error
+	return copies, err
+}
+
+// paid reports a copy's purchase price when it's in the stats' currency
+func (s *ValueStats) paid(c valuedCopy) (float64, bool) {
+	if c.PurchasePrice == nil || (c.Currency != "" && c.Currency != s.currency) {
+		return 0, false
+	}
+	return *c.PurchasePrice, true
+}
+
+// ValueSeries returns the collection's value at the end of each day, week or
+// month in r
+func (s *ValueStats) ValueSeries(r PlaytimeRange, interval string) ([]ValuePoint, error) {
+	if interval != PlaytimeByDay && interval != PlaytimeByWeek && interval != PlaytimeByMonth {
+		return nil, fmt.Errorf("unknown value interval %q", interval)
+	}
+	copies, err := s.copies()
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.priceHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	var series []ValuePoint
+	for start := periodStart(r.From, interval); start.Before(r.To); start = nextPeriod(start, interval) {
+		end := nextPeriod(start, interval)
+		point := ValuePoint{Period: periodLabel(start, interval), Start: start}
+		for _, c := range copies {
+			if !c.ownedSince().Before(end) {
+				continue
+			}
+			point.Copies++
+			if price, ok := prices.at(c.GameID, PriceGrade(c.Completeness), end); ok {
+				point.Value += price
+			} else {
+				point.UnpricedCopies++
+			}
+		}
+		point.Value = roundMoney(point.Value)
+		series = append(series, point)
+	}
+	return series, nil
+}
+
+// Value returns the collection's value at t
+func (s *ValueStats) Value(t time.Time) (ValuePoint, error) {
+	day := periodStart(t, PlaytimeByDay)
+	series, err := s.ValueSeries(PlaytimeRange{From: day, To: day.AddDate(0, 0, 1), Location: t.Location()}, PlaytimeByDay)
+	if err != nil {
+		return ValuePoint{}, err
+	}
+	return series[0], nil
+}
+
+// Spending totals purchase prices per purchase year, in loc, and platform
+func (s *ValueStats) Spending(loc *time.Location) (*Spending, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	copies, err := s.copies()
+	if err != nil {
+		return nil, err
+	}
+
+	spending := &Spending{Currency: s.currency, ByYear: []SpendingBreakdown{}, ByPlatform: []SpendingBreakdown{}}
+	years := make(map[string]*SpendingBreakdown)
+	platforms := make(map[string]*SpendingBreakdown)
+	add := func(totals map[string]*SpendingBreakdown, name string, amount float64) {
+		if totals[name] == nil {
+			totals[name] = &SpendingBreakdown{Name: name}
+		}
+		totals[name].Amount += amount
+		totals[name].Copies++
+	}
+	for _, c := range copies {
+		amount, ok := s.paid(c)
+		if !ok {
+			if c.PurchasePrice != nil {
+				spending.Skipped++
+			}
+			continue
+		}
+		spending.Total += amount
+		spending.Copies++
+		year := "unknown"
+		if c.PurchaseDate != nil {
+			year = strconv.Itoa(c.PurchaseDate.In(loc).Year())
+		}
+		add(years, year, amount)
+		add(platforms, c.Platform, amount)
+	}
+	spending.Total = roundMoney(spending.Total)
+
+	for _, total := range years {
+		total.Amount = roundMoney(total.Amount)
+		spending.ByYear = append(spending.ByYear, *total)
+	}
+	// "unknown" sorts after the years
+	sort.Slice(spending.ByYear, func(a, b int) bool { return spending.ByYear[a].Name < spending.ByYear[b].Name })
+	for _, total := range platforms {
+		total.Amount = roundMoney(total.Amount)
+		spending.ByPlatform = append(spending.ByPlatform, *total)
+	}
+	sort.Slice(spending.ByPlatform, func(a, b int) bool {
+		if spending.ByPlatform[a].Amount != spending.ByPlatform[b].Amount {
+			return spending.ByPlatform[a].Amount > spending.ByPlatform[b].Amount
+		}
+		return spending.ByPlatform[a].Name < spending.ByPlatform[b].Name
+	})
+	return spending, nil
+}
+
+// SpentBetween totals purchase prices of copies bought in [from, to), and
+// how many copies that covers
+func (s *ValueStats) SpentBetween(from, to time.Time) (float64, int, error) {
+	copies, err := s.copies()
+	if err != nil {
+		return 0, 0, err
+	}
+	var total float64
+	count := 0
+	for _, c := range copies {
+		amount, ok := s.paid(c)
+		if ok && c.PurchaseDate != nil && !c.PurchaseDate.Before(from) && c.PurchaseDate.Before(to) {
+			total += amount
+			count++
+		}
+	}
+	return roundMoney(total), count, nil
+}
+
+// CostPerHour returns what each game with priced copies cost per hour played,
+// cheapest first, then unplayed games by amount spent; limit <= 0 returns all
+func (s *ValueStats) CostPerHour(limit int) ([]GameCostPerHour, error) {
+	copies, err := s.copies()
+	if err != nil {
+		return nil, err
+	}
+	games := make(map[uint]*GameCostPerHour)
+	var ids []uint
+	for _, c := range copies {
+		amount, ok := s.paid(c)
+		if !ok {
+			continue
+		}
+		if games[c.GameID] == nil {
+			games[c.GameID] = &GameCostPerHour{GameID: c.GameID, Title: c.Title, Platform: c.Platform}
+			ids = append(ids, c.GameID)
+		}
+		games[c.GameID].Spent += amount
+	}
+
+	var played []struct {
+		GameID  uint
+		Minutes int
+	}
+	err = s.db.Model(&models.PlaySession{}).
+		Select("game_id, COALESCE(SUM(duration), 0) AS minutes").
+		Where("game_id IN ? AND end_time IS NOT NULL", ids).
+		Group("game_id").
+		Scan(&played).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]GameCostPerHour, 0, len(games))
+	minutes := make(map[uint]int, len(played))
+	for _, game := range played {
+		minutes[game.GameID] = game.Minutes
+	}
+	for _, id := range ids {
+		game := games[id]
+		game.Spent = roundMoney(game.Spent)
+		game.Hours = minutesToHours(minutes[id])
+		if minutes[id] > 0 {
+			cost := roundMoney(game.Spent / (float64(minutes[id]) / 60))
+			game.CostPerHour = &cost
+		}
+		result = append(result, *game)
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		ca, cb := result[a].CostPerHour, result[b].CostPerHour
+		switch {
+		case ca != nil && cb != nil:
+			return *ca < *cb
+		case ca != nil || cb != nil:
+			return ca != nil
+		}
+		return result[a].Spent > result[b].Spent
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// priceHistory holds each game and grade's prices, oldest first
+type priceHistory map[string][]models.PricePoint
+
+func (s *ValueStats) priceHistory() (priceHistory, error) {
+	var points []models.PricePoint
+	if err := s.db.Where("currency = ?", s.currency).Order("date").Find(&points).Error; err != nil {
+		return nil, err
+	}
+	history := make(priceHistory)
+	for _, point := range points {
+		key := priceKey(point.GameID, point.Grade)
+		history[key] = append(history[key], point)
+	}
+	return history, nil
+}
+
+// at returns the latest price recorded before t
+func (h priceHistory) at(gameID uint, grade string, t time.Time) (float64, bool) {
+	points := h[priceKey(gameID, grade)]
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(t) })
+	if i == 0 {
+		return 0, false
+	}
+	return points[i-1].Price, true
+}
+
+func priceKey(gameID uint, grade string) string {
+	return strconv.FormatUint(uint64(gameID), 10) + "/" + grade
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
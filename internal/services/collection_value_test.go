@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func addCopy(t *testing.T, db *gorm.DB, physicalCopy models.PhysicalCopy) {
+	require.NoError(t, db.Create(&physicalCopy).Error)
+}
+
+func addPrice(t *testing.T, db *gorm.DB, gameID uint, grade string, date time.Time, price float64, currency string) {
+	require.NoError(t, db.Create(&models.PricePoint{GameID: gameID, Grade: grade, Date: date, Price: price, Currency: currency}).Error)
+}
+
+func TestValueSeries(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	utc := time.UTC
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, utc) }
+	bought := func(month time.Month, d int) *time.Time { date := day(month, d); return &date }
+
+	addCopy(t, db, models.PhysicalCopy{GameID: 1, Completeness: "cib", PurchaseDate: bought(1, 10)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 2, Completeness: "box", PurchaseDate: bought(2, 20)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 3, Completeness: "sealed", PurchaseDate: bought(3, 5)})
+	addPrice(t, db, 1, PriceGradeCIB, day(1, 1), 50, "USD")
+	addPrice(t, db, 1, PriceGradeCIB, day(2, 15), 65, "USD")
+	addPrice(t, db, 1, PriceGradeLoose, day(1, 1), 20, "USD") // not the grade the copy is valued at
+	addPrice(t, db, 2, PriceGradeLoose, day(1, 1), 30, "USD")
+	addPrice(t, db, 2, PriceGradeLoose, day(3, 1), 9000, "JPY") // not the reported currency
+
+	stats := NewValueStats(db, "")
+	r := PlaytimeRange{From: day(1, 1), To: day(4, 1), Location: utc}
+	series, err := stats.ValueSeries(r, PlaytimeByMonth)
+	require.NoError(t, err)
+	require.Len(t, series, 3)
+	assert.Equal(t, ValuePoint{Period: "2024-01", Start: day(1, 1), Value: 50, Copies: 1}, series[0])
+	assert.Equal(t, 95.0, series[1].Value)
+	assert.Equal(t, 2, series[1].Copies)
+	assert.Equal(t, 95.0, series[2].Value)
+	assert.Equal(t, 1, series[2].UnpricedCopies, "Hollow Knight has no new price")
+
+	point, err := stats.Value(time.Date(2024, 2, 14, 12, 0, 0, 0, utc))
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, point.Value, "the February price isn't known yet")
+
+	_, err = stats.ValueSeries(r, "year")
+	assert.Error(t, err)
+}
+
+func TestSpendingAndCostPerHour(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	price := func(amount float64) *float64 { return &amount }
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	addCopy(t, db, models.PhysicalCopy{GameID: 1, PurchasePrice: price(30), Currency: "USD", PurchaseDate: date(2023, 4, 1)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 1, PurchasePrice: price(10.10), PurchaseDate: date(2024, 1, 1)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 2, PurchasePrice: price(120), Currency: "USD"})
+	addCopy(t, db, models.PhysicalCopy{GameID: 3, PurchasePrice: price(2000), Currency: "JPY", PurchaseDate: date(2024, 5, 1)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 4, PurchasePrice: price(5), Currency: "USD", PurchaseDate: date(2024, 6, 1)})
+	addPlayedSession(t, db, 1, time.Date(2024, 2, 1, 20, 0, 0, 0, time.UTC), 120)
+	addPlayedSession(t, db, 2, time.Date(2024, 2, 2, 20, 0, 0, 0, time.UTC), 60)
+
+	stats := NewValueStats(db, "USD")
+	// New Year's Day in UTC is still 2023 in New York
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	spending, err := stats.Spending(newYork)
+	require.NoError(t, err)
+	assert.Equal(t, 165.1, spending.Total)
+	assert.Equal(t, 4, spending.Copies)
+	assert.Equal(t, 1, spending.Skipped)
+	assert.Equal(t, []SpendingBreakdown{
+		{Name: "2023", Amount: 40.1, Copies: 2},
+		{Name: "2024", Amount: 5, Copies: 1},
+		{Name: "unknown", Amount: 120, Copies: 1},
+	}, spending.ByYear)
+	assert.Equal(t, []SpendingBreakdown{
+		{Name: "SNES", Amount: 160.1, Copies: 3},
+		{Name: "PC", Amount: 5, Copies: 1},
+	}, spending.ByPlatform)
+
+	spent, copies, err := stats.SpentBetween(*date(2024, 1, 1), *date(2025, 1, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 15.1, spent)
+	assert.Equal(t, 2, copies)
+
+	costs, err := stats.CostPerHour(0)
+	require.NoError(t, err)
+	require.Len(t, costs, 3)
+	assert.Equal(t, uint(1), costs[0].GameID)
+	assert.Equal(t, 20.05, *costs[0].CostPerHour)
+	assert.Equal(t, 2.0, costs[0].Hours)
+	assert.Equal(t, uint(2), costs[1].GameID)
+	assert.Equal(t, 120.0, *costs[1].CostPerHour)
+	assert.Equal(t, uint(4), costs[2].GameID)
+	assert.Nil(t, costs[2].CostPerHour)
+
+	limited, err := stats.CostPerHour(1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+}
+
+func TestYearReviewCopyPurchases(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	price := func(amount float64) *float64 { return &amount }
+	date := func(month time.Month, day int) *time.Time {
+		d := time.Date(2024, month, day, 12, 0, 0, 0, time.UTC)
+		return &d
+	}
+	require.NoError(t, db.Model(&models.Game{ID: 1}).Update("purchase_date", date(3, 1)).Error)
+	addCopy(t, db, models.PhysicalCopy{GameID: 1, PurchasePrice: price(25), Currency: "EUR", PurchaseDate: date(3, 1)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 2, PurchasePrice: price(14.5), PurchaseDate: date(6, 9)})
+	addCopy(t, db, models.PhysicalCopy{GameID: 3, PurchaseDate: date(7, 1)})
+
+	review, err := NewPlaytimeStats(db).YearReview(2024, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 3, review.Purchases.Games)
+	require.NotNil(t, review.Purchases.MoneySpent)
+	assert.Equal(t, 39.5, *review.Purchases.MoneySpent)
+	assert.Equal(t, "EUR", review.Purchases.Currency)
+	var markdown bytes.Buffer
+	require.NoError(t, RenderYearReviewMarkdown(&markdown, review))
+	assert.Contains(t, markdown.String(), "(39.50 EUR spent)")
+
+	// Totals across currencies would be meaningless
+	addCopy(t, db, models.PhysicalCopy{GameID: 4, PurchasePrice: price(3000), Currency: "JPY", PurchaseDate: date(8, 1)})
+	review, err = NewPlaytimeStats(db).YearReview(2024, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 4, review.Purchases.Games)
+	assert.Nil(t, review.Purchases.MoneySpent)
+	assert.Empty(t, review.Purchases.Currency)
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Custom fields let users track what Pelico has no column for. Each value is
+// stored in the game_custom_values column for its field's type, so GetGames
+// can filter and sort on it in SQL.
+
+// Custom field types
+const (
+	CustomFieldText    = "text"
+	CustomFieldNumber  = "number"
+	CustomFieldDate    = "date"
+	CustomFieldBoolean = "boolean"
+	CustomFieldEnum    = "enum"
+)
+
+var ErrInvalidCustomField = errors.New("invalid custom field")
+
+// Limits on field definitions and values
+const (
+	maxCustomFieldOptions = 50
+	maxCustomTextLength   = 1000
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// customFieldColumns is the game_custom_values column each type is kept in
+var customFieldColumns = map[string]string{
+	CustomFieldText:    "text_value",
+	CustomFieldNumber:  "number_value",
+	CustomFieldDate:    "date_value",
+	CustomFieldBoolean: "bool_value",
+	CustomFieldEnum:    "text_value",
+}
+
+func invalidCustomField(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCustomField, fmt.Sprintf(format, args...))
+}
+
+// ValidateCustomField checks a field definition and tidies its enum options
+func ValidateCustomField(field *models.CustomField) error {
+	if !customFieldKeyPattern.MatchString(field.Key) {
+		return invalidCustomField("key must be lowercase letters, digits and underscores, starting with a letter")
+	}
+	field.Name = strings.TrimSpace(field.Name)
+	if field.Name == "" {
+		return invalidCustomField("name is required")
+	}
+	if _, ok := customFieldColumns[field.Type]; !ok {
+		return invalidCustomField("type must be text, number, date, boolean or enum, not %q", field.Type)
+	}
+	if field.Type != CustomFieldEnum {
+		if len(field.Options) > 0 {
+			return invalidCustomField("only enum fields take options")
+		}
+		field.Options = nil
+		return nil
+	}
+	options := make(models.StringList, 0, len(field.Options))
+	for _, option := range field.Options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return invalidCustomField("options can't be empty")
+		}
+		if findFold(options, option) != "" {
+			return invalidCustomField("option %q is listed twice", option)
+		}
+		options = append(options, option)
+	}
+	if len(options) == 0 || len(options) > maxCustomFieldOptions {
+		return invalidCustomField("enum fields need 1 to %d options", maxCustomFieldOptions)
+	}
+	field.Options = options
+	return nil
+}
+
+// CustomValueFor converts a JSON value into a value for field. A nil value
+// converts to an empty GameCustomValue, which clears the field.
+func CustomValueFor(field models.CustomField, value interface{}) (models.GameCustomValue, error) {
+	result := models.GameCustomValue{FieldID: field.ID}
+	if value == nil {
+		return result, nil
+	}
+	switch field.Type {
+	case CustomFieldText:
+		text, ok := value.(string)
+		if !ok {
+			return result, invalidCustomField("%s needs a string", field.Key)
+		}
+		if text = strings.TrimSpace(text); len(text) > maxCustomTextLength {
+			return result, invalidCustomField("%s is longer than %d characters", field.Key, maxCustomTextLength)
+		}
+		result.TextValue = &text
+	case CustomFieldNumber:
+		number, ok := value.(float64)
+		if !ok {
+			return result, invalidCustomField("%s needs a number", field.Key)
+		}
+		result.NumberValue = &number
+	case CustomFieldDate:
+		date, ok := value.(string)
+		if !ok {
+			return result, invalidCustomField("%s needs a date", field.Key)
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return result, invalidCustomField("%s: dates must be YYYY-MM-DD", field.Key)
+		}
+		result.DateValue = &date
+	case CustomFieldBoolean:
+		flag, ok := value.(bool)
+		if !ok {
+			return result, invalidCustomField("%s needs true or false", field.Key)
+		}
+		result.BoolValue = &flag
+	case CustomFieldEnum:
+		text, _ := value.(string)
+		option := findFold(field.Options, text)
+		if option == "" {
+			return result, invalidCustomField("%s must be one of %s", field.Key, strings.Join(field.Options, ", "))
+		}
+		result.TextValue = &option
+	}
+	return result, nil
+}
+
+// ParseCustomValues converts a game's custom_fields, keyed by field key,
+// into values for SaveGameCustomValues
+func ParseCustomValues(db *gorm.DB, values map[string]interface{}) ([]models.GameCustomValue, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	var fields []models.CustomField
+	if err := db.Where("key IN ?", keys).Order("key").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	if len(fields) < len(keys) {
+		for _, key := range keys {
+			if !containsFieldKey(fields, key) {
+				return nil, invalidCustomField("unknown custom field %q", key)
+			}
+		}
+	}
+	parsed := make([]models.GameCustomValue, 0, len(fields))
+	for _, field := range fields {
+		value, err := CustomValueFor(field, values[field.Key])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, value)
+	}
+	return parsed, nil
+}
+
+// SaveGameCustomValues sets a game's values from ParseCustomValues, leaving
+// fields not in values as they are
+func SaveGameCustomValues(db *gorm.DB, gameID uint, values []models.GameCustomValue) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, value := range values {
+			if value.Typed() == nil {
+				if err := tx.Where("game_id = ? AND field_id = ?", gameID, value.FieldID).
+					Delete(&models.GameCustomValue{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			value.GameID = gameID
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "game_id"}, {Name: "field_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"text_value", "number_value", "date_value", "bool_value"}),
+			}).Create(&value).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// CustomFieldCondition matches games whose value for field equals raw, a
+// query string value. Text and enum values compare ignoring case.
+func CustomFieldCondition(field models.CustomField, raw string) (clause.Expression, error) {
+	switch field.Type {
+	case CustomFieldText, CustomFieldEnum:
+		return customValueSubquery(field, EqualFold("text_value", raw)), nil
+	case CustomFieldBoolean:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalidCustomField("%s needs true or false", field.Key)
+		}
+		return customValueSubquery(field, gorm.Expr("bool_value = ?", flag)), nil
+	}
+	return CustomFieldRangeCondition(field, raw, raw)
+}
+
+// CustomFieldRangeCondition matches games whose number or date value for
+// field lies between low and high, either of which may be empty
+func CustomFieldRangeCondition(field models.CustomField, low, high string) (clause.Expression, error) {
+	if field.Type != CustomFieldNumber && field.Type != CustomFieldDate {
+		return nil, invalidCustomField("%s is a %s field; ranges need a number or date field", field.Key, field.Type)
+	}
+	column := customFieldColumns[field.Type]
+	var conditions []clause.Expression
+	for _, bound := range []struct{ value, op string }{{low, ">="}, {high, "<="}} {
+		if bound.value == "" {
+			continue
+		}
+		value, err := parseCustomBound(field, bound.value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, gorm.Expr(column+" "+bound.op+" ?", value))
+	}
+	if len(conditions) == 0 {
+		return nil, invalidCustomField("%s needs a value", field.Key)
+	}
+	return customValueSubquery(field, clause.And(conditions...)), nil
+}
+
+// CustomFieldOrder sorts games by their value for field, games without one
+// last, then by title
+func CustomFieldOrder(field models.CustomField, desc bool) clause.OrderBy {
+	value := "(SELECT " + customFieldColumns[field.Type] + " FROM game_custom_values WHERE game_custom_values.game_id = games.id AND game_custom_values.field_id = ?)"
+	sorted := value
+	if field.Type == CustomFieldText || field.Type == CustomFieldEnum {
+		sorted = "LOWER(" + value + ")"
+	}
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                value + " IS NULL, " + sorted + direction + ", games.title ASC",
+		Vars:               []interface{}{field.ID, field.ID},
+		WithoutParentheses: true,
+	}}
+}
+
+func customValueSubquery(field models.CustomField, condition clause.Expression) clause.Expression {
+	subquery := gorm.Expr("SELECT game_id FROM game_custom_values WHERE field_id = ? AND ?", field.ID, condition)
+	return gorm.Expr("games.id IN (?)", subquery)
+}
+
+func parseCustomBound(field models.CustomField, raw string) (interface{}, error) {
+	if field.Type == CustomFieldDate {
+		if _, err := time.Parse("2006-01-02", raw); err != nil {
+			return nil, invalidCustomField("%s: dates must be YYYY-MM-DD", field.Key)
+		}
+		return raw, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, invalidCustomField("%s needs a number", field.Key)
+	}
+	return number, nil
+}
+
+// findFold returns the element of list equal to value ignoring case, or ""
+func findFold(list []string, value string) string {
+	for _, element := range list {
+		if strings.EqualFold(element, value) {
+			return element
+		}
+	}
+	return ""
+}
+
+func containsFieldKey(fields []models.CustomField, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
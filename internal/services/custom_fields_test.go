@@ -0,0 +1,81 @@
+package services
+
+import (
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomValueFor(t *testing.T) {
+	enum := models.CustomField{ID: 1, Key: "edition", Type: CustomFieldEnum, Options: models.StringList{"Standard", "Collector's"}}
+	value, err := CustomValueFor(enum, "COLLECTOR'S")
+	require.NoError(t, err)
+	assert.Equal(t, "Collector's", value.Typed())
+
+	number := models.CustomField{ID: 2, Key: "price_paid", Type: CustomFieldNumber}
+	value, err = CustomValueFor(number, 12.5)
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, value.Typed())
+	value, err = CustomValueFor(number, nil)
+	require.NoError(t, err)
+	assert.Nil(t, value.Typed())
+	assert.Equal(t, uint(2), value.FieldID)
+
+	for _, tt := range []struct {
+		field   models.CustomField
+		value   interface{}
+		message string
+	}{
+		{number, "12", "price_paid needs a number"},
+		{enum, "Deluxe", "edition must be one of Standard, Collector's"},
+		{models.CustomField{Key: "finished_on", Type: CustomFieldDate}, "2024-13-01", "dates must be YYYY-MM-DD"},
+		{models.CustomField{Key: "boxed", Type: CustomFieldBoolean}, "true", "boxed needs true or false"},
+		{models.CustomField{Key: "notes", Type: CustomFieldText}, 3.0, "notes needs a string"},
+	} {
+		_, err := CustomValueFor(tt.field, tt.value)
+		if assert.ErrorIs(t, err, ErrInvalidCustomField, tt.value) {
+			assert.Contains(t, err.Error(), tt.message)
+		}
+	}
+}
+
+func TestSaveGameCustomValues(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	fields := []models.CustomField{
+		{Key: "price_paid", Name: "Price paid", Type: CustomFieldNumber},
+		{Key: "boxed", Name: "Boxed", Type: CustomFieldBoolean},
+	}
+	require.NoError(t, db.Create(&fields).Error)
+
+	values, err := ParseCustomValues(db, map[string]interface{}{"price_paid": 30.0, "boxed": true})
+	require.NoError(t, err)
+	require.NoError(t, SaveGameCustomValues(db, 1, values))
+	values, err = ParseCustomValues(db, map[string]interface{}{"price_paid": 25.0, "boxed": nil})
+	require.NoError(t, err)
+	require.NoError(t, SaveGameCustomValues(db, 1, values))
+
+	var game models.Game
+	require.NoError(t, db.Preload("CustomValues.Field").First(&game, 1).Error)
+	assert.Equal(t, map[string]interface{}{"price_paid": 25.0}, game.CustomFields)
+
+	_, err = ParseCustomValues(db, map[string]interface{}{"region": "PAL"})
+	assert.ErrorContains(t, err, `unknown custom field "region"`)
+}
+
+func TestCustomFieldCondition_Postgres(t *testing.T) {
+	pg := newPostgresStandIn(t)
+	field := models.CustomField{ID: 3, Key: "edition", Type: CustomFieldEnum}
+	condition, err := CustomFieldCondition(field, "Standard")
+	require.NoError(t, err)
+	stmt := pg.Where(condition).Order(CustomFieldOrder(field, true)).Find(&[]models.Game{}).Statement
+	assert.Equal(t, `SELECT * FROM "games" WHERE games.id IN (SELECT game_id FROM game_custom_values WHERE field_id = $1 AND LOWER(text_value) = LOWER($2)) `+
+		`ORDER BY (SELECT text_value FROM game_custom_values WHERE game_custom_values.game_id = games.id AND game_custom_values.field_id = $3) IS NULL, `+
+		`LOWER((SELECT text_value FROM game_custom_values WHERE game_custom_values.game_id = games.id AND game_custom_values.field_id = $4)) DESC, games.title ASC`,
+		stmt.SQL.String())
+
+	_, err = CustomFieldRangeCondition(field, "a", "")
+	assert.ErrorIs(t, err, ErrInvalidCustomField)
+}
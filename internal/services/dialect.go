@@ -0,0 +1,48 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Pelico runs on PostgreSQL in production and on SQLite in tests and small
+// installs. Conditions whose SQL differs between the two are built here, so
+// handlers and services never write dialect-specific SQL themselves.
+
+// IsPostgres reports whether db talks to PostgreSQL; anything else is treated as SQLite
+func IsPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// ContainsFold matches rows where column contains value, ignoring case.
+// % and _ in value match literally.
+func ContainsFold(db *gorm.DB, column, value string) clause.Expr {
+	pattern := "%" + escapeLike(value) + "%"
+	if IsPostgres(db) {
+		return gorm.Expr(column+` ILIKE ? ESCAPE '\'`, pattern)
+	}
+	// SQLite's LIKE folds ASCII only, so fold both sides the same way
+	return gorm.Expr("LOWER("+column+`) LIKE ? ESCAPE '\'`, strings.ToLower(pattern))
+}
+
+// EqualFold matches rows where column equals value, ignoring case
+func EqualFold(column, value string) clause.Expr {
+	return gorm.Expr("LOWER("+column+") = LOWER(?)", value)
+}
+
+// JSONArrayContains matches rows where column, a JSON array of strings, has
+// value as an element
+func JSONArrayContains(db *gorm.DB, column, value string) clause.Expr {
+	if IsPostgres(db) {
+		element, _ := json.Marshal([]string{value})
+		return gorm.Expr("CAST("+column+" AS jsonb) @> CAST(? AS jsonb)", string(element))
+	}
+	// The driver may store the array as a blob, which json_each reads as JSONB
+	return gorm.Expr("EXISTS (SELECT 1 FROM json_each(CAST("+column+" AS TEXT)) WHERE json_each.value = ?)", value)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
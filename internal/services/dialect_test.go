@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newPostgresStandIn renders PostgreSQL statements without a server
+func newPostgresStandIn(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=pelico"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return db
+}
+
+func TestDialectConditions(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 1).
+		Update("collection_formats", models.CollectionFormats{"physical", "digital"}).Error)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 3).
+		Update("collection_formats", models.CollectionFormats{"digital_extra"}).Error)
+	require.NoError(t, db.Create(&models.Game{Title: "100% Orange Juice", PlatformID: 2}).Error)
+
+	titles := func(db *gorm.DB) []string {
+		var titles []string
+		require.NoError(t, db.Model(&models.Game{}).Order("id").Pluck("title", &titles).Error)
+		return titles
+	}
+
+	assert.Equal(t, []string{"Hollow Knight"}, titles(db.Where(ContainsFold(db, "title", "LOW kn"))))
+	assert.Equal(t, []string{"100% Orange Juice"}, titles(db.Where(ContainsFold(db, "title", "0% o"))))
+	assert.Empty(t, titles(db.Where(ContainsFold(db, "title", "_"))))
+	assert.Equal(t, []string{"Tetris"}, titles(db.Where(EqualFold("title", "TETRIS"))))
+	assert.Equal(t, []string{"Super Mario World"}, titles(db.Where(JSONArrayContains(db, "collection_formats", "digital"))))
+	assert.Empty(t, titles(db.Where(JSONArrayContains(db, "collection_formats", "rom"))))
+
+	pg := newPostgresStandIn(t)
+	assert.True(t, IsPostgres(pg))
+	assert.False(t, IsPostgres(db))
+	var games []models.Game
+	stmt := pg.Where(ContainsFold(pg, "title", "50%_off")).Where(JSONArrayContains(pg, "collection_formats", "physical")).Find(&games).Statement
+	assert.Equal(t, `SELECT * FROM "games" WHERE title ILIKE $1 ESCAPE '\' AND CAST(collection_formats AS jsonb) @> CAST($2 AS jsonb)`, stmt.SQL.String())
+	assert.Equal(t, []interface{}{`%50\%\_off%`, `["physical"]`}, stmt.Vars)
+}
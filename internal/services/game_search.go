@@ -0,0 +1,649 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// Full-text search backends, picked from the database in use
+const (
+	searchPostgres = "postgres" // tsvector column with a GIN index
+	searchFTS5     = "fts5"     // SQLite FTS5 virtual table, ranked with bm25()
+	searchFTS4     = "fts4"     // SQLite default, in every go-sqlite3 build; ranked in Go
+)
+
+const (
+	searchIndexTable    = "game_search_index"
+	searchCallbackName  = "pelico:search_index"
+	searchMaxTerms      = 8
+	searchSnippetWords  = 24
+	searchRebuildChunk  = 200
+	searchHighlightOpen = "<mark>"
+	searchHighlightEnd  = "</mark>"
+)
+
+var ErrEmptySearchQuery = errors.New("search query has no words")
+
+// searchField is an indexed column, in index column order
+type searchField struct {
+	Column string
+	Name   string  // as reported in matched_fields
+	Weight float64 // relative importance when ranking
+}
+
+var searchFields = []searchField{
+	{"title", "title", 10},
+	{"alt_titles", "alternative_titles", 6},
+	{"description", "description", 2},
+	{"completion_notes", "completion_notes", 1.5},
+	{"session_notes", "session_notes", 1},
+}
+
+// SearchHit is one matching game. Title and Snippet are HTML-escaped with
+// <mark> around the matched words.
+type SearchHit struct {
+	Game          *models.Game `json:"game"`
+	Score         float64      `json:"score"`
+	Title         string       `json:"title"`
+	Snippet       string       `json:"snippet,omitempty"`
+	SnippetField  string       `json:"snippet_field,omitempty"`
+	MatchedFields []string     `json:"matched_fields"`
+}
+
+// SearchResults is one page of hits, best first
+type SearchResults struct {
+	Query string      `json:"query"`
+	Total int         `json:"total"`
+	Hits  []SearchHit `json:"hits"`
+}
+
+// SearchOptions narrows and pages a search
+type SearchOptions struct {
+	PlatformID uint
+	Limit      int
+	Offset     int
+}
+
+// GameSearch is the full-text index over game titles, alternative titles,
+// descriptions, completion notes and session notes. Setup registers GORM
+// callbacks that re-index a game whenever it, one of its sessions or one of
+// its alternative names is written through GORM.
+type GameSearch struct {
+	db      *gorm.DB
+	backend string
+}
+
+func NewGameSearch(db *gorm.DB) *GameSearch {
+	backend := searchFTS4
+	if IsPostgres(db) {
+		backend = searchPostgres
+	}
+	return &GameSearch{
+		db:      db,
+		backend: backend,
+	}
+}
+
+// Backend names the index implementation in use
+func (s *GameSearch) Backend() string {
+	return s.backend
+}
+
+// Setup checks the index created by the migrations, keeps it current from
+// then on and rebuilds it when it doesn't cover every game
+func (s *GameSearch) Setup() error {
+	if err := s.detectBackend(); err != nil {
+		return err
+	}
+	s.registerCallbacks()
+
+	var indexed, games int64
+	if err := s.db.Table(searchIndexTable).Count(&indexed).Error; err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.Game{}).Count(&games).Error; err != nil {
+		return err
+	}
+	if indexed != games {
+		return s.Rebuild()
+	}
+	return nil
+}
+
+// detectBackend picks the SQLite module the index was created with: FTS4 by
+// the migrations, FTS5 by earlier versions built with the sqlite_fts5 tag.
+// FTS4 is kept as the default so no build needs that tag.
+func (s *GameSearch) detectBackend() error {
+	if s.backend == searchPostgres {
+		if !s.db.Migrator().HasTable(searchIndexTable) {
+			return fmt.Errorf("%s is missing; run the database migrations", searchIndexTable)
+		}
+		return nil
+	}
+
+	var existing string
+	s.db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", searchIndexTable).Scan(&existing)
+	switch {
+	case existing == "":
+		return fmt.Errorf("%s is missing; run the database migrations", searchIndexTable)
+	case strings.Contains(strings.ToLower(existing), "fts5"):
+		s.backend = searchFTS5
+	default:
+		s.backend = searchFTS4
+	}
+	return nil
+}
+
+// registerCallbacks re-indexes games after GORM writes to games, sessions and
+// alternative names. Bulk writes by condition and raw SQL carry no IDs, so
+// code making them re-indexes the games itself (the backup restore runs
+// Rebuild); POST /search/reindex repairs anything else.
+func (s *GameSearch) registerCallbacks() {
+	callbacks := s.db.Callback()
+	if callbacks.Create().Get(searchCallbackName) != nil {
+		return
+	}
+	callbacks.Create().After("gorm:create").Register(searchCallbackName, s.afterWrite)
+	callbacks.Update().After("gorm:update").Register(searchCallbackName, s.afterWrite)
+	callbacks.Delete().After("gorm:delete").Register(searchCallbackName, s.afterWrite)
+}
+
+func (s *GameSearch) afterWrite(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.Schema == nil {
+		return
+	}
+	var key string
+	switch tx.Statement.Schema.Table {
+	case "games":
+		key = "ID"
+	case "play_sessions", "alternative_names":
+		key = "GameID"
+	default:
+		return
+	}
+	field := tx.Statement.Schema.LookUpField(key)
+	if field == nil {
+		return
+	}
+
+	var ids []uint
+	collect := func(value reflect.Value) {
+		if value.Kind() != reflect.Struct {
+			return
+		}
+		if id, zero := field.ValueOf(tx.Statement.Context, value); !zero {
+			if id, ok := id.(uint); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	value := reflect.Indirect(tx.Statement.ReflectValue)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			collect(reflect.Indirect(value.Index(i)))
+		}
+	default:
+		collect(value)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	// Same connection and transaction as the write, under a savepoint: a failure
+	// is logged rather than undoing the write, and repaired by the next Rebuild
+	err := tx.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+		return s.index(tx, ids)
+	})
+	if err != nil {
+		tx.Logger.Error(tx.Statement.Context, "search index update for games %v failed: %v", ids, err)
+	}
+}
+
+// Rebuild re-indexes every game
+func (s *GameSearch) Rebuild() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + searchIndexTable).Error; err != nil {
+			return err
+		}
+		var ids []uint
+		if err := tx.Model(&models.Game{}).Order("id").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for start := 0; start < len(ids); start += searchRebuildChunk {
+			end := min(start+searchRebuildChunk, len(ids))
+			if err := s.index(tx, ids[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// searchDocument is the indexed text of one game
+type searchDocument struct {
+	GameID uint
+	Values []string // in searchFields order
+}
+
+// index rewrites the index rows of the given games, dropping deleted ones
+func (s *GameSearch) index(db *gorm.DB, ids []uint) error {
+	documents, err := s.documents(db, ids)
+	if err != nil {
+		return err
+	}
+
+	key := s.keyColumn()
+	if err := db.Exec("DELETE FROM "+searchIndexTable+" WHERE "+key+" IN ?", ids).Error; err != nil {
+		return err
+	}
+	columns := []string{key}
+	placeholders := []string{"?"}
+	for _, field := range searchFields {
+		columns = append(columns, field.Column)
+		placeholders = append(placeholders, "?")
+	}
+	insert := "INSERT INTO " + searchIndexTable + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	for _, document := range documents {
+		args := []interface{}{document.GameID}
+		for _, value := range document.Values {
+			args = append(args, value)
+		}
+		if err := db.Exec(insert, args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// documents gathers the searchable text of the given games
+func (s *GameSearch) documents(db *gorm.DB, ids []uint) ([]searchDocument, error) {
+	var games []models.Game
+	if err := db.Select("id", "title", "description", "completion_notes").Where("id IN ?", ids).Order("id").Find(&games).Error; err != nil {
+		return nil, err
+	}
+	var names []models.AlternativeName
+	if err := db.Select("game_id", "name").Where("game_id IN ?", ids).Order("id").Find(&names).Error; err != nil {
+		return nil, err
+	}
+	var sessions []models.PlaySession
+	err := db.Select("game_id", "notes").
+		Where("game_id IN ? AND notes IS NOT NULL AND notes <> ''", ids).
+		Order("start_time").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	altTitles := make(map[uint][]string)
+	for _, name := range names {
+		altTitles[name.GameID] = append(altTitles[name.GameID], name.Name)
+	}
+	sessionNotes := make(map[uint][]string)
+	for _, session := range sessions {
+		sessionNotes[session.GameID] = append(sessionNotes[session.GameID], session.Notes)
+	}
+
+	documents := make([]searchDocument, len(games))
+	for i, game := range games {
+		documents[i] = searchDocument{
+			GameID: game.ID,
+			Values: []string{
+				game.Title,
+				strings.Join(altTitles[game.ID], "\n"),
+				game.Description,
+				game.CompletionNotes,
+				strings.Join(sessionNotes[game.ID], "\n"),
+			},
+		}
+	}
+	return documents, nil
+}
+
+func (s *GameSearch) keyColumn() string {
+	if s.backend == searchPostgres {
+		return "game_id"
+	}
+	return "rowid"
+}
+
+// searchRank is a matching game's score and the number of matches in all
+type searchRank struct {
+	GameID uint
+	Score  float64
+	Total  int
+}
+
+// Search finds games matching every word of the query, each as a prefix, best
+// matches first
+func (s *GameSearch) Search(query string, opts SearchOptions) (*SearchResults, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	var rows []searchRank
+	var err error
+	platformFilter := ""
+	args := []interface{}{}
+
+	switch s.backend {
+	case searchPostgres:
+		prefixed := make([]string, len(terms))
+		for i, term := range terms {
+			prefixed[i] = term + ":*"
+		}
+		args = append(args, strings.Join(prefixed, " & "))
+		if opts.PlatformID != 0 {
+			platformFilter = " AND game_id IN (SELECT id FROM games WHERE platform_id = ?)"
+			args = append(args, opts.PlatformID)
+		}
+		args = append(args, opts.Limit, opts.Offset)
+		// Normalization 1 divides by the document length so long descriptions don't dominate
+		err = s.db.Raw(`SELECT game_id, ts_rank(document, query, 1) AS score, COUNT(*) OVER () AS total
+			FROM `+searchIndexTable+`, to_tsquery('simple', ?) query
+			WHERE document @@ query`+platformFilter+`
+			ORDER BY score DESC, game_id LIMIT ? OFFSET ?`, args...).Scan(&rows).Error
+
+	case searchFTS5:
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = `"` + term + `"*`
+		}
+		weights := make([]string, len(searchFields))
+		for i, field := range searchFields {
+			weights[i] = fmt.Sprintf("%g", field.Weight)
+		}
+		args = append(args, strings.Join(quoted, " "))
+		if opts.PlatformID != 0 {
+			platformFilter = " AND rowid IN (SELECT id FROM games WHERE platform_id = ?)"
+			args = append(args, opts.PlatformID)
+		}
+		args = append(args, opts.Limit, opts.Offset)
+		// bm25() is lower for better matches, and can't share a query with window functions
+		err = s.db.Raw(`SELECT game_id, score, COUNT(*) OVER () AS total FROM (
+				SELECT rowid AS game_id, -bm25(`+searchIndexTable+`, `+strings.Join(weights, ", ")+`) AS score
+				FROM `+searchIndexTable+`
+				WHERE `+searchIndexTable+` MATCH ?`+platformFilter+`
+			) ranked
+			ORDER BY score DESC, game_id LIMIT ? OFFSET ?`, args...).Scan(&rows).Error
+
+	case searchFTS4:
+		rows, err = s.searchFTS4(terms, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResults{Query: query, Hits: []SearchHit{}}
+	if len(rows) == 0 {
+		return results, nil
+	}
+	results.Total = rows[0].Total
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.GameID
+	}
+	documents, err := s.indexedDocuments(ids)
+	if err != nil {
+		return nil, err
+	}
+	var games []models.Game
+	if err := s.db.Preload("Platform").Preload("Genres").Where("id IN ?", ids).Find(&games).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Game, len(games))
+	for i := range games {
+		byID[games[i].ID] = &games[i]
+	}
+
+	for _, row := range rows {
+		game, ok := byID[row.GameID]
+		if !ok {
+			continue // deleted since it was indexed
+		}
+		hit := SearchHit{
+			Game:          game,
+			Score:         row.Score,
+			Title:         highlightSearchTerms(game.Title, terms),
+			MatchedFields: []string{},
+		}
+		values := documents[row.GameID]
+		for i, field := range searchFields {
+			if i >= len(values) || !containsSearchTerm(values[i], terms) {
+				continue
+			}
+			hit.MatchedFields = append(hit.MatchedFields, field.Name)
+			if hit.Snippet == "" && field.Column != "title" {
+				hit.Snippet = searchSnippet(values[i], terms)
+				hit.SnippetField = field.Name
+			}
+		}
+		results.Hits = append(results.Hits, hit)
+	}
+	return results, nil
+}
+
+// searchFTS4 matches with FTS4 and ranks in Go: per field, term hits from
+// matchinfo() weighted by the field and by how rare the term is
+func (s *GameSearch) searchFTS4(terms []string, opts SearchOptions) ([]searchRank, error) {
+	prefixed := make([]string, len(terms))
+	for i, term := range terms {
+		prefixed[i] = term + "*"
+	}
+	args := []interface{}{strings.Join(prefixed, " ")}
+	platformFilter := ""
+	if opts.PlatformID != 0 {
+		platformFilter = " AND rowid IN (SELECT id FROM games WHERE platform_id = ?)"
+		args = append(args, opts.PlatformID)
+	}
+
+	var matches []struct {
+		GameID    uint
+		MatchInfo []byte
+	}
+	err := s.db.Raw(`SELECT rowid AS game_id, matchinfo(`+searchIndexTable+`, 'pcnx') AS match_info
+		FROM `+searchIndexTable+` WHERE `+searchIndexTable+` MATCH ?`+platformFilter, args...).Scan(&matches).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]searchRank, 0, len(matches))
+	for _, match := range matches {
+		rows = append(rows, searchRank{GameID: match.GameID, Score: fts4Score(match.MatchInfo), Total: len(matches)})
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		if rows[a].Score != rows[b].Score {
+			return rows[a].Score > rows[b].Score
+		}
+		return rows[a].GameID < rows[b].GameID
+	})
+
+	if opts.Offset >= len(rows) {
+		return nil, nil
+	}
+	return rows[opts.Offset:min(opts.Offset+opts.Limit, len(rows))], nil
+}
+
+// fts4Score scores a matchinfo 'pcnx' blob: phrases, columns, rows, then per
+// phrase and column the hits in this row, in all rows, and rows with a hit
+func fts4Score(info []byte) float64 {
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(values) < 3 {
+		return 0
+	}
+	phrases, columns, rows := int(values[0]), int(values[1]), float64(values[2])
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(searchFields); c++ {
+			at := 3 + 3*(p*columns+c)
+			if at+2 >= len(values) || values[at] == 0 {
+				continue
+			}
+			hits, withHit := float64(values[at]), float64(values[at+2])
+			rarity := math.Log(1 + rows/math.Max(1, withHit))
+			score += searchFields[c].Weight * (1 + math.Log(hits)) * rarity
+		}
+	}
+	return score
+}
+
+// indexedDocuments loads the indexed text of the given games
+func (s *GameSearch) indexedDocuments(ids []uint) (map[uint][]string, error) {
+	columns := []string{s.keyColumn() + " AS game_id"}
+	for _, field := range searchFields {
+		columns = append(columns, field.Column)
+	}
+	rows, err := s.db.Raw("SELECT "+strings.Join(columns, ", ")+" FROM "+searchIndexTable+" WHERE "+s.keyColumn()+" IN ?", ids).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := make(map[uint][]string, len(ids))
+	for rows.Next() {
+		var id uint
+		values := make([]string, len(searchFields))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		documents[id] = values
+	}
+	return documents, rows.Err()
+}
+
+// searchTerms splits a query into lower-case words of letters and digits,
+// which also keeps the backends' query syntax out of user input
+func searchTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isNotSearchWordRune) {
+		if !seen[word] && len(terms) < searchMaxTerms {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func isNotSearchWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// searchWord is a word's byte range within a text
+type searchWord struct {
+	start, end int
+	match      bool
+}
+
+func searchWords(text string, terms []string) []searchWord {
+	var words []searchWord
+	start := -1
+	for i, r := range text + " " {
+		if !isNotSearchWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			word := strings.ToLower(text[start:i])
+			match := false
+			for _, term := range terms {
+				if strings.HasPrefix(word, term) {
+					match = true
+					break
+				}
+			}
+			words = append(words, searchWord{start: start, end: i, match: match})
+			start = -1
+		}
+	}
+	return words
+}
+
+func containsSearchTerm(text string, terms []string) bool {
+	for _, word := range searchWords(text, terms) {
+		if word.match {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightSearchTerms HTML-escapes text and marks the words matching a term
+func highlightSearchTerms(text string, terms []string) string {
+	return markSearchWords(text, 0, len(text), searchWords(text, terms))
+}
+
+func markSearchWords(text string, from, to int, words []searchWord) string {
+	var out strings.Builder
+	at := from
+	for _, word := range words {
+		if !word.match || word.start < from || word.end > to {
+			continue
+		}
+		out.WriteString(html.EscapeString(text[at:word.start]))
+		out.WriteString(searchHighlightOpen)
+		out.WriteString(html.EscapeString(text[word.start:word.end]))
+		out.WriteString(searchHighlightEnd)
+		at = word.end
+	}
+	out.WriteString(html.EscapeString(text[at:to]))
+	return out.String()
+}
+
+// searchSnippet returns about searchSnippetWords words around the first match,
+// highlighted, with an ellipsis where text was cut
+func searchSnippet(text string, terms []string) string {
+	words := searchWords(text, terms)
+	first := -1
+	for i, word := range words {
+		if word.match {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	from := max(0, first-searchSnippetWords/4)
+	to := min(len(words), from+searchSnippetWords)
+	start, end := words[from].start, words[to-1].end
+	if from == 0 {
+		start = 0
+	}
+	if to == len(words) {
+		end = len(text)
+	}
+
+	snippet := strings.Join(strings.Fields(markSearchWords(text, start, end, words)), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
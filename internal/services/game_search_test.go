@@ -0,0 +1,146 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pelico/internal/database"
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func searchTitles(results *SearchResults) []string {
+	titles := make([]string, len(results.Hits))
+	for i, hit := range results.Hits {
+		titles[i] = hit.Game.Title
+	}
+	return titles
+}
+
+// newTestSearchDB is the playtime test database with the search index
+// created by its migration
+func newTestSearchDB(t *testing.T) *gorm.DB {
+	db := newTestPlaytimeDB(t)
+	migrations, err := database.Migrations(database.DialectSQLite)
+	require.NoError(t, err)
+	for _, migration := range migrations {
+		if migration.Name == "game_search_index" {
+			require.NoError(t, db.Exec(migration.Up).Error)
+			return db
+		}
+	}
+	t.Fatal("no game_search_index migration")
+	return nil
+}
+
+func TestGameSearch(t *testing.T) {
+	db := newTestSearchDB(t)
+	require.NoError(t, db.Model(&models.Game{}).Where("id = ?", 2).
+		Update("description", "A time-travelling RPG where a knight who became a frog joins the party.").Error)
+	require.NoError(t, db.Create(&models.AlternativeName{GameID: 1, Name: "Super Mario Bros. 4"}).Error)
+
+	// The index table comes from the migrations
+	assert.Error(t, NewGameSearch(newTestPlaytimeDB(t)).Setup())
+
+	// Games written before Setup are picked up by the initial rebuild
+	search := NewGameSearch(db)
+	require.NoError(t, search.Setup())
+	assert.Equal(t, searchFTS4, search.Backend())
+
+	// Title matches outrank description matches; words match as prefixes
+	results, err := search.Search("knig", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, results.Total)
+	assert.Equal(t, []string{"Hollow Knight", "Chrono Trigger"}, searchTitles(results))
+	assert.Equal(t, "Hollow <mark>Knight</mark>", results.Hits[0].Title)
+	assert.Equal(t, []string{"title"}, results.Hits[0].MatchedFields)
+	assert.Equal(t, "description", results.Hits[1].SnippetField)
+	assert.Equal(t, "A time-travelling RPG where a <mark>knight</mark> who became a frog joins the party.", results.Hits[1].Snippet)
+	assert.Greater(t, results.Hits[0].Score, results.Hits[1].Score)
+
+	// Every word must match, in any field
+	results, err = search.Search("mario bros", SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Super Mario World"}, searchTitles(results))
+	assert.Equal(t, []string{"title", "alternative_titles"}, results.Hits[0].MatchedFields)
+	assert.Equal(t, "Super <mark>Mario</mark> <mark>Bros</mark>. 4", results.Hits[0].Snippet)
+
+	// Paging and platform filter
+	results, err = search.Search("knight", SearchOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, results.Total)
+	assert.Equal(t, []string{"Chrono Trigger"}, searchTitles(results))
+	results, err = search.Search("knight", SearchOptions{PlatformID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hollow Knight"}, searchTitles(results))
+
+	// Query syntax is not passed through
+	results, err = search.Search(`"tetris" OR NEAR(*`, SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results.Hits)
+	_, err = search.Search(" -*- ", SearchOptions{})
+	assert.ErrorIs(t, err, ErrEmptySearchQuery)
+}
+
+func TestGameSearch_FollowsWrites(t *testing.T) {
+	db := newTestSearchDB(t)
+	search := NewGameSearch(db)
+	require.NoError(t, search.Setup())
+
+	// Session notes are indexed as they are written
+	end := time.Date(2024, 5, 1, 21, 0, 0, 0, time.UTC)
+	session := models.PlaySession{GameID: 4, StartTime: end.Add(-time.Hour), EndTime: &end, Duration: 60,
+		Notes: "Finally cleared level 29 after the speed-up; &lt;marathon&gt; next"}
+	require.NoError(t, db.Create(&session).Error)
+	results, err := search.Search("marathon", SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Tetris"}, searchTitles(results))
+	assert.Equal(t, "session_notes", results.Hits[0].SnippetField)
+	assert.Contains(t, results.Hits[0].Snippet, "&amp;lt;<mark>marathon</mark>&amp;gt;")
+
+	require.NoError(t, db.Delete(&session).Error)
+	results, err = search.Search("marathon", SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results.Hits)
+
+	// Game updates, completion notes and new games
+	game := models.Game{ID: 3}
+	require.NoError(t, db.Model(&game).Updates(map[string]interface{}{"title": "Hollow Knight: Silksong", "completion_notes": "Beat every boss"}).Error)
+	results, err = search.Search("silk boss", SearchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Hollow Knight: Silksong"}, searchTitles(results))
+	assert.Equal(t, []string{"title", "completion_notes"}, results.Hits[0].MatchedFields)
+
+	require.NoError(t, db.Create(&models.Game{Title: "Silksong Speedrun Practice", PlatformID: 2}).Error)
+	results, err = search.Search("silksong", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, results.Total)
+
+	require.NoError(t, db.Delete(&models.Game{ID: 3}).Error)
+	results, err = search.Search("silksong", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Silksong Speedrun Practice"}, searchTitles(results))
+
+	// Rebuild catches writes made around GORM
+	require.NoError(t, db.Exec("UPDATE games SET description = 'Falling blocks' WHERE id = 4").Error)
+	require.NoError(t, search.Rebuild())
+	results, err = search.Search("blocks", SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tetris"}, searchTitles(results))
+}
+
+func TestSearchSnippet(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen " +
+		"sixteen seventeen eighteen nineteen twenty twentyone twentytwo twentythree twentyfour twentyfive " +
+		"twentysix twentyseven twentyeight twentynine thirty target thirtyone thirtytwo"
+	snippet := searchSnippet(text, []string{"target"})
+	assert.True(t, len(snippet) < len(text))
+	assert.Contains(t, snippet, "<mark>target</mark> thirtyone thirtytwo")
+	assert.Regexp(t, "^…", snippet)
+	assert.Empty(t, searchSnippet(text, []string{"absent"}))
+
+	assert.Equal(t, []string{"zelda", "ocarina", "n64", "64"}, searchTerms(`Zelda: "Ocarina" (N64)? zelda 64`))
+}
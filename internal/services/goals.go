@@ -0,0 +1,468 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// Goal kinds
+const (
+	GoalFinishGames = "finish_games" // games marked completed or 100% in the period
+	GoalPlayTime    = "play_time"    // hours played in the period
+	GoalPlayDays    = "play_days"    // days with at least one session in the period
+)
+
+// Goal periods; week, month and year goals repeat every calendar period
+const (
+	GoalPeriodWeek   = "week"
+	GoalPeriodMonth  = "month"
+	GoalPeriodYear   = "year"
+	GoalPeriodCustom = "custom"
+)
+
+// Goal statuses
+const (
+	GoalCompleted = "completed"
+	GoalOnTrack   = "on_track"
+	GoalBehind    = "behind"
+	GoalMissed    = "missed"
+	GoalUpcoming  = "upcoming"
+)
+
+var ErrInvalidGoal = errors.New("invalid goal")
+
+// goalStreakLimit bounds how many earlier periods a goal streak looks back
+const goalStreakLimit = 104
+
+// GoalProgress is a goal's standing in its current period
+type GoalProgress struct {
+	Goal      models.Goal `json:"goal"`
+	From      string      `json:"from"` // inclusive dates of the period measured
+	To        string      `json:"to"`
+	Current   float64     `json:"current"`
+	Target    float64     `json:"target"`
+	Percent   float64     `json:"percent"`  // of the target, capped at 100
+	Expected  float64     `json:"expected"` // progress by now at a steady pace
+	Remaining float64     `json:"remaining"`
+	DaysLeft  int         `json:"days_left"`
+	Status    string      `json:"status"`
+	// Streak counts the consecutive earlier periods the target was met, for repeating goals
+	Streak   int    `json:"streak"`
+	Reminder string `json:"reminder,omitempty"`
+}
+
+// PlayStreak tracks consecutive days with play
+type PlayStreak struct {
+	Current     int    `json:"current"` // ends today, or yesterday if not played yet today
+	Longest     int    `json:"longest"`
+	LongestFrom string `json:"longest_from,omitempty"`
+	LongestTo   string `json:"longest_to,omitempty"`
+	LastPlayed  string `json:"last_played,omitempty"`
+	PlayedToday bool   `json:"played_today"`
+}
+
+// GoalSummary is the dashboard view of all active goals
+type GoalSummary struct {
+	Timezone  string         `json:"timezone"`
+	Streak    PlayStreak     `json:"streak"`
+	Goals     []GoalProgress `json:"goals"`
+	Statuses  map[string]int `json:"statuses"`
+	Reminders []string       `json:"reminders"`
+}
+
+// GoalTracker measures goals against play sessions and game completion
+type GoalTracker struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewGoalTracker(db *gorm.DB) *GoalTracker {
+	return &GoalTracker{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// ValidateGoal checks a goal's kind, period, target and dates fit together
+func ValidateGoal(goal *models.Goal) error {
+	switch goal.Kind {
+	case GoalFinishGames, GoalPlayTime, GoalPlayDays:
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidGoal, goal.Kind)
+	}
+	if goal.Target <= 0 {
+		return fmt.Errorf("%w: target must be positive", ErrInvalidGoal)
+	}
+	if goal.Kind != GoalPlayTime && goal.Target != math.Trunc(goal.Target) {
+		return fmt.Errorf("%w: %s target must be a whole number", ErrInvalidGoal, goal.Kind)
+	}
+
+	switch goal.Period {
+	case GoalPeriodWeek, GoalPeriodMonth, GoalPeriodYear:
+		if goal.StartDate != "" || goal.EndDate != "" {
+			return fmt.Errorf("%w: start_date and end_date are only used with a custom period", ErrInvalidGoal)
+		}
+	case GoalPeriodCustom:
+		if _, err := NewPlaytimeRange(goal.StartDate, goal.EndDate, time.UTC, time.Now()); err != nil || goal.StartDate == "" || goal.EndDate == "" {
+			return fmt.Errorf("%w: a custom period needs start_date and end_date (YYYY-MM-DD), start first", ErrInvalidGoal)
+		}
+	default:
+		return fmt.Errorf("%w: unknown period %q", ErrInvalidGoal, goal.Period)
+	}
+
+	if goal.Kind == GoalPlayDays {
+		days := map[string]int{GoalPeriodWeek: 7, GoalPeriodMonth: 31, GoalPeriodYear: 366}[goal.Period]
+		if goal.Period == GoalPeriodCustom {
+			r, _ := NewPlaytimeRange(goal.StartDate, goal.EndDate, time.UTC, time.Now())
+			days = r.Days()
+		}
+		if goal.Target > float64(days) {
+			return fmt.Errorf("%w: the period has fewer than %.0f days", ErrInvalidGoal, goal.Target)
+		}
+	}
+	return nil
+}
+
+// Progress measures a goal over its current period in loc
+func (t *GoalTracker) Progress(goal models.Goal, loc *time.Location) (*GoalProgress, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	now := t.now().In(loc)
+	r, err := goalPeriod(&goal, now, loc)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := t.activity(&goal, r)
+	if err != nil {
+		return nil, err
+	}
+	current := activity.measure(&goal, r)
+
+	progress := &GoalProgress{
+		Goal:      goal,
+		From:      r.FirstDay(),
+		To:        r.LastDay(),
+		Current:   current,
+		Target:    goal.Target,
+		Percent:   math.Min(100, math.Round(current/goal.Target*1000)/10),
+		Remaining: math.Max(0, roundGoalValue(goal.Target-current)),
+	}
+
+	elapsed := now.Sub(r.From).Seconds() / r.To.Sub(r.From).Seconds()
+	elapsed = math.Max(0, math.Min(1, elapsed))
+	progress.Expected = roundGoalValue(goal.Target * elapsed)
+	if now.Before(r.To) {
+		for day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc); day.Before(r.To); day = day.AddDate(0, 0, 1) {
+			progress.DaysLeft++
+		}
+	}
+
+	switch {
+	case current >= goal.Target:
+		progress.Status = GoalCompleted
+	case now.Before(r.From):
+		progress.Status = GoalUpcoming
+	case !now.Before(r.To):
+		progress.Status = GoalMissed
+	case current >= progress.Expected:
+		progress.Status = GoalOnTrack
+	default:
+		progress.Status = GoalBehind
+		progress.Reminder = goalReminder(&goal, progress.Remaining, r)
+	}
+
+	if goal.Period != GoalPeriodCustom {
+		if progress.Streak, err = t.goalStreak(&goal, r, loc); err != nil {
+			return nil, err
+		}
+	}
+	return progress, nil
+}
+
+// goalStreak counts the consecutive periods before r, since the goal was
+// created, in which the target was met. Activity over all the periods looked
+// back on is loaded at once and split by period in memory.
+func (t *GoalTracker) goalStreak(goal *models.Goal, r PlaytimeRange, loc *time.Location) (int, error) {
+	created := goal.CreatedAt.In(loc)
+	var periods []PlaytimeRange // newest first
+	for len(periods) < goalStreakLimit {
+		previous, err := goalPeriod(goal, r.From.AddDate(0, 0, -1), loc)
+		if err != nil {
+			return 0, err
+		}
+		if !created.Before(previous.To) {
+			break
+		}
+		periods = append(periods, previous)
+		r = previous
+	}
+	if len(periods) == 0 {
+		return 0, nil
+	}
+
+	window := PlaytimeRange{From: periods[len(periods)-1].From, To: periods[0].To, Location: loc}
+	activity, err := t.activity(goal, window)
+	if err != nil {
+		return 0, err
+	}
+	streak := 0
+	for _, period := range periods {
+		if activity.measure(goal, period) < goal.Target {
+			break
+		}
+		streak++
+	}
+	return streak, nil
+}
+
+// goalActivity is what a goal is measured from over a range: the completion
+// dates of finished games, or the completed sessions of the games it counts
+type goalActivity struct {
+	finished []time.Time
+	sessions []playedSession
+}
+
+// activity loads the goal's activity over r
+func (t *GoalTracker) activity(goal *models.Goal, r PlaytimeRange) (*goalActivity, error) {
+	games, err := t.goalGames(goal)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &goalActivity{}
+	if goal.Kind == GoalFinishGames {
+		var finished []struct {
+			ID             uint
+			CompletionDate *time.Time
+		}
+		query := t.db.Model(&models.Game{}).
+			Select("id", "completion_date").
+			Where("completion_status IN ? AND completion_date IS NOT NULL", []string{"completed", "100_percent"})
+		if games != nil {
+			query = query.Where("id IN ?", games)
+		}
+		if err := query.Find(&finished).Error; err != nil {
+			return nil, err
+		}
+		for _, game := range finished {
+			if !game.CompletionDate.Before(r.From) && game.CompletionDate.Before(r.To) {
+				activity.finished = append(activity.finished, *game.CompletionDate)
+			}
+		}
+		return activity, nil
+	}
+
+	played, err := NewPlaytimeStats(t.db).sessions(r)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[uint]bool)
+	for _, id := range games {
+		allowed[id] = true
+	}
+	for _, session := range played {
+		if games == nil || allowed[session.GameID] {
+			activity.sessions = append(activity.sessions, session)
+		}
+	}
+	return activity, nil
+}
+
+// measure returns the goal's progress over r, which must lie within the range
+// the activity was loaded for: games finished, hours played (to one decimal)
+// or days played
+func (a *goalActivity) measure(goal *models.Goal, r PlaytimeRange) float64 {
+	if goal.Kind == GoalFinishGames {
+		count := 0
+		for _, date := range a.finished {
+			if !date.Before(r.From) && date.Before(r.To) {
+				count++
+			}
+		}
+		return float64(count)
+	}
+
+	minutes := 0
+	days := make(map[string]bool)
+	for _, session := range a.sessions {
+		if session.StartTime.Before(r.From) || !session.StartTime.Before(r.To) {
+			continue
+		}
+		minutes += session.Duration
+		days[session.StartTime.In(r.Location).Format("2006-01-02")] = true
+	}
+	if goal.Kind == GoalPlayDays {
+		return float64(len(days))
+	}
+	return minutesToHours(minutes)
+}
+
+// goalGames returns the IDs of the games a goal counts, or nil for all games
+func (t *GoalTracker) goalGames(goal *models.Goal) ([]uint, error) {
+	if goal.GameID != nil {
+		return []uint{*goal.GameID}, nil
+	}
+	if goal.PlatformID == nil {
+		return nil, nil
+	}
+	ids := []uint{}
+	err := t.db.Model(&models.Game{}).Where("platform_id = ?", *goal.PlatformID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Streak finds the current and longest runs of consecutive days played in loc
+func (t *GoalTracker) Streak(loc *time.Location) (PlayStreak, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	var starts []time.Time
+	err := t.db.Model(&models.PlaySession{}).
+		Where("end_time IS NOT NULL AND duration > 0").
+		Pluck("start_time", &starts).Error
+	if err != nil {
+		return PlayStreak{}, err
+	}
+
+	played := make(map[string]bool)
+	days := make([]string, 0, len(starts))
+	for _, start := range starts {
+		day := start.In(loc).Format("2006-01-02")
+		if !played[day] {
+			played[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+
+	var streak PlayStreak
+	if len(days) == 0 {
+		return streak, nil
+	}
+	streak.LastPlayed = days[len(days)-1]
+
+	run := 0
+	var previous time.Time
+	for _, day := range days {
+		date, _ := time.ParseInLocation("2006-01-02", day, loc)
+		if run > 0 && date.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > streak.Longest {
+			streak.Longest = run
+			streak.LongestFrom = date.AddDate(0, 0, 1-run).Format("2006-01-02")
+			streak.LongestTo = day
+		}
+		previous = date
+	}
+
+	now := t.now().In(loc)
+	today := now.Format("2006-01-02")
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	streak.PlayedToday = played[today]
+	if streak.LastPlayed == today || streak.LastPlayed == yesterday {
+		streak.Current = run
+	}
+	return streak, nil
+}
+
+// Summary measures all goals that aren't archived and adds the play streak
+// and reminders for goals falling behind
+func (t *GoalTracker) Summary(loc *time.Location) (*GoalSummary, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	var goals []models.Goal
+	if err := t.db.Preload("Game").Preload("Platform").Where("archived = ?", false).Order("id").Find(&goals).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &GoalSummary{
+		Timezone:  loc.String(),
+		Goals:     []GoalProgress{},
+		Statuses:  map[string]int{},
+		Reminders: []string{},
+	}
+	streak, err := t.Streak(loc)
+	if err != nil {
+		return nil, err
+	}
+	summary.Streak = streak
+	if streak.Current > 1 && !streak.PlayedToday {
+		summary.Reminders = append(summary.Reminders, fmt.Sprintf("Play today to keep your %d-day streak going", streak.Current))
+	}
+
+	for _, goal := range goals {
+		progress, err := t.Progress(goal, loc)
+		if err != nil {
+			return nil, err
+		}
+		summary.Goals = append(summary.Goals, *progress)
+		summary.Statuses[progress.Status]++
+		if progress.Reminder != "" {
+			summary.Reminders = append(summary.Reminders, progress.Reminder)
+		}
+	}
+	return summary, nil
+}
+
+// goalPeriod returns the period containing now: the calendar week (from
+// Monday), month or year, or the custom date range
+func goalPeriod(goal *models.Goal, now time.Time, loc *time.Location) (PlaytimeRange, error) {
+	now = now.In(loc)
+	var from, to time.Time
+	switch goal.Period {
+	case GoalPeriodWeek:
+		from = periodStart(now, PlaytimeByWeek)
+		to = from.AddDate(0, 0, 7)
+	case GoalPeriodMonth:
+		from = periodStart(now, PlaytimeByMonth)
+		to = from.AddDate(0, 1, 0)
+	case GoalPeriodYear:
+		from = time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+		to = from.AddDate(1, 0, 0)
+	case GoalPeriodCustom:
+		return NewPlaytimeRange(goal.StartDate, goal.EndDate, loc, now)
+	default:
+		return PlaytimeRange{}, fmt.Errorf("%w: unknown period %q", ErrInvalidGoal, goal.Period)
+	}
+	return PlaytimeRange{From: from, To: to, Location: loc}, nil
+}
+
+// goalReminder says what is left to do for a goal that's behind
+func goalReminder(goal *models.Goal, remaining float64, r PlaytimeRange) string {
+	deadline := r.To.AddDate(0, 0, -1).Format("Mon, Jan 2")
+	subject := ""
+	if goal.Game != nil {
+		subject = " of " + goal.Game.Title
+	} else if goal.Platform != nil {
+		subject = " on " + goal.Platform.Name
+	}
+
+	switch goal.Kind {
+	case GoalFinishGames:
+		return fmt.Sprintf("%s: finish %s%s by %s", goal.Title, pluralize(int(remaining), "more game"), subject, deadline)
+	case GoalPlayDays:
+		return fmt.Sprintf("%s: play%s on %s by %s", goal.Title, subject, pluralize(int(remaining), "more day"), deadline)
+	}
+	return fmt.Sprintf("%s: play %.1f more hours%s by %s", goal.Title, remaining, subject, deadline)
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// roundGoalValue rounds to one decimal like the hours goals are measured in
+func roundGoalValue(value float64) float64 {
+	return math.Round(value*10) / 10
+}
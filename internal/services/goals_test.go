@@ -0,0 +1,158 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestValidateGoal(t *testing.T) {
+	tests := []struct {
+		name  string
+		goal  models.Goal
+		valid bool
+	}{
+		{"yearly games", models.Goal{Kind: GoalFinishGames, Target: 12, Period: GoalPeriodYear}, true},
+		{"weekly hours", models.Goal{Kind: GoalPlayTime, Target: 2.5, Period: GoalPeriodWeek}, true},
+		{"custom days", models.Goal{Kind: GoalPlayDays, Target: 10, Period: GoalPeriodCustom, StartDate: "2024-06-01", EndDate: "2024-06-30"}, true},
+		{"unknown kind", models.Goal{Kind: "beat_bosses", Target: 1, Period: GoalPeriodYear}, false},
+		{"no target", models.Goal{Kind: GoalPlayTime, Period: GoalPeriodWeek}, false},
+		{"half a game", models.Goal{Kind: GoalFinishGames, Target: 1.5, Period: GoalPeriodYear}, false},
+		{"eight days a week", models.Goal{Kind: GoalPlayDays, Target: 8, Period: GoalPeriodWeek}, false},
+		{"dates on a weekly goal", models.Goal{Kind: GoalPlayTime, Target: 1, Period: GoalPeriodWeek, StartDate: "2024-06-01"}, false},
+		{"custom without end", models.Goal{Kind: GoalPlayTime, Target: 1, Period: GoalPeriodCustom, StartDate: "2024-06-01"}, false},
+		{"custom backwards", models.Goal{Kind: GoalPlayTime, Target: 1, Period: GoalPeriodCustom, StartDate: "2024-06-30", EndDate: "2024-06-01"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGoal(&tt.goal)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidGoal)
+			}
+		})
+	}
+}
+
+func TestGoalTracker(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	at := func(month time.Month, day, minutes int, gameID uint) {
+		addPlayedSession(t, db, gameID, time.Date(2024, month, day, 19, 0, 0, 0, time.UTC), minutes)
+	}
+	for day := 1; day <= 5; day++ {
+		at(time.February, day, 30, 1)
+	}
+	at(time.March, 4, 200, 2)
+	at(time.March, 10, 60, 1)
+	at(time.March, 11, 60, 2)
+	at(time.March, 12, 90, 2)
+	at(time.March, 13, 30, 2)
+
+	finished := func(id uint, status string, date time.Time) {
+		require.NoError(t, db.Model(&models.Game{ID: id}).Updates(map[string]interface{}{
+			"completion_status": status, "completion_date": date,
+		}).Error)
+	}
+	finished(2, "completed", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC))
+	finished(3, "100_percent", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	finished(4, "completed", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	gameID := uint(2)
+	goals := []models.Goal{
+		{Title: "Chrono weekly", Kind: GoalPlayTime, Target: 3, Period: GoalPeriodWeek, GameID: &gameID},
+		{Title: "Finish 12", Kind: GoalFinishGames, Target: 12, Period: GoalPeriodYear},
+		{Title: "Play often", Kind: GoalPlayDays, Target: 10, Period: GoalPeriodMonth},
+		{Title: "January marathon", Kind: GoalPlayTime, Target: 10, Period: GoalPeriodCustom, StartDate: "2024-01-01", EndDate: "2024-01-31"},
+		{Title: "April", Kind: GoalPlayTime, Target: 10, Period: GoalPeriodCustom, StartDate: "2024-04-01", EndDate: "2024-04-30"},
+		{Title: "Old", Kind: GoalPlayTime, Target: 1, Period: GoalPeriodWeek, Archived: true},
+	}
+	for i := range goals {
+		goals[i].CreatedAt = created
+		require.NoError(t, db.Create(&goals[i]).Error)
+	}
+
+	tracker := NewGoalTracker(db)
+	now := time.Date(2024, 3, 13, 21, 0, 0, 0, time.UTC) // Wednesday
+	tracker.now = func() time.Time { return now }
+
+	summary, err := tracker.Summary(time.UTC)
+	require.NoError(t, err)
+	require.Len(t, summary.Goals, 5, "archived goals are left out")
+	byTitle := make(map[string]GoalProgress)
+	for _, progress := range summary.Goals {
+		byTitle[progress.Goal.Title] = progress
+	}
+
+	weekly := byTitle["Chrono weekly"]
+	assert.Equal(t, "2024-03-11", weekly.From)
+	assert.Equal(t, "2024-03-17", weekly.To)
+	assert.Equal(t, 3.0, weekly.Current)
+	assert.Equal(t, GoalCompleted, weekly.Status)
+	assert.Equal(t, 100.0, weekly.Percent)
+	assert.Equal(t, 5, weekly.DaysLeft)
+	assert.Equal(t, 1, weekly.Streak, "met the week before, not the week it was created in")
+
+	yearly := byTitle["Finish 12"]
+	assert.Equal(t, 2.0, yearly.Current)
+	assert.Equal(t, 10.0, yearly.Remaining)
+	assert.Equal(t, GoalBehind, yearly.Status)
+	assert.Equal(t, "Finish 12: finish 10 more games by Tue, Dec 31", yearly.Reminder)
+
+	monthly := byTitle["Play often"]
+	assert.Equal(t, 5.0, monthly.Current)
+	assert.Equal(t, GoalOnTrack, monthly.Status)
+	assert.Empty(t, monthly.Reminder)
+
+	assert.Equal(t, GoalMissed, byTitle["January marathon"].Status)
+	assert.Equal(t, GoalUpcoming, byTitle["April"].Status)
+	assert.Equal(t, map[string]int{GoalCompleted: 1, GoalBehind: 1, GoalOnTrack: 1, GoalMissed: 1, GoalUpcoming: 1}, summary.Statuses)
+	assert.Equal(t, []string{yearly.Reminder}, summary.Reminders)
+
+	assert.Equal(t, PlayStreak{
+		Current: 4, Longest: 5, LongestFrom: "2024-02-01", LongestTo: "2024-02-05",
+		LastPlayed: "2024-03-13", PlayedToday: true,
+	}, summary.Streak)
+
+	// A day later the streak is still alive but needs playing today
+	now = now.AddDate(0, 0, 1)
+	summary, err = tracker.Summary(time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 4, summary.Streak.Current)
+	assert.False(t, summary.Streak.PlayedToday)
+	assert.Contains(t, summary.Reminders, "Play today to keep your 4-day streak going")
+
+	// Two days later it's broken
+	now = now.AddDate(0, 0, 1)
+	streak, err := tracker.Streak(time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 0, streak.Current)
+	assert.Equal(t, 5, streak.Longest)
+}
+
+func TestGoalTracker_StreakLoadsActivityOnce(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	now := time.Date(2024, 6, 12, 12, 0, 0, 0, time.UTC) // Wednesday
+	for week := 1; week <= 10; week++ {
+		addPlayedSession(t, db, 1, now.AddDate(0, 0, -7*week), 60)
+	}
+	goal := models.Goal{Title: "Weekly hour", Kind: GoalPlayTime, Target: 1, Period: GoalPeriodWeek, CreatedAt: now.AddDate(-3, 0, 0)}
+	require.NoError(t, db.Create(&goal).Error)
+
+	queries := 0
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count_queries", func(*gorm.DB) {
+		queries++
+	}))
+	tracker := NewGoalTracker(db)
+	tracker.now = func() time.Time { return now }
+	progress, err := tracker.Progress(goal, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 10, progress.Streak)
+	assert.Equal(t, 2, queries, "one load for the current week, one for the streak's lookback")
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// SourceICalendar marks sessions imported from calendar events
+const SourceICalendar = "ical"
+
+var ErrInvalidCalendar = errors.New("invalid iCalendar data")
+
+// icsGameIDPattern finds a game ID written into an event title as "#42"
+var icsGameIDPattern = regexp.MustCompile(`#(\d+)\b`)
+
+// icsSummaryPrefixes are stripped from event titles before matching games
+var icsSummaryPrefixes = []string{"playing:", "playing", "played:", "played", "play:", "gaming:"}
+
+// ICalendarImporter turns calendar events into play sessions. An event is
+// matched to a game by its X-PELICO-GAME-ID property (set on exported feeds),
+// a "#<id>" in its title, or a title equal to the game's.
+type ICalendarImporter struct {
+	db    *gorm.DB
+	timer *SessionTimer
+}
+
+func NewICalendarImporter(db *gorm.DB, timer *SessionTimer) *ICalendarImporter {
+	return &ICalendarImporter{db: db, timer: timer}
+}
+
+// Import creates a session for each timed event matching a game. Floating
+// times are read in loc. Events are remembered by UID, so importing the same
+// calendar again only adds new events; events exported by Pelico itself are
+// skipped while their session still exists. Only the first occurrence of a
+// recurring event is imported. Events overlapping existing sessions follow the
+// timer's overlap policy and are listed in Errors either way.
+func (i *ICalendarImporter) Import(r io.Reader, loc *time.Location) (*PlaytimeImportResult, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	events, err := parseICalendar(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	games, err := i.loadGameTitles()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlaytimeImportResult{
+		Source:    SourceICalendar,
+		Unmatched: []string{},
+		Errors:    []string{},
+	}
+	now := time.Now()
+	for _, event := range events {
+		if strings.EqualFold(event.text("STATUS"), "CANCELLED") {
+			continue
+		}
+		result.ItemsFound++
+		summary := event.text("SUMMARY")
+		label := summary
+		if label == "" {
+			label = "(untitled event)"
+		}
+
+		if id := exportedSessionID(event.text("UID")); id != 0 {
+			var count int64
+			if err := i.db.Model(&models.PlaySession{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				result.Unchanged++
+				continue
+			}
+		}
+
+		gameID := games.match(event)
+		if gameID == 0 {
+			result.Unmatched = append(result.Unmatched, label)
+			continue
+		}
+
+		start, end, err := icsEventTimes(event, loc)
+		if err == nil {
+			err = ValidateSessionTimes(start, &end, now)
+		}
+		if err == nil && SessionMinutes(end.Sub(start)) == 0 {
+			err = fmt.Errorf("shorter than a minute")
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+
+		minutes, overlaps, err := i.apply(icsEventKey(event, start), gameID, start, end, event.text("DESCRIPTION"))
+		result.add(label, minutes, false, overlaps, err)
+	}
+	return result, nil
+}
+
+// apply creates the session for an event not imported before; returns 0
+// minutes when it was. The overlap check and the insert run under the session
+// timer's lock, so a session started meanwhile can't slip in between.
+func (i *ICalendarImporter) apply(key string, gameID uint, start, end time.Time, notes string) (int, []SessionOverlap, error) {
+	minutes := 0
+	var overlaps []SessionOverlap
+	err := i.timer.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&models.ImportedPlaytime{}).
+			Where("source = ? AND external_key = ?", SourceICalendar, key).
+			Count(&count).Error
+		if err != nil || count > 0 {
+			return err
+		}
+		if overlaps, err = i.timer.CheckOverlapTx(tx, start, &end, 0); err != nil {
+			return err
+		}
+
+		session := models.PlaySession{
+			GameID:    gameID,
+			StartTime: start,
+			Notes:     notes,
+			Source:    SourceICalendar,
+		}
+		EndSessionAt(&session, end)
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		minutes = session.Duration
+
+		return tx.Create(&models.ImportedPlaytime{
+			GameID:       gameID,
+			Source:       SourceICalendar,
+			ExternalKey:  key,
+			TotalSeconds: int64(end.Sub(start) / time.Second),
+			LastPlayed:   &end,
+		}).Error
+	})
+	if err != nil {
+		return 0, overlaps, err
+	}
+	return minutes, overlaps, nil
+}
+
+// describeOverlaps names the sessions an event overlaps for import errors
+func describeOverlaps(overlaps []SessionOverlap) string {
+	ids := make([]string, len(overlaps))
+	for i, overlap := range overlaps {
+		ids[i] = strconv.FormatUint(uint64(overlap.SessionID), 10)
+	}
+	if len(ids) == 1 {
+		return "session " + ids[0]
+	}
+	return "sessions " + strings.Join(ids, ", ")
+}
+
+// icsEventKey identifies an event across imports: its UID, plus the
+// RECURRENCE-ID for a moved occurrence of a recurring event. Events without a
+// UID fall back to their start time and title.
+func icsEventKey(event icsEvent, start time.Time) string {
+	uid := event.text("UID")
+	if uid == "" {
+		return start.UTC().Format(icsTimeLayout) + "/" + strings.ToLower(event.text("SUMMARY"))
+	}
+	if recurrence := event.text("RECURRENCE-ID"); recurrence != "" {
+		return uid + "/" + recurrence
+	}
+	return uid
+}
+
+// icsEventTimes reads an event's start and its end from DTEND or DURATION
+func icsEventTimes(event icsEvent, loc *time.Location) (time.Time, time.Time, error) {
+	property, ok := event["DTSTART"]
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("missing DTSTART")
+	}
+	start, err := parseICalendarTime(property, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if property, ok := event["DTEND"]; ok {
+		end, err := parseICalendarTime(property, loc)
+		return start, end, err
+	}
+	if property, ok := event["DURATION"]; ok {
+		duration, err := parseICalendarDuration(property.Value)
+		return start, start.Add(duration), err
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("missing DTEND or DURATION")
+}
+
+// gameTitleIndex matches calendar events to games
+type gameTitleIndex struct {
+	ids    map[uint]bool
+	titles map[string][]uint
+}
+
+func (i *ICalendarImporter) loadGameTitles() (*gameTitleIndex, error) {
+	var games []models.Game
+	if err := i.db.Select("id", "title").Find(&games).Error; err != nil {
+		return nil, err
+	}
+	index := &gameTitleIndex{ids: make(map[uint]bool), titles: make(map[string][]uint)}
+	for _, game := range games {
+		index.ids[game.ID] = true
+		title := strings.ToLower(strings.TrimSpace(game.Title))
+		index.titles[title] = append(index.titles[title], game.ID)
+	}
+	return index, nil
+}
+
+// match returns the event's game ID, or 0 when none or several games match
+func (g *gameTitleIndex) match(event icsEvent) uint {
+	if id, err := strconv.ParseUint(event.text("X-PELICO-GAME-ID"), 10, 32); err == nil && g.ids[uint(id)] {
+		return uint(id)
+	}
+
+	summary := event.text("SUMMARY")
+	if found := icsGameIDPattern.FindStringSubmatch(summary); found != nil {
+		if id, err := strconv.ParseUint(found[1], 10, 32); err == nil && g.ids[uint(id)] {
+			return uint(id)
+		}
+	}
+
+	title := strings.ToLower(summary)
+	candidates := []string{title}
+	for _, prefix := range icsSummaryPrefixes {
+		if trimmed, ok := strings.CutPrefix(title, prefix); ok {
+			candidates = append(candidates, strings.TrimSpace(trimmed))
+		}
+	}
+	for _, candidate := range candidates {
+		if ids := g.titles[candidate]; len(ids) > 0 {
+			if len(ids) == 1 {
+				return ids[0]
+			}
+			return 0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"pelico/internal/models"
+)
+
+// icsTimeLayout is an iCalendar DATE-TIME in UTC
+const icsTimeLayout = "20060102T150405Z"
+
+// icsMaxLineOctets is where RFC 5545 folds content lines
+const icsMaxLineOctets = 75
+
+// WriteSessionCalendar writes ended play sessions as an iCalendar (RFC 5545)
+// feed. Sessions must have their Game and Game.Platform preloaded. Each event
+// carries X-PELICO-GAME-ID so a re-import links it back to the same game.
+func WriteSessionCalendar(w io.Writer, name string, sessions []models.PlaySession, now time.Time) error {
+	out := &icsWriter{w: bufio.NewWriter(w)}
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:-//Pelico//Play Sessions//EN")
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	out.line("X-WR-CALNAME:" + icsEscape(name))
+	out.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	out.line("X-PUBLISHED-TTL:PT1H")
+
+	stamp := now.UTC().Format(icsTimeLayout)
+	for _, session := range sessions {
+		if session.EndTime == nil {
+			continue
+		}
+		out.line("BEGIN:VEVENT")
+		out.line(fmt.Sprintf("UID:%s", sessionEventUID(session.ID)))
+		out.line("DTSTAMP:" + stamp)
+		out.line("DTSTART:" + session.StartTime.UTC().Format(icsTimeLayout))
+		out.line("DTEND:" + session.EndTime.UTC().Format(icsTimeLayout))
+		out.line("SUMMARY:" + icsEscape(session.Game.Title))
+
+		description := fmt.Sprintf("Played %d min", session.Duration)
+		if session.Notes != "" {
+			description += "\n\n" + session.Notes
+		}
+		out.line("DESCRIPTION:" + icsEscape(description))
+		if session.Game.Platform.Name != "" {
+			out.line("CATEGORIES:" + icsEscape(session.Game.Platform.Name))
+		}
+		out.line("TRANSP:TRANSPARENT")
+		out.line("X-PELICO-GAME-ID:" + strconv.FormatUint(uint64(session.GameID), 10))
+		out.line("END:VEVENT")
+	}
+	out.line("END:VCALENDAR")
+
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// sessionEventUID is the UID a session is exported under
+func sessionEventUID(id uint) string {
+	return fmt.Sprintf("session-%d@pelico", id)
+}
+
+// exportedSessionID returns the session ID from a UID written by
+// WriteSessionCalendar, or 0 for events from elsewhere
+func exportedSessionID(uid string) uint {
+	id, ok := strings.CutPrefix(uid, "session-")
+	if !ok {
+		return 0
+	}
+	if id, ok = strings.CutSuffix(id, "@pelico"); !ok {
+		return 0
+	}
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(parsed)
+}
+
+// icsWriter writes CRLF-terminated content lines folded at 75 octets,
+// remembering the first write error
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (o *icsWriter) line(text string) {
+	if o.err != nil {
+		return
+	}
+	var folded strings.Builder
+	limit := icsMaxLineOctets
+	for len(text) > limit {
+		// Never split a UTF-8 sequence across lines
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		folded.WriteString(text[:cut])
+		folded.WriteString("\r\n ")
+		text = text[cut:]
+		limit = icsMaxLineOctets - 1 // the leading space counts
+	}
+	folded.WriteString(text)
+	folded.WriteString("\r\n")
+	_, o.err = o.w.WriteString(folded.String())
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsEscape escapes a TEXT value
+func icsEscape(text string) string {
+	return icsEscaper.Replace(text)
+}
+
+// icsUnescape reverses icsEscape
+func icsUnescape(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			out.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n', 'N':
+			out.WriteByte('\n')
+		default:
+			out.WriteByte(text[i])
+		}
+	}
+	return out.String()
+}
+
+// icsProperty is one content line: NAME;PARAM=value:VALUE
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// icsEvent is a VEVENT's properties by upper-case name; the first of a repeated
+// property wins
+type icsEvent map[string]icsProperty
+
+func (e icsEvent) text(name string) string {
+	return strings.TrimSpace(icsUnescape(e[name].Value))
+}
+
+// parseICalendar reads the VEVENTs of an iCalendar stream. Nested components
+// such as VALARM are skipped; other components (VTIMEZONE, VTODO) are ignored.
+func parseICalendar(r io.Reader) ([]icsEvent, error) {
+	lines, err := unfoldICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []icsEvent
+	var current icsEvent
+	depth := 0 // components opened inside the current VEVENT
+	sawCalendar := false
+	for number, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		property, err := parseICalendarLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("ics line %d: %w", number+1, err)
+		}
+		value := strings.ToUpper(strings.TrimSpace(property.Value))
+		switch {
+		case property.Name == "BEGIN" && value == "VCALENDAR":
+			sawCalendar = true
+		case property.Name == "BEGIN" && value == "VEVENT" && current == nil:
+			current = icsEvent{}
+		case property.Name == "BEGIN" && current != nil:
+			depth++
+		case property.Name == "END" && current != nil && depth > 0:
+			depth--
+		case property.Name == "END" && value == "VEVENT" && current != nil:
+			events = append(events, current)
+			current = nil
+		case current != nil && depth == 0:
+			if _, seen := current[property.Name]; !seen {
+				current[property.Name] = property
+			}
+		}
+	}
+	if !sawCalendar {
+		return nil, fmt.Errorf("not an iCalendar file: missing BEGIN:VCALENDAR")
+	}
+	if current != nil {
+		return nil, fmt.Errorf("ics: unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// unfoldICalendar splits a stream into content lines, joining folded ones
+func unfoldICalendar(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalendarLine splits a content line into name, parameters and value;
+// colons and semicolons inside quoted parameter values are kept
+func parseICalendarLine(line string) (icsProperty, error) {
+	property := icsProperty{Params: map[string]string{}}
+	quoted := false
+	start := 0
+	var parts []string
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';', ':':
+			if quoted {
+				continue
+			}
+			parts = append(parts, line[start:i])
+			start = i + 1
+			if line[i] == ':' {
+				property.Value = line[start:]
+				i = len(line)
+			}
+		}
+	}
+	if len(parts) == 0 || start == 0 || line[start-1] != ':' {
+		return property, fmt.Errorf("missing ':' in %q", line)
+	}
+
+	property.Name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		property.Params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return property, nil
+}
+
+// parseICalendarTime reads a DATE-TIME property: UTC (…Z), with a TZID
+// parameter, or floating, which is read in loc. A TZID that is neither an IANA
+// name nor a known Windows zone name (Outlook writes those) is also read in loc.
+// All-day DATE values are rejected since they say nothing about when play started.
+func parseICalendarTime(property icsProperty, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(property.Value)
+	if strings.EqualFold(property.Params["VALUE"], "DATE") || len(value) == len("20060102") {
+		return time.Time{}, fmt.Errorf("all-day event")
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icsTimeLayout, value)
+	}
+	if tzid := property.Params["TZID"]; tzid != "" {
+		if zone := icsTimezone(tzid); zone != nil {
+			loc = zone
+		}
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// icsTimezone resolves a TZID as an IANA name or a Windows zone name; nil when
+// it is neither
+func icsTimezone(tzid string) *time.Location {
+	tzid = strings.TrimPrefix(strings.TrimSpace(tzid), "/")
+	if tzid == "" || tzid == "Local" {
+		return nil
+	}
+	if zone, err := time.LoadLocation(tzid); err == nil {
+		return zone
+	}
+	if name, ok := windowsTimezones[tzid]; ok {
+		if zone, err := time.LoadLocation(name); err == nil {
+			return zone
+		}
+	}
+	return nil
+}
+
+// windowsTimezones maps Windows zone names to IANA ones, following the CLDR
+// windowsZones table for each zone's primary territory
+var windowsTimezones = map[string]string{
+	"Dateline Standard Time":         "Etc/GMT+12",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Mountain Standard Time":         "America/Denver",
+	"Central Standard Time":          "America/Chicago",
+	"Central America Standard Time":  "America/Guatemala",
+	"Canada Central Standard Time":   "America/Regina",
+	"Central Standard Time (Mexico)": "America/Mexico_City",
+	"Eastern Standard Time":          "America/New_York",
+	"US Eastern Standard Time":       "America/Indianapolis",
+	"SA Pacific Standard Time":       "America/Bogota",
+	"Atlantic Standard Time":         "America/Halifax",
+	"Newfoundland Standard Time":     "America/St_Johns",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"Argentina Standard Time":        "America/Buenos_Aires",
+	"Pacific SA Standard Time":       "America/Santiago",
+	"UTC":                            "Etc/UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Romance Standard Time":          "Europe/Paris",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Turkey Standard Time":           "Europe/Istanbul",
+	"Israel Standard Time":           "Asia/Jerusalem",
+	"South Africa Standard Time":     "Africa/Johannesburg",
+	"Egypt Standard Time":            "Africa/Cairo",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Arab Standard Time":             "Asia/Riyadh",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"Iran Standard Time":             "Asia/Tehran",
+	"Pakistan Standard Time":         "Asia/Karachi",
+	"India Standard Time":            "Asia/Calcutta",
+	"Bangladesh Standard Time":       "Asia/Dhaka",
+	"SE Asia Standard Time":          "Asia/Bangkok",
+	"China Standard Time":            "Asia/Shanghai",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"Taipei Standard Time":           "Asia/Taipei",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Korea Standard Time":            "Asia/Seoul",
+	"W. Australia Standard Time":     "Australia/Perth",
+	"Cen. Australia Standard Time":   "Australia/Adelaide",
+	"AUS Central Standard Time":      "Australia/Darwin",
+	"E. Australia Standard Time":     "Australia/Brisbane",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"Tasmania Standard Time":         "Australia/Hobart",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+// parseICalendarDuration reads a DURATION value such as PT1H30M or P1DT2H
+func parseICalendarDuration(value string) (time.Duration, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	rest, found := strings.CutPrefix(strings.TrimPrefix(value, "+"), "P")
+	if !found || rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, char := range rest {
+		switch {
+		case char >= '0' && char <= '9':
+			number += string(char)
+		case char == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			number = ""
+			switch {
+			case char == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case char == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case char == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case char == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case char == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return total, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSessionCalendar(t *testing.T) {
+	start := time.Date(2024, 3, 4, 18, 0, 0, 0, time.FixedZone("CET", 3600))
+	end := start.Add(90 * time.Minute)
+	sessions := []models.PlaySession{
+		{
+			ID: 7, GameID: 2, StartTime: start, EndTime: &end, Duration: 90,
+			Notes: "Beat Magus; saved at the End of Time, finally",
+			Game:  models.Game{Title: "Chrono Trigger", Platform: models.Platform{Name: "SNES"}},
+		},
+		{ID: 8, GameID: 2, StartTime: start.Add(time.Hour), Game: models.Game{Title: "Chrono Trigger"}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteSessionCalendar(&out, "Pelico play sessions", sessions, start))
+	text := out.String()
+
+	assert.True(t, strings.HasPrefix(text, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Equal(t, 1, strings.Count(text, "BEGIN:VEVENT"), "running sessions are left out")
+	assert.Contains(t, text, "UID:session-7@pelico\r\n")
+	assert.Contains(t, text, "DTSTART:20240304T170000Z\r\n")
+	assert.Contains(t, text, "DTEND:20240304T183000Z\r\n")
+	assert.Contains(t, text, "X-PELICO-GAME-ID:2\r\n")
+	for _, line := range strings.Split(text, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+
+	events, err := parseICalendar(&out)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Chrono Trigger", events[0].text("SUMMARY"))
+	assert.Equal(t, "Played 90 min\n\nBeat Magus; saved at the End of Time, finally", events[0].text("DESCRIPTION"))
+	assert.Equal(t, uint(7), exportedSessionID(events[0].text("UID")))
+}
+
+func TestParseICalendar(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:abc\r\n" +
+		"SUMMARY:Hollow\r\n  Knight\r\n" +
+		"DTSTART;TZID=\"Europe/Warsaw\":20240310T203000\r\n" +
+		"DURATION:PT1H15M\r\n" +
+		"BEGIN:VALARM\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	events, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Hollow Knight", events[0].text("SUMMARY"))
+	assert.Empty(t, events[0].text("DESCRIPTION"), "alarm properties stay out of the event")
+
+	start, end, err := icsEventTimes(events[0], time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 10, 19, 30, 0, 0, time.UTC), start.UTC())
+	assert.Equal(t, 75*time.Minute, end.Sub(start))
+
+	// Windows zone names resolve to IANA zones; unknown ones fall back to loc
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	for tzid, want := range map[string]time.Time{
+		"W. Europe Standard Time": time.Date(2024, 7, 1, 18, 0, 0, 0, time.UTC),
+		"Pacific Standard Time":   time.Date(2024, 7, 2, 3, 0, 0, 0, time.UTC),
+		"Custom Time Zone":        time.Date(2024, 7, 1, 18, 0, 0, 0, time.UTC),
+		"/Europe/Warsaw":          time.Date(2024, 7, 1, 18, 0, 0, 0, time.UTC),
+	} {
+		property := icsProperty{Params: map[string]string{"TZID": tzid}, Value: "20240701T200000"}
+		got, err := parseICalendarTime(property, warsaw)
+		require.NoError(t, err, tzid)
+		assert.Equal(t, want, got.UTC(), tzid)
+	}
+
+	_, err = parseICalendar(strings.NewReader("SUMMARY:nope\r\n"))
+	assert.Error(t, err)
+
+	for value, want := range map[string]time.Duration{
+		"PT45M":   45 * time.Minute,
+		"P1DT2H":  26 * time.Hour,
+		"PT1H30S": time.Hour + 30*time.Second,
+	} {
+		got, err := parseICalendarDuration(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "P", "1H", "PT1X", "PT5"} {
+		_, err := parseICalendarDuration(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestICalendarImporter(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	require.NoError(t, db.Create(&models.Game{ID: 5, Title: "Tetris", PlatformID: 1}).Error)
+
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:one", "SUMMARY:Playing: Chrono Trigger", "DTSTART:20240304T180000Z", "DTEND:20240304T193000Z", "DESCRIPTION:Zeal", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:two", "SUMMARY:Speedrun practice #3", "DTSTART:20240305T200000", "DURATION:PT45M", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:three", "SUMMARY:Gym", "DTSTART:20240305T070000Z", "DTEND:20240305T080000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:four", "SUMMARY:Tetris", "DTSTART:20240306T070000Z", "DTEND:20240306T080000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:five", "SUMMARY:super mario world", "DTSTART;VALUE=DATE:20240307", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:six", "SUMMARY:Super Mario World", "STATUS:CANCELLED", "DTSTART:20240308T070000Z", "DTEND:20240308T080000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:seven", "X-PELICO-GAME-ID:1", "SUMMARY:Anything", "DTSTART:20240309T070000Z", "DTEND:20240309T060000Z", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	timer := NewSessionTimer(db, SessionConflictReject, SessionOverlapReject, 0)
+	importer := NewICalendarImporter(db, timer)
+	result, err := importer.Import(strings.NewReader(calendar), warsaw)
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, result.ItemsFound)
+	assert.Equal(t, 2, result.SessionsCreated)
+	assert.Equal(t, 135, result.MinutesImported)
+	assert.Equal(t, []string{"Gym", "Tetris"}, result.Unmatched, "two games are called Tetris")
+	assert.Len(t, result.Errors, 2)
+
+	var sessions []models.PlaySession
+	require.NoError(t, db.Order("id").Find(&sessions).Error)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, uint(2), sessions[0].GameID)
+	assert.Equal(t, 90, sessions[0].Duration)
+	assert.Equal(t, "Zeal", sessions[0].Notes)
+	assert.Equal(t, SourceICalendar, sessions[0].Source)
+	assert.Equal(t, uint(3), sessions[1].GameID)
+	assert.Equal(t, time.Date(2024, 3, 5, 19, 0, 0, 0, time.UTC), sessions[1].StartTime.UTC(), "floating times are read in the given timezone")
+
+	// Importing again adds nothing
+	result, err = importer.Import(strings.NewReader(calendar), warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, 2, result.Unchanged)
+
+	// Our own feed is recognized while its sessions exist
+	var feed bytes.Buffer
+	require.NoError(t, db.Preload("Game").Find(&sessions).Error)
+	require.NoError(t, WriteSessionCalendar(&feed, "Pelico", sessions, time.Now()))
+	result, err = importer.Import(&feed, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, 2, result.Unchanged)
+
+	// Events overlapping existing sessions follow the overlap policy
+	overlapping := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT", "UID:eight", "SUMMARY:Hollow Knight", "DTSTART:20240304T190000Z", "DTEND:20240304T200000Z", "END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	result, err = importer.Import(strings.NewReader(overlapping), time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, []string{fmt.Sprintf("Hollow Knight: not imported, overlaps session %d", sessions[0].ID)}, result.Errors)
+
+	timer.overlapPolicy = SessionOverlapWarn
+	result, err = importer.Import(strings.NewReader(overlapping), time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.SessionsCreated)
+	assert.Equal(t, []string{fmt.Sprintf("Hollow Knight: imported, but overlaps session %d", sessions[0].ID)}, result.Errors)
+
+	_, err = importer.Import(strings.NewReader("not a calendar"), time.UTC)
+	assert.ErrorIs(t, err, ErrInvalidCalendar)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxIGDBLimit is the largest page size IGDB accepts
+const maxIGDBLimit = 500
+
+// igdbFieldPattern accepts IGDB field paths such as "name", "cover.url" or "involved_companies.company.*"
+var igdbFieldPattern = regexp.MustCompile(`^(\*|[a-z_][a-z0-9_]*(\.([a-z_][a-z0-9_]*|\*))*)$`)
+
+var igdbNumberOperators = map[string]bool{"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true}
+
+// "~" is IGDB's case-insensitive equality, usable with a trailing * for prefix matches
+var igdbStringOperators = map[string]bool{"=": true, "!=": true, "~": true}
+
+// IGDBQuery builds Apicalypse queries for the IGDB API. Every user-supplied
+// value passes through typed setters that quote and escape it, so titles with
+// quotes, semicolons or backslashes can't break out of their clause.
+type IGDBQuery struct {
+	search     string
+	hasSearch  bool
+	fields     []string
+	conditions []string
+	sort       string
+	limit      int
+	offset     int
+	errs       []string
+}
+
+func NewIGDBQuery() *IGDBQuery {
+	return &IGDBQuery{}
+}
+
+// Search sets the full-text search term
+func (q *IGDBQuery) Search(term string) *IGDBQuery {
+	q.search = term
+	q.hasSearch = true
+	return q
+}
+
+// Fields adds fields to return; invalid field paths are reported by Build
+func (q *IGDBQuery) Fields(fields ...string) *IGDBQuery {
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if !igdbFieldPattern.MatchString(field) {
+			q.errs = append(q.errs, fmt.Sprintf("invalid field %q", field))
+			continue
+		}
+		q.fields = append(q.fields, field)
+	}
+	return q
+}
+
+// WhereIn matches records whose field contains any of the given IDs, e.g. platforms = (19,58)
+func (q *IGDBQuery) WhereIn(field string, ids ...int) *IGDBQuery {
+	if !q.validField(field) {
+		return q
+	}
+	if len(ids) == 0 {
+		q.errs = append(q.errs, fmt.Sprintf("no values for %s", field))
+		return q
+	}
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.Itoa(id)
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf("%s = (%s)", field, strings.Join(values, ",")))
+	return q
+}
+
+// WhereNumber adds a numeric comparison such as rating >= 80
+func (q *IGDBQuery) WhereNumber(field, operator string, value int64) *IGDBQuery {
+	if !q.validField(field) {
+		return q
+	}
+	if !igdbNumberOperators[operator] {
+		q.errs = append(q.errs, fmt.Sprintf("invalid operator %q", operator))
+		return q
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf("%s %s %d", field, operator, value))
+	return q
+}
+
+// WhereString adds a quoted string comparison; use operator "~" for case-insensitive matching
+func (q *IGDBQuery) WhereString(field, operator, value string) *IGDBQuery {
+	if !q.validField(field) {
+		return q
+	}
+	if !igdbStringOperators[operator] {
+		q.errs = append(q.errs, fmt.Sprintf("invalid operator %q", operator))
+		return q
+	}
+	q.conditions = append(q.conditions, fmt.Sprintf("%s %s %s", field, operator, QuoteIGDBString(value)))
+	return q
+}
+
+// Sort orders results by a field; IGDB does not allow sorting search queries
+func (q *IGDBQuery) Sort(field string, descending bool) *IGDBQuery {
+	if !q.validField(field) {
+		return q
+	}
+	direction := "asc"
+	if descending {
+		direction = "desc"
+	}
+	q.sort = field + " " + direction
+	return q
+}
+
+// Limit sets the page size (1-500)
+func (q *IGDBQuery) Limit(limit int) *IGDBQuery {
+	if limit < 1 || limit > maxIGDBLimit {
+		q.errs = append(q.errs, fmt.Sprintf("limit must be between 1 and %d", maxIGDBLimit))
+		return q
+	}
+	q.limit = limit
+	return q
+}
+
+// Offset skips the first n results
+func (q *IGDBQuery) Offset(offset int) *IGDBQuery {
+	if offset < 0 {
+		q.errs = append(q.errs, "offset must not be negative")
+		return q
+	}
+	q.offset = offset
+	return q
+}
+
+// Build renders the query, or returns the first validation error
+func (q *IGDBQuery) Build() (string, error) {
+	if len(q.errs) > 0 {
+		return "", fmt.Errorf("invalid IGDB query: %s", strings.Join(q.errs, "; "))
+	}
+	if len(q.fields) == 0 {
+		return "", fmt.Errorf("invalid IGDB query: no fields requested")
+	}
+	if q.hasSearch && q.sort != "" {
+		return "", fmt.Errorf("invalid IGDB query: search results cannot be sorted")
+	}
+
+	var b strings.Builder
+	if q.hasSearch {
+		fmt.Fprintf(&b, "search %s; ", QuoteIGDBString(q.search))
+	}
+	fmt.Fprintf(&b, "fields %s;", strings.Join(q.fields, ","))
+	if len(q.conditions) > 0 {
+		fmt.Fprintf(&b, " where %s;", strings.Join(q.conditions, " & "))
+	}
+	if q.sort != "" {
+		fmt.Fprintf(&b, " sort %s;", q.sort)
+	}
+	if q.limit > 0 {
+		fmt.Fprintf(&b, " limit %d;", q.limit)
+	}
+	if q.offset > 0 {
+		fmt.Fprintf(&b, " offset %d;", q.offset)
+	}
+	return b.String(), nil
+}
+
+func (q *IGDBQuery) validField(field string) bool {
+	if field == "*" || !igdbFieldPattern.MatchString(field) {
+		q.errs = append(q.errs, fmt.Sprintf("invalid field %q", field))
+		return false
+	}
+	return true
+}
+
+// QuoteIGDBString wraps a value in double quotes, escaping backslashes and quotes.
+// Control characters (including newlines) are replaced with spaces.
+func QuoteIGDBString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '"':
+			b.WriteString(`\"`)
+		case r < 0x20 || r == 0x7f:
+			b.WriteByte(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteIGDBString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Super Metroid", want: `"Super Metroid"`},
+		{input: `Tony Hawk's "Pro" Skater`, want: `"Tony Hawk's \"Pro\" Skater"`},
+		{input: `trailing\`, want: `"trailing\\"`},
+		{input: `\"; fields *; where id > 0; "`, want: `"\\\"; fields *; where id > 0; \""`},
+		{input: "line\nbreak\ttab", want: `"line break tab"`},
+		{input: "ポケモン", want: `"ポケモン"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, QuoteIGDBString(tt.input))
+		})
+	}
+}
+
+func TestIGDBQuery_Build(t *testing.T) {
+	query, err := NewIGDBQuery().
+		Search(`Zelda"; fields *; limit 500; "`).
+		Fields("name", "cover.url").
+		WhereIn("platforms", 19, 58).
+		Limit(10).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, `search "Zelda\"; fields *; limit 500; \""; fields name,cover.url; where platforms = (19,58); limit 10;`, query)
+
+	query, err = NewIGDBQuery().
+		Fields("name").
+		WhereString("name", "~", `Mario" | id > 0`).
+		WhereNumber("rating", ">=", 80).
+		Sort("id", false).
+		Limit(500).
+		Offset(1000).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, `fields name; where name ~ "Mario\" | id > 0" & rating >= 80; sort id asc; limit 500; offset 1000;`, query)
+}
+
+func TestIGDBQuery_RejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *IGDBQuery
+	}{
+		{name: "no fields", query: NewIGDBQuery().Search("Mario")},
+		{name: "injected field", query: NewIGDBQuery().Fields("name; where id > 0")},
+		{name: "wildcard where field", query: NewIGDBQuery().Fields("name").WhereNumber("*", "=", 1)},
+		{name: "injected where field", query: NewIGDBQuery().Fields("name").WhereIn("id = 1 | platforms", 1)},
+		{name: "invalid number operator", query: NewIGDBQuery().Fields("name").WhereNumber("rating", "; limit", 1)},
+		{name: "invalid string operator", query: NewIGDBQuery().Fields("name").WhereString("name", ">", "Mario")},
+		{name: "empty where in", query: NewIGDBQuery().Fields("name").WhereIn("platforms")},
+		{name: "injected sort", query: NewIGDBQuery().Fields("name").Sort("id desc; limit 1", false)},
+		{name: "sorted search", query: NewIGDBQuery().Search("Mario").Fields("name").Sort("id", true)},
+		{name: "limit too large", query: NewIGDBQuery().Fields("name").Limit(501)},
+		{name: "limit zero", query: NewIGDBQuery().Fields("name").Limit(0)},
+		{name: "negative offset", query: NewIGDBQuery().Fields("name").Offset(-1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.query.Build()
+			assert.Error(t, err)
+			assert.Empty(t, query)
+		})
+	}
+}
+
+func TestIGDBService_SearchGamesEscapesTitle(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	service := NewIGDBService("client", "secret", NewLoggerService(slog.LevelError))
+	service.baseURL = server.URL
+	service.accessToken = "token"
+	service.tokenExpiry = time.Now().Add(time.Hour)
+
+	_, err := service.SearchGames(`Evil"; fields *; where id = 1; "`, &models.Platform{Name: "Super Nintendo", IGDBID: 19})
+	require.NoError(t, err)
+	assert.Contains(t, received, `search "Evil\"; fields *; where id = 1; \"";`)
+	assert.Contains(t, received, "where platforms = (19);")
+	assert.Contains(t, received, "limit 10;")
+}
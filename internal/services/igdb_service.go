@@ -4,18 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+	"pelico/internal/models"
 )
 
 type IGDBService struct {
 	client       *http.Client
+	baseURL      string
 	clientID     string
 	clientSecret string
 	accessToken  string
 	tokenExpiry  time.Time
+	logger       *LoggerService
 }
 
 type TwitchOAuthResponse struct {
@@ -33,8 +38,28 @@ type IGDBGame struct {
 	Cover        *IGDBCover  `json:"cover"`
 	Genres       []IGDBGenre `json:"genres"`
 	Platforms    []IGDBPlatform `json:"platforms"`
+	
+	InvolvedCompanies  []IGDBInvolvedCompany `json:"involved_companies"`
+	Franchises         []IGDBNamedEntity     `json:"franchises"`
+	Collections        []IGDBNamedEntity     `json:"collections"`
+	GameModes          []IGDBNamedEntity     `json:"game_modes"`
+	Themes             []IGDBNamedEntity     `json:"themes"`
+	PlayerPerspectives []IGDBNamedEntity     `json:"player_perspectives"`
+	Screenshots        []IGDBCover           `json:"screenshots"`
+	Artworks           []IGDBCover           `json:"artworks"`
+	Videos             []IGDBVideo           `json:"videos"`
+	AlternativeNames   []IGDBAlternativeName `json:"alternative_names"`
+	ReleaseDates       []IGDBReleaseDate     `json:"release_dates"`
+	AgeRatings         []IGDBAgeRating       `json:"age_ratings"`
 }
 
+// igdbGameFields is the field list requested for every game lookup
+const igdbGameFields = "name,summary,first_release_date,rating,cover.url,genres.name,platforms.name," +
+	"involved_companies.company.name,involved_companies.developer,involved_companies.publisher," +
+	"franchises.name,collections.name,game_modes.name,themes.name,player_perspectives.name," +
+	"screenshots.url,artworks.url,videos.name,videos.video_id,alternative_names.name,alternative_names.comment," +
+	"release_dates.date,release_dates.region,release_dates.platform,release_dates.human,age_ratings.category,age_ratings.rating"
+
 type IGDBCover struct {
 	ID  int    `json:"id"`
 	URL string `json:"url"`
@@ -50,13 +75,73 @@ type IGDBPlatform struct {
 	Name string `json:"name"`
 }
 
-func NewIGDBService(clientID, clientSecret string) *IGDBService {
+// IGDBPlatformInfo is an entry of IGDB's platform list
+type IGDBPlatformInfo struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Abbreviation    string `json:"abbreviation"`
+	AlternativeName string `json:"alternative_name"`
+	Slug            string `json:"slug"`
+}
+
+// IGDBNamedEntity covers the simple id/name lookups (franchises, themes, game modes, ...)
+type IGDBNamedEntity struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type IGDBInvolvedCompany struct {
+	ID        int             `json:"id"`
+	Company   IGDBNamedEntity `json:"company"`
+	Developer bool            `json:"developer"`
+	Publisher bool            `json:"publisher"`
+}
+
+type IGDBVideo struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	VideoID string `json:"video_id"` // YouTube ID
+}
+
+type IGDBReleaseDate struct {
+	ID       int    `json:"id"`
+	Date     int64  `json:"date"` // unix timestamp, 0 when not yet known
+	Region   int    `json:"region"`
+	Platform int    `json:"platform"`
+	Human    string `json:"human"`
+}
+
+type IGDBAgeRating struct {
+	ID       int `json:"id"`
+	Category int `json:"category"` // rating board
+	Rating   int `json:"rating"`
+}
+
+// IGDBTimeToBeat comes from the separate game_time_to_beats endpoint; times are in seconds
+type IGDBTimeToBeat struct {
+	ID         int `json:"id"`
+	GameID     int `json:"game_id"`
+	Hastily    int `json:"hastily"`
+	Normally   int `json:"normally"`
+	Completely int `json:"completely"`
+	Count      int `json:"count"`
+}
+
+type IGDBAlternativeName struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Comment string `json:"comment"`
+}
+
+func NewIGDBService(clientID, clientSecret string, logger *LoggerService) *IGDBService {
 	return &IGDBService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		baseURL:      "https://api.igdb.com/v4",
 		clientID:     clientID,
 		clientSecret: clientSecret,
+		logger:       logger,
 	}
 }
 
@@ -97,60 +182,61 @@ func (s *IGDBService) authenticate() error {
 	return nil
 }
 
-func (s *IGDBService) SearchGames(title, platform string) ([]GameMetadata, error) {
-	if err := s.authenticate(); err != nil {
-		return nil, err
-	}
+// SearchGames searches IGDB, restricting results to the platform's linked IGDB ID
+// when known. Unlinked platforms fall back to the built-in name map, then to
+// filtering results by platform name.
+func (s *IGDBService) SearchGames(title string, platform *models.Platform) ([]GameMetadata, error) {
+	results, _, err := s.search(title, platform)
+	return results, err
+}
 
-	// Build IGDB query with platform filtering
-	var query string
-	if platform != "" {
-		// Map common platform names to IGDB platform IDs for better matching
-		platformFilter := s.getPlatformFilter(platform)
-		if platformFilter != "" {
-			query = fmt.Sprintf(`search "%s"; where platforms = (%s); fields name,summary,first_release_date,rating,cover.url,genres.name,platforms.name; limit 10;`, title, platformFilter)
-		} else {
-			// If no specific platform mapping, search all and filter results
-			query = fmt.Sprintf(`search "%s"; fields name,summary,first_release_date,rating,cover.url,genres.name,platforms.name; limit 20;`, title)
+// search is SearchGames, also reporting whether the results are complete:
+// they lack time-to-beat data when that lookup failed
+func (s *IGDBService) search(title string, platform *models.Platform) ([]GameMetadata, bool, error) {
+	platformName := ""
+	platformID := 0
+	if platform != nil {
+		platformName = platform.Name
+		if platform.IGDBID > 0 {
+			platformID = platform.IGDBID
+		} else if id, err := strconv.Atoi(legacyIGDBPlatformID(platform.Name)); err == nil {
+			platformID = id
 		}
-	} else {
-		query = fmt.Sprintf(`search "%s"; fields name,summary,first_release_date,rating,cover.url,genres.name,platforms.name; limit 10;`, title)
 	}
 
-	// Make request to IGDB
-	req, err := http.NewRequest("POST", "https://api.igdb.com/v4/games", strings.NewReader(query))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	// Build IGDB query with platform filtering
+	q := NewIGDBQuery().Search(title).Fields(strings.Split(igdbGameFields, ",")...).Limit(10)
+	if platformID > 0 {
+		q.WhereIn("platforms", platformID)
+	} else if platformName != "" {
+		// If no specific platform mapping, search wider and filter results
+		q.Limit(20)
 	}
-
-	// Set headers
-	req.Header.Set("Client-ID", s.clientID)
-	req.Header.Set("Authorization", "Bearer "+s.accessToken)
-	req.Header.Set("Content-Type", "text/plain")
-
-	resp, err := s.client.Do(req)
+	query, err := q.Build()
 	if err != nil {
-		return nil, fmt.Errorf("failed to make IGDB request: %v", err)
+		return nil, false, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("IGDB request failed with status %d: %s", resp.StatusCode, string(body))
+	var igdbGames []IGDBGame
+	if err := s.query("games", query, &igdbGames); err != nil {
+		return nil, false, err
 	}
 
-	// Parse response
-	var igdbGames []IGDBGame
-	if err := json.NewDecoder(resp.Body).Decode(&igdbGames); err != nil {
-		return nil, fmt.Errorf("failed to decode IGDB response: %v", err)
+	// Time-to-beat data lives on its own endpoint; a failure there shouldn't fail the search
+	timesToBeat, err := s.fetchTimesToBeat(igdbGames)
+	complete := err == nil
+	if err != nil {
+		s.logger.LogWarn("igdb_time_to_beat_failed",
+			slog.String("title", title),
+			slog.String("error", err.Error()))
 	}
 
 	// Convert to our format and filter by platform if needed
 	var results []GameMetadata
 	for _, game := range igdbGames {
 		// If platform filtering was requested but not applied in query, filter results
-		if platform != "" && s.getPlatformFilter(platform) == "" {
-			if !s.gameMatchesPlatform(game, platform) {
+		if platformName != "" && platformID == 0 {
+			if !s.gameMatchesPlatform(game, platformName) {
 				continue
 			}
 		}
@@ -167,31 +253,231 @@ func (s *IGDBService) SearchGames(title, platform string) ([]GameMetadata, error
 			metadata.Year = releaseTime.Year()
 		}
 
-		// Get genre
-		if len(game.Genres) > 0 {
-			metadata.Genre = game.Genres[0].Name
+		// Get genres, keeping the first as the primary genre
+		for _, genre := range game.Genres {
+			if genre.Name != "" {
+				metadata.Genres = append(metadata.Genres, genre.Name)
+			}
+		}
+		if len(metadata.Genres) > 0 {
+			metadata.Genre = metadata.Genres[0]
 		}
 
 		// Get cover art
 		if game.Cover != nil && game.Cover.URL != "" {
-			// IGDB returns URLs like "//images.igdb.com/igdb/image/upload/..."
-			coverURL := game.Cover.URL
-			if strings.HasPrefix(coverURL, "//") {
-				coverURL = "https:" + coverURL
+			metadata.CoverArtURL = igdbImageURL(game.Cover.URL, "t_cover_big")
+		}
+
+		s.applyExtendedMetadata(&metadata, game)
+		applyReleaseInfo(&metadata, game, platformID)
+		if ttb, found := timesToBeat[game.ID]; found {
+			metadata.TimeToBeat = &MetadataTimeToBeat{
+				HastilyMinutes:    secondsToMinutes(ttb.Hastily),
+				NormallyMinutes:   secondsToMinutes(ttb.Normally),
+				CompletelyMinutes: secondsToMinutes(ttb.Completely),
+				SubmissionCount:   ttb.Count,
 			}
-			// Convert to high-res cover (replace t_thumb with t_cover_big)
-			coverURL = strings.Replace(coverURL, "t_thumb", "t_cover_big", 1)
-			metadata.CoverArtURL = coverURL
 		}
 
 		results = append(results, metadata)
 	}
 
-	return results, nil
+	return results, complete, nil
+}
+
+// fetchTimesToBeat loads time-to-beat estimates for the given games, keyed by IGDB game ID
+func (s *IGDBService) fetchTimesToBeat(games []IGDBGame) (map[int]IGDBTimeToBeat, error) {
+	result := make(map[int]IGDBTimeToBeat)
+	if len(games) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int, len(games))
+	for i, game := range games {
+		ids[i] = game.ID
+	}
+	query, err := NewIGDBQuery().
+		Fields("game_id", "hastily", "normally", "completely", "count").
+		WhereIn("game_id", ids...).
+		Limit(len(ids)).
+		Build()
+	if err != nil {
+		return result, err
+	}
+
+	var times []IGDBTimeToBeat
+	if err := s.query("game_time_to_beats", query, &times); err != nil {
+		return result, err
+	}
+	for _, ttb := range times {
+		result[ttb.GameID] = ttb
+	}
+	return result, nil
+}
+
+// secondsToMinutes rounds IGDB's second-based durations to whole minutes
+func secondsToMinutes(seconds int) int {
+	return (seconds + 30) / 60
+}
+
+// ListPlatforms returns IGDB's full platform list, used to link local platforms to IGDB IDs
+func (s *IGDBService) ListPlatforms() ([]IGDBPlatformInfo, error) {
+	var platforms []IGDBPlatformInfo
+	for offset := 0; ; offset += maxIGDBLimit {
+		var page []IGDBPlatformInfo
+		query, err := NewIGDBQuery().
+			Fields("name", "abbreviation", "alternative_name", "slug").
+			Sort("id", false).
+			Limit(maxIGDBLimit).
+			Offset(offset).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.query("platforms", query, &page); err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, page...)
+		if len(page) < maxIGDBLimit {
+			break
+		}
+	}
+	return platforms, nil
+}
+
+// query posts an Apicalypse query to an IGDB endpoint and decodes the JSON response into dest
+func (s *IGDBService) query(endpoint, body string, dest interface{}) error {
+	if err := s.authenticate(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.baseURL+"/"+endpoint, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Set headers
+	req.Header.Set("Client-ID", s.clientID)
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make IGDB request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("IGDB request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode IGDB response: %v", err)
+	}
+	return nil
+}
+
+// applyReleaseInfo copies regional release dates and ESRB/PEGI/CERO ratings into metadata.
+// When the search was restricted to an IGDB platform, releases for other platforms are dropped.
+func applyReleaseInfo(metadata *GameMetadata, game IGDBGame, platformID int) {
+	for _, release := range game.ReleaseDates {
+		region, known := igdbReleaseRegions[release.Region]
+		if !known || (platformID > 0 && release.Platform > 0 && release.Platform != platformID) {
+			continue
+		}
+		entry := MetadataReleaseDate{Region: region, Human: release.Human, PlatformIGDBID: release.Platform}
+		if release.Date > 0 {
+			date := time.Unix(release.Date, 0).UTC()
+			entry.Date = &date
+		}
+		metadata.ReleaseDates = append(metadata.ReleaseDates, entry)
+	}
+
+	for _, rating := range game.AgeRatings {
+		board, knownBoard := igdbAgeRatingBoards[rating.Category]
+		label, knownRating := igdbAgeRatings[rating.Rating]
+		if !knownBoard || !knownRating {
+			continue
+		}
+		metadata.AgeRatings = append(metadata.AgeRatings, MetadataAgeRating{
+			Board:      board,
+			Rating:     label.Rating,
+			MinimumAge: label.MinimumAge,
+		})
+	}
+}
+
+// applyExtendedMetadata copies companies, franchises, classifications and media into metadata
+func (s *IGDBService) applyExtendedMetadata(metadata *GameMetadata, game IGDBGame) {
+	for _, involved := range game.InvolvedCompanies {
+		if involved.Company.Name == "" {
+			continue
+		}
+		if involved.Developer {
+			metadata.Developers = append(metadata.Developers, involved.Company.Name)
+		}
+		if involved.Publisher {
+			metadata.Publishers = append(metadata.Publishers, involved.Company.Name)
+		}
+	}
+
+	metadata.Franchises = append(igdbNames(game.Franchises), igdbNames(game.Collections)...)
+	metadata.GameModes = igdbNames(game.GameModes)
+	metadata.Themes = igdbNames(game.Themes)
+	metadata.PlayerPerspectives = igdbNames(game.PlayerPerspectives)
+
+	for _, screenshot := range game.Screenshots {
+		if screenshot.URL != "" {
+			metadata.Screenshots = append(metadata.Screenshots, igdbImageURL(screenshot.URL, "t_screenshot_big"))
+		}
+	}
+	for _, artwork := range game.Artworks {
+		if artwork.URL != "" {
+			metadata.Artworks = append(metadata.Artworks, igdbImageURL(artwork.URL, "t_1080p"))
+		}
+	}
+	for _, video := range game.Videos {
+		if video.VideoID != "" {
+			metadata.Videos = append(metadata.Videos, MetadataVideo{
+				Title: video.Name,
+				URL:   "https://www.youtube.com/watch?v=" + video.VideoID,
+			})
+		}
+	}
+	for _, alt := range game.AlternativeNames {
+		if alt.Name != "" {
+			metadata.AlternativeNames = append(metadata.AlternativeNames, MetadataAlternativeName{
+				Name:    alt.Name,
+				Comment: alt.Comment,
+			})
+		}
+	}
+}
+
+func igdbNames(entities []IGDBNamedEntity) []string {
+	var names []string
+	for _, entity := range entities {
+		if entity.Name != "" {
+			names = append(names, entity.Name)
+		}
+	}
+	return names
+}
+
+// igdbImageURL turns IGDB's protocol-relative thumbnail URLs
+// ("//images.igdb.com/igdb/image/upload/t_thumb/...") into absolute URLs of the given size
+func igdbImageURL(rawURL, size string) string {
+	imageURL := rawURL
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+	return strings.Replace(imageURL, "t_thumb", size, 1)
 }
 
-// getPlatformFilter maps platform names to IGDB platform IDs for precise filtering
-func (s *IGDBService) getPlatformFilter(platformName string) string {
+// legacyIGDBPlatformID maps common platform names to IGDB platform IDs; it is only
+// used for platforms that haven't been linked to an IGDB ID yet
+func legacyIGDBPlatformID(platformName string) string {
 	platformMap := map[string]string{
 		"Nintendo Entertainment System": "18",
 		"NES": "18",
@@ -301,7 +587,7 @@ func (s *IGDBService) gameMatchesPlatform(game IGDBGame, platformName string) bo
 	return false
 }
 
-func (s *IGDBService) FetchGameMetadata(title, platform string) (*GameMetadata, error) {
+func (s *IGDBService) FetchGameMetadata(title string, platform *models.Platform) (*GameMetadata, error) {
 	results, err := s.SearchGames(title, platform)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+	_ "image/gif" // register decoders for the formats metadata providers serve
+	_ "image/png"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxImageSize caps downloads and uploads to keep a single bad URL from filling the disk
+const maxImageSize = 20 << 20
+
+// maxImagePixels caps decoded dimensions, since a small file can declare a
+// canvas big enough to exhaust memory when decoded
+const maxImagePixels = 50_000_000
+
+var imageHashPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+// ImageService downloads, stores and thumbnails game artwork on local disk.
+// Files are content-addressed by SHA-256 so identical art is only stored once.
+type ImageService struct {
+	db           *gorm.DB
+	root         string
+	autoDownload bool
+	client       *http.Client
+
+	// screenScraper re-adds credentials to ScreenScraper media URLs, which are stored without them
+	screenScraper *ScreenScraperService
+}
+
+// NewImageService creates the image store; screenScraper may be nil
+func NewImageService(db *gorm.DB, root string, autoDownload bool, screenScraper *ScreenScraperService) *ImageService {
+	return &ImageService{
+		db:           db,
+		root:         root,
+		autoDownload: autoDownload,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		screenScraper: screenScraper,
+	}
+}
+
+// AutoDownload reports whether art should be fetched whenever metadata is applied
+func (s *ImageService) AutoDownload() bool {
+	return s != nil && s.autoDownload
+}
+
+// CacheGameArt downloads the game's cover and box art URLs into the local store.
+// Images that were already fetched from the same URL, and custom uploads, are kept.
+func (s *ImageService) CacheGameArt(game *models.Game) error {
+	sources := map[string]string{
+		"cover":   game.CoverArtURL,
+		"box_art": game.BoxArtURL,
+	}
+
+	var errs []error
+	for kind, sourceURL := range sources {
+		if sourceURL == "" {
+			continue
+		}
+
+		var existing models.GameImage
+		err := s.db.Where("game_id = ? AND kind = ?", game.ID, kind).First(&existing).Error
+		if err == nil && (existing.Custom || existing.SourceURL == sourceURL) {
+			continue
+		}
+
+		data, err := s.download(sourceURL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", kind, err))
+			continue
+		}
+
+		if _, err := s.StoreGameImage(game.ID, kind, data, sourceURL, false); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", kind, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to cache art for game %d: %v", game.ID, errs)
+	}
+	return nil
+}
+
+// StoreGameImage writes image data to disk, generates thumbnails and records it
+// as the game's image of the given kind, replacing any previous one
+func (s *ImageService) StoreGameImage(gameID uint, kind string, data []byte, sourceURL string, custom bool) (*models.GameImage, error) {
+	decoded, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256(data))
+	originalPath := s.originalPath(hash)
+	if _, err := os.Stat(originalPath); os.IsNotExist(err) {
+		if err := writeFileAtomic(originalPath, data); err != nil {
+			return nil, fmt.Errorf("failed to store image: %v", err)
+		}
+	}
+
+	for size, width := range models.ThumbnailSizes {
+		if err := s.writeThumbnail(hash, size, width, decoded); err != nil {
+			return nil, fmt.Errorf("failed to generate %s thumbnail: %v", size, err)
+		}
+	}
+
+	bounds := decoded.Bounds()
+	gameImage := models.GameImage{
+		GameID:      gameID,
+		Kind:        kind,
+		Hash:        hash,
+		SourceURL:   sourceURL,
+		ContentType: http.DetectContentType(data),
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		FileSize:    int64(len(data)),
+		Custom:      custom,
+	}
+
+	var replaced []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var previous []models.GameImage
+		if err := tx.Where("game_id = ? AND kind = ?", gameID, kind).Find(&previous).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("game_id = ? AND kind = ?", gameID, kind).Delete(&models.GameImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&gameImage).Error; err != nil {
+			return err
+		}
+		for _, old := range previous {
+			if old.Hash != hash {
+				replaced = append(replaced, old.Hash)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.RemoveUnreferenced(replaced...)
+
+	gameImage.SetURLs()
+	return &gameImage, nil
+}
+
+// DeleteGameImage removes an image record and its files once no other game uses them
+func (s *ImageService) DeleteGameImage(gameImage *models.GameImage) error {
+	if err := s.db.Delete(gameImage).Error; err != nil {
+		return err
+	}
+	s.RemoveUnreferenced(gameImage.Hash)
+	return nil
+}
+
+// RemoveUnreferenced deletes the files of images no game uses any more. Call it
+// after the transaction dropping the records commits, so a rollback never
+// leaves records pointing at deleted files.
+func (s *ImageService) RemoveUnreferenced(hashes ...string) {
+	if s == nil {
+		return
+	}
+	for _, hash := range hashes {
+		var count int64
+		if err := s.db.Model(&models.GameImage{}).Where("hash = ?", hash).Count(&count).Error; err != nil || count > 0 {
+			continue
+		}
+		os.Remove(s.originalPath(hash))
+		for size := range models.ThumbnailSizes {
+			os.Remove(s.thumbnailPath(hash, size))
+		}
+	}
+}
+
+// FilePath resolves a stored image (size "" or "original" for the full image).
+// Missing thumbnails are regenerated from the original.
+func (s *ImageService) FilePath(hash, size string) (string, error) {
+	if !imageHashPattern.MatchString(hash) {
+		return "", fmt.Errorf("invalid image hash")
+	}
+
+	originalPath := s.originalPath(hash)
+	if _, err := os.Stat(originalPath); err != nil {
+		return "", err
+	}
+
+	if size == "" || size == "original" {
+		return originalPath, nil
+	}
+
+	width, ok := models.ThumbnailSizes[size]
+	if !ok {
+		return "", fmt.Errorf("unknown image size: %s", size)
+	}
+
+	thumbPath := s.thumbnailPath(hash, size)
+	if _, err := os.Stat(thumbPath); err == nil {
+		return thumbPath, nil
+	}
+
+	data, err := os.ReadFile(originalPath)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := decodeImage(data)
+	if err != nil {
+		// Fall back to the original when it can't be thumbnailed
+		return originalPath, nil
+	}
+	if err := s.writeThumbnail(hash, size, width, decoded); err != nil {
+		return "", err
+	}
+	return thumbPath, nil
+}
+
+func (s *ImageService) download(sourceURL string) ([]byte, error) {
+	resp, err := s.client.Get(s.screenScraper.AuthorizeURL(sourceURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %v", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("image exceeds %d bytes", maxImageSize)
+	}
+	return data, nil
+}
+
+func (s *ImageService) writeThumbnail(hash, size string, width int, src image.Image) error {
+	path := s.thumbnailPath(hash, size)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeToWidth(src, width), &jpeg.Options{Quality: 85}); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// decodeImage decodes data after checking its declared size against maxImagePixels
+func decodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image data: %v", err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxImagePixels {
+		return nil, fmt.Errorf("image is %dx%d, larger than %d pixels", config.Width, config.Height, maxImagePixels)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image data: %v", err)
+	}
+	return decoded, nil
+}
+
+// originalPath shards files by hash prefix to keep directories small
+func (s *ImageService) originalPath(hash string) string {
+	return filepath.Join(s.root, "originals", hash[:2], hash)
+}
+
+func (s *ImageService) thumbnailPath(hash, size string) string {
+	return filepath.Join(s.root, "thumbs", size, hash[:2], hash+".jpg")
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resizeToWidth downscales with a box filter, preserving aspect ratio; smaller images are returned as-is
+func resizeToWidth(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= width || srcW == 0 {
+		return src
+	}
+
+	height := srcH * width / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := bounds.Min.Y + (y+1)*srcH/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := bounds.Min.X + (x+1)*srcW/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
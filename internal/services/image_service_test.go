@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testPNG encodes a solid width x height image
+func testPNG(t *testing.T, width, height int, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// pngHeader is the start of a PNG declaring width x height, enough for
+// image.DecodeConfig but not for decoding
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6 // 8-bit RGBA
+	chunk := append([]byte("IHDR"), ihdr...)
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func newTestImageService(t *testing.T) (*ImageService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+	return NewImageService(db, t.TempDir(), false, nil), db
+}
+
+func TestImageService_StoreGameImage(t *testing.T) {
+	images, db := newTestImageService(t)
+
+	data := testPNG(t, 800, 600, color.RGBA{200, 40, 40, 255})
+	stored, err := images.StoreGameImage(1, "cover", data, "https://example.com/cover.png", false)
+	require.NoError(t, err)
+	assert.Len(t, stored.Hash, 64)
+	assert.Equal(t, 800, stored.Width)
+	assert.Equal(t, 600, stored.Height)
+	assert.Equal(t, "image/png", stored.ContentType)
+	assert.Equal(t, int64(len(data)), stored.FileSize)
+	assert.Equal(t, "/api/v1/images/"+stored.Hash+"/small", stored.URLs["small"])
+
+	original, err := os.ReadFile(images.originalPath(stored.Hash))
+	require.NoError(t, err)
+	assert.Equal(t, data, original)
+	for size, width := range models.ThumbnailSizes {
+		file, err := os.Open(images.thumbnailPath(stored.Hash, size))
+		require.NoError(t, err, size)
+		thumb, err := jpeg.DecodeConfig(file)
+		file.Close()
+		require.NoError(t, err, size)
+		assert.Equal(t, width, thumb.Width, size)
+		assert.Equal(t, 600*width/800, thumb.Height, size)
+	}
+
+	// Storing again replaces the game's image of that kind
+	replacement := testPNG(t, 64, 64, color.RGBA{0, 0, 255, 255})
+	second, err := images.StoreGameImage(1, "cover", replacement, "", true)
+	require.NoError(t, err)
+	assert.Equal(t, 64, second.Width)
+	var count int64
+	db.Model(&models.GameImage{}).Where("game_id = ? AND kind = ?", 1, "cover").Count(&count)
+	assert.Equal(t, int64(1), count)
+	assert.NoFileExists(t, images.originalPath(stored.Hash))
+
+	_, err = images.StoreGameImage(1, "cover", []byte("not an image"), "", true)
+	assert.Error(t, err)
+	_, err = images.StoreGameImage(1, "cover", pngHeader(20000, 20000), "", true)
+	assert.ErrorContains(t, err, "larger than")
+}
+
+func TestImageService_Dedupe(t *testing.T) {
+	images, db := newTestImageService(t)
+
+	data := testPNG(t, 300, 400, color.RGBA{10, 200, 10, 255})
+	first, err := images.StoreGameImage(1, "cover", data, "", false)
+	require.NoError(t, err)
+	second, err := images.StoreGameImage(2, "box_art", data, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, first.Hash, second.Hash)
+	entries, err := os.ReadDir(filepath.Dir(images.originalPath(first.Hash)))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Files stay while another game still uses them
+	require.NoError(t, images.DeleteGameImage(first))
+	assert.FileExists(t, images.originalPath(first.Hash))
+	for size := range models.ThumbnailSizes {
+		assert.FileExists(t, images.thumbnailPath(first.Hash, size))
+	}
+
+	var remaining models.GameImage
+	require.NoError(t, db.First(&remaining, second.ID).Error)
+	require.NoError(t, images.DeleteGameImage(&remaining))
+	assert.NoFileExists(t, images.originalPath(first.Hash))
+	for size := range models.ThumbnailSizes {
+		assert.NoFileExists(t, images.thumbnailPath(first.Hash, size))
+	}
+}
+
+func TestImageService_RemoveUnreferenced(t *testing.T) {
+	images, db := newTestImageService(t)
+
+	stored, err := images.StoreGameImage(1, "cover", testPNG(t, 32, 32, color.White), "", false)
+	require.NoError(t, err)
+
+	images.RemoveUnreferenced(stored.Hash)
+	assert.FileExists(t, images.originalPath(stored.Hash), "still referenced")
+
+	require.NoError(t, db.Where("game_id = ?", 1).Delete(&models.GameImage{}).Error)
+	images.RemoveUnreferenced(stored.Hash)
+	assert.NoFileExists(t, images.originalPath(stored.Hash))
+	for size := range models.ThumbnailSizes {
+		assert.NoFileExists(t, images.thumbnailPath(stored.Hash, size))
+	}
+
+	var none *ImageService
+	none.RemoveUnreferenced(stored.Hash)
+}
+
+func TestImageService_FilePath(t *testing.T) {
+	images, _ := newTestImageService(t)
+
+	stored, err := images.StoreGameImage(1, "cover", testPNG(t, 600, 300, color.Black), "", false)
+	require.NoError(t, err)
+
+	path, err := images.FilePath(stored.Hash, "")
+	require.NoError(t, err)
+	assert.Equal(t, images.originalPath(stored.Hash), path)
+	path, err = images.FilePath(stored.Hash, "original")
+	require.NoError(t, err)
+	assert.Equal(t, images.originalPath(stored.Hash), path)
+
+	// Missing thumbnails are generated again
+	thumbPath := images.thumbnailPath(stored.Hash, "medium")
+	require.NoError(t, os.Remove(thumbPath))
+	path, err = images.FilePath(stored.Hash, "medium")
+	require.NoError(t, err)
+	assert.Equal(t, thumbPath, path)
+	assert.FileExists(t, thumbPath)
+
+	_, err = images.FilePath(stored.Hash, "huge")
+	assert.Error(t, err)
+	_, err = images.FilePath("../../etc/passwd", "")
+	assert.Error(t, err)
+	_, err = images.FilePath(strings.Repeat("a", 64), "")
+	assert.Error(t, err)
+}
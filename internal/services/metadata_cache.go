@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MetadataResponseCache persists metadata provider responses in the database so
+// repeated searches and batch re-runs survive restarts without hitting the API
+type MetadataResponseCache struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewMetadataResponseCache creates a database-backed response cache; a zero TTL disables caching
+func NewMetadataResponseCache(db *gorm.DB, ttl time.Duration) *MetadataResponseCache {
+	return &MetadataResponseCache{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+// Enabled reports whether responses are cached at all
+func (c *MetadataResponseCache) Enabled() bool {
+	return c != nil && c.db != nil && c.ttl > 0
+}
+
+// Get loads a cached response into dest, returning false on a miss or expired entry
+func (c *MetadataResponseCache) Get(provider, query, platform string, dest interface{}) bool {
+	if !c.Enabled() {
+		return false
+	}
+
+	var entry models.MetadataCacheEntry
+	result := c.db.Where("provider = ? AND query = ? AND platform = ? AND expires_at > ?",
+		provider, normalizeCacheKey(query), normalizeCacheKey(platform), time.Now()).
+		First(&entry)
+	if result.Error != nil {
+		return false
+	}
+
+	if err := json.Unmarshal([]byte(entry.Response), dest); err != nil {
+		return false
+	}
+	return true
+}
+
+// Set stores a response, replacing any previous entry for the same key
+func (c *MetadataResponseCache) Set(provider, query, platform string, response interface{}) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	entry := models.MetadataCacheEntry{
+		Provider:  provider,
+		Query:     normalizeCacheKey(query),
+		Platform:  normalizeCacheKey(platform),
+		Response:  string(data),
+		ExpiresAt: time.Now().Add(c.ttl),
+	}
+
+	return c.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "query"}, {Name: "platform"}},
+		DoUpdates: clause.AssignmentColumns([]string{"response", "expires_at", "updated_at"}),
+	}).Create(&entry).Error
+}
+
+// PurgeExpired deletes entries past their TTL and returns how many were removed
+func (c *MetadataResponseCache) PurgeExpired() (int64, error) {
+	if c == nil || c.db == nil {
+		return 0, nil
+	}
+	result := c.db.Where("expires_at <= ?", time.Now()).Delete(&models.MetadataCacheEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// Clear removes every cached response
+func (c *MetadataResponseCache) Clear() error {
+	if c == nil || c.db == nil {
+		return nil
+	}
+	return c.db.Where("1 = 1").Delete(&models.MetadataCacheEntry{}).Error
+}
+
+// Count returns the number of stored entries, including expired ones not yet purged
+func (c *MetadataResponseCache) Count() int64 {
+	var count int64
+	if c == nil || c.db == nil {
+		return count
+	}
+	c.db.Model(&models.MetadataCacheEntry{}).Count(&count)
+	return count
+}
+
+// normalizeCacheKey lowercases and collapses whitespace so "Chrono  Trigger" and
+// "chrono trigger" share a cache entry
+func normalizeCacheKey(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), " ")
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"strings"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveMetadataDetails persists the extended metadata (genres, companies, franchises,
+// classifications, media, alternative names, release dates, age ratings and
+// time-to-beat estimates) for a game. Each category is only replaced when the
+// metadata actually carries values for it, so a sparse provider response doesn't
+// wipe details fetched earlier.
+func SaveMetadataDetails(db *gorm.DB, gameID uint, metadata *GameMetadata) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		game := &models.Game{ID: gameID}
+
+		genres := metadata.Genres
+		if len(genres) == 0 && metadata.Genre != "" {
+			genres = []string{metadata.Genre}
+		}
+		if len(genres) > 0 {
+			if err := SaveGameGenres(tx, gameID, genres); err != nil {
+				return err
+			}
+		}
+
+		if len(metadata.Developers) > 0 || len(metadata.Publishers) > 0 {
+			if err := saveGameCompanies(tx, gameID, metadata); err != nil {
+				return err
+			}
+		}
+
+		if names := uniqueNames(metadata.Franchises); len(names) > 0 {
+			franchises, err := findOrCreateNamed[models.Franchise](tx, names)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(game).Association("Franchises").Replace(franchises); err != nil {
+				return err
+			}
+		}
+
+		if names := uniqueNames(metadata.GameModes); len(names) > 0 {
+			modes, err := findOrCreateNamed[models.GameMode](tx, names)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(game).Association("GameModes").Replace(modes); err != nil {
+				return err
+			}
+		}
+
+		if names := uniqueNames(metadata.Themes); len(names) > 0 {
+			themes, err := findOrCreateNamed[models.Theme](tx, names)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(game).Association("Themes").Replace(themes); err != nil {
+				return err
+			}
+		}
+
+		if names := uniqueNames(metadata.PlayerPerspectives); len(names) > 0 {
+			perspectives, err := findOrCreateNamed[models.PlayerPerspective](tx, names)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(game).Association("PlayerPerspectives").Replace(perspectives); err != nil {
+				return err
+			}
+		}
+
+		if len(metadata.Screenshots) > 0 || len(metadata.Artworks) > 0 || len(metadata.Videos) > 0 || metadata.WheelURL != "" {
+			if err := saveGameMedia(tx, gameID, metadata); err != nil {
+				return err
+			}
+		}
+
+		if len(metadata.AlternativeNames) > 0 {
+			if err := tx.Where("game_id = ?", gameID).Delete(&models.AlternativeName{}).Error; err != nil {
+				return err
+			}
+			for _, alt := range metadata.AlternativeNames {
+				name := models.AlternativeName{GameID: gameID, Name: alt.Name, Comment: alt.Comment}
+				if err := tx.Create(&name).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(metadata.ReleaseDates) > 0 {
+			if err := tx.Where("game_id = ?", gameID).Delete(&models.ReleaseDate{}).Error; err != nil {
+				return err
+			}
+			for _, release := range metadata.ReleaseDates {
+				date := models.ReleaseDate{
+					GameID:         gameID,
+					Region:         release.Region,
+					Date:           release.Date,
+					Human:          release.Human,
+					PlatformIGDBID: release.PlatformIGDBID,
+				}
+				if err := tx.Create(&date).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(metadata.AgeRatings) > 0 {
+			if err := tx.Where("game_id = ?", gameID).Delete(&models.AgeRating{}).Error; err != nil {
+				return err
+			}
+			for _, rating := range metadata.AgeRatings {
+				ageRating := models.AgeRating{
+					GameID:     gameID,
+					Board:      rating.Board,
+					Rating:     rating.Rating,
+					MinimumAge: rating.MinimumAge,
+				}
+				if err := tx.Create(&ageRating).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if metadata.TimeToBeat != nil {
+			timeToBeat := models.TimeToBeat{
+				GameID:            gameID,
+				HastilyMinutes:    metadata.TimeToBeat.HastilyMinutes,
+				NormallyMinutes:   metadata.TimeToBeat.NormallyMinutes,
+				CompletelyMinutes: metadata.TimeToBeat.CompletelyMinutes,
+				SubmissionCount:   metadata.TimeToBeat.SubmissionCount,
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "game_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"hastily_minutes", "normally_minutes", "completely_minutes", "submission_count", "updated_at"}),
+			}).Create(&timeToBeat).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SaveGameGenres replaces a game's genres and keeps the legacy primary genre column in sync
+func SaveGameGenres(db *gorm.DB, gameID uint, names []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		genres, err := findOrCreateNamed[models.Genre](tx, uniqueNames(names))
+		if err != nil {
+			return err
+		}
+
+		game := &models.Game{ID: gameID}
+		if err := tx.Model(game).Association("Genres").Replace(genres); err != nil {
+			return err
+		}
+
+		primary := ""
+		if len(genres) > 0 {
+			primary = genres[0].Name
+		}
+		return tx.Model(game).Update("genre", primary).Error
+	})
+}
+
+// saveGameCompanies replaces the companies for each role the metadata names;
+// roles it leaves empty keep their existing links
+func saveGameCompanies(tx *gorm.DB, gameID uint, metadata *GameMetadata) error {
+	roles := map[string][]string{
+		"developer": uniqueNames(metadata.Developers),
+		"publisher": uniqueNames(metadata.Publishers),
+	}
+	for role, names := range roles {
+		if len(names) == 0 {
+			continue
+		}
+		if err := tx.Where("game_id = ? AND role = ?", gameID, role).Delete(&models.GameCompany{}).Error; err != nil {
+			return err
+		}
+		companies, err := findOrCreateNamed[models.Company](tx, names)
+		if err != nil {
+			return err
+		}
+		for _, company := range companies {
+			link := models.GameCompany{GameID: gameID, CompanyID: company.ID, Role: role}
+			if err := tx.Create(&link).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func saveGameMedia(tx *gorm.DB, gameID uint, metadata *GameMetadata) error {
+	if err := tx.Where("game_id = ?", gameID).Delete(&models.GameMedia{}).Error; err != nil {
+		return err
+	}
+
+	var media []models.GameMedia
+	for _, url := range metadata.Screenshots {
+		media = append(media, models.GameMedia{GameID: gameID, Kind: "screenshot", URL: url})
+	}
+	for _, url := range metadata.Artworks {
+		media = append(media, models.GameMedia{GameID: gameID, Kind: "artwork", URL: url})
+	}
+	if metadata.WheelURL != "" {
+		media = append(media, models.GameMedia{GameID: gameID, Kind: "wheel", URL: metadata.WheelURL})
+	}
+	for _, video := range metadata.Videos {
+		media = append(media, models.GameMedia{GameID: gameID, Kind: "video", URL: video.URL, Title: video.Title})
+	}
+	if len(media) == 0 {
+		return nil
+	}
+	return tx.Create(&media).Error
+}
+
+// findOrCreateNamed loads lookup rows (companies, franchises, themes, ...) by name, creating missing ones
+func findOrCreateNamed[T any](tx *gorm.DB, names []string) ([]T, error) {
+	items := make([]T, 0, len(names))
+	for _, name := range names {
+		var item T
+		if err := tx.Where("name = ?", name).Attrs(map[string]interface{}{"name": name}).FirstOrCreate(&item).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// uniqueNames trims names and drops blanks and duplicates, keeping the provider's order
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
@@ -2,27 +2,93 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
+	"pelico/internal/models"
 )
 
+// igdbCacheProvider keys cached IGDB responses; bump the suffix whenever
+// GameMetadata gains fields so stale entries aren't served
+const igdbCacheProvider = "igdb:v5"
+
+// screenScraperCacheProvider keys cached ScreenScraper ROM lookups
+const screenScraperCacheProvider = "screenscraper:v1"
+
 type MetadataService struct {
-	client      *http.Client
-	igdbService *IGDBService
+	client        *http.Client
+	igdbService   *IGDBService
+	screenScraper *ScreenScraperService
+	responseCache *MetadataResponseCache
+	logger        *LoggerService
 }
 
 type GameMetadata struct {
 	Title       string  `json:"title"`
 	Description string  `json:"description"`
 	Rating      float32 `json:"rating"`
-	Genre       string  `json:"genre"`
+	Genre       string  `json:"genre"`   // primary (first) genre
+	Genres      []string `json:"genres,omitempty"`
 	Year        int     `json:"year"`
 	CoverArtURL string  `json:"cover_art_url"`
 	BoxArtURL   string  `json:"box_art_url"`
 	IGDBID      int     `json:"igdb_id"`
+	ScreenScraperID int `json:"screenscraper_id,omitempty"`
+	WheelURL    string  `json:"wheel_url,omitempty"` // transparent title logo
+	
+	// Extended metadata, persisted in normalized tables by SaveMetadataDetails
+	Developers         []string                  `json:"developers,omitempty"`
+	Publishers         []string                  `json:"publishers,omitempty"`
+	Franchises         []string                  `json:"franchises,omitempty"`
+	GameModes          []string                  `json:"game_modes,omitempty"`
+	Themes             []string                  `json:"themes,omitempty"`
+	PlayerPerspectives []string                  `json:"player_perspectives,omitempty"`
+	Screenshots        []string                  `json:"screenshots,omitempty"`
+	Artworks           []string                  `json:"artworks,omitempty"`
+	Videos             []MetadataVideo           `json:"videos,omitempty"`
+	AlternativeNames   []MetadataAlternativeName `json:"alternative_names,omitempty"`
+	ReleaseDates       []MetadataReleaseDate     `json:"release_dates,omitempty"`
+	AgeRatings         []MetadataAgeRating       `json:"age_ratings,omitempty"`
+	TimeToBeat         *MetadataTimeToBeat       `json:"time_to_beat,omitempty"`
+}
+
+// MetadataVideo is a trailer or gameplay video reference
+type MetadataVideo struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// MetadataReleaseDate is a release in one region; Date is nil for announced (TBD) releases
+type MetadataReleaseDate struct {
+	Region         string     `json:"region"`
+	Date           *time.Time `json:"date,omitempty"`
+	Human          string     `json:"human,omitempty"`
+	PlatformIGDBID int        `json:"platform_igdb_id,omitempty"`
+}
+
+// MetadataAgeRating is a rating board classification
+type MetadataAgeRating struct {
+	Board      string `json:"board"`
+	Rating     string `json:"rating"`
+	MinimumAge int    `json:"minimum_age"`
+}
+
+// MetadataTimeToBeat holds completion time estimates in minutes
+type MetadataTimeToBeat struct {
+	HastilyMinutes    int `json:"hastily_minutes"`
+	NormallyMinutes   int `json:"normally_minutes"`
+	CompletelyMinutes int `json:"completely_minutes"`
+	SubmissionCount   int `json:"submission_count"`
+}
+
+// MetadataAlternativeName is another title a game is known by
+type MetadataAlternativeName struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment"`
 }
 
 // TheGamesDB API structures
@@ -88,31 +154,153 @@ type RAWGPlatform struct {
 	} `json:"platform"`
 }
 
-func NewMetadataService(clientID, clientSecret string) *MetadataService {
+// NewMetadataService creates the metadata service; screenScraper may be nil to skip
+// hash-based ROM identification and responseCache may be nil to always query providers
+func NewMetadataService(clientID, clientSecret string, screenScraper *ScreenScraperService, responseCache *MetadataResponseCache, logger *LoggerService) *MetadataService {
 	return &MetadataService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		igdbService: NewIGDBService(clientID, clientSecret),
+		igdbService:   NewIGDBService(clientID, clientSecret, logger),
+		screenScraper: screenScraper,
+		responseCache: responseCache,
+		logger:        logger,
 	}
 }
 
-func (s *MetadataService) FetchGameMetadata(title, platform string) (*GameMetadata, error) {
-	// Use IGDB if available
-	if s.igdbService != nil {
-		return s.igdbService.FetchGameMetadata(title, platform)
+// ResponseCache exposes the persistent provider response cache (may be nil)
+func (s *MetadataService) ResponseCache() *MetadataResponseCache {
+	return s.responseCache
+}
+
+func (s *MetadataService) FetchGameMetadata(title string, platform *models.Platform) (*GameMetadata, error) {
+	// Share cached search results so a fetch after a search (or a batch re-run) is free
+	results, err := s.SearchGames(title, platform)
+	if err != nil {
+		return nil, err
+	}
+	
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no game found with title: %s", title)
 	}
 	
-	return nil, fmt.Errorf("no metadata service configured")
+	// Return the first (best) match
+	return &results[0], nil
 }
 
-func (s *MetadataService) SearchGames(title, platform string) ([]GameMetadata, error) {
+// FetchMetadataForGame identifies ROM games by file hash through ScreenScraper when the
+// platform is linked to a ScreenScraper system, falling back to an IGDB title search.
+// game must have Platform and FileLocations loaded.
+func (s *MetadataService) FetchMetadataForGame(game *models.Game) (*GameMetadata, error) {
+	if s.screenScraper.Enabled() && game.Platform.ScreenScraperID > 0 {
+		for _, file := range game.FileLocations {
+			rom := ROMIdentityFromFile(file)
+			if !rom.HasHash() {
+				continue
+			}
+			metadata, err := s.IdentifyROM(rom, game.Platform.ScreenScraperID)
+			if err == nil {
+				return metadata, nil
+			}
+			if !errors.Is(err, ErrROMNotIdentified) {
+				s.logger.LogWarn("screenscraper_lookup_failed",
+					slog.Uint64("game_id", uint64(game.ID)),
+					slog.String("file", file.FilePath),
+					slog.String("error", err.Error()))
+				break
+			}
+		}
+	}
+	
+	return s.FetchGameMetadata(game.Title, &game.Platform)
+}
+
+// IdentifyROM looks a dump up on ScreenScraper, caching hits and misses
+func (s *MetadataService) IdentifyROM(rom ROMIdentity, systemID int) (*GameMetadata, error) {
+	if !s.screenScraper.Enabled() {
+		return nil, fmt.Errorf("ScreenScraper is not configured")
+	}
+	
+	platformKey := fmt.Sprintf("ss:%d", systemID)
+	
+	// Results are cached as a slice so a known miss (empty) differs from no entry
+	var cached []GameMetadata
+	if s.responseCache.Get(screenScraperCacheProvider, rom.cacheKey(), platformKey, &cached) {
+		if len(cached) == 0 {
+			return nil, ErrROMNotIdentified
+		}
+		return &cached[0], nil
+	}
+	
+	metadata, err := s.screenScraper.IdentifyROM(rom, systemID)
+	results := []GameMetadata{}
+	switch {
+	case err == nil:
+		results = append(results, *metadata)
+	case !errors.Is(err, ErrROMNotIdentified):
+		return nil, err
+	}
+	
+	if cacheErr := s.responseCache.Set(screenScraperCacheProvider, rom.cacheKey(), platformKey, results); cacheErr != nil {
+		s.logger.LogError("metadata_cache_write_failed", cacheErr,
+			slog.String("provider", screenScraperCacheProvider),
+			slog.String("file", rom.FileName))
+	}
+	
+	return metadata, err
+}
+
+// SearchGames searches metadata providers; platform may be nil for an unfiltered search
+func (s *MetadataService) SearchGames(title string, platform *models.Platform) ([]GameMetadata, error) {
 	// Use IGDB if available
-	if s.igdbService != nil {
-		return s.igdbService.SearchGames(title, platform)
+	if s.igdbService == nil {
+		return nil, fmt.Errorf("no metadata service configured")
 	}
 	
-	return nil, fmt.Errorf("no metadata service configured")
+	platformKey := platformCacheKey(platform)
+	
+	var cached []GameMetadata
+	if s.responseCache.Get(igdbCacheProvider, title, platformKey, &cached) {
+		return cached, nil
+	}
+	
+	results, complete, err := s.igdbService.search(title, platform)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Empty result sets are cached too, so unknown titles don't re-query on every
+	// batch run. Results missing time-to-beat data aren't, so the next search
+	// can fill it in.
+	if !complete {
+		return results, nil
+	}
+	if err := s.responseCache.Set(igdbCacheProvider, title, platformKey, results); err != nil {
+		s.logger.LogError("metadata_cache_write_failed", err,
+			slog.String("provider", igdbCacheProvider),
+			slog.String("title", title))
+	}
+	
+	return results, nil
+}
+
+// ListIGDBPlatforms returns IGDB's platform list for linking local platforms
+func (s *MetadataService) ListIGDBPlatforms() ([]IGDBPlatformInfo, error) {
+	if s.igdbService == nil {
+		return nil, fmt.Errorf("no metadata service configured")
+	}
+	return s.igdbService.ListPlatforms()
+}
+
+// platformCacheKey keys linked platforms by IGDB ID so renamed platforms keep their cache
+func platformCacheKey(platform *models.Platform) string {
+	if platform == nil {
+		return ""
+	}
+	if platform.IGDBID > 0 {
+		return fmt.Sprintf("igdb:%d", platform.IGDBID)
+	}
+	return platform.Name
 }
 
 func (s *MetadataService) fetchFromTheGamesDB(title, platform string) (*GameMetadata, error) {
@@ -168,13 +356,15 @@ func (s *MetadataService) fetchFromTheGamesDB(title, platform string) (*GameMeta
 		}
 	}
 	
-	// Get genre from TheGamesDB genres
-	if len(game.Genres) > 0 {
-		genreID := game.Genres[0] // Take first genre
+	// Get genres from TheGamesDB genres
+	for _, genreID := range game.Genres {
 		if genre, exists := apiResp.Include.Genres[fmt.Sprintf("%d", genreID)]; exists {
-			metadata.Genre = genre.Name
+			metadata.Genres = append(metadata.Genres, genre.Name)
 		}
 	}
+	if len(metadata.Genres) > 0 {
+		metadata.Genre = metadata.Genres[0]
+	}
 	
 	// Find cover art
 	for _, boxart := range apiResp.Include.Boxart {
@@ -238,13 +428,15 @@ func (s *MetadataService) searchTheGamesDB(title, platform string) ([]GameMetada
 			}
 		}
 		
-		// Get genre from TheGamesDB genres
-		if len(game.Genres) > 0 {
-			genreID := game.Genres[0] // Take first genre
+		// Get genres from TheGamesDB genres
+		for _, genreID := range game.Genres {
 			if genre, exists := apiResp.Include.Genres[fmt.Sprintf("%d", genreID)]; exists {
-				metadata.Genre = genre.Name
+				metadata.Genres = append(metadata.Genres, genre.Name)
 			}
 		}
+		if len(metadata.Genres) > 0 {
+			metadata.Genre = metadata.Genres[0]
+		}
 		
 		// Find cover art
 		for _, boxart := range apiResp.Include.Boxart {
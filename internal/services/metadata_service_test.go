@@ -0,0 +1,67 @@
+package services
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMetadataService_SearchGamesCache(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+
+	searches := 0
+	timeToBeatDown := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/games":
+			searches++
+			w.Write([]byte(`[{"id": 1026, "name": "The Legend of Zelda: A Link to the Past"}]`))
+		case "/game_time_to_beats":
+			if timeToBeatDown {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`[{"game_id": 1026, "normally": 57600, "count": 12}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	metadata := NewMetadataService("client", "secret", nil, NewMetadataResponseCache(db, time.Hour), NewLoggerService(slog.LevelError))
+	metadata.igdbService.baseURL = server.URL
+	metadata.igdbService.accessToken = "token"
+	metadata.igdbService.tokenExpiry = time.Now().Add(time.Hour)
+
+	// Results missing time-to-beat data are returned but not cached
+	results, err := metadata.SearchGames("Link to the Past", nil)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].TimeToBeat)
+	assert.Zero(t, metadata.responseCache.Count())
+
+	timeToBeatDown = false
+	results, err = metadata.SearchGames("Link to the Past", nil)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NotNil(t, results[0].TimeToBeat)
+	assert.Equal(t, 960, results[0].TimeToBeat.NormallyMinutes)
+	assert.Equal(t, int64(1), metadata.responseCache.Count())
+
+	// Complete results are served from the cache
+	results, err = metadata.SearchGames("link to the  past", nil)
+	require.NoError(t, err)
+	require.NotNil(t, results[0].TimeToBeat)
+	assert.Equal(t, 2, searches)
+}
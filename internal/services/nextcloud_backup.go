@@ -68,7 +68,7 @@ func (n *NextcloudBackup) TestConnection() error {
 
 func (n *NextcloudBackup) CreateBackup() (*BackupData, error) {
 	backup := &BackupData{
-		Version:   "1.0",
+		Version:   BackupVersion,
 		Timestamp: time.Now(),
 	}
 
@@ -89,6 +89,10 @@ func (n *NextcloudBackup) CreateBackup() (*BackupData, error) {
 		return nil, fmt.Errorf("failed to export file locations: %w", err)
 	}
 
+	if err := exportBackupTables(n.db, backup); err != nil {
+		return nil, err
+	}
+
 	return backup, nil
 }
 
@@ -157,6 +161,33 @@ type BackupData struct {
 	Platforms     []models.Platform      `json:"platforms"`
 	Sessions      []models.PlaySession   `json:"sessions"`
 	FileLocations []models.FileLocation  `json:"file_locations"`
+	
+	// Added in 2.0
+	Genres             []models.Genre             `json:"genres,omitempty"`
+	Tags               []models.Tag               `json:"tags,omitempty"`
+	Companies          []models.Company           `json:"companies,omitempty"`
+	Franchises         []models.Franchise         `json:"franchises,omitempty"`
+	GameModes          []models.GameMode          `json:"game_modes,omitempty"`
+	Themes             []models.Theme             `json:"themes,omitempty"`
+	PlayerPerspectives []models.PlayerPerspective `json:"player_perspectives,omitempty"`
+	GameLinks          map[string][]BackupLink    `json:"game_links,omitempty"` // many-to-many rows by table
+	GameCompanies      []models.GameCompany       `json:"game_companies,omitempty"`
+	Media              []models.GameMedia         `json:"media,omitempty"`
+	AlternativeNames   []models.AlternativeName   `json:"alternative_names,omitempty"`
+	ReleaseDates       []models.ReleaseDate       `json:"release_dates,omitempty"`
+	AgeRatings         []models.AgeRating         `json:"age_ratings,omitempty"`
+	TimesToBeat        []models.TimeToBeat        `json:"times_to_beat,omitempty"`
+	Images             []models.GameImage         `json:"images,omitempty"`
+	Collections        []models.Collection        `json:"collections,omitempty"`
+	CollectionItems    []models.CollectionItem    `json:"collection_items,omitempty"`
+	CustomFields       []models.CustomField       `json:"custom_fields,omitempty"`
+	CustomValues       []models.GameCustomValue   `json:"custom_values,omitempty"`
+	PhysicalCopies     []models.PhysicalCopy      `json:"physical_copies,omitempty"`
+	PricePoints        []models.PricePoint        `json:"price_points,omitempty"`
+	Goals              []models.Goal              `json:"goals,omitempty"`
+	Wishlist           []models.Wishlist          `json:"wishlist,omitempty"`
+	Shortlist          []models.Shortlist         `json:"shortlist,omitempty"`
+	ImportedPlaytime   []models.ImportedPlaytime  `json:"imported_playtime,omitempty"`
 }
 
 // BackupStats provides information about backup contents
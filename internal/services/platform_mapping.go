@@ -0,0 +1,157 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// platformManufacturers are stripped when comparing names, so "Sega Saturn"
+// matches "Saturn" and "Sony PlayStation" matches "PlayStation"
+var platformManufacturers = []string{"nintendo", "sega", "sony", "microsoft", "atari", "nec", "snk", "bandai"}
+
+// PlatformLink describes a local platform matched to an IGDB platform
+type PlatformLink struct {
+	PlatformID   uint   `json:"platform_id"`
+	PlatformName string `json:"platform_name"`
+	IGDBID       int    `json:"igdb_id"`
+	IGDBName     string `json:"igdb_name"`
+}
+
+// PlatformLinkResult reports the outcome of an auto-link run
+type PlatformLinkResult struct {
+	Linked    []PlatformLink `json:"linked"`
+	Skipped   []string       `json:"skipped"`   // already linked
+	Unmatched []string       `json:"unmatched"` // no IGDB platform found
+}
+
+// LinkPlatforms matches local platforms against IGDB's platform list by name,
+// abbreviation and alternative names and stores the IGDB IDs. Platforms that are
+// already linked are left alone unless overwrite is set.
+func LinkPlatforms(db *gorm.DB, igdbPlatforms []IGDBPlatformInfo, overwrite bool) (*PlatformLinkResult, error) {
+	var platforms []models.Platform
+	if err := db.Order("name ASC").Find(&platforms).Error; err != nil {
+		return nil, err
+	}
+
+	result := &PlatformLinkResult{
+		Linked:    []PlatformLink{},
+		Skipped:   []string{},
+		Unmatched: []string{},
+	}
+
+	for _, platform := range platforms {
+		if platform.IGDBID > 0 && !overwrite {
+			result.Skipped = append(result.Skipped, platform.Name)
+			continue
+		}
+
+		match := MatchIGDBPlatform(platform.Name, igdbPlatforms)
+		if match == nil {
+			result.Unmatched = append(result.Unmatched, platform.Name)
+			continue
+		}
+
+		if err := db.Model(&platform).Update("igdb_id", match.ID).Error; err != nil {
+			return nil, err
+		}
+		result.Linked = append(result.Linked, PlatformLink{
+			PlatformID:   platform.ID,
+			PlatformName: platform.Name,
+			IGDBID:       match.ID,
+			IGDBName:     match.Name,
+		})
+	}
+
+	return result, nil
+}
+
+// MatchIGDBPlatform finds the IGDB platform best matching a local platform name.
+// Exact name matches win over abbreviation/alternative name matches; the built-in
+// name map is consulted last.
+func MatchIGDBPlatform(name string, igdbPlatforms []IGDBPlatformInfo) *IGDBPlatformInfo {
+	localKeys := platformNameKeys(name)
+	if len(localKeys) == 0 {
+		return nil
+	}
+
+	normalizedName := normalizePlatformName(name)
+	var fallback *IGDBPlatformInfo
+	for i := range igdbPlatforms {
+		candidate := &igdbPlatforms[i]
+		if normalizePlatformName(candidate.Name) == normalizedName {
+			return candidate
+		}
+		if fallback == nil && keysOverlap(localKeys, igdbPlatformKeys(candidate)) {
+			fallback = candidate
+		}
+	}
+	if fallback != nil {
+		return fallback
+	}
+
+	// Last resort: the historical hardcoded mapping knows a few common aliases
+	if id, err := strconv.Atoi(legacyIGDBPlatformID(name)); err == nil {
+		for i := range igdbPlatforms {
+			if igdbPlatforms[i].ID == id {
+				return &igdbPlatforms[i]
+			}
+		}
+	}
+	return nil
+}
+
+func igdbPlatformKeys(platform *IGDBPlatformInfo) map[string]bool {
+	keys := make(map[string]bool)
+	for _, value := range []string{platform.Name, platform.Abbreviation, platform.AlternativeName, strings.ReplaceAll(platform.Slug, "-", " ")} {
+		// IGDB packs several aliases into one field, e.g. "Sega Mega Drive/Genesis" or "SNES, Super Famicom"
+		for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == ',' }) {
+			for key := range platformNameKeys(part) {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// platformNameKeys returns the normalized name plus its manufacturer-less variant
+func platformNameKeys(name string) map[string]bool {
+	keys := make(map[string]bool)
+	normalized := normalizePlatformName(name)
+	if normalized == "" {
+		return keys
+	}
+	keys[normalized] = true
+
+	for _, manufacturer := range platformManufacturers {
+		stripped := strings.TrimPrefix(normalized, manufacturer+" ")
+		// Skip short or numeric remainders ("64", "ds") that would match unrelated platforms
+		if stripped == normalized || len(stripped) < 3 || strings.Trim(stripped, "0123456789 ") == "" {
+			continue
+		}
+		keys[stripped] = true
+	}
+	return keys
+}
+
+// normalizePlatformName lowercases and reduces punctuation to single spaces
+func normalizePlatformName(name string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+func keysOverlap(a, b map[string]bool) bool {
+	for key := range a {
+		if b[key] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchIGDBPlatform(t *testing.T) {
+	igdbPlatforms := []IGDBPlatformInfo{
+		{ID: 4, Name: "Nintendo 64", Abbreviation: "N64"},
+		{ID: 15, Name: "Commodore C64/128/MAX", Abbreviation: "C64"},
+		{ID: 19, Name: "Super Nintendo Entertainment System", Abbreviation: "SNES", AlternativeName: "Super Nintendo, Super Famicom"},
+		{ID: 29, Name: "Sega Mega Drive/Genesis", Abbreviation: "Genesis/MegaDrive", AlternativeName: "Sega Genesis"},
+		{ID: 78, Name: "Sega CD", Abbreviation: "Sega CD", AlternativeName: "Mega-CD"},
+		{ID: 7, Name: "PlayStation", Abbreviation: "PS1", AlternativeName: "PSX, PSOne"},
+	}
+
+	tests := []struct {
+		name   string
+		wantID int
+	}{
+		{name: "Nintendo 64", wantID: 4},
+		{name: "Super Famicom", wantID: 19},
+		{name: "SNES", wantID: 19},
+		{name: "Sega Genesis", wantID: 29},
+		{name: "Mega Drive", wantID: 29},
+		{name: "Sega CD", wantID: 78},
+		{name: "Mega CD", wantID: 78},
+		{name: "Sony PlayStation", wantID: 7},
+		{name: "psx", wantID: 7},
+		{name: "Vectrex", wantID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := MatchIGDBPlatform(tt.name, igdbPlatforms)
+			if tt.wantID == 0 {
+				assert.Nil(t, match)
+				return
+			}
+			if assert.NotNil(t, match) {
+				assert.Equal(t, tt.wantID, match.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// PlaytimeImportResult summarizes one run of a play time importer
+type PlaytimeImportResult struct {
+	Source          string   `json:"source"`
+	ItemsFound      int      `json:"items_found"`
+	SessionsCreated int      `json:"sessions_created"`
+	MinutesImported int      `json:"minutes_imported"`
+	Baselined       int      `json:"baselined"` // seen for the first time; earlier play time isn't imported
+	Unchanged       int      `json:"unchanged"`
+	Unmatched       []string `json:"unmatched"`
+	Errors          []string `json:"errors"`
+}
+
+// importedPlaytime is one item's total play time as reported by an external source
+type importedPlaytime struct {
+	Key        string // stable per source, e.g. a runtime log path or Steam app ID
+	GameID     uint
+	Total      time.Duration
+	LastPlayed time.Time
+	Notes      string
+}
+
+// applyImportedPlaytime records the play time added since the previous import
+// of the same item as a session ending when it was last played. The first time
+// an item is seen its total is only stored as the baseline: it spans play
+// before the game was tracked, which as one session would swamp the stats. A
+// total lower than the previous one means the source's counter was reset, so
+// all of it is new. Returns the minutes added, 0 when nothing changed, and
+// whether the item was baselined.
+//
+// The new session is checked for overlaps under the timer's lock like any
+// other. When the reject policy refuses it, the total still becomes the new
+// baseline, since the time was most likely tracked in Pelico already, and
+// ErrSessionOverlap is returned with the overlaps.
+func applyImportedPlaytime(timer *SessionTimer, source string, item importedPlaytime) (int, bool, []SessionOverlap, error) {
+	minutes := 0
+	baselined := false
+	rejected := false
+	var overlaps []SessionOverlap
+	err := timer.Transaction(func(tx *gorm.DB) error {
+		var record models.ImportedPlaytime
+		err := tx.Where("source = ? AND external_key = ?", source, item.Key).First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			baselined = true
+			record = models.ImportedPlaytime{GameID: item.GameID, Source: source, ExternalKey: item.Key}
+			record.TotalSeconds = int64(item.Total / time.Second)
+			if !item.LastPlayed.IsZero() {
+				record.LastPlayed = &item.LastPlayed
+			}
+			return tx.Create(&record).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		played := item.Total - time.Duration(record.TotalSeconds)*time.Second
+		if played < 0 {
+			played = item.Total
+		}
+		// Less than half a minute is left to accumulate until the next import
+		if minutes = SessionMinutes(played); minutes == 0 {
+			return nil
+		}
+
+		end := item.LastPlayed
+		if end.IsZero() {
+			end = time.Now()
+		}
+		record.GameID = item.GameID
+		record.TotalSeconds = int64(item.Total / time.Second)
+		record.LastPlayed = &end
+
+		start := end.Add(-played)
+		overlaps, err = timer.CheckOverlapTx(tx, start, &end, 0)
+		if errors.Is(err, ErrSessionOverlap) {
+			rejected = true
+			return tx.Save(&record).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		session := models.PlaySession{
+			GameID:    item.GameID,
+			StartTime: start,
+			Notes:     item.Notes,
+			Source:    source,
+		}
+		EndSessionAt(&session, end)
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		return tx.Save(&record).Error
+	})
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if rejected {
+		return 0, false, overlaps, ErrSessionOverlap
+	}
+	return minutes, baselined, overlaps, nil
+}
+
+// add records the outcome of applying one item, listing failures and overlaps
+// with other sessions under label
+func (r *PlaytimeImportResult) add(label string, minutes int, baselined bool, overlaps []SessionOverlap, err error) {
+	if errors.Is(err, ErrSessionOverlap) {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: not imported, overlaps %s", label, describeOverlaps(overlaps)))
+		return
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", label, err))
+		return
+	}
+	if minutes > 0 && len(overlaps) > 0 {
+		r.Errors = append(r.Errors, fmt.Sprintf("%s: imported, but overlaps %s", label, describeOverlaps(overlaps)))
+	}
+	r.record(minutes, baselined)
+}
+
+// record adds one applied item to the result
+func (r *PlaytimeImportResult) record(minutes int, baselined bool) {
+	if baselined {
+		r.Baselined++
+		return
+	}
+	if minutes == 0 {
+		r.Unchanged++
+		return
+	}
+	r.SessionsCreated++
+	r.MinutesImported += minutes
+}
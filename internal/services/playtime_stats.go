@@ -0,0 +1,457 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// Playtime series intervals
+const (
+	PlaytimeByDay   = "day"
+	PlaytimeByWeek  = "week"
+	PlaytimeByMonth = "month"
+)
+
+var ErrInvalidPlaytimeRange = errors.New("invalid playtime date range")
+
+// PlaytimeRange is a range of whole days in a timezone: From is midnight of
+// the first day, To midnight after the last one
+type PlaytimeRange struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// NewPlaytimeRange builds a range from inclusive YYYY-MM-DD dates. A missing
+// to is today, a missing from is a year before to.
+func NewPlaytimeRange(from, to string, loc *time.Location, now time.Time) (PlaytimeRange, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	today := now.In(loc)
+	last := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, loc)
+		if err != nil {
+			return PlaytimeRange{}, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidPlaytimeRange)
+		}
+		last = parsed
+	}
+	first := last.AddDate(-1, 0, 1)
+	if from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, loc)
+		if err != nil {
+			return PlaytimeRange{}, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidPlaytimeRange)
+		}
+		first = parsed
+	}
+	if first.After(last) {
+		return PlaytimeRange{}, fmt.Errorf("%w: from is after to", ErrInvalidPlaytimeRange)
+	}
+	return PlaytimeRange{From: first, To: last.AddDate(0, 0, 1), Location: loc}, nil
+}
+
+// Days is the number of days in the range
+func (r PlaytimeRange) Days() int {
+	days := 0
+	for day := r.From; day.Before(r.To); day = day.AddDate(0, 0, 1) {
+		days++
+	}
+	return days
+}
+
+// FirstDay and LastDay format the inclusive range for responses
+func (r PlaytimeRange) FirstDay() string {
+	return r.From.Format("2006-01-02")
+}
+
+func (r PlaytimeRange) LastDay() string {
+	return r.To.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// PlaytimeBucket is the play time within one day, week or month
+type PlaytimeBucket struct {
+	Period   string    `json:"period"` // 2024-03-01, 2024-W09 or 2024-03
+	Start    time.Time `json:"start"`
+	Minutes  int       `json:"minutes"`
+	Hours    float64   `json:"hours"`
+	Sessions int       `json:"sessions"`
+}
+
+// PlaytimeBreakdown is the play time of one game, platform or genre
+type PlaytimeBreakdown struct {
+	ID       uint    `json:"id"`
+	Name     string  `json:"name"`
+	Platform string  `json:"platform,omitempty"` // games only
+	Minutes  int     `json:"minutes"`
+	Hours    float64 `json:"hours"`
+	Sessions int     `json:"sessions"`
+	Share    float64 `json:"share"` // fraction of all play time in the range
+}
+
+// HeatmapDay is one cell of a contribution-calendar view
+type HeatmapDay struct {
+	Date     string `json:"date"`
+	Weekday  int    `json:"weekday"` // 0 = Monday
+	Minutes  int    `json:"minutes"`
+	Sessions int    `json:"sessions"`
+	Level    int    `json:"level"` // 0 (none) to 4 (most)
+}
+
+// PlaytimeHeatmap lists every day in a range with its weekday, so clients can
+// lay the days out in week columns
+type PlaytimeHeatmap struct {
+	From         string       `json:"from"`
+	To           string       `json:"to"`
+	Days         []HeatmapDay `json:"days"`
+	TotalMinutes int          `json:"total_minutes"`
+	ActiveDays   int          `json:"active_days"`
+	MaxMinutes   int          `json:"max_minutes"`
+	Thresholds   [4]int       `json:"thresholds"` // minimum minutes for levels 1-4
+}
+
+// playedSession is the part of a play session the aggregations need
+type playedSession struct {
+	ID        uint
+	GameID    uint
+	StartTime time.Time
+	Duration  int
+}
+
+// PlaytimeStats aggregates completed play sessions. Sessions count towards
+// the day they started on, in the range's timezone.
+type PlaytimeStats struct {
+	db *gorm.DB
+}
+
+func NewPlaytimeStats(db *gorm.DB) *PlaytimeStats {
+	return &PlaytimeStats{db: db}
+}
+
+// sessions loads completed sessions starting within the range. The query
+// window is widened by a day and narrowed in Go, since SQLite compares stored
+// times as text and those may carry different offsets.
+func (s *PlaytimeStats) sessions(r PlaytimeRange) ([]playedSession, error) {
+	var rows []playedSession
+	err := s.db.Model(&models.PlaySession{}).
+		Select("id", "game_id", "start_time", "duration").
+		Where("end_time IS NOT NULL AND duration > 0").
+		Where("start_time >= ? AND start_time < ?", r.From.AddDate(0, 0, -1), r.To.AddDate(0, 0, 1)).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := rows[:0]
+	for _, session := range rows {
+		if !session.StartTime.Before(r.From) && session.StartTime.Before(r.To) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// Totals returns the minutes played and number of sessions in the range
+func (s *PlaytimeStats) Totals(r PlaytimeRange) (minutes, sessions int, err error) {
+	played, err := s.sessions(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, session := range played {
+		minutes += session.Duration
+	}
+	return minutes, len(played), nil
+}
+
+// Series returns play time per day, ISO week or month, including empty periods
+func (s *PlaytimeStats) Series(r PlaytimeRange, interval string) ([]PlaytimeBucket, error) {
+	if interval != PlaytimeByDay && interval != PlaytimeByWeek && interval != PlaytimeByMonth {
+		return nil, fmt.Errorf("unknown playtime interval %q", interval)
+	}
+	played, err := s.sessions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []PlaytimeBucket
+	index := make(map[string]int)
+	for start := periodStart(r.From, interval); start.Before(r.To); start = nextPeriod(start, interval) {
+		period := periodLabel(start, interval)
+		index[period] = len(buckets)
+		buckets = append(buckets, PlaytimeBucket{Period: period, Start: start})
+	}
+	for _, session := range played {
+		bucket := &buckets[index[periodLabel(periodStart(session.StartTime.In(r.Location), interval), interval)]]
+		bucket.Minutes += session.Duration
+		bucket.Sessions++
+	}
+	for i := range buckets {
+		buckets[i].Hours = minutesToHours(buckets[i].Minutes)
+	}
+	return buckets, nil
+}
+
+// ByGame returns play time per game, most played first; limit <= 0 returns all
+func (s *PlaytimeStats) ByGame(r PlaytimeRange, limit int) ([]PlaytimeBreakdown, error) {
+	played, err := s.sessions(r)
+	if err != nil {
+		return nil, err
+	}
+	totals, total := sumByGame(played)
+
+	var games []struct {
+		ID       uint
+		Title    string
+		Platform string
+	}
+	err = s.db.Table("games").
+		Select("games.id, games.title, platforms.name AS platform").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Where("games.id IN ?", gameIDs(totals)).
+		Scan(&games).Error
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := make([]PlaytimeBreakdown, 0, len(games))
+	for _, game := range games {
+		entry := totals[game.ID]
+		entry.ID = game.ID
+		entry.Name = game.Title
+		entry.Platform = game.Platform
+		breakdown = append(breakdown, entry)
+	}
+	return finishBreakdown(breakdown, total, limit), nil
+}
+
+// ByPlatform returns play time per platform, most played first
+func (s *PlaytimeStats) ByPlatform(r PlaytimeRange) ([]PlaytimeBreakdown, error) {
+	played, err := s.sessions(r)
+	if err != nil {
+		return nil, err
+	}
+	totals, total := sumByGame(played)
+
+	var games []struct {
+		ID         uint
+		PlatformID uint
+		Platform   string
+	}
+	err = s.db.Table("games").
+		Select("games.id, games.platform_id, platforms.name AS platform").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Where("games.id IN ?", gameIDs(totals)).
+		Scan(&games).Error
+	if err != nil {
+		return nil, err
+	}
+
+	platforms := make(map[uint]*PlaytimeBreakdown)
+	for _, game := range games {
+		entry, ok := platforms[game.PlatformID]
+		if !ok {
+			entry = &PlaytimeBreakdown{ID: game.PlatformID, Name: game.Platform}
+			if entry.Name == "" {
+				entry.Name = "Unknown"
+			}
+			platforms[game.PlatformID] = entry
+		}
+		entry.Minutes += totals[game.ID].Minutes
+		entry.Sessions += totals[game.ID].Sessions
+	}
+	return finishBreakdown(collectBreakdown(platforms), total, 0), nil
+}
+
+// ByGenre returns play time per genre, most played first. A game with several
+// genres counts fully towards each, so shares can add up to more than 1.
+func (s *PlaytimeStats) ByGenre(r PlaytimeRange) ([]PlaytimeBreakdown, error) {
+	played, err := s.sessions(r)
+	if err != nil {
+		return nil, err
+	}
+	totals, total := sumByGame(played)
+
+	var links []struct {
+		GameID  uint
+		GenreID uint
+		Name    string
+	}
+	err = s.db.Table("game_genres").
+		Select("game_genres.game_id, genres.id AS genre_id, genres.name").
+		Joins("JOIN genres ON genres.id = game_genres.genre_id").
+		Where("game_genres.game_id IN ?", gameIDs(totals)).
+		Scan(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	genres := make(map[uint]*PlaytimeBreakdown)
+	withGenre := make(map[uint]bool)
+	for _, link := range links {
+		entry, ok := genres[link.GenreID]
+		if !ok {
+			entry = &PlaytimeBreakdown{ID: link.GenreID, Name: link.Name}
+			genres[link.GenreID] = entry
+		}
+		entry.Minutes += totals[link.GameID].Minutes
+		entry.Sessions += totals[link.GameID].Sessions
+		withGenre[link.GameID] = true
+	}
+	for gameID, entry := range totals {
+		if withGenre[gameID] {
+			continue
+		}
+		unknown, ok := genres[0]
+		if !ok {
+			unknown = &PlaytimeBreakdown{Name: "Unknown"}
+			genres[0] = unknown
+		}
+		unknown.Minutes += entry.Minutes
+		unknown.Sessions += entry.Sessions
+	}
+	return finishBreakdown(collectBreakdown(genres), total, 0), nil
+}
+
+// Heatmap returns minutes played for every day in the range. Levels split the
+// range up to the busiest day into quarters, like a contribution calendar.
+func (s *PlaytimeStats) Heatmap(r PlaytimeRange) (*PlaytimeHeatmap, error) {
+	buckets, err := s.Series(r, PlaytimeByDay)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := &PlaytimeHeatmap{
+		From: r.FirstDay(),
+		To:   r.LastDay(),
+		Days: make([]HeatmapDay, len(buckets)),
+	}
+	for i, bucket := range buckets {
+		heatmap.Days[i] = HeatmapDay{
+			Date:     bucket.Period,
+			Weekday:  (int(bucket.Start.Weekday()) + 6) % 7,
+			Minutes:  bucket.Minutes,
+			Sessions: bucket.Sessions,
+		}
+		heatmap.TotalMinutes += bucket.Minutes
+		if bucket.Minutes > 0 {
+			heatmap.ActiveDays++
+		}
+		if bucket.Minutes > heatmap.MaxMinutes {
+			heatmap.MaxMinutes = bucket.Minutes
+		}
+	}
+
+	heatmap.Thresholds = heatmapThresholds(heatmap.MaxMinutes)
+	for i := range heatmap.Days {
+		day := &heatmap.Days[i]
+		for level := 4; level >= 1; level-- {
+			if day.Minutes > 0 && day.Minutes >= heatmap.Thresholds[level-1] {
+				day.Level = level
+				break
+			}
+		}
+	}
+	return heatmap, nil
+}
+
+// heatmapThresholds returns the minimum minutes for levels 1-4: any play,
+// then more than a quarter, half and three quarters of the busiest day
+func heatmapThresholds(maxMinutes int) [4]int {
+	return [4]int{1, maxMinutes/4 + 1, maxMinutes/2 + 1, maxMinutes*3/4 + 1}
+}
+
+func sumByGame(played []playedSession) (map[uint]PlaytimeBreakdown, int) {
+	totals := make(map[uint]PlaytimeBreakdown)
+	total := 0
+	for _, session := range played {
+		entry := totals[session.GameID]
+		entry.Minutes += session.Duration
+		entry.Sessions++
+		totals[session.GameID] = entry
+		total += session.Duration
+	}
+	return totals, total
+}
+
+func gameIDs(totals map[uint]PlaytimeBreakdown) []uint {
+	ids := make([]uint, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		// IN () is invalid SQL; no game has ID 0
+		ids = append(ids, 0)
+	}
+	return ids
+}
+
+func collectBreakdown(entries map[uint]*PlaytimeBreakdown) []PlaytimeBreakdown {
+	breakdown := make([]PlaytimeBreakdown, 0, len(entries))
+	for _, entry := range entries {
+		breakdown = append(breakdown, *entry)
+	}
+	return breakdown
+}
+
+// finishBreakdown sorts by minutes played, fills in hours and shares, and applies limit
+func finishBreakdown(breakdown []PlaytimeBreakdown, total, limit int) []PlaytimeBreakdown {
+	sort.Slice(breakdown, func(a, b int) bool {
+		if breakdown[a].Minutes != breakdown[b].Minutes {
+			return breakdown[a].Minutes > breakdown[b].Minutes
+		}
+		return breakdown[a].Name < breakdown[b].Name
+	})
+	if limit > 0 && len(breakdown) > limit {
+		breakdown = breakdown[:limit]
+	}
+	for i := range breakdown {
+		breakdown[i].Hours = minutesToHours(breakdown[i].Minutes)
+		if total > 0 {
+			breakdown[i].Share = math.Round(float64(breakdown[i].Minutes)/float64(total)*1000) / 1000
+		}
+	}
+	return breakdown
+}
+
+// minutesToHours rounds to one decimal
+func minutesToHours(minutes int) float64 {
+	return math.Round(float64(minutes)/6) / 10
+}
+
+// periodStart returns the start of the day, ISO week (Monday) or month containing t
+func periodStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case PlaytimeByWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case PlaytimeByMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return day
+}
+
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case PlaytimeByWeek:
+		return start.AddDate(0, 0, 7)
+	case PlaytimeByMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func periodLabel(start time.Time, interval string) string {
+	switch interval {
+	case PlaytimeByWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PlaytimeByMonth:
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestPlaytimeDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+
+	snes := models.Platform{Name: "SNES"}
+	pc := models.Platform{Name: "PC"}
+	require.NoError(t, db.Create(&[]*models.Platform{&snes, &pc}).Error)
+	platformer := models.Genre{Name: "Platform"}
+	rpg := models.Genre{Name: "RPG"}
+	require.NoError(t, db.Create(&[]*models.Genre{&platformer, &rpg}).Error)
+
+	require.NoError(t, db.Create(&[]models.Game{
+		{ID: 1, Title: "Super Mario World", PlatformID: snes.ID, Genres: []models.Genre{platformer}},
+		{ID: 2, Title: "Chrono Trigger", PlatformID: snes.ID, Genres: []models.Genre{rpg}},
+		{ID: 3, Title: "Hollow Knight", PlatformID: pc.ID, Genres: []models.Genre{platformer, rpg}},
+		{ID: 4, Title: "Tetris", PlatformID: pc.ID},
+	}).Error)
+	return db
+}
+
+func addPlayedSession(t *testing.T, db *gorm.DB, gameID uint, start time.Time, minutes int) {
+	end := start.Add(time.Duration(minutes) * time.Minute)
+	require.NoError(t, db.Create(&models.PlaySession{GameID: gameID, StartTime: start, EndTime: &end, Duration: minutes}).Error)
+}
+
+func TestPlaytimeStats(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+
+	// Monday 2024-03-04 .. Sunday 2024-03-17 in Warsaw (UTC+1)
+	addPlayedSession(t, db, 1, time.Date(2024, 3, 4, 18, 0, 0, 0, warsaw), 60)
+	addPlayedSession(t, db, 2, time.Date(2024, 3, 4, 21, 0, 0, 0, warsaw), 120)
+	addPlayedSession(t, db, 3, time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC), 45) // Monday 00:30 in Warsaw
+	addPlayedSession(t, db, 4, time.Date(2024, 3, 17, 12, 0, 0, 0, warsaw), 15)
+	// Outside the range, still running, or empty
+	addPlayedSession(t, db, 1, time.Date(2024, 3, 3, 22, 0, 0, 0, warsaw), 30)
+	addPlayedSession(t, db, 1, time.Date(2024, 3, 18, 0, 0, 0, 0, warsaw), 30)
+	require.NoError(t, db.Create(&models.PlaySession{GameID: 2, StartTime: time.Date(2024, 3, 5, 10, 0, 0, 0, warsaw)}).Error)
+
+	r, err := NewPlaytimeRange("2024-03-04", "2024-03-17", warsaw, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 14, r.Days())
+
+	stats := NewPlaytimeStats(db)
+	minutes, sessions, err := stats.Totals(r)
+	require.NoError(t, err)
+	assert.Equal(t, 240, minutes)
+	assert.Equal(t, 4, sessions)
+
+	days, err := stats.Series(r, PlaytimeByDay)
+	require.NoError(t, err)
+	require.Len(t, days, 14)
+	assert.Equal(t, "2024-03-04", days[0].Period)
+	assert.Equal(t, 180, days[0].Minutes)
+	assert.Equal(t, 2, days[0].Sessions)
+	assert.Equal(t, 3.0, days[0].Hours)
+	assert.Equal(t, 45, days[7].Minutes)
+	assert.Equal(t, 0, days[6].Minutes)
+
+	weeks, err := stats.Series(r, PlaytimeByWeek)
+	require.NoError(t, err)
+	require.Len(t, weeks, 2)
+	assert.Equal(t, "2024-W10", weeks[0].Period)
+	assert.Equal(t, 180, weeks[0].Minutes)
+	assert.Equal(t, 60, weeks[1].Minutes)
+
+	months, err := stats.Series(r, PlaytimeByMonth)
+	require.NoError(t, err)
+	require.Len(t, months, 1)
+	assert.Equal(t, "2024-03", months[0].Period)
+	assert.Equal(t, 240, months[0].Minutes)
+
+	games, err := stats.ByGame(r, 2)
+	require.NoError(t, err)
+	require.Len(t, games, 2)
+	assert.Equal(t, "Chrono Trigger", games[0].Name)
+	assert.Equal(t, "SNES", games[0].Platform)
+	assert.Equal(t, 0.5, games[0].Share)
+	assert.Equal(t, "Super Mario World", games[1].Name)
+
+	platforms, err := stats.ByPlatform(r)
+	require.NoError(t, err)
+	require.Len(t, platforms, 2)
+	assert.Equal(t, PlaytimeBreakdown{ID: platforms[0].ID, Name: "SNES", Minutes: 180, Hours: 3, Sessions: 2, Share: 0.75}, platforms[0])
+	assert.Equal(t, 60, platforms[1].Minutes)
+
+	genres, err := stats.ByGenre(r)
+	require.NoError(t, err)
+	require.Len(t, genres, 3)
+	assert.Equal(t, "RPG", genres[0].Name)
+	assert.Equal(t, 165, genres[0].Minutes)
+	assert.Equal(t, "Platform", genres[1].Name)
+	assert.Equal(t, 105, genres[1].Minutes)
+	assert.Equal(t, "Unknown", genres[2].Name)
+	assert.Equal(t, 15, genres[2].Minutes)
+
+	heatmap, err := stats.Heatmap(r)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-04", heatmap.From)
+	assert.Equal(t, "2024-03-17", heatmap.To)
+	require.Len(t, heatmap.Days, 14)
+	assert.Equal(t, 0, heatmap.Days[0].Weekday)
+	assert.Equal(t, 6, heatmap.Days[13].Weekday)
+	assert.Equal(t, 3, heatmap.ActiveDays)
+	assert.Equal(t, 180, heatmap.MaxMinutes)
+	assert.Equal(t, 240, heatmap.TotalMinutes)
+	assert.Equal(t, 4, heatmap.Days[0].Level)
+	assert.Equal(t, 0, heatmap.Days[1].Level)
+	assert.Equal(t, 1, heatmap.Days[13].Level)
+}
+
+func TestNewPlaytimeRange(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	r, err := NewPlaytimeRange("", "", time.UTC, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-03-11", r.FirstDay())
+	assert.Equal(t, "2024-03-10", r.LastDay())
+
+	r, err = NewPlaytimeRange("2024-03-10", "2024-03-10", time.UTC, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Days())
+
+	for _, invalid := range [][2]string{{"2024-03-11", "2024-03-10"}, {"03/01/2024", ""}, {"", "2024-3-1"}} {
+		_, err := NewPlaytimeRange(invalid[0], invalid[1], time.UTC, now)
+		assert.ErrorIs(t, err, ErrInvalidPlaytimeRange)
+	}
+}
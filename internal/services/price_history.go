@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SourcePriceCharting marks prices imported from PriceCharting CSV exports
+const SourcePriceCharting = "pricecharting"
+
+// DefaultCurrency is used when no currency is configured
+const DefaultCurrency = "USD"
+
+// Price grades. A copy is valued at the grade matching its completeness.
+const (
+	PriceGradeLoose = "loose"
+	PriceGradeCIB   = "cib"
+	PriceGradeNew   = "new"
+)
+
+var ErrInvalidPriceCSV = errors.New("invalid price CSV")
+
+// priceChartingColumns are PriceCharting's price columns and their grades
+var priceChartingColumns = []struct{ name, grade string }{
+	{"loose-price", PriceGradeLoose},
+	{"cib-price", PriceGradeCIB},
+	{"new-price", PriceGradeNew},
+}
+
+// NormalizeCurrency upper-cases an ISO 4217 currency code, reporting whether
+// it looks like one
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return code, false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return code, false
+		}
+	}
+	return code, true
+}
+
+// PriceGrade is the grade a copy with the given completeness is valued at.
+// Copies missing their box or manual are valued as loose.
+func PriceGrade(completeness string) string {
+	switch completeness {
+	case "sealed":
+		return PriceGradeNew
+	case "cib":
+		return PriceGradeCIB
+	}
+	return PriceGradeLoose
+}
+
+// PriceImportResult summarizes a price import
+type PriceImportResult struct {
+	Source         string   `json:"source"`
+	Date           string   `json:"date"`
+	Currency       string   `json:"currency"`
+	Rows           int      `json:"rows"`
+	GamesPriced    int      `json:"games_priced"`
+	PricesImported int      `json:"prices_imported"`
+	Unmatched      []string `json:"unmatched"`
+	Errors         []string `json:"errors"`
+}
+
+// PriceImporter records market prices from price guide exports
+type PriceImporter struct {
+	db       *gorm.DB
+	currency string
+}
+
+func NewPriceImporter(db *gorm.DB, currency string) *PriceImporter {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return &PriceImporter{db: db, currency: currency}
+}
+
+// ImportPriceCharting reads a PriceCharting-style CSV, with product-name,
+// console-name and loose-price, cib-price and new-price columns, and records
+// each price for date in currency (the configured one when empty). A row is
+// matched to a game by title, using the console to pick between games with
+// the same title. Importing again for the same date and currency replaces
+// its prices. The import is all or nothing: a malformed CSV stores no prices.
+func (i *PriceImporter) ImportPriceCharting(r io.Reader, date time.Time, currency string) (*PriceImportResult, error) {
+	if currency == "" {
+		currency = i.currency
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPriceCSV, err)
+	}
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = index
+	}
+	if _, ok := columns["product-name"]; !ok {
+		return nil, fmt.Errorf("%w: no product-name column", ErrInvalidPriceCSV)
+	}
+
+	games, err := i.loadGames()
+	if err != nil {
+		return nil, err
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	result := &PriceImportResult{
+		Source:    SourcePriceCharting,
+		Date:      day.Format("2006-01-02"),
+		Currency:  currency,
+		Unmatched: []string{},
+		Errors:    []string{},
+	}
+	field := func(record []string, name string) string {
+		if index, ok := columns[name]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+	priced := make(map[uint]bool)
+	err = i.db.Transaction(func(tx *gorm.DB) error {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidPriceCSV, err)
+			}
+			title, console := field(record, "product-name"), field(record, "console-name")
+			if title == "" {
+				continue
+			}
+			result.Rows++
+			label := title
+			if console != "" {
+				label += " (" + console + ")"
+			}
+
+			gameID := games.match(title, console)
+			if gameID == 0 {
+				result.Unmatched = append(result.Unmatched, label)
+				continue
+			}
+			for _, column := range priceChartingColumns {
+				value := field(record, column.name)
+				if value == "" {
+					continue
+				}
+				price, err := parsePrice(value)
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %s %q is not a price", label, column.name, value))
+					continue
+				}
+				point := models.PricePoint{GameID: gameID, Grade: column.grade, Date: day, Price: price, Currency: currency, Source: SourcePriceCharting}
+				err = tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "game_id"}, {Name: "grade"}, {Name: "date"}, {Name: "currency"}},
+					DoUpdates: clause.AssignmentColumns([]string{"price", "source"}),
+				}).Create(&point).Error
+				if err != nil {
+					return err
+				}
+				result.PricesImported++
+				priced[gameID] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.GamesPriced = len(priced)
+	return result, nil
+}
+
+// parsePrice reads a price such as "$1,024.50"
+func parsePrice(value string) (float64, error) {
+	value = strings.NewReplacer("$", "", "€", "", "£", "", ",", "", " ", "").Replace(value)
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 {
+		return 0, fmt.Errorf("invalid price %q", value)
+	}
+	return price, nil
+}
+
+// priceGameIndex finds games by title and platform for price imports
+type priceGameIndex struct {
+	byTitle map[string][]priceGame
+}
+
+type priceGame struct {
+	id           uint
+	platformKeys map[string]bool
+}
+
+func (i *PriceImporter) loadGames() (*priceGameIndex, error) {
+	var games []struct {
+		ID       uint
+		Title    string
+		Platform string
+	}
+	err := i.db.Table("games").
+		Select("games.id, games.title, platforms.name AS platform").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Scan(&games).Error
+	if err != nil {
+		return nil, err
+	}
+	index := &priceGameIndex{byTitle: make(map[string][]priceGame)}
+	for _, game := range games {
+		// Titles fold like platform names, so "Pokemon: Red" matches "Pokemon Red"
+		title := normalizePlatformName(game.Title)
+		index.byTitle[title] = append(index.byTitle[title], priceGame{id: game.ID, platformKeys: platformNameKeys(game.Platform)})
+	}
+	return index, nil
+}
+
+// match returns the game with the title, or 0 when none or several match
+func (g *priceGameIndex) match(title, console string) uint {
+	candidates := g.byTitle[normalizePlatformName(title)]
+	if len(candidates) == 1 {
+		return candidates[0].id
+	}
+	consoleKeys := platformNameKeys(console)
+	var found uint
+	for _, candidate := range candidates {
+		if keysOverlap(candidate.platformKeys, consoleKeys) {
+			if found != 0 {
+				return 0
+			}
+			found = candidate.id
+		}
+	}
+	return found
+}
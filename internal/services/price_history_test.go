@@ -0,0 +1,84 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPriceCharting(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	// A second Tetris, so the console has to pick between them
+	require.NoError(t, db.Create(&models.Game{Title: "Tetris", PlatformID: 1}).Error)
+
+	csv := "\ufeffid,product-name,console-name,loose-price,cib-price,new-price\n" +
+		"1,Super Mario World,Super Nintendo,$25.00,$60.00,\"$1,024.50\"\n" +
+		"2,Tetris,PC,5,,\n" +
+		"3,Tetris,Game Boy,10,,\n" +
+		"4,Hollow Knight,PC,abc,,\n" +
+		"5,EarthBound,Super Nintendo,$90.00,,\n"
+	importer := NewPriceImporter(db, "")
+	date := time.Date(2024, 5, 1, 15, 30, 0, 0, time.UTC)
+	result, err := importer.ImportPriceCharting(strings.NewReader(csv), date, "")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01", result.Date)
+	assert.Equal(t, DefaultCurrency, result.Currency)
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 2, result.GamesPriced)
+	assert.Equal(t, 4, result.PricesImported)
+	assert.Equal(t, []string{"Tetris (Game Boy)", "EarthBound (Super Nintendo)"}, result.Unmatched)
+	assert.Len(t, result.Errors, 1)
+
+	var mario []models.PricePoint
+	require.NoError(t, db.Where("game_id = ?", 1).Order("price").Find(&mario).Error)
+	require.Len(t, mario, 3)
+	assert.Equal(t, 1024.5, mario[2].Price)
+	assert.Equal(t, PriceGradeNew, mario[2].Grade)
+	var tetris models.PricePoint
+	require.NoError(t, db.Where("game_id = ?", 4).First(&tetris).Error)
+	assert.Equal(t, 5.0, tetris.Price)
+
+	// Importing the same date again replaces its prices
+	_, err = importer.ImportPriceCharting(strings.NewReader("product-name,loose-price\nSuper Mario World,30\n"), date, "")
+	require.NoError(t, err)
+	var loose models.PricePoint
+	require.NoError(t, db.Where("game_id = ? AND grade = ?", 1, PriceGradeLoose).First(&loose).Error)
+	assert.Equal(t, 30.0, loose.Price)
+	var count int64
+	db.Model(&models.PricePoint{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+
+	// Prices in another currency are kept alongside
+	_, err = importer.ImportPriceCharting(strings.NewReader("product-name,loose-price\nSuper Mario World,28\n"), date, "EUR")
+	require.NoError(t, err)
+	require.NoError(t, db.Where("game_id = ? AND grade = ? AND currency = ?", 1, PriceGradeLoose, DefaultCurrency).First(&loose).Error)
+	assert.Equal(t, 30.0, loose.Price)
+	db.Model(&models.PricePoint{}).Count(&count)
+	assert.Equal(t, int64(5), count)
+
+	_, err = importer.ImportPriceCharting(strings.NewReader("title,price\n"), date, "")
+	assert.ErrorIs(t, err, ErrInvalidPriceCSV)
+
+	// A file that breaks partway stores none of its prices
+	_, err = importer.ImportPriceCharting(strings.NewReader("product-name,loose-price\nSuper Mario World,99\n\"Tetris,5\n"), date, "")
+	assert.ErrorIs(t, err, ErrInvalidPriceCSV)
+	require.NoError(t, db.Where("game_id = ? AND grade = ? AND currency = ?", 1, PriceGradeLoose, DefaultCurrency).First(&loose).Error)
+	assert.Equal(t, 30.0, loose.Price)
+}
+
+func TestPriceGradeAndCurrency(t *testing.T) {
+	assert.Equal(t, PriceGradeNew, PriceGrade("sealed"))
+	assert.Equal(t, PriceGradeCIB, PriceGrade("cib"))
+	assert.Equal(t, PriceGradeLoose, PriceGrade("box"))
+
+	code, ok := NormalizeCurrency(" eur ")
+	assert.True(t, ok)
+	assert.Equal(t, "EUR", code)
+	_, ok = NormalizeCurrency("E1R")
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"strings"
+)
+
+// igdbReleaseRegions maps IGDB's release_dates.region enum to stored region names
+var igdbReleaseRegions = map[int]string{
+	1:  "europe",
+	2:  "north_america",
+	3:  "australia",
+	4:  "new_zealand",
+	5:  "japan",
+	6:  "china",
+	7:  "asia",
+	8:  "worldwide",
+	9:  "korea",
+	10: "brazil",
+}
+
+// releaseRegionAliases lets filters use common shorthand for regions
+var releaseRegionAliases = map[string]string{
+	"eu":     "europe",
+	"pal":    "europe",
+	"na":     "north_america",
+	"us":     "north_america",
+	"usa":    "north_america",
+	"jp":     "japan",
+	"jpn":    "japan",
+	"ntsc_u": "north_america",
+	"ntsc_j": "japan",
+	"ww":     "worldwide",
+	"wor":    "worldwide",
+	"kr":     "korea",
+	"cn":     "china",
+	"au":     "australia",
+	"br":     "brazil",
+}
+
+// igdbAgeRatingBoards maps IGDB's age_ratings.category enum; other boards are not stored
+var igdbAgeRatingBoards = map[int]string{
+	1: "ESRB",
+	2: "PEGI",
+	3: "CERO",
+}
+
+type ageRatingLabel struct {
+	Rating     string
+	MinimumAge int
+}
+
+// igdbAgeRatings maps IGDB's age_ratings.rating enum to labels and the minimum
+// recommended age. "Rating pending" (6) carries no information and is skipped.
+var igdbAgeRatings = map[int]ageRatingLabel{
+	1:  {"3", 3},
+	2:  {"7", 7},
+	3:  {"12", 12},
+	4:  {"16", 16},
+	5:  {"18", 18},
+	7:  {"EC", 3},
+	8:  {"E", 6},
+	9:  {"E10+", 10},
+	10: {"T", 13},
+	11: {"M", 17},
+	12: {"AO", 18},
+	13: {"A", 0},
+	14: {"B", 12},
+	15: {"C", 15},
+	16: {"D", 17},
+	17: {"Z", 18},
+}
+
+// NormalizeReleaseRegion resolves a region name or alias ("jp", "PAL", "North America")
+// to the stored region name; ok is false for unknown regions
+func NormalizeReleaseRegion(region string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(region))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	if alias, exists := releaseRegionAliases[key]; exists {
+		return alias, true
+	}
+	for _, name := range igdbReleaseRegions {
+		if name == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ParseAgeRatingFilter splits "PEGI:12" or "esrb:e10+" into a board and rating
+func ParseAgeRatingFilter(value string) (board, rating string, ok bool) {
+	board, rating, found := strings.Cut(value, ":")
+	board = strings.ToUpper(strings.TrimSpace(board))
+	rating = strings.ToUpper(strings.TrimSpace(rating))
+	if !found || board == "" || rating == "" {
+		return "", "", false
+	}
+	return board, rating, true
+}
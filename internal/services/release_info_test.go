@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyReleaseInfo(t *testing.T) {
+	game := IGDBGame{
+		ReleaseDates: []IGDBReleaseDate{
+			{Date: 775267200, Region: 5, Platform: 19},  // Japan, SNES
+			{Date: 802310400, Region: 2, Platform: 19},  // North America, SNES
+			{Date: 1147996800, Region: 2, Platform: 24}, // North America, GBA re-release
+			{Region: 1, Platform: 19, Human: "TBD"},     // Europe, undated
+			{Date: 802310400, Region: 99, Platform: 19}, // unknown region
+		},
+		AgeRatings: []IGDBAgeRating{
+			{Category: 1, Rating: 10}, // ESRB T
+			{Category: 2, Rating: 3},  // PEGI 12
+			{Category: 3, Rating: 14}, // CERO B
+			{Category: 1, Rating: 6},  // ESRB rating pending
+			{Category: 4, Rating: 3},  // USK, not stored
+		},
+	}
+
+	var metadata GameMetadata
+	applyReleaseInfo(&metadata, game, 19)
+
+	require.Len(t, metadata.ReleaseDates, 3)
+	assert.Equal(t, "japan", metadata.ReleaseDates[0].Region)
+	assert.Equal(t, time.Date(1994, 7, 27, 0, 0, 0, 0, time.UTC), *metadata.ReleaseDates[0].Date)
+	assert.Equal(t, "north_america", metadata.ReleaseDates[1].Region)
+	assert.Equal(t, "europe", metadata.ReleaseDates[2].Region)
+	assert.Nil(t, metadata.ReleaseDates[2].Date)
+	assert.Equal(t, "TBD", metadata.ReleaseDates[2].Human)
+
+	assert.Equal(t, []MetadataAgeRating{
+		{Board: "ESRB", Rating: "T", MinimumAge: 13},
+		{Board: "PEGI", Rating: "12", MinimumAge: 12},
+		{Board: "CERO", Rating: "B", MinimumAge: 12},
+	}, metadata.AgeRatings)
+
+	// Without a platform restriction every release is kept
+	var unfiltered GameMetadata
+	applyReleaseInfo(&unfiltered, game, 0)
+	assert.Len(t, unfiltered.ReleaseDates, 4)
+}
+
+func TestNormalizeReleaseRegion(t *testing.T) {
+	tests := map[string]string{
+		"japan":         "japan",
+		"JP":            "japan",
+		"pal":           "europe",
+		"North America": "north_america",
+		"north-america": "north_america",
+		"wor":           "worldwide",
+	}
+	for input, want := range tests {
+		got, ok := NormalizeReleaseRegion(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, ok := NormalizeReleaseRegion("atlantis")
+	assert.False(t, ok)
+}
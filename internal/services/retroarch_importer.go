@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// SourceRetroArch marks sessions imported from RetroArch runtime logs
+const SourceRetroArch = "retroarch"
+
+var ErrRetroArchDirNotSet = errors.New("no RetroArch directory configured")
+
+var retroArchCRC = regexp.MustCompile(`^[0-9a-fA-F]{8}$`)
+
+// RetroArchImporter imports play time from RetroArch runtime logs
+// (playlists/logs/[core/]<content>.lrtl). Content names in the logs are
+// resolved to full paths through RetroArch's playlists, including the content
+// history, and matched to scanned file locations.
+type RetroArchImporter struct {
+	db    *gorm.DB
+	dir   string
+	timer *SessionTimer
+}
+
+func NewRetroArchImporter(db *gorm.DB, dir string, timer *SessionTimer) *RetroArchImporter {
+	return &RetroArchImporter{
+		db:    db,
+		dir:   dir,
+		timer: timer,
+	}
+}
+
+// retroArchRuntimeLog is the JSON written to .lrtl files
+type retroArchRuntimeLog struct {
+	Runtime    string `json:"runtime"`     // H:MM:SS
+	LastPlayed string `json:"last_played"` // local time, YYYY-MM-DD HH:MM:SS
+}
+
+// retroArchContent is a playlist entry
+type retroArchContent struct {
+	Path     string
+	CRC      string
+	CoreName string
+}
+
+type retroArchLog struct {
+	path    string
+	key     string // log path relative to the logs directory
+	content string // content file name, lowercased
+	core    string // empty for aggregate logs
+}
+
+// Import reads the runtime logs under dir (the RetroArch config directory, or
+// its playlists/logs directory); an empty dir uses the configured one. Times
+// in the logs are read in loc. A log's runtime when first seen is its
+// baseline; later imports add sessions for the runtime gained since.
+func (i *RetroArchImporter) Import(dir string, loc *time.Location) (*PlaytimeImportResult, error) {
+	if dir == "" {
+		dir = i.dir
+	}
+	if dir == "" {
+		return nil, ErrRetroArchDirNotSet
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	logsDir := filepath.Join(dir, "playlists", "logs")
+	if info, err := os.Stat(logsDir); err != nil || !info.IsDir() {
+		logsDir = dir
+	}
+
+	logs, err := findRetroArchLogs(logsDir)
+	if err != nil {
+		return nil, err
+	}
+	contents := readRetroArchPlaylists(dir)
+	library, err := loadFileLocationIndex(i.db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlaytimeImportResult{
+		Source:    SourceRetroArch,
+		Unmatched: []string{},
+		Errors:    []string{},
+	}
+	for _, log := range logs {
+		result.ItemsFound++
+
+		runtime, lastPlayed, err := readRetroArchLog(log.path, loc)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", log.key, err))
+			continue
+		}
+
+		content := contents[log.content]
+		gameID := library.match(content.Path, content.CRC, log.content)
+		if gameID == 0 {
+			result.Unmatched = append(result.Unmatched, log.key)
+			continue
+		}
+
+		notes := "Imported from RetroArch runtime log"
+		if core := firstNonEmpty(log.core, content.CoreName); core != "" {
+			notes += " (" + core + ")"
+		}
+		minutes, baselined, overlaps, err := applyImportedPlaytime(i.timer, SourceRetroArch, importedPlaytime{
+			Key:        log.key,
+			GameID:     gameID,
+			Total:      runtime,
+			LastPlayed: lastPlayed,
+			Notes:      notes,
+		})
+		result.add(log.key, minutes, baselined, overlaps, err)
+	}
+	return result, nil
+}
+
+// findRetroArchLogs lists runtime logs. RetroArch can keep an aggregate log and
+// per-core logs for the same content; the aggregate one already includes the
+// per-core time, so those are skipped when it exists.
+func findRetroArchLogs(logsDir string) ([]retroArchLog, error) {
+	var logs []retroArchLog
+	err := filepath.WalkDir(logsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(path), ".lrtl") {
+			return nil
+		}
+		rel, err := filepath.Rel(logsDir, path)
+		if err != nil {
+			return err
+		}
+		core := filepath.Dir(rel)
+		if core == "." {
+			core = ""
+		}
+		logs = append(logs, retroArchLog{
+			path:    path,
+			key:     filepath.ToSlash(rel),
+			content: strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))),
+			core:    core,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	aggregated := make(map[string]bool)
+	for _, log := range logs {
+		if log.core == "" {
+			aggregated[log.content] = true
+		}
+	}
+	filtered := logs[:0]
+	for _, log := range logs {
+		if log.core == "" || !aggregated[log.content] {
+			filtered = append(filtered, log)
+		}
+	}
+	sort.Slice(filtered, func(a, b int) bool { return filtered[a].key < filtered[b].key })
+	return filtered, nil
+}
+
+func readRetroArchLog(path string, loc *time.Location) (time.Duration, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var log retroArchRuntimeLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		return 0, time.Time{}, fmt.Errorf("invalid runtime log: %w", err)
+	}
+
+	runtime, err := parseRetroArchRuntime(log.Runtime)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var lastPlayed time.Time
+	if log.LastPlayed != "" {
+		lastPlayed, err = time.ParseInLocation("2006-01-02 15:04:05", log.LastPlayed, loc)
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("invalid last_played %q", log.LastPlayed)
+		}
+	}
+	return runtime, lastPlayed, nil
+}
+
+// parseRetroArchRuntime parses "H:MM:SS"; hours are not capped at 24
+func parseRetroArchRuntime(value string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid runtime %q", value)
+	}
+	var total time.Duration
+	for index, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[index])
+		if err != nil || n < 0 || (index > 0 && n > 59) {
+			return 0, fmt.Errorf("invalid runtime %q", value)
+		}
+		total += time.Duration(n) * unit
+	}
+	return total, nil
+}
+
+// readRetroArchPlaylists maps lowercased content file names to playlist entries
+// from the playlists in dir, dir/playlists and dir/playlists/builtin. The
+// content history is read last so its entries win. Unreadable playlists are skipped.
+func readRetroArchPlaylists(dir string) map[string]retroArchContent {
+	contents := make(map[string]retroArchContent)
+	var history []string
+	for _, playlistDir := range []string{dir, filepath.Join(dir, "playlists"), filepath.Join(dir, "playlists", "builtin")} {
+		paths, _ := filepath.Glob(filepath.Join(playlistDir, "*.lpl"))
+		for _, path := range paths {
+			if strings.HasPrefix(strings.ToLower(filepath.Base(path)), "content_history") {
+				history = append(history, path)
+				continue
+			}
+			readRetroArchPlaylist(path, contents)
+		}
+	}
+	for _, path := range history {
+		readRetroArchPlaylist(path, contents)
+	}
+	return contents
+}
+
+func readRetroArchPlaylist(path string, contents map[string]retroArchContent) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	for _, entry := range parseRetroArchPlaylist(data) {
+		if entry.Path == "" {
+			continue
+		}
+		contents[strings.ToLower(retroArchContentName(entry.Path))] = entry
+	}
+}
+
+// parseRetroArchPlaylist reads JSON playlists (RetroArch 1.7.6+) and the older
+// format of six lines per entry: path, label, core path, core name, CRC, database
+func parseRetroArchPlaylist(data []byte) []retroArchContent {
+	var entries []retroArchContent
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var playlist struct {
+			Items []struct {
+				Path     string `json:"path"`
+				CoreName string `json:"core_name"`
+				CRC32    string `json:"crc32"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(trimmed, &playlist); err != nil {
+			return nil
+		}
+		for _, item := range playlist.Items {
+			entries = append(entries, retroArchContent{
+				Path:     item.Path,
+				CRC:      retroArchPlaylistCRC(item.CRC32),
+				CoreName: retroArchCoreName(item.CoreName),
+			})
+		}
+		return entries
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	for start := 0; start+6 <= len(lines); start += 6 {
+		entries = append(entries, retroArchContent{
+			Path:     lines[start],
+			CRC:      retroArchPlaylistCRC(lines[start+4]),
+			CoreName: retroArchCoreName(lines[start+3]),
+		})
+	}
+	return entries
+}
+
+// retroArchPlaylistCRC extracts "1A2B3C4D" from "1A2B3C4D|crc"; DETECT and zero CRCs are unknown
+func retroArchPlaylistCRC(value string) string {
+	crc, _, _ := strings.Cut(value, "|")
+	if !retroArchCRC.MatchString(crc) || crc == "00000000" {
+		return ""
+	}
+	return strings.ToLower(crc)
+}
+
+func retroArchCoreName(value string) string {
+	if value == "DETECT" {
+		return ""
+	}
+	return value
+}
+
+// retroArchContentName is the file name RetroArch names runtime logs after:
+// the last path element, or the file inside an archive ("game.zip#game.sfc")
+func retroArchContentName(path string) string {
+	if _, inner, found := strings.Cut(path, "#"); found && inner != "" {
+		path = inner
+	}
+	return pathBase(path)
+}
+
+// pathBase is filepath.Base for both slash styles, since playlists may come from Windows
+func pathBase(path string) string {
+	if index := strings.LastIndexAny(path, `/\`); index >= 0 {
+		return path[index+1:]
+	}
+	return path
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// fileLocationIndex matches external content paths to games through scanned file locations
+type fileLocationIndex struct {
+	byPath map[string]uint
+	byCRC  map[string]uint
+	byName map[string][]uint // lowercased file name
+	byStem map[string][]uint // lowercased file name without extension
+}
+
+func loadFileLocationIndex(db *gorm.DB) (*fileLocationIndex, error) {
+	var locations []models.FileLocation
+	if err := db.Select("game_id", "file_path", "file_crc").Find(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	index := &fileLocationIndex{
+		byPath: make(map[string]uint),
+		byCRC:  make(map[string]uint),
+		byName: make(map[string][]uint),
+		byStem: make(map[string][]uint),
+	}
+	for _, location := range locations {
+		index.byPath[location.FilePath] = location.GameID
+		if location.FileCRC != "" {
+			index.byCRC[strings.ToLower(location.FileCRC)] = location.GameID
+		}
+		name := strings.ToLower(pathBase(location.FilePath))
+		index.byName[name] = appendUnique(index.byName[name], location.GameID)
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+		index.byStem[stem] = appendUnique(index.byStem[stem], location.GameID)
+	}
+	return index, nil
+}
+
+// match tries the exact path (or its archive), then the CRC, then a file name
+// or stem that belongs to exactly one game. Returns 0 when nothing matches.
+func (i *fileLocationIndex) match(path, crc, name string) uint {
+	if path != "" {
+		archive, _, _ := strings.Cut(path, "#")
+		if gameID, ok := i.byPath[archive]; ok {
+			return gameID
+		}
+	}
+	if gameID, ok := i.byCRC[crc]; crc != "" && ok {
+		return gameID
+	}
+
+	names := []string{strings.ToLower(name)}
+	if path != "" {
+		archive, _, _ := strings.Cut(path, "#")
+		names = append(names, strings.ToLower(pathBase(archive)))
+	}
+	for _, candidate := range names {
+		if games := i.byName[candidate]; len(games) == 1 {
+			return games[0]
+		}
+	}
+	for _, candidate := range names {
+		stem := strings.TrimSuffix(candidate, filepath.Ext(candidate))
+		if games := i.byStem[stem]; len(games) == 1 {
+			return games[0]
+		}
+	}
+	return 0
+}
+
+func appendUnique(ids []uint, id uint) []uint {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
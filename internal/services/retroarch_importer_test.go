@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const retroArchHistory = `{
+  "version": "1.5",
+  "default_core_path": "",
+  "default_core_name": "",
+  "items": [
+    {
+      "path": "/mnt/retro/snes/Super Mario World (USA).sfc",
+      "label": "Super Mario World (USA)",
+      "core_path": "/usr/lib/libretro/snes9x_libretro.so",
+      "core_name": "Snes9x",
+      "crc32": "DETECT",
+      "db_name": "Nintendo - Super Nintendo Entertainment System.lpl"
+    },
+    {
+      "path": "/mnt/retro/md/sonic2.zip#Sonic The Hedgehog 2 (World).md",
+      "label": "Sonic The Hedgehog 2",
+      "core_path": "DETECT",
+      "core_name": "DETECT",
+      "crc32": "50ABC90A|crc",
+      "db_name": "Sega - Mega Drive - Genesis.lpl"
+    }
+  ]
+}`
+
+// Pre-1.7.6 playlists list six lines per entry
+const retroArchLegacyPlaylist = `C:\RetroArch\roms\gb\Tetris (World).gb
+Tetris
+C:\RetroArch\cores\gambatte_libretro.dll
+Gambatte
+00000000|crc
+Nintendo - Game Boy.lpl
+`
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func writeRetroArchLog(t *testing.T, path, runtime, lastPlayed string) {
+	writeTestFile(t, path, `{
+  "runtime": "`+runtime+`",
+  "last_played": "`+lastPlayed+`"
+}`)
+}
+
+func TestRetroArchImporter_Import(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+
+	mario := models.Game{Title: "Super Mario World", PlatformID: 1}
+	sonic := models.Game{Title: "Sonic The Hedgehog 2", PlatformID: 2}
+	tetris := models.Game{Title: "Tetris", PlatformID: 3}
+	require.NoError(t, db.Create(&[]*models.Game{&mario, &sonic, &tetris}).Error)
+	require.NoError(t, db.Create(&[]models.FileLocation{
+		// Scanned under a different mount than RetroArch uses: matched by name
+		{GameID: mario.ID, FilePath: "/data/roms/snes/Super Mario World (USA).sfc"},
+		{GameID: sonic.ID, FilePath: "/data/roms/md/sonic2.zip", FileCRC: "50abc90a"},
+		{GameID: tetris.ID, FilePath: "/data/roms/gb/Tetris (World).gb"},
+	}).Error)
+
+	dir := t.TempDir()
+	logs := filepath.Join(dir, "playlists", "logs")
+	writeTestFile(t, filepath.Join(dir, "playlists", "builtin", "content_history.lpl"), retroArchHistory)
+	writeTestFile(t, filepath.Join(dir, "playlists", "Nintendo - Game Boy.lpl"), retroArchLegacyPlaylist)
+	writeRetroArchLog(t, filepath.Join(logs, "Snes9x", "Super Mario World (USA).sfc.lrtl"), "1:30:00", "2024-03-01 21:00:00")
+	// Aggregate log wins over the per-core one
+	writeRetroArchLog(t, filepath.Join(logs, "Sonic The Hedgehog 2 (World).md.lrtl"), "0:45:10", "2024-03-02 10:00:00")
+	writeRetroArchLog(t, filepath.Join(logs, "Genesis Plus GX", "Sonic The Hedgehog 2 (World).md.lrtl"), "0:40:00", "2024-03-02 10:00:00")
+	writeRetroArchLog(t, filepath.Join(logs, "Gambatte", "Tetris (World).gb.lrtl"), "0:00:10", "2024-03-02 11:00:00")
+	writeRetroArchLog(t, filepath.Join(logs, "Snes9x", "Unknown Homebrew.sfc.lrtl"), "0:10:00", "2024-03-02 12:00:00")
+	writeTestFile(t, filepath.Join(logs, "Snes9x", "Broken.sfc.lrtl"), `{"runtime": "soon"}`)
+
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	importer := NewRetroArchImporter(db, dir, NewSessionTimer(db, SessionConflictReject, SessionOverlapReject, 0))
+
+	result, err := importer.Import("", warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.ItemsFound)
+	// The first import only records each log's total as the baseline
+	assert.Equal(t, 3, result.Baselined)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, []string{"Snes9x/Unknown Homebrew.sfc.lrtl"}, result.Unmatched)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "Broken.sfc.lrtl")
+
+	// Re-importing unchanged logs adds nothing
+	result, err = importer.Import("", warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, 3, result.Unchanged)
+
+	// Only time played since the last import becomes a new session
+	writeRetroArchLog(t, filepath.Join(logs, "Snes9x", "Super Mario World (USA).sfc.lrtl"), "2:15:00", "2024-03-05 19:00:00")
+	writeRetroArchLog(t, filepath.Join(logs, "Gambatte", "Tetris (World).gb.lrtl"), "0:20:10", "2024-03-05 20:00:00")
+	result, err = importer.Import(dir, warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.SessionsCreated)
+	assert.Equal(t, 65, result.MinutesImported)
+
+	var session models.PlaySession
+	require.NoError(t, db.Where("game_id = ?", mario.ID).First(&session).Error)
+	assert.Equal(t, SourceRetroArch, session.Source)
+	assert.Equal(t, 45, session.Duration)
+	assert.True(t, session.EndTime.Equal(time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)))
+	assert.Contains(t, session.Notes, "Snes9x")
+
+	var record models.ImportedPlaytime
+	require.NoError(t, db.Where("external_key = ?", "Gambatte/Tetris (World).gb.lrtl").First(&record).Error)
+	assert.Equal(t, tetris.ID, record.GameID)
+	assert.Equal(t, int64(20*60+10), record.TotalSeconds)
+
+	// A log that appears later starts from its own baseline, and a runtime
+	// below the previous one means the log was reset, so all of it is new
+	writeRetroArchLog(t, filepath.Join(logs, "Snes9x", "Unknown Homebrew.sfc.lrtl"), "0:25:00", "2024-03-06 12:00:00")
+	homebrew := models.Game{Title: "Unknown Homebrew", PlatformID: 1}
+	require.NoError(t, db.Create(&homebrew).Error)
+	require.NoError(t, db.Create(&models.FileLocation{GameID: homebrew.ID, FilePath: "/data/roms/snes/Unknown Homebrew.sfc"}).Error)
+	writeRetroArchLog(t, filepath.Join(logs, "Gambatte", "Tetris (World).gb.lrtl"), "0:05:00", "2024-03-06 20:00:00")
+	result, err = importer.Import(dir, warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Baselined)
+	assert.Equal(t, 1, result.SessionsCreated)
+	assert.Equal(t, 5, result.MinutesImported)
+
+	// Time already logged in Pelico isn't imported again under the reject
+	// policy, and the log's new total still becomes the baseline
+	tracked := models.PlaySession{GameID: mario.ID, StartTime: time.Date(2024, 3, 7, 18, 0, 0, 0, time.UTC)}
+	EndSessionAt(&tracked, time.Date(2024, 3, 7, 19, 0, 0, 0, time.UTC))
+	require.NoError(t, db.Create(&tracked).Error)
+	writeRetroArchLog(t, filepath.Join(logs, "Snes9x", "Super Mario World (USA).sfc.lrtl"), "3:15:00", "2024-03-07 20:00:00")
+	result, err = importer.Import(dir, warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.SessionsCreated)
+	require.Len(t, result.Errors, 2)
+	assert.Contains(t, result.Errors, fmt.Sprintf("Snes9x/Super Mario World (USA).sfc.lrtl: not imported, overlaps session %d", tracked.ID))
+	result, err = importer.Import(dir, warsaw)
+	require.NoError(t, err)
+	assert.Equal(t, 4, result.Unchanged)
+
+	_, err = NewRetroArchImporter(db, "", nil).Import("", nil)
+	assert.ErrorIs(t, err, ErrRetroArchDirNotSet)
+}
+
+func TestParseRetroArchRuntime(t *testing.T) {
+	runtime, err := parseRetroArchRuntime("112:05:09")
+	require.NoError(t, err)
+	assert.Equal(t, 112*time.Hour+5*time.Minute+9*time.Second, runtime)
+
+	for _, invalid := range []string{"", "1:30", "1:60:00", "a:00:00", "-1:00:00"} {
+		_, err := parseRetroArchRuntime(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
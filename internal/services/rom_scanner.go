@@ -2,7 +2,9 @@ package services
 
 import (
 	"crypto/md5"
+	"crypto/sha1"
 	"fmt"
+	"hash/crc32"
 	"gorm.io/gorm"
 	"io"
 	"os"
@@ -103,8 +105,8 @@ func (s *ROMScanner) isSupportedFile(filePath string) bool {
 }
 
 func (s *ROMScanner) createFileLocation(filePath, serverLocation string, fileSize int64) (*models.FileLocation, error) {
-	// Calculate file hash
-	hash, err := s.calculateFileHash(filePath)
+	// Calculate file hashes
+	hashes, err := s.calculateFileHashes(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate hash: %v", err)
 	}
@@ -113,25 +115,41 @@ func (s *ROMScanner) createFileLocation(filePath, serverLocation string, fileSiz
 		ServerLocation: serverLocation,
 		FilePath:       filePath,
 		FileSize:       fileSize,
-		FileHash:       hash,
+		FileHash:       hashes.MD5,
+		FileCRC:        hashes.CRC,
+		FileSHA1:       hashes.SHA1,
 	}
 
 	return fileLocation, nil
 }
 
-func (s *ROMScanner) calculateFileHash(filePath string) (string, error) {
+// FileHashes holds the checksums ROM databases identify dumps by
+type FileHashes struct {
+	CRC  string
+	MD5  string
+	SHA1 string
+}
+
+// calculateFileHashes reads the file once, computing CRC32, MD5 and SHA1 together
+func (s *ROMScanner) calculateFileHashes(filePath string) (*FileHashes, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	crcHash := crc32.NewIEEE()
+	md5Hash := md5.New()
+	sha1Hash := sha1.New()
+	if _, err := io.Copy(io.MultiWriter(crcHash, md5Hash, sha1Hash), file); err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	return &FileHashes{
+		CRC:  fmt.Sprintf("%08x", crcHash.Sum32()),
+		MD5:  fmt.Sprintf("%x", md5Hash.Sum(nil)),
+		SHA1: fmt.Sprintf("%x", sha1Hash.Sum(nil)),
+	}, nil
 }
 
 func (s *ROMScanner) findOrCreateGame(filePath string, platformID uint, fileLocation *models.FileLocation) (*models.Game, bool, error) {
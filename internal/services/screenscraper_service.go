@@ -0,0 +1,341 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+)
+
+// ErrROMNotIdentified is returned when ScreenScraper doesn't know a ROM's hashes
+var ErrROMNotIdentified = errors.New("ROM not identified by ScreenScraper")
+
+// screenScraperSecretParams are the auth parameters stripped from media URLs
+// before they are stored, since IDs identify the account as much as passwords
+// do; AuthorizeURL adds them back when the files are downloaded
+var screenScraperSecretParams = []string{"devid", "devpassword", "softname", "ssid", "sspassword"}
+
+type ScreenScraperService struct {
+	client      *http.Client
+	baseURL     string
+	devID       string
+	devPassword string
+	software    string
+	user        string
+	password    string
+	regions     []string // preferred regions, most preferred first
+}
+
+// ScreenScraper API structures; IDs and numbers are sent as strings
+type ScreenScraperResponse struct {
+	Response struct {
+		Game ScreenScraperGame `json:"jeu"`
+	} `json:"response"`
+}
+
+type ScreenScraperGame struct {
+	ID        string                   `json:"id"`
+	Names     []ScreenScraperText      `json:"noms"`
+	Synopsis  []ScreenScraperText      `json:"synopsis"`
+	Dates     []ScreenScraperText      `json:"dates"`
+	Genres    []ScreenScraperGenre     `json:"genres"`
+	Publisher ScreenScraperText        `json:"editeur"`
+	Developer ScreenScraperText        `json:"developpeur"`
+	Rating    ScreenScraperText        `json:"note"`
+	Medias    []ScreenScraperMedia     `json:"medias"`
+}
+
+// ScreenScraperText is a value tagged with a region or language
+type ScreenScraperText struct {
+	Region   string `json:"region"`
+	Language string `json:"langue"`
+	Text     string `json:"text"`
+}
+
+type ScreenScraperGenre struct {
+	Names []ScreenScraperText `json:"noms"`
+}
+
+type ScreenScraperMedia struct {
+	Type   string `json:"type"`
+	Region string `json:"region"`
+	URL    string `json:"url"`
+	Format string `json:"format"`
+}
+
+// ROMIdentity is everything ScreenScraper can match a dump on
+type ROMIdentity struct {
+	FileName string
+	FileSize int64
+	CRC      string
+	MD5      string
+	SHA1     string
+}
+
+// ROMIdentityFromFile builds a lookup identity from a scanned file location
+func ROMIdentityFromFile(file models.FileLocation) ROMIdentity {
+	return ROMIdentity{
+		FileName: filepath.Base(file.FilePath),
+		FileSize: file.FileSize,
+		CRC:      file.FileCRC,
+		MD5:      file.FileHash,
+		SHA1:     file.FileSHA1,
+	}
+}
+
+// HasHash reports whether at least one checksum is known
+func (r ROMIdentity) HasHash() bool {
+	return r.CRC != "" || r.MD5 != "" || r.SHA1 != ""
+}
+
+// cacheKey identifies the dump independent of its file name
+func (r ROMIdentity) cacheKey() string {
+	return fmt.Sprintf("crc=%s md5=%s sha1=%s size=%d", r.CRC, r.MD5, r.SHA1, r.FileSize)
+}
+
+func NewScreenScraperService(devID, devPassword, software, user, password string, regions []string) *ScreenScraperService {
+	return &ScreenScraperService{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		baseURL:     "https://api.screenscraper.fr/api2",
+		devID:       devID,
+		devPassword: devPassword,
+		software:    software,
+		user:        user,
+		password:    password,
+		regions:     regions,
+	}
+}
+
+// Enabled reports whether developer credentials are configured (nil-safe)
+func (s *ScreenScraperService) Enabled() bool {
+	return s != nil && s.devID != "" && s.devPassword != ""
+}
+
+// IdentifyROM looks a dump up by its hashes, size and ScreenScraper system ID
+func (s *ScreenScraperService) IdentifyROM(rom ROMIdentity, systemID int) (*GameMetadata, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("ScreenScraper is not configured")
+	}
+	if !rom.HasHash() {
+		return nil, fmt.Errorf("no ROM hashes available")
+	}
+
+	params := s.credentials()
+	params.Set("output", "json")
+	params.Set("romtype", "rom")
+	params.Set("systemeid", strconv.Itoa(systemID))
+	params.Set("romnom", rom.FileName)
+	params.Set("romtaille", strconv.FormatInt(rom.FileSize, 10))
+	if rom.CRC != "" {
+		params.Set("crc", strings.ToUpper(rom.CRC))
+	}
+	if rom.MD5 != "" {
+		params.Set("md5", strings.ToUpper(rom.MD5))
+	}
+	if rom.SHA1 != "" {
+		params.Set("sha1", strings.ToUpper(rom.SHA1))
+	}
+
+	resp, err := s.client.Get(s.baseURL + "/jeuInfos.php?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to make ScreenScraper request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ScreenScraper response: %v", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrROMNotIdentified
+	case resp.StatusCode == 429 || resp.StatusCode == 430 || resp.StatusCode == 431:
+		return nil, fmt.Errorf("ScreenScraper quota exceeded (status %d)", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("ScreenScraper request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var apiResp ScreenScraperResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse ScreenScraper response: %v", err)
+	}
+	if apiResp.Response.Game.ID == "" {
+		return nil, ErrROMNotIdentified
+	}
+
+	return s.toMetadata(apiResp.Response.Game), nil
+}
+
+// AuthorizeURL restores the credentials stripped from stored ScreenScraper media
+// URLs; other URLs are returned unchanged
+func (s *ScreenScraperService) AuthorizeURL(rawURL string) string {
+	if !s.Enabled() || !s.isMediaURL(rawURL) {
+		return rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	for key, values := range s.credentials() {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func (s *ScreenScraperService) credentials() url.Values {
+	params := url.Values{}
+	params.Set("devid", s.devID)
+	params.Set("devpassword", s.devPassword)
+	params.Set("softname", s.software)
+	if s.user != "" {
+		params.Set("ssid", s.user)
+		params.Set("sspassword", s.password)
+	}
+	return params
+}
+
+func (s *ScreenScraperService) isMediaURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	base, err := url.Parse(s.baseURL)
+	if err == nil && host == strings.ToLower(base.Hostname()) {
+		return true
+	}
+	return host == "screenscraper.fr" || strings.HasSuffix(host, ".screenscraper.fr")
+}
+
+func (s *ScreenScraperService) toMetadata(game ScreenScraperGame) *GameMetadata {
+	metadata := &GameMetadata{
+		Title:       s.pickRegional(game.Names),
+		Description: pickLanguage(game.Synopsis, "en"),
+	}
+	metadata.ScreenScraperID, _ = strconv.Atoi(game.ID)
+
+	// Regional titles other than the chosen one become alternative names
+	seen := map[string]bool{metadata.Title: true}
+	for _, name := range game.Names {
+		if name.Text == "" || seen[name.Text] {
+			continue
+		}
+		seen[name.Text] = true
+		metadata.AlternativeNames = append(metadata.AlternativeNames, MetadataAlternativeName{
+			Name:    name.Text,
+			Comment: strings.ToUpper(name.Region),
+		})
+	}
+
+	if date := s.pickRegional(game.Dates); len(date) >= 4 {
+		metadata.Year, _ = strconv.Atoi(date[:4])
+	}
+
+	// ScreenScraper rates out of 20, we use 0-10
+	if rating, err := strconv.ParseFloat(game.Rating.Text, 32); err == nil {
+		metadata.Rating = float32(rating / 2)
+	}
+
+	for _, genre := range game.Genres {
+		if name := pickLanguage(genre.Names, "en"); name != "" {
+			metadata.Genres = append(metadata.Genres, name)
+		}
+	}
+	if len(metadata.Genres) > 0 {
+		metadata.Genre = metadata.Genres[0]
+	}
+
+	if game.Developer.Text != "" {
+		metadata.Developers = []string{game.Developer.Text}
+	}
+	if game.Publisher.Text != "" {
+		metadata.Publishers = []string{game.Publisher.Text}
+	}
+
+	metadata.CoverArtURL = s.pickMedia(game.Medias, "box-2D")
+	metadata.BoxArtURL = s.pickMedia(game.Medias, "box-3D")
+	metadata.WheelURL = s.pickMedia(game.Medias, "wheel")
+	for _, media := range game.Medias {
+		if media.Type == "ss" && media.URL != "" {
+			metadata.Screenshots = append(metadata.Screenshots, stripScreenScraperSecrets(media.URL))
+		}
+	}
+
+	return metadata
+}
+
+// pickRegional returns the text for the most preferred region, falling back to
+// ScreenScraper's own default ("ss") and then to the first entry
+func (s *ScreenScraperService) pickRegional(texts []ScreenScraperText) string {
+	regions := append(append([]string{}, s.regions...), "ss")
+	for _, region := range regions {
+		for _, text := range texts {
+			if strings.EqualFold(text.Region, region) && text.Text != "" {
+				return text.Text
+			}
+		}
+	}
+	for _, text := range texts {
+		if text.Text != "" {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// pickMedia returns the URL of a media type in the most preferred region
+func (s *ScreenScraperService) pickMedia(medias []ScreenScraperMedia, mediaType string) string {
+	for _, region := range s.regions {
+		for _, media := range medias {
+			if media.Type == mediaType && strings.EqualFold(media.Region, region) && media.URL != "" {
+				return stripScreenScraperSecrets(media.URL)
+			}
+		}
+	}
+	for _, media := range medias {
+		if media.Type == mediaType && media.URL != "" {
+			return stripScreenScraperSecrets(media.URL)
+		}
+	}
+	return ""
+}
+
+func pickLanguage(texts []ScreenScraperText, language string) string {
+	for _, text := range texts {
+		if strings.EqualFold(text.Language, language) && text.Text != "" {
+			return text.Text
+		}
+	}
+	for _, text := range texts {
+		if text.Text != "" {
+			return text.Text
+		}
+	}
+	return ""
+}
+
+// stripScreenScraperSecrets removes the credentials ScreenScraper embeds in media URLs
+func stripScreenScraperSecrets(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	for _, key := range screenScraperSecretParams {
+		query.Del(key)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
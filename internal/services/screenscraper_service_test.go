@@ -0,0 +1,193 @@
+package services
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedJeuInfos is a trimmed jeuInfos.php response for Sonic The Hedgehog 2 (Mega Drive)
+const recordedJeuInfos = `{
+  "header": {"APIversion": "2.0", "success": "true"},
+  "response": {
+    "jeu": {
+      "id": "3",
+      "noms": [
+        {"region": "ss", "text": "Sonic The Hedgehog 2"},
+        {"region": "jp", "text": "ソニック・ザ・ヘッジホッグ2"},
+        {"region": "us", "text": "Sonic The Hedgehog 2"},
+        {"region": "eu", "text": "Sonic 2"}
+      ],
+      "editeur": {"id": "3", "text": "SEGA"},
+      "developpeur": {"id": "7", "text": "Sonic Team"},
+      "note": {"text": "17"},
+      "synopsis": [
+        {"langue": "fr", "text": "Le Dr Robotnik est de retour."},
+        {"langue": "en", "text": "Dr. Robotnik is back."}
+      ],
+      "dates": [
+        {"region": "jp", "text": "1992-11-21"},
+        {"region": "us", "text": "1992-11-24"}
+      ],
+      "genres": [
+        {"id": "7", "noms": [{"langue": "de", "text": "Plattform"}, {"langue": "en", "text": "Platform"}]},
+        {"id": "2", "noms": [{"langue": "en", "text": "Action"}]}
+      ],
+      "medias": [
+        {"type": "ss", "region": "wor", "format": "png", "url": "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&softname=pelico&ssid=&sspassword=&systemeid=1&jeuid=3&media=ss"},
+        {"type": "box-2D", "region": "jp", "format": "png", "url": "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&jeuid=3&media=box-2D(jp)"},
+        {"type": "box-2D", "region": "us", "format": "png", "url": "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&jeuid=3&media=box-2D(us)"},
+        {"type": "box-3D", "region": "eu", "format": "png", "url": "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&jeuid=3&media=box-3D(eu)"},
+        {"type": "wheel", "region": "wor", "format": "png", "url": "https://neoclone.screenscraper.fr/api2/mediaJeu.php?devid=dev&devpassword=secret&jeuid=3&media=wheel(wor)"}
+      ]
+    }
+  }
+}`
+
+func newScreenScraperStandIn(t *testing.T) (*ScreenScraperService, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "/jeuInfos.php", r.URL.Path)
+		assert.Equal(t, "dev", query.Get("devid"))
+		assert.Equal(t, "json", query.Get("output"))
+
+		if query.Get("crc") != "50ABC90A" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Erreur : Rom/Iso/Dossier non trouvée !"))
+			return
+		}
+		assert.Equal(t, "1", query.Get("systemeid"))
+		assert.Equal(t, "1048576", query.Get("romtaille"))
+		assert.Equal(t, "9FEAB07DA8E3E7A0C9E8B4B1F33D1B4C", query.Get("md5"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(recordedJeuInfos))
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewScreenScraperService("dev", "secret", "pelico", "", "", []string{"us", "wor", "eu", "jp"})
+	service.baseURL = server.URL
+	return service, server
+}
+
+var sonic2ROM = ROMIdentity{
+	FileName: "Sonic The Hedgehog 2 (World).md",
+	FileSize: 1048576,
+	CRC:      "50abc90a",
+	MD5:      "9feab07da8e3e7a0c9e8b4b1f33d1b4c",
+}
+
+func TestScreenScraperService_IdentifyROM(t *testing.T) {
+	service, _ := newScreenScraperStandIn(t)
+
+	metadata, err := service.IdentifyROM(sonic2ROM, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, metadata.ScreenScraperID)
+	assert.Equal(t, "Sonic The Hedgehog 2", metadata.Title)
+	assert.Equal(t, "Dr. Robotnik is back.", metadata.Description)
+	assert.Equal(t, 1992, metadata.Year)
+	assert.InDelta(t, 8.5, metadata.Rating, 0.01)
+	assert.Equal(t, []string{"Platform", "Action"}, metadata.Genres)
+	assert.Equal(t, "Platform", metadata.Genre)
+	assert.Equal(t, []string{"Sonic Team"}, metadata.Developers)
+	assert.Equal(t, []string{"SEGA"}, metadata.Publishers)
+	assert.Equal(t, []MetadataAlternativeName{
+		{Name: "ソニック・ザ・ヘッジホッグ2", Comment: "JP"},
+		{Name: "Sonic 2", Comment: "EU"},
+	}, metadata.AlternativeNames)
+
+	// Preferred region wins, and stored URLs don't carry credentials
+	assert.Contains(t, metadata.CoverArtURL, "box-2D%28us%29")
+	assert.Contains(t, metadata.BoxArtURL, "box-3D%28eu%29")
+	assert.Contains(t, metadata.WheelURL, "wheel%28wor%29")
+	require.Len(t, metadata.Screenshots, 1)
+	for _, mediaURL := range append(metadata.Screenshots, metadata.CoverArtURL, metadata.BoxArtURL, metadata.WheelURL) {
+		parsed, err := url.Parse(mediaURL)
+		require.NoError(t, err)
+		for _, param := range []string{"devid", "devpassword", "softname", "ssid", "sspassword"} {
+			assert.False(t, parsed.Query().Has(param), "%s in %s", param, mediaURL)
+		}
+		assert.Equal(t, "3", parsed.Query().Get("jeuid"))
+	}
+}
+
+func TestScreenScraperService_RegionPreference(t *testing.T) {
+	service, _ := newScreenScraperStandIn(t)
+	service.regions = []string{"jp"}
+
+	metadata, err := service.IdentifyROM(sonic2ROM, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "ソニック・ザ・ヘッジホッグ2", metadata.Title)
+	assert.Contains(t, metadata.CoverArtURL, "box-2D%28jp%29")
+	// No Japanese 3D box exists, so any region is used
+	assert.Contains(t, metadata.BoxArtURL, "box-3D%28eu%29")
+}
+
+func TestScreenScraperService_NotFound(t *testing.T) {
+	service, _ := newScreenScraperStandIn(t)
+
+	unknown := sonic2ROM
+	unknown.CRC = "deadbeef"
+	_, err := service.IdentifyROM(unknown, 1)
+	assert.ErrorIs(t, err, ErrROMNotIdentified)
+
+	_, err = service.IdentifyROM(ROMIdentity{FileName: "nohash.md"}, 1)
+	assert.Error(t, err)
+}
+
+func TestScreenScraperService_AuthorizeURL(t *testing.T) {
+	service := NewScreenScraperService("dev", "secret", "pelico", "user", "pass", nil)
+
+	authorized := service.AuthorizeURL("https://neoclone.screenscraper.fr/api2/mediaJeu.php?jeuid=3&media=ss")
+	assert.Contains(t, authorized, "devid=dev")
+	assert.Contains(t, authorized, "devpassword=secret")
+	assert.Contains(t, authorized, "softname=pelico")
+	assert.Contains(t, authorized, "ssid=user")
+	assert.Contains(t, authorized, "sspassword=pass")
+	assert.Contains(t, authorized, "jeuid=3")
+
+	other := "https://images.igdb.com/igdb/image/upload/t_cover_big/co1.jpg"
+	assert.Equal(t, other, service.AuthorizeURL(other))
+
+	var disabled *ScreenScraperService
+	assert.Equal(t, other, disabled.AuthorizeURL(other))
+}
+
+func TestMetadataService_FetchMetadataForGameUsesROMHashes(t *testing.T) {
+	screenScraper, _ := newScreenScraperStandIn(t)
+	service := NewMetadataService("", "", screenScraper, nil, NewLoggerService(slog.LevelError))
+
+	game := &models.Game{
+		Title:    "sonic 2 world",
+		Platform: models.Platform{Name: "Sega Mega Drive", ScreenScraperID: 1},
+		FileLocations: []models.FileLocation{
+			{FilePath: "/roms/md/unknown.md", FileSize: 1048576, FileCRC: "deadbeef"},
+			{FilePath: "/roms/md/Sonic The Hedgehog 2 (World).md", FileSize: 1048576, FileCRC: sonic2ROM.CRC, FileHash: sonic2ROM.MD5},
+		},
+	}
+
+	metadata, err := service.FetchMetadataForGame(game)
+	require.NoError(t, err)
+	assert.Equal(t, "Sonic The Hedgehog 2", metadata.Title)
+	assert.Equal(t, 3, metadata.ScreenScraperID)
+}
+
+func TestROMScanner_CalculateFileHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.bin")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o644))
+
+	hashes, err := NewROMScanner(nil).calculateFileHashes(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0d4a1185", hashes.CRC)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", hashes.MD5)
+	assert.Equal(t, "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed", hashes.SHA1)
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// Conflict policies for opening a session while another one is still running
+const (
+	SessionConflictReject    = "reject"
+	SessionConflictAutoClose = "auto_close"
+)
+
+var (
+	ErrSessionActive    = errors.New("another play session is already active")
+	ErrNoActiveSession  = errors.New("no play session is active")
+	ErrSessionEnded     = errors.New("play session has already ended")
+	ErrSessionPaused    = errors.New("play session is already paused")
+	ErrSessionNotPaused = errors.New("play session is not paused")
+)
+
+// SessionTimer runs the play session timer. At most one session is open at a
+// time; paused time is excluded from Duration, and sessions left open longer
+// than maxDuration are ended automatically.
+type SessionTimer struct {
+	db             *gorm.DB
+	conflictPolicy string
+	overlapPolicy  string
+	maxDuration    time.Duration // 0 disables auto-ending
+	now            func() time.Time
+	mutex          sync.Mutex
+}
+
+func NewSessionTimer(db *gorm.DB, conflictPolicy, overlapPolicy string, maxDuration time.Duration) *SessionTimer {
+	if conflictPolicy != SessionConflictAutoClose {
+		conflictPolicy = SessionConflictReject
+	}
+	if overlapPolicy != SessionOverlapWarn {
+		overlapPolicy = SessionOverlapReject
+	}
+	return &SessionTimer{
+		db:             db,
+		conflictPolicy: conflictPolicy,
+		overlapPolicy:  overlapPolicy,
+		maxDuration:    maxDuration,
+		now:            time.Now,
+	}
+}
+
+// ConflictPolicy reports how an already open session is handled on start
+func (t *SessionTimer) ConflictPolicy() string {
+	return t.conflictPolicy
+}
+
+// OverlapPolicy reports whether overlapping sessions are rejected or only reported
+func (t *SessionTimer) OverlapPolicy() string {
+	return t.overlapPolicy
+}
+
+// Now is the timer's current time
+func (t *SessionTimer) Now() time.Time {
+	return t.now()
+}
+
+// MaxDuration is how long a session may stay open before it is ended automatically
+func (t *SessionTimer) MaxDuration() time.Duration {
+	return t.maxDuration
+}
+
+// Active returns the open session (with its game), or nil when none is running
+func (t *SessionTimer) Active() (*models.PlaySession, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, err := t.autoEndStale(); err != nil {
+		return nil, err
+	}
+	return t.active(t.db.Preload("Game").Preload("Game.Platform"))
+}
+
+// Start opens a session for a game. An already open session is either refused
+// (ErrSessionActive) or ended at startTime, depending on the conflict policy;
+// the session closed that way is returned as closed.
+func (t *SessionTimer) Start(gameID uint, startTime time.Time, notes string) (session, closed *models.PlaySession, err error) {
+	session, closed, _, err = t.start(gameID, startTime, notes, 0, false)
+	return session, closed, err
+}
+
+// StartChecked is Start for a session that began in the past, stored with its
+// rating: completed sessions overlapping it are checked in the same transaction
+// as the insert, returned as overlaps, and refused with ErrSessionOverlap under
+// the reject policy
+func (t *SessionTimer) StartChecked(gameID uint, startTime time.Time, notes string, rating int) (session, closed *models.PlaySession, overlaps []SessionOverlap, err error) {
+	return t.start(gameID, startTime, notes, rating, true)
+}
+
+func (t *SessionTimer) start(gameID uint, startTime time.Time, notes string, rating int, checkOverlap bool) (session, closed *models.PlaySession, overlaps []SessionOverlap, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, err := t.autoEndStale(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		if checkOverlap {
+			var err error
+			if overlaps, err = t.CheckOverlapTx(tx, startTime, nil, 0); err != nil {
+				return err
+			}
+		}
+		open, err := t.active(tx)
+		if err != nil {
+			return err
+		}
+		if open != nil {
+			if t.conflictPolicy != SessionConflictAutoClose {
+				closed = open
+				return ErrSessionActive
+			}
+			end := startTime
+			if end.Before(open.StartTime) {
+				end = t.now()
+			}
+			if err := t.finish(tx, open, end); err != nil {
+				return err
+			}
+			closed = open
+		}
+
+		session = &models.PlaySession{
+			GameID:    gameID,
+			StartTime: startTime,
+			Notes:     notes,
+			Rating:    rating,
+		}
+		return tx.Create(session).Error
+	})
+	if err != nil {
+		return nil, closed, overlaps, err
+	}
+	return session, closed, overlaps, nil
+}
+
+// Stop ends a session now; sessionID 0 stops the active session. Non-empty notes
+// and a non-zero rating are stored with it.
+func (t *SessionTimer) Stop(sessionID uint, notes string, rating int) (*models.PlaySession, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	session, err := t.open(sessionID)
+	if err != nil {
+		return session, err
+	}
+	if notes != "" {
+		session.Notes = notes
+	}
+	if rating != 0 {
+		session.Rating = rating
+	}
+	if err := t.finish(t.db, session, t.now()); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Pause stops the clock on a running session; sessionID 0 pauses the active session
+func (t *SessionTimer) Pause(sessionID uint) (*models.PlaySession, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	session, err := t.open(sessionID)
+	if err != nil {
+		return session, err
+	}
+	if session.PausedAt != nil {
+		return nil, ErrSessionPaused
+	}
+	now := t.now()
+	session.PausedAt = &now
+	if err := t.db.Save(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Resume restarts the clock on a paused session; sessionID 0 resumes the active session
+func (t *SessionTimer) Resume(sessionID uint) (*models.PlaySession, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	session, err := t.open(sessionID)
+	if err != nil {
+		return session, err
+	}
+	if session.PausedAt == nil {
+		return nil, ErrSessionNotPaused
+	}
+	session.PausedSeconds += int(t.now().Sub(*session.PausedAt).Seconds())
+	session.PausedAt = nil
+	if err := t.db.Save(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// CheckOverlap finds sessions overlapping start..end, ignoring excludeID. A nil
+// end means the session is still running: only completed sessions ending after
+// start count, since open ones fall under the conflict policy. Under the reject
+// policy any overlap returns ErrSessionOverlap along with the overlaps.
+func (t *SessionTimer) CheckOverlap(start time.Time, end *time.Time, excludeID uint) ([]SessionOverlap, error) {
+	return t.CheckOverlapTx(t.db, start, end, excludeID)
+}
+
+// Transaction runs fn in a transaction under the timer's lock, so an overlap
+// check made with CheckOverlapTx inside it still holds when fn writes the session
+func (t *SessionTimer) Transaction(fn func(tx *gorm.DB) error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.db.Transaction(fn)
+}
+
+// CheckOverlapTx is CheckOverlap within tx. The query window is widened by a
+// day and narrowed in Go, since SQLite compares stored times as text and those
+// may carry different offsets.
+func (t *SessionTimer) CheckOverlapTx(tx *gorm.DB, start time.Time, end *time.Time, excludeID uint) ([]SessionOverlap, error) {
+	query := tx.Model(&models.PlaySession{})
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if end == nil {
+		query = query.Where("end_time IS NOT NULL AND end_time > ?", start.AddDate(0, 0, -1))
+	} else {
+		query = query.Where("start_time < ? AND (end_time IS NULL OR end_time > ?)", end.AddDate(0, 0, 1), start.AddDate(0, 0, -1))
+	}
+
+	var rows []models.PlaySession
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	sessions := rows[:0]
+	for _, session := range rows {
+		if session.EndTime != nil && !session.EndTime.After(start) {
+			continue
+		}
+		if end != nil && !session.StartTime.Before(*end) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartTime.Before(sessions[j].StartTime)
+	})
+
+	overlaps := make([]SessionOverlap, len(sessions))
+	for i, session := range sessions {
+		overlaps[i] = SessionOverlap{
+			SessionID: session.ID,
+			GameID:    session.GameID,
+			StartTime: session.StartTime,
+			EndTime:   session.EndTime,
+		}
+	}
+	if t.overlapPolicy == SessionOverlapReject {
+		return overlaps, ErrSessionOverlap
+	}
+	return overlaps, nil
+}
+
+// AutoEndStale ends sessions open longer than the configured limit and returns how many were ended
+func (t *SessionTimer) AutoEndStale() (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.autoEndStale()
+}
+
+// StartAutoEnd periodically applies the auto-end policy in the background,
+// logging the sessions it ends and any failure
+func (t *SessionTimer) StartAutoEnd(interval time.Duration, logger *LoggerService) {
+	if t.maxDuration <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if ended, err := t.AutoEndStale(); err != nil {
+				logger.LogError("session_auto_end_failed", err)
+			} else if ended > 0 {
+				logger.LogInfo("sessions_auto_ended",
+					slog.Int("ended", ended),
+					slog.Duration("max_duration", t.maxDuration))
+			}
+		}
+	}()
+}
+
+// Elapsed returns a session's played time so far, excluding pauses
+func (t *SessionTimer) Elapsed(session *models.PlaySession) time.Duration {
+	return PlayedDuration(session, t.now())
+}
+
+// PlayedDuration is the time played in a session up to at (or its end time),
+// excluding paused time
+func PlayedDuration(session *models.PlaySession, at time.Time) time.Duration {
+	end := at
+	if session.EndTime != nil {
+		end = *session.EndTime
+	}
+	if session.PausedAt != nil && session.PausedAt.Before(end) {
+		end = *session.PausedAt
+	}
+	played := end.Sub(session.StartTime) - time.Duration(session.PausedSeconds)*time.Second
+	if played < 0 {
+		return 0
+	}
+	return played
+}
+
+func (t *SessionTimer) autoEndStale() (int, error) {
+	if t.maxDuration <= 0 {
+		return 0, nil
+	}
+
+	// Widened by a day and filtered below, as SQLite compares times as text
+	cutoff := t.now().Add(-t.maxDuration)
+	var open []models.PlaySession
+	err := t.db.Where("end_time IS NULL AND start_time < ?", cutoff.AddDate(0, 0, 1)).Find(&open).Error
+	if err != nil {
+		return 0, err
+	}
+	stale := open[:0]
+	for _, session := range open {
+		if session.StartTime.Before(cutoff) {
+			stale = append(stale, session)
+		}
+	}
+
+	for i := range stale {
+		session := &stale[i]
+		// End where the limit was reached, or where the player paused if earlier
+		end := session.StartTime.Add(t.maxDuration)
+		if session.PausedAt != nil && session.PausedAt.Before(end) {
+			end = *session.PausedAt
+		}
+		session.AutoEnded = true
+		if err := t.finish(t.db, session, end); err != nil {
+			return i, err
+		}
+	}
+	return len(stale), nil
+}
+
+// active returns the most recently started open session, or nil
+func (t *SessionTimer) active(tx *gorm.DB) (*models.PlaySession, error) {
+	var session models.PlaySession
+	err := tx.Where("end_time IS NULL").Order("start_time DESC").First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// open loads a session that is still running; sessionID 0 means the active one.
+// An ended session is returned along with ErrSessionEnded.
+func (t *SessionTimer) open(sessionID uint) (*models.PlaySession, error) {
+	if _, err := t.autoEndStale(); err != nil {
+		return nil, err
+	}
+
+	if sessionID == 0 {
+		session, err := t.active(t.db)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, ErrNoActiveSession
+		}
+		return session, nil
+	}
+
+	var session models.PlaySession
+	if err := t.db.First(&session, sessionID).Error; err != nil {
+		return nil, err
+	}
+	if session.EndTime != nil {
+		return &session, ErrSessionEnded
+	}
+	return &session, nil
+}
+
+// finish closes a session at end and saves it
+func (t *SessionTimer) finish(tx *gorm.DB, session *models.PlaySession, end time.Time) error {
+	EndSessionAt(session, end)
+	return tx.Save(session).Error
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeClock lets tests move the timer's notion of now
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestSessionTimer(t *testing.T, policy string, maxDuration time.Duration) (*SessionTimer, *fakeClock, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Platform{}, &models.Game{}, &models.PlaySession{}))
+
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)}
+	timer := NewSessionTimer(db, policy, SessionOverlapReject, maxDuration)
+	timer.now = clock.Now
+	return timer, clock, db
+}
+
+func TestSessionTimer_PauseExcludedFromDuration(t *testing.T) {
+	timer, clock, _ := newTestSessionTimer(t, SessionConflictReject, 0)
+
+	session, _, err := timer.Start(1, clock.now, "")
+	require.NoError(t, err)
+
+	clock.Advance(30 * time.Minute)
+	_, err = timer.Pause(0)
+	require.NoError(t, err)
+
+	clock.Advance(20 * time.Minute)
+	active, err := timer.Active()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, timer.Elapsed(active))
+
+	_, err = timer.Resume(0)
+	require.NoError(t, err)
+	clock.Advance(15 * time.Minute)
+
+	// A pause that is still running when the session stops is excluded too
+	_, err = timer.Pause(0)
+	require.NoError(t, err)
+	clock.Advance(10 * time.Minute)
+
+	stopped, err := timer.Stop(session.ID, "done", 8)
+	require.NoError(t, err)
+	assert.Equal(t, 45, stopped.Duration)
+	assert.Equal(t, 30*60, stopped.PausedSeconds)
+	assert.Nil(t, stopped.PausedAt)
+	assert.Equal(t, clock.now, *stopped.EndTime)
+	assert.Equal(t, "done", stopped.Notes)
+
+	_, err = timer.Stop(session.ID, "", 0)
+	assert.ErrorIs(t, err, ErrSessionEnded)
+	_, err = timer.Resume(0)
+	assert.ErrorIs(t, err, ErrNoActiveSession)
+}
+
+func TestSessionTimer_ConflictPolicies(t *testing.T) {
+	timer, clock, _ := newTestSessionTimer(t, SessionConflictReject, 0)
+	first, _, err := timer.Start(1, clock.now, "")
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	_, open, err := timer.Start(2, clock.now, "")
+	assert.ErrorIs(t, err, ErrSessionActive)
+	require.NotNil(t, open)
+	assert.Equal(t, first.ID, open.ID)
+
+	timer, clock, db := newTestSessionTimer(t, SessionConflictAutoClose, 0)
+	first, _, err = timer.Start(1, clock.now, "")
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	second, closed, err := timer.Start(2, clock.now, "")
+	require.NoError(t, err)
+	require.NotNil(t, closed)
+	assert.Equal(t, first.ID, closed.ID)
+	assert.Equal(t, 60, closed.Duration)
+
+	var running int64
+	db.Model(&models.PlaySession{}).Where("end_time IS NULL").Count(&running)
+	assert.Equal(t, int64(1), running)
+	active, err := timer.Active()
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, active.ID)
+}
+
+func TestSessionTimer_AutoEnd(t *testing.T) {
+	timer, clock, db := newTestSessionTimer(t, SessionConflictReject, 4*time.Hour)
+
+	forgotten, _, err := timer.Start(1, clock.now, "")
+	require.NoError(t, err)
+
+	clock.Advance(3 * time.Hour)
+	ended, err := timer.AutoEndStale()
+	require.NoError(t, err)
+	assert.Equal(t, 0, ended)
+
+	// Overnight: the session is capped at the limit and a new one may start
+	clock.Advance(9 * time.Hour)
+	_, _, err = timer.Start(2, clock.now, "")
+	require.NoError(t, err)
+
+	var reloaded models.PlaySession
+	require.NoError(t, db.First(&reloaded, forgotten.ID).Error)
+	assert.True(t, reloaded.AutoEnded)
+	assert.Equal(t, 240, reloaded.Duration)
+	assert.Equal(t, forgotten.StartTime.Add(4*time.Hour), reloaded.EndTime.UTC())
+
+	// A session paused before the limit ends where it was paused
+	_, err = timer.Pause(0)
+	require.NoError(t, err)
+	clock.Advance(5 * time.Hour)
+	ended, err = timer.AutoEndStale()
+	require.NoError(t, err)
+	assert.Equal(t, 1, ended)
+	active, err := timer.Active()
+	require.NoError(t, err)
+	assert.Nil(t, active)
+}
+
+func TestSessionTimer_AutoEndMixedOffsets(t *testing.T) {
+	timer, clock, db := newTestSessionTimer(t, SessionConflictReject, 4*time.Hour)
+	east := time.FixedZone("+05:00", 5*3600)
+	west := time.FixedZone("-05:00", -5*3600)
+
+	// Started 4.5 and 3.5 hours ago, stored with offsets that sort the other way as text
+	stale := models.PlaySession{GameID: 1, StartTime: clock.now.Add(-270 * time.Minute).In(east)}
+	fresh := models.PlaySession{GameID: 2, StartTime: clock.now.Add(-210 * time.Minute).In(west)}
+	require.NoError(t, db.Create(&stale).Error)
+	require.NoError(t, db.Create(&fresh).Error)
+
+	ended, err := timer.AutoEndStale()
+	require.NoError(t, err)
+	assert.Equal(t, 1, ended)
+	require.NoError(t, db.First(&stale, stale.ID).Error)
+	assert.True(t, stale.AutoEnded)
+	require.NoError(t, db.First(&fresh, fresh.ID).Error)
+	assert.Nil(t, fresh.EndTime)
+}
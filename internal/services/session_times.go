@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"pelico/internal/models"
+)
+
+// Overlap policies for sessions whose time range overlaps an existing one
+const (
+	SessionOverlapReject = "reject"
+	SessionOverlapWarn   = "warn"
+)
+
+var (
+	ErrInvalidSessionTime    = errors.New("unrecognized session time")
+	ErrSessionEndBeforeStart = errors.New("session end time is before its start time")
+	ErrSessionInFuture       = errors.New("session time is in the future")
+	ErrSessionOverlap        = errors.New("session overlaps an existing play session")
+)
+
+// sessionFutureTolerance allows for clock skew between client and server
+const sessionFutureTolerance = 5 * time.Minute
+
+// zonedSessionTimeLayouts carry their own offset
+var zonedSessionTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04 -0700",
+	time.RFC1123Z,
+}
+
+// localSessionTimeLayouts have no offset and are read in the caller's timezone
+var localSessionTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// SessionTimeFormats describes the accepted formats for error responses
+const SessionTimeFormats = "RFC3339 (2024-03-01T18:30:00+01:00), or a local time (2024-03-01 18:30[:00]) read in the request timezone"
+
+// LoadSessionTimezone resolves an IANA timezone name; an empty name is the server's local time
+func LoadSessionTimezone(name string) (*time.Location, error) {
+	if strings.TrimSpace(name) == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(strings.TrimSpace(name))
+}
+
+// ParseSessionTime parses a session start or end time. Times with an offset
+// keep it; times without one are read in loc.
+func ParseSessionTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if loc == nil {
+		loc = time.Local
+	}
+	for _, layout := range zonedSessionTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	for _, layout := range localSessionTimeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSessionTime, value)
+}
+
+// ValidateSessionTimes checks that a session ends after it starts and that
+// neither end lies in the future; end is nil for a session still running
+func ValidateSessionTimes(start time.Time, end *time.Time, now time.Time) error {
+	latest := now.Add(sessionFutureTolerance)
+	if start.After(latest) || (end != nil && end.After(latest)) {
+		return ErrSessionInFuture
+	}
+	if end != nil && end.Before(start) {
+		return ErrSessionEndBeforeStart
+	}
+	return nil
+}
+
+// SessionMinutes rounds a played duration to the nearest whole minute
+func SessionMinutes(played time.Duration) int {
+	return int(played.Round(time.Minute) / time.Minute)
+}
+
+// EndSessionAt closes a session at end without saving it, folding an ongoing
+// pause into the paused total and recalculating Duration
+func EndSessionAt(session *models.PlaySession, end time.Time) {
+	if session.PausedAt != nil {
+		if end.After(*session.PausedAt) {
+			session.PausedSeconds += int(end.Sub(*session.PausedAt).Seconds())
+		}
+		session.PausedAt = nil
+	}
+	session.EndTime = &end
+	session.Duration = SessionMinutes(PlayedDuration(session, end))
+}
+
+// SessionOverlap describes an existing session that overlaps a proposed time range
+type SessionOverlap struct {
+	SessionID uint       `json:"session_id"`
+	GameID    uint       `json:"game_id"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseSessionTime(t *testing.T) {
+	warsaw, err := LoadSessionTimezone("Europe/Warsaw")
+	require.NoError(t, err)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2024-03-01T18:30:00Z", time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)},
+		{"2024-03-01T18:30:00.250+02:00", time.Date(2024, 3, 1, 16, 30, 0, 250000000, time.UTC)},
+		{"2024-03-01T18:30+01:00", time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)},
+		{"2024-03-01T18:30:00+0100", time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)},
+		{"2024-03-01 18:30:00 -0500", time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)},
+		{"Fri, 01 Mar 2024 18:30:00 +0000", time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)},
+		// No offset: read in the given timezone, including across DST
+		{"2024-03-01 18:30", time.Date(2024, 3, 1, 17, 30, 0, 0, time.UTC)},
+		{"2024-07-01T18:30:00", time.Date(2024, 7, 1, 16, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		parsed, err := ParseSessionTime(tt.value, warsaw)
+		require.NoError(t, err, tt.value)
+		assert.True(t, tt.expected.Equal(parsed), "%s: got %s", tt.value, parsed)
+	}
+
+	for _, invalid := range []string{"", "now", "01/03/2024 18:30", "2024-03-01", "2024-13-01T18:30:00Z"} {
+		_, err := ParseSessionTime(invalid, warsaw)
+		assert.ErrorIs(t, err, ErrInvalidSessionTime, invalid)
+	}
+
+	_, err = LoadSessionTimezone("Nowhere/Special")
+	assert.Error(t, err)
+}
+
+func TestValidateSessionTimes(t *testing.T) {
+	now := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	start := now.Add(-time.Hour)
+	before := start.Add(-time.Minute)
+	skewed := now.Add(2 * time.Minute)
+	future := now.Add(time.Hour)
+
+	assert.NoError(t, ValidateSessionTimes(start, nil, now))
+	assert.NoError(t, ValidateSessionTimes(start, &now, now))
+	assert.NoError(t, ValidateSessionTimes(start, &start, now))
+	assert.NoError(t, ValidateSessionTimes(start, &skewed, now))
+	assert.ErrorIs(t, ValidateSessionTimes(start, &before, now), ErrSessionEndBeforeStart)
+	assert.ErrorIs(t, ValidateSessionTimes(future, nil, now), ErrSessionInFuture)
+	assert.ErrorIs(t, ValidateSessionTimes(start, &future, now), ErrSessionInFuture)
+}
+
+func TestSessionMinutes(t *testing.T) {
+	assert.Equal(t, 0, SessionMinutes(29*time.Second))
+	assert.Equal(t, 1, SessionMinutes(30*time.Second))
+	assert.Equal(t, 90, SessionMinutes(90*time.Minute+29*time.Second))
+	assert.Equal(t, 91, SessionMinutes(90*time.Minute+40*time.Second))
+}
+
+func TestSessionTimer_CheckOverlap(t *testing.T) {
+	timer, clock, _ := newTestSessionTimer(t, SessionConflictReject, 0)
+	start := clock.now.Add(-3 * time.Hour)
+	end := clock.now.Add(-2 * time.Hour)
+
+	session, _, err := timer.Start(1, start, "")
+	require.NoError(t, err)
+	_, err = timer.Stop(session.ID, "", 0)
+	require.NoError(t, err)
+	// Stop ends it now; move the end back to keep a closed one-hour session
+	session.EndTime = &end
+	require.NoError(t, timer.db.Save(session).Error)
+
+	inside := start.Add(30 * time.Minute)
+	overlaps, err := timer.CheckOverlap(inside, &clock.now, 0)
+	assert.ErrorIs(t, err, ErrSessionOverlap)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, session.ID, overlaps[0].SessionID)
+
+	// Touching ranges and the session itself don't count
+	overlaps, err = timer.CheckOverlap(end, &clock.now, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, overlaps)
+	overlaps, err = timer.CheckOverlap(inside, &clock.now, session.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, overlaps)
+
+	// A running session started before the closed one ended overlaps it
+	_, err = timer.CheckOverlap(inside, nil, 0)
+	assert.ErrorIs(t, err, ErrSessionOverlap)
+
+	timer.overlapPolicy = SessionOverlapWarn
+	overlaps, err = timer.CheckOverlap(inside, &clock.now, 0)
+	assert.NoError(t, err)
+	assert.Len(t, overlaps, 1)
+}
+
+func TestSessionTimer_CheckOverlapMixedOffsets(t *testing.T) {
+	timer, _, db := newTestSessionTimer(t, SessionConflictReject, 0)
+	paris := time.FixedZone("+01:00", 3600)
+	at := func(hour, minute int, loc *time.Location) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, loc)
+	}
+
+	// 17:00-19:00 UTC, stored with its +01:00 offset
+	end := at(20, 0, paris)
+	session := models.PlaySession{GameID: 1, StartTime: at(18, 0, paris), EndTime: &end, Duration: 120}
+	require.NoError(t, db.Create(&session).Error)
+
+	laterEnd := at(20, 30, time.UTC)
+	overlaps, err := timer.CheckOverlap(at(19, 30, time.UTC), &laterEnd, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, overlaps)
+
+	earlierEnd := at(17, 10, time.UTC)
+	overlaps, err = timer.CheckOverlap(at(16, 50, time.UTC), &earlierEnd, 0)
+	assert.ErrorIs(t, err, ErrSessionOverlap)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, session.ID, overlaps[0].SessionID)
+
+	_, err = timer.CheckOverlap(at(18, 55, time.UTC), nil, 0)
+	assert.ErrorIs(t, err, ErrSessionOverlap)
+	overlaps, err = timer.CheckOverlap(at(19, 5, time.UTC), nil, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, overlaps)
+}
+
+func TestSessionTimer_OverlapCheckedWithWrite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "pelico.db")+"?_busy_timeout=5000"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Platform{}, &models.Game{}, &models.PlaySession{}))
+	timer := NewSessionTimer(db, SessionConflictReject, SessionOverlapReject, 0)
+
+	// Concurrent writes of the same range can't all pass the check
+	start := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	errs := make(chan error, 8)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- timer.Transaction(func(tx *gorm.DB) error {
+				if _, err := timer.CheckOverlapTx(tx, start, &end, 0); err != nil {
+					return err
+				}
+				time.Sleep(10 * time.Millisecond) // let the others reach their check
+				session := models.PlaySession{GameID: 1, StartTime: start}
+				EndSessionAt(&session, end)
+				return tx.Create(&session).Error
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else {
+			assert.ErrorIs(t, err, ErrSessionOverlap)
+		}
+	}
+	assert.Equal(t, 1, created)
+
+	// Backdated running sessions are checked against completed ones
+	_, _, overlaps, err := timer.StartChecked(2, start.Add(30*time.Minute), "", 0)
+	assert.ErrorIs(t, err, ErrSessionOverlap)
+	assert.Len(t, overlaps, 1)
+	session, _, overlaps, err := timer.StartChecked(2, end, "", 8)
+	require.NoError(t, err)
+	assert.Empty(t, overlaps)
+	assert.Nil(t, session.EndTime)
+	var stored models.PlaySession
+	require.NoError(t, timer.db.First(&stored, session.ID).Error)
+	assert.Equal(t, 8, stored.Rating)
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"pelico/internal/models"
+	"gorm.io/gorm"
+)
+
+// SourceSteam marks sessions imported from Steam's local playtime
+const SourceSteam = "steam"
+
+// steamPlatformName is the platform Steam games are filed under
+const steamPlatformName = "PC"
+
+var ErrSteamDirNotSet = errors.New("no Steam directory configured")
+
+// steamNonGameApps are runtimes and redistributables Steam installs like games
+var steamNonGameApps = map[int]bool{
+	228980:  true, // Steamworks Common Redistributables
+	1070560: true, // Steam Linux Runtime
+	1391110: true, // Steam Linux Runtime - Soldier
+	1628350: true, // Steam Linux Runtime - Sniper
+}
+
+var steamNonGamePrefixes = []string{"Proton ", "Steam Linux Runtime", "Steamworks "}
+
+// SteamImporter reads a Steam installation's local files: libraryfolders.vdf
+// for library locations, appmanifest_*.acf for installed apps, and each
+// user's localconfig.vdf for playtime. No network access is needed.
+type SteamImporter struct {
+	db     *gorm.DB
+	dir    string
+	userID string // limits playtime to one Steam account; empty reads all
+	timer  *SessionTimer
+}
+
+func NewSteamImporter(db *gorm.DB, dir, userID string, timer *SessionTimer) *SteamImporter {
+	return &SteamImporter{
+		db:     db,
+		dir:    dir,
+		userID: userID,
+		timer:  timer,
+	}
+}
+
+// SteamImportResult summarizes a Steam import: games found in the libraries,
+// plus the playtime import for apps with recorded play time
+type SteamImportResult struct {
+	PlaytimeImportResult
+	Libraries    []string `json:"libraries"`
+	AppsFound    int      `json:"apps_found"`
+	GamesCreated int      `json:"games_created"`
+	GamesUpdated int      `json:"games_updated"`
+}
+
+// steamApp is an installed app from an appmanifest
+type steamApp struct {
+	AppID int
+	Name  string
+}
+
+// steamPlaytime is an app's play time from a user's localconfig.vdf
+type steamPlaytime struct {
+	UserID     string
+	AppID      int
+	Minutes    int
+	LastPlayed time.Time
+}
+
+// Import creates or links games for installed Steam apps and imports their
+// play time. An empty dir or userID uses the configured ones. An app's play
+// time when first seen is its baseline; re-running it updates existing games
+// and adds sessions for play time since the previous import.
+func (i *SteamImporter) Import(dir, userID string) (*SteamImportResult, error) {
+	if dir == "" {
+		dir = i.dir
+	}
+	if dir == "" {
+		return nil, ErrSteamDirNotSet
+	}
+	if userID == "" {
+		userID = i.userID
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	result := &SteamImportResult{
+		PlaytimeImportResult: PlaytimeImportResult{
+			Source:    SourceSteam,
+			Unmatched: []string{},
+			Errors:    []string{},
+		},
+		Libraries: []string{},
+	}
+
+	libraries, missing := findSteamLibraries(dir)
+	result.Libraries = libraries
+	for _, library := range missing {
+		result.Errors = append(result.Errors, "library not found: "+library)
+	}
+
+	apps := make(map[int]steamApp)
+	for _, library := range libraries {
+		manifests, _ := filepath.Glob(filepath.Join(library, "steamapps", "appmanifest_*.acf"))
+		for _, manifest := range manifests {
+			app, err := readSteamAppManifest(manifest)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filepath.Base(manifest), err))
+				continue
+			}
+			if !isSteamGame(app) {
+				continue
+			}
+			apps[app.AppID] = app
+		}
+	}
+	result.AppsFound = len(apps)
+
+	platform, err := i.steamPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	appIDs := make([]int, 0, len(apps))
+	for appID := range apps {
+		appIDs = append(appIDs, appID)
+	}
+	sort.Ints(appIDs)
+	for _, appID := range appIDs {
+		created, updated, err := i.upsertGame(apps[appID], platform.ID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("app %d: %v", appID, err))
+			continue
+		}
+		if created {
+			result.GamesCreated++
+		} else if updated {
+			result.GamesUpdated++
+		}
+	}
+
+	playtimes, errs := readSteamPlaytimes(dir, userID)
+	result.Errors = append(result.Errors, errs...)
+	for _, playtime := range playtimes {
+		result.ItemsFound++
+
+		var game models.Game
+		err := i.db.Select("id").Where("steam_app_id = ?", playtime.AppID).First(&game).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Played but not installed: the name isn't known without the Steam API
+			result.Unmatched = append(result.Unmatched, fmt.Sprintf("app %d", playtime.AppID))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		minutes, baselined, overlaps, err := applyImportedPlaytime(i.timer, SourceSteam, importedPlaytime{
+			Key:        playtime.UserID + "/" + strconv.Itoa(playtime.AppID),
+			GameID:     game.ID,
+			Total:      time.Duration(playtime.Minutes) * time.Minute,
+			LastPlayed: playtime.LastPlayed,
+			Notes:      "Imported from Steam playtime",
+		})
+		result.add(fmt.Sprintf("app %d", playtime.AppID), minutes, baselined, overlaps, err)
+	}
+	return result, nil
+}
+
+// steamPlatform finds the PC platform, creating it when missing
+func (i *SteamImporter) steamPlatform() (*models.Platform, error) {
+	var platform models.Platform
+	err := i.db.Where(EqualFold("name", steamPlatformName)).First(&platform).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		platform = models.Platform{Name: steamPlatformName}
+		err = i.db.Create(&platform).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &platform, nil
+}
+
+// upsertGame links an app to its game by Steam app ID, or by title on the PC
+// platform for games added by hand, creating the game when neither exists.
+// Linked games gain the digital format; titles edited in Pelico are kept.
+func (i *SteamImporter) upsertGame(app steamApp, platformID uint) (created, updated bool, err error) {
+	var game models.Game
+	err = i.db.Where("steam_app_id = ?", app.AppID).First(&game).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = i.db.Where("LOWER(title) = ? AND platform_id = ? AND (steam_app_id = 0 OR steam_app_id IS NULL)",
+			strings.ToLower(app.Name), platformID).First(&game).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		game = models.Game{
+			Title:             app.Name,
+			PlatformID:        platformID,
+			SteamAppID:        app.AppID,
+			CollectionFormats: models.CollectionFormats{"digital"},
+		}
+		return true, false, i.db.Create(&game).Error
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	if game.SteamAppID != app.AppID {
+		game.SteamAppID = app.AppID
+		updated = true
+	}
+	hasDigital := false
+	for _, format := range game.CollectionFormats {
+		if format == "digital" {
+			hasDigital = true
+			break
+		}
+	}
+	if !hasDigital {
+		game.CollectionFormats = append(game.CollectionFormats, "digital")
+		updated = true
+	}
+	if !updated {
+		return false, false, nil
+	}
+	return false, true, i.db.Model(&game).Select("steam_app_id", "collection_formats").Updates(&game).Error
+}
+
+// findSteamLibraries returns the library folders listed in libraryfolders.vdf
+// that exist here, always including the Steam directory itself. Libraries
+// listed but not found (e.g. not mounted) are returned as missing.
+func findSteamLibraries(dir string) (libraries, missing []string) {
+	seen := map[string]bool{filepath.Clean(dir): true}
+	libraries = []string{filepath.Clean(dir)}
+	missing = []string{}
+
+	var root vdfObject
+	for _, path := range []string{filepath.Join(dir, "steamapps", "libraryfolders.vdf"), filepath.Join(dir, "config", "libraryfolders.vdf")} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if parsed, err := parseVDF(string(data)); err == nil {
+			root = parsed.get("libraryfolders")
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(root))
+	for key := range root {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, err := strconv.Atoi(key); err != nil {
+			continue
+		}
+		// Newer files nest {"path": ...}; older ones map the index straight to the path
+		var path string
+		switch value := root[key].(type) {
+		case vdfObject:
+			path = value.str("path")
+		case string:
+			path = value
+		}
+		if path == "" || seen[filepath.Clean(path)] {
+			continue
+		}
+		seen[filepath.Clean(path)] = true
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			libraries = append(libraries, filepath.Clean(path))
+		} else {
+			missing = append(missing, path)
+		}
+	}
+	return libraries, missing
+}
+
+func readSteamAppManifest(path string) (steamApp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return steamApp{}, err
+	}
+	root, err := parseVDF(string(data))
+	if err != nil {
+		return steamApp{}, err
+	}
+	state := root.get("AppState")
+	if state == nil {
+		return steamApp{}, fmt.Errorf("missing AppState")
+	}
+	appID, err := strconv.Atoi(state.str("appid"))
+	if err != nil || appID <= 0 {
+		return steamApp{}, fmt.Errorf("invalid appid %q", state.str("appid"))
+	}
+	name := strings.TrimSpace(strings.NewReplacer("™", "", "®", "").Replace(state.str("name")))
+	if name == "" {
+		return steamApp{}, fmt.Errorf("app %d has no name", appID)
+	}
+	return steamApp{AppID: appID, Name: name}, nil
+}
+
+func isSteamGame(app steamApp) bool {
+	if steamNonGameApps[app.AppID] {
+		return false
+	}
+	for _, prefix := range steamNonGamePrefixes {
+		if strings.HasPrefix(app.Name, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// readSteamPlaytimes reads Playtime (minutes) and LastPlayed (unix time) for
+// each app from userdata/<account>/config/localconfig.vdf; userID limits it to
+// one account
+func readSteamPlaytimes(dir, userID string) ([]steamPlaytime, []string) {
+	var playtimes []steamPlaytime
+	var errs []string
+
+	configs, _ := filepath.Glob(filepath.Join(dir, "userdata", "*", "config", "localconfig.vdf"))
+	sort.Strings(configs)
+	for _, path := range configs {
+		account := filepath.Base(filepath.Dir(filepath.Dir(path)))
+		if userID != "" && account != userID {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("userdata/%s: %v", account, err))
+			continue
+		}
+		root, err := parseVDF(string(data))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("userdata/%s: %v", account, err))
+			continue
+		}
+
+		apps := root.path("UserLocalConfigStore", "Software", "Valve", "Steam", "apps")
+		keys := make([]string, 0, len(apps))
+		for key := range apps {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			appID, err := strconv.Atoi(key)
+			app := apps.get(key)
+			if err != nil || app == nil {
+				continue
+			}
+			minutes, _ := strconv.Atoi(app.str("Playtime"))
+			if minutes <= 0 {
+				continue
+			}
+			playtime := steamPlaytime{UserID: account, AppID: appID, Minutes: minutes}
+			if lastPlayed, err := strconv.ParseInt(app.str("LastPlayed"), 10, 64); err == nil && lastPlayed > 0 {
+				playtime.LastPlayed = time.Unix(lastPlayed, 0)
+			}
+			playtimes = append(playtimes, playtime)
+		}
+	}
+	return playtimes, errs
+}
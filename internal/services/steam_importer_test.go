@@ -0,0 +1,188 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseVDF(t *testing.T) {
+	root, err := parseVDF(`// generated by Steam
+"AppState"
+{
+	"appid"		"620"
+	"name"		"Portal \"2\"\\Edition"
+	"UserConfig"
+	{
+		"language"		"english"
+	}
+	"MountedConfig" { "language" "polish" [$WIN32] }
+	bare	value
+}`)
+	require.NoError(t, err)
+
+	state := root.get("appstate")
+	require.NotNil(t, state)
+	assert.Equal(t, "620", state.str("AppID"))
+	assert.Equal(t, `Portal "2"\Edition`, state.str("name"))
+	assert.Equal(t, "english", state.path("UserConfig").str("language"))
+	assert.Equal(t, "polish", state.path("MountedConfig").str("language"))
+	assert.Equal(t, "value", state.str("bare"))
+	assert.Nil(t, state.path("UserConfig", "missing"))
+	assert.Equal(t, "", state.str("UserConfig"))
+
+	for _, invalid := range []string{`"a" {`, `"a" "b" }`, `"a"`, `"unterminated`} {
+		_, err := parseVDF(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSteamImporter_Import(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, models.AutoMigrate(db))
+
+	pc := models.Platform{Name: "pc"}
+	require.NoError(t, db.Create(&pc).Error)
+	// Added by hand before the import, owned on disc as well
+	portal := models.Game{Title: "Portal 2", PlatformID: pc.ID, CollectionFormats: models.CollectionFormats{"physical"}}
+	require.NoError(t, db.Create(&portal).Error)
+
+	steam := t.TempDir()
+	extra := t.TempDir()
+	writeTestFile(t, filepath.Join(steam, "steamapps", "libraryfolders.vdf"), `"libraryfolders"
+{
+	"0"
+	{
+		"path"		"`+steam+`"
+		"apps" { "620" "12345" }
+	}
+	"1"
+	{
+		"path"		"`+extra+`"
+	}
+	"2"
+	{
+		"path"		"/mnt/unplugged/SteamLibrary"
+	}
+}`)
+	writeTestFile(t, filepath.Join(steam, "steamapps", "appmanifest_620.acf"), `"AppState"
+{
+	"appid"		"620"
+	"name"		"Portal 2"
+	"installdir"		"Portal 2"
+}`)
+	writeTestFile(t, filepath.Join(steam, "steamapps", "appmanifest_228980.acf"), `"AppState"
+{
+	"appid"		"228980"
+	"name"		"Steamworks Common Redistributables"
+}`)
+	writeTestFile(t, filepath.Join(extra, "steamapps", "appmanifest_1145360.acf"), `"AppState"
+{
+	"appid"		"1145360"
+	"name"		"Hades™"
+}`)
+	writeTestFile(t, filepath.Join(extra, "steamapps", "appmanifest_broken.acf"), `"AppState" { "name" "No ID" }`)
+	writeTestFile(t, filepath.Join(steam, "userdata", "40000001", "config", "localconfig.vdf"), `"UserLocalConfigStore"
+{
+	"Software"
+	{
+		"Valve"
+		{
+			"Steam"
+			{
+				"Apps"
+				{
+					"620"
+					{
+						"LastPlayed"		"1709316000"
+						"Playtime"		"754"
+					}
+					"1145360"
+					{
+						"Playtime"		"0"
+					}
+					"400"
+					{
+						"LastPlayed"		"1400000000"
+						"Playtime"		"180"
+					}
+				}
+			}
+		}
+	}
+}`)
+	writeTestFile(t, filepath.Join(steam, "userdata", "40000002", "config", "localconfig.vdf"), `"UserLocalConfigStore" {}`)
+
+	importer := NewSteamImporter(db, steam, "", NewSessionTimer(db, SessionConflictReject, SessionOverlapReject, 0))
+	result, err := importer.Import("", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{steam, extra}, result.Libraries)
+	assert.Equal(t, 2, result.AppsFound)
+	assert.Equal(t, 1, result.GamesCreated)
+	assert.Equal(t, 1, result.GamesUpdated)
+	assert.Equal(t, 2, result.ItemsFound)
+	// Play time from before the first import is the baseline, not a session
+	assert.Equal(t, 1, result.Baselined)
+	assert.Equal(t, 0, result.SessionsCreated)
+	assert.Equal(t, 0, result.MinutesImported)
+	assert.Equal(t, []string{"app 400"}, result.Unmatched)
+	require.Len(t, result.Errors, 2)
+	assert.Contains(t, result.Errors[0], "/mnt/unplugged/SteamLibrary")
+	assert.Contains(t, result.Errors[1], "appmanifest_broken.acf")
+
+	require.NoError(t, db.First(&portal, portal.ID).Error)
+	assert.Equal(t, 620, portal.SteamAppID)
+	assert.Equal(t, models.CollectionFormats{"physical", "digital"}, portal.CollectionFormats)
+
+	var hades models.Game
+	require.NoError(t, db.Where("steam_app_id = ?", 1145360).First(&hades).Error)
+	assert.Equal(t, "Hades", hades.Title)
+	assert.Equal(t, pc.ID, hades.PlatformID)
+	assert.Equal(t, models.CollectionFormats{"digital"}, hades.CollectionFormats)
+
+	var sessions int64
+	db.Model(&models.PlaySession{}).Count(&sessions)
+	assert.Zero(t, sessions)
+	var baseline models.ImportedPlaytime
+	require.NoError(t, db.Where("source = ? AND external_key = ?", SourceSteam, "40000001/620").First(&baseline).Error)
+	assert.Equal(t, portal.ID, baseline.GameID)
+	assert.Equal(t, int64(754*60), baseline.TotalSeconds)
+	require.NotNil(t, baseline.LastPlayed)
+	assert.True(t, baseline.LastPlayed.Equal(time.Unix(1709316000, 0)))
+
+	// A re-run imports only time played since and never duplicates games
+	writeTestFile(t, filepath.Join(steam, "userdata", "40000001", "config", "localconfig.vdf"), `"UserLocalConfigStore" { "Software" { "Valve" { "Steam" { "apps" {
+	"620" { "LastPlayed" "1709920800" "Playtime" "814" }
+} } } } }`)
+	result, err = importer.Import(steam, "40000001")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.GamesCreated)
+	assert.Equal(t, 0, result.GamesUpdated)
+	assert.Equal(t, 0, result.Baselined)
+	assert.Equal(t, 1, result.SessionsCreated)
+	assert.Equal(t, 60, result.MinutesImported)
+
+	var session models.PlaySession
+	require.NoError(t, db.Where("game_id = ?", portal.ID).First(&session).Error)
+	assert.Equal(t, SourceSteam, session.Source)
+	assert.Equal(t, 60, session.Duration)
+	assert.True(t, session.EndTime.Equal(time.Unix(1709920800, 0)))
+
+	var games int64
+	db.Model(&models.Game{}).Count(&games)
+	assert.Equal(t, int64(2), games)
+	var platforms int64
+	db.Model(&models.Platform{}).Count(&platforms)
+	assert.Equal(t, int64(1), platforms)
+
+	_, err = NewSteamImporter(db, "", "", nil).Import("", "")
+	assert.ErrorIs(t, err, ErrSteamDirNotSet)
+}
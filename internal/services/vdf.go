@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// vdfObject is a parsed Valve KeyValues (VDF/ACF) block. Values are strings or
+// nested vdfObjects; keys keep their original case, lookups ignore it.
+type vdfObject map[string]interface{}
+
+// get returns a nested object, or nil when the key is missing or holds a string
+func (o vdfObject) get(key string) vdfObject {
+	for name, value := range o {
+		if strings.EqualFold(name, key) {
+			if object, ok := value.(vdfObject); ok {
+				return object
+			}
+		}
+	}
+	return nil
+}
+
+// path follows nested objects, e.g. path("Software", "Valve", "Steam")
+func (o vdfObject) path(keys ...string) vdfObject {
+	current := o
+	for _, key := range keys {
+		if current = current.get(key); current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// str returns a string value, or "" when the key is missing or holds an object
+func (o vdfObject) str(key string) string {
+	for name, value := range o {
+		if strings.EqualFold(name, key) {
+			if text, ok := value.(string); ok {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// parseVDF parses Valve's text KeyValues format as used by libraryfolders.vdf,
+// appmanifest_*.acf and localconfig.vdf. Comments and [$PLATFORM] conditionals
+// are skipped; a repeated key keeps its last value.
+func parseVDF(data string) (vdfObject, error) {
+	parser := &vdfParser{data: data}
+	root, err := parser.object(false)
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+type vdfParser struct {
+	data string
+	pos  int
+}
+
+func (p *vdfParser) object(nested bool) (vdfObject, error) {
+	object := vdfObject{}
+	for {
+		token, quoted, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case token == "" && !quoted && p.pos >= len(p.data):
+			if nested {
+				return nil, fmt.Errorf("vdf: unexpected end of input")
+			}
+			return object, nil
+		case token == "}" && !quoted:
+			if !nested {
+				return nil, fmt.Errorf("vdf: unexpected } at offset %d", p.pos)
+			}
+			return object, nil
+		case token == "{" && !quoted:
+			return nil, fmt.Errorf("vdf: unexpected { at offset %d", p.pos)
+		}
+
+		key := token
+		value, valueQuoted, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if value == "{" && !valueQuoted {
+			child, err := p.object(true)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = child
+		} else if (value == "}" || value == "") && !valueQuoted {
+			return nil, fmt.Errorf("vdf: missing value for %q", key)
+		} else {
+			object[key] = value
+		}
+		p.skipConditional()
+	}
+}
+
+// token reads the next quoted string, bare word or brace
+func (p *vdfParser) token() (string, bool, error) {
+	p.skipSpace()
+	if p.pos >= len(p.data) {
+		return "", false, nil
+	}
+
+	switch p.data[p.pos] {
+	case '{', '}':
+		p.pos++
+		return p.data[p.pos-1 : p.pos], false, nil
+	case '"':
+		p.pos++
+		var value strings.Builder
+		for p.pos < len(p.data) {
+			char := p.data[p.pos]
+			p.pos++
+			switch {
+			case char == '"':
+				return value.String(), true, nil
+			case char == '\\' && p.pos < len(p.data):
+				escaped := p.data[p.pos]
+				p.pos++
+				switch escaped {
+				case 'n':
+					value.WriteByte('\n')
+				case 't':
+					value.WriteByte('\t')
+				default:
+					value.WriteByte(escaped)
+				}
+			default:
+				value.WriteByte(char)
+			}
+		}
+		return "", false, fmt.Errorf("vdf: unterminated string")
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && !strings.ContainsRune(" \t\r\n{}\"", rune(p.data[p.pos])) {
+		p.pos++
+	}
+	return p.data[start:p.pos], false, nil
+}
+
+// skipSpace skips whitespace and // comments
+func (p *vdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(p.data[p.pos])):
+			p.pos++
+		case strings.HasPrefix(p.data[p.pos:], "//"):
+			if end := strings.IndexByte(p.data[p.pos:], '\n'); end >= 0 {
+				p.pos += end + 1
+			} else {
+				p.pos = len(p.data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipConditional skips a trailing [$WIN32]-style condition after a value
+func (p *vdfParser) skipConditional() {
+	save := p.pos
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == '[' {
+		if end := strings.IndexByte(p.data[p.pos:], ']'); end >= 0 {
+			p.pos += end + 1
+			return
+		}
+	}
+	p.pos = save
+}
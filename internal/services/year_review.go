@@ -0,0 +1,291 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+	"pelico/internal/models"
+)
+
+// yearReviewTopN is how many games, platforms and genres the review ranks
+const yearReviewTopN = 5
+
+// YearReview summarizes one calendar year of play
+type YearReview struct {
+	Year         int     `json:"year"`
+	Timezone     string  `json:"timezone"`
+	TotalMinutes int     `json:"total_minutes"`
+	TotalHours   float64 `json:"total_hours"`
+	Sessions     int     `json:"sessions"`
+	ActiveDays   int     `json:"active_days"`
+	GamesPlayed  int     `json:"games_played"`
+
+	GamesStarted   []YearReviewGame `json:"games_started"` // first session ever falls in the year
+	GamesFinished  []YearReviewGame `json:"games_finished"`
+	GamesAbandoned []YearReviewGame `json:"games_abandoned"`
+	// CompletionRate is the share of games played or finished in the year that were finished
+	CompletionRate float64 `json:"completion_rate"`
+
+	TopGames     []PlaytimeBreakdown `json:"top_games"`
+	TopPlatforms []PlaytimeBreakdown `json:"top_platforms"`
+	TopGenres    []PlaytimeBreakdown `json:"top_genres"`
+	Months       []PlaytimeBucket    `json:"months"`
+
+	LongestSession *YearReviewSession `json:"longest_session"`
+	LongestStreak  YearReviewStreak   `json:"longest_streak"`
+	BusiestDay     *HeatmapDay        `json:"busiest_day"`
+
+	Purchases YearReviewPurchases `json:"purchases"`
+}
+
+// YearReviewGame is a game with the date it was started, finished or abandoned
+type YearReviewGame struct {
+	ID       uint      `json:"id"`
+	Title    string    `json:"title"`
+	Platform string    `json:"platform"`
+	Date     time.Time `json:"date"`
+}
+
+type YearReviewSession struct {
+	SessionID uint      `json:"session_id"`
+	GameID    uint      `json:"game_id"`
+	Title     string    `json:"title"`
+	StartTime time.Time `json:"start_time"`
+	Minutes   int       `json:"minutes"`
+}
+
+// YearReviewStreak is the longest run of consecutive days with play
+type YearReviewStreak struct {
+	Days int    `json:"days"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// YearReviewPurchases counts games bought in the year, by the game's or a
+// physical copy's purchase date. MoneySpent totals the year's copy prices; it
+// is nil when none are recorded or they are in different currencies.
+type YearReviewPurchases struct {
+	Games      int      `json:"games"`
+	MoneySpent *float64 `json:"money_spent"`
+	Currency   string   `json:"currency,omitempty"`
+}
+
+// YearReview builds the review for a calendar year in loc
+func (s *PlaytimeStats) YearReview(year int, loc *time.Location) (*YearReview, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	r := PlaytimeRange{
+		From:     time.Date(year, 1, 1, 0, 0, 0, 0, loc),
+		To:       time.Date(year+1, 1, 1, 0, 0, 0, 0, loc),
+		Location: loc,
+	}
+	review := &YearReview{
+		Year:           year,
+		Timezone:       loc.String(),
+		GamesStarted:   []YearReviewGame{},
+		GamesFinished:  []YearReviewGame{},
+		GamesAbandoned: []YearReviewGame{},
+	}
+
+	played, err := s.sessions(r)
+	if err != nil {
+		return nil, err
+	}
+	review.Sessions = len(played)
+	playedGames := make(map[uint]bool)
+	var longest *playedSession
+	for i, session := range played {
+		review.TotalMinutes += session.Duration
+		playedGames[session.GameID] = true
+		if longest == nil || session.Duration > longest.Duration {
+			longest = &played[i]
+		}
+	}
+	review.TotalHours = minutesToHours(review.TotalMinutes)
+
+	if review.TopGames, err = s.ByGame(r, yearReviewTopN); err != nil {
+		return nil, err
+	}
+	if review.TopPlatforms, err = s.ByPlatform(r); err != nil {
+		return nil, err
+	}
+	if len(review.TopPlatforms) > yearReviewTopN {
+		review.TopPlatforms = review.TopPlatforms[:yearReviewTopN]
+	}
+	if review.TopGenres, err = s.ByGenre(r); err != nil {
+		return nil, err
+	}
+	if len(review.TopGenres) > yearReviewTopN {
+		review.TopGenres = review.TopGenres[:yearReviewTopN]
+	}
+	if review.Months, err = s.Series(r, PlaytimeByMonth); err != nil {
+		return nil, err
+	}
+
+	heatmap, err := s.Heatmap(r)
+	if err != nil {
+		return nil, err
+	}
+	review.ActiveDays = heatmap.ActiveDays
+	review.LongestStreak = longestStreak(heatmap.Days)
+	for i, day := range heatmap.Days {
+		if day.Minutes > 0 && (review.BusiestDay == nil || day.Minutes > review.BusiestDay.Minutes) {
+			review.BusiestDay = &heatmap.Days[i]
+		}
+	}
+
+	if longest != nil {
+		var session models.PlaySession
+		if err := s.db.Preload("Game").First(&session, longest.ID).Error; err != nil {
+			return nil, err
+		}
+		review.LongestSession = &YearReviewSession{
+			SessionID: session.ID,
+			GameID:    session.GameID,
+			Title:     session.Game.Title,
+			StartTime: session.StartTime,
+			Minutes:   session.Duration,
+		}
+	}
+
+	if err := s.reviewGames(review, r, playedGames); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// reviewGames fills in games started, finished and abandoned, the completion
+// rate and purchases
+func (s *PlaytimeStats) reviewGames(review *YearReview, r PlaytimeRange, playedGames map[uint]bool) error {
+	var games []struct {
+		ID               uint
+		Title            string
+		Platform         string
+		CompletionStatus string
+		CompletionDate   *time.Time
+		PurchaseDate     *time.Time
+	}
+	err := s.db.Table("games").
+		Select("games.id, games.title, platforms.name AS platform, games.completion_status, games.completion_date, games.purchase_date").
+		Joins("LEFT JOIN platforms ON platforms.id = games.platform_id").
+		Scan(&games).Error
+	if err != nil {
+		return err
+	}
+
+	// First session ever per game, found in Go since MIN() over SQLite times loses their type
+	var sessions []playedSession
+	if err := s.db.Model(&models.PlaySession{}).Select("game_id", "start_time").Find(&sessions).Error; err != nil {
+		return err
+	}
+	firstPlayed := make(map[uint]time.Time)
+	for _, session := range sessions {
+		if first, ok := firstPlayed[session.GameID]; !ok || session.StartTime.Before(first) {
+			firstPlayed[session.GameID] = session.StartTime
+		}
+	}
+
+	inYear := func(t *time.Time) bool {
+		return t != nil && !t.Before(r.From) && t.Before(r.To)
+	}
+	bought, err := s.reviewPurchases(review, inYear)
+	if err != nil {
+		return err
+	}
+	considered := make(map[uint]bool)
+	for id := range playedGames {
+		considered[id] = true
+	}
+	for _, game := range games {
+		entry := YearReviewGame{ID: game.ID, Title: game.Title, Platform: game.Platform}
+		if first, ok := firstPlayed[game.ID]; ok && inYear(&first) {
+			entry.Date = first
+			review.GamesStarted = append(review.GamesStarted, entry)
+		}
+		if inYear(game.CompletionDate) {
+			entry.Date = *game.CompletionDate
+			switch game.CompletionStatus {
+			case "completed", "100_percent":
+				review.GamesFinished = append(review.GamesFinished, entry)
+				considered[game.ID] = true
+			case "abandoned":
+				review.GamesAbandoned = append(review.GamesAbandoned, entry)
+			}
+		}
+		if inYear(game.PurchaseDate) {
+			bought[game.ID] = true
+		}
+	}
+	review.Purchases.Games = len(bought)
+
+	for _, list := range [][]YearReviewGame{review.GamesStarted, review.GamesFinished, review.GamesAbandoned} {
+		sort.Slice(list, func(a, b int) bool { return list[a].Date.Before(list[b].Date) })
+	}
+	review.GamesPlayed = len(playedGames)
+	if len(considered) > 0 {
+		review.CompletionRate = math.Round(float64(len(review.GamesFinished))/float64(len(considered))*1000) / 1000
+	}
+	return nil
+}
+
+// reviewPurchases totals what copies bought in the year cost, returning the
+// games they're copies of
+func (s *PlaytimeStats) reviewPurchases(review *YearReview, inYear func(*time.Time) bool) (map[uint]bool, error) {
+	var copies []struct {
+		GameID        uint
+		PurchasePrice *float64
+		Currency      string
+		PurchaseDate  *time.Time
+	}
+	err := s.db.Model(&models.PhysicalCopy{}).Select("game_id", "purchase_price", "currency", "purchase_date").Scan(&copies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	bought := make(map[uint]bool)
+	var spent float64
+	priced, mixed := false, false
+	for _, physicalCopy := range copies {
+		if !inYear(physicalCopy.PurchaseDate) {
+			continue
+		}
+		bought[physicalCopy.GameID] = true
+		if physicalCopy.PurchasePrice == nil {
+			continue
+		}
+		spent += *physicalCopy.PurchasePrice
+		priced = true
+		// Copies without a currency are in whichever the others use
+		if physicalCopy.Currency != "" {
+			if review.Purchases.Currency != "" && review.Purchases.Currency != physicalCopy.Currency {
+				mixed = true
+			}
+			review.Purchases.Currency = physicalCopy.Currency
+		}
+	}
+	if priced && !mixed {
+		spent = roundMoney(spent)
+		review.Purchases.MoneySpent = &spent
+	} else {
+		review.Purchases.Currency = ""
+	}
+	return bought, nil
+}
+
+// longestStreak finds the longest run of consecutive days with play
+func longestStreak(days []HeatmapDay) YearReviewStreak {
+	var best YearReviewStreak
+	run := 0
+	for i, day := range days {
+		if day.Minutes == 0 {
+			run = 0
+			continue
+		}
+		run++
+		if run > best.Days {
+			best = YearReviewStreak{Days: run, From: days[i-run+1].Date, To: day.Date}
+		}
+	}
+	return best
+}
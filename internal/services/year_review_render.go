@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// yearReviewFuncs are shared by the Markdown and HTML templates
+var yearReviewFuncs = map[string]interface{}{
+	"hours": func(minutes int) string {
+		return fmt.Sprintf("%.1f h", minutesToHours(minutes))
+	},
+	"percent": func(share float64) string {
+		return fmt.Sprintf("%.0f%%", share*100)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("Jan 2")
+	},
+	"day": func(date string) string {
+		if parsed, err := time.Parse("2006-01-02", date); err == nil {
+			return parsed.Format("Mon, Jan 2")
+		}
+		return date
+	},
+	"month": func(start time.Time) string {
+		return start.Format("Jan")
+	},
+	"money": func(amount *float64) string {
+		return fmt.Sprintf("%.2f", *amount)
+	},
+	// md escapes characters Markdown would treat as formatting in titles
+	"md": func(text string) string {
+		return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "|", `\|`, "[", `\[`, "]", `\]`, "#", `\#`).Replace(text)
+	},
+}
+
+const yearReviewMarkdown = `# {{.Year}} in review
+
+**{{hours .TotalMinutes}}** played over {{.Sessions}} sessions on {{.ActiveDays}} days, across {{.GamesPlayed}} games.
+
+| | |
+|---|---|
+| Games started | {{len .GamesStarted}} |
+| Games finished | {{len .GamesFinished}} |
+| Games abandoned | {{len .GamesAbandoned}} |
+| Completion rate | {{percent .CompletionRate}} |
+| Longest streak | {{.LongestStreak.Days}} days{{if .LongestStreak.From}} ({{day .LongestStreak.From}} to {{day .LongestStreak.To}}){{end}} |
+{{- with .LongestSession}}
+| Longest session | {{hours .Minutes}} of {{md .Title}} on {{date .StartTime}} |
+{{- end}}
+{{- with .BusiestDay}}
+| Busiest day | {{day .Date}}, {{hours .Minutes}} |
+{{- end}}
+| Games bought | {{.Purchases.Games}}{{with .Purchases.MoneySpent}} ({{money .}}{{with $.Purchases.Currency}} {{.}}{{end}} spent){{end}} |
+{{if .TopGames}}
+## Top games
+
+| # | Game | Platform | Time | Share |
+|---|---|---|---|---|
+{{- range $i, $game := .TopGames}}
+| {{inc $i}} | {{md $game.Name}} | {{md $game.Platform}} | {{hours $game.Minutes}} | {{percent $game.Share}} |
+{{- end}}
+{{end}}
+{{- if .TopPlatforms}}
+## Top platforms
+
+| Platform | Time | Share |
+|---|---|---|
+{{- range .TopPlatforms}}
+| {{md .Name}} | {{hours .Minutes}} | {{percent .Share}} |
+{{- end}}
+{{end}}
+{{- if .TopGenres}}
+## Top genres
+
+| Genre | Time |
+|---|---|
+{{- range .TopGenres}}
+| {{md .Name}} | {{hours .Minutes}} |
+{{- end}}
+{{end}}
+## Month by month
+
+| Month | Time | Sessions |
+|---|---|---|
+{{- range .Months}}
+| {{month .Start}} | {{hours .Minutes}} | {{.Sessions}} |
+{{- end}}
+{{if .GamesFinished}}
+## Finished
+
+{{range .GamesFinished}}- {{md .Title}} ({{md .Platform}}), {{date .Date}}
+{{end}}{{end}}
+{{- if .GamesAbandoned}}
+## Abandoned
+
+{{range .GamesAbandoned}}- {{md .Title}} ({{md .Platform}}), {{date .Date}}
+{{end}}{{end}}`
+
+const yearReviewHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Year}} in review · Pelico</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { margin-bottom: 0.25rem; }
+.summary { font-size: 1.1rem; color: #555; }
+.tiles { display: grid; grid-template-columns: repeat(auto-fill, minmax(10rem, 1fr)); gap: 0.75rem; margin: 1.5rem 0; }
+.tile { background: #f4f5f7; border-radius: 0.5rem; padding: 0.75rem; }
+.tile strong { display: block; font-size: 1.5rem; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1.5rem; }
+th, td { text-align: left; padding: 0.35rem 0.5rem; border-bottom: 1px solid #e2e4e8; }
+td.num, th.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Year}} in review</h1>
+<p class="summary"><strong>{{hours .TotalMinutes}}</strong> played over {{.Sessions}} sessions on {{.ActiveDays}} days, across {{.GamesPlayed}} games.</p>
+<div class="tiles">
+<div class="tile"><strong>{{len .GamesStarted}}</strong>games started</div>
+<div class="tile"><strong>{{len .GamesFinished}}</strong>games finished</div>
+<div class="tile"><strong>{{len .GamesAbandoned}}</strong>games abandoned</div>
+<div class="tile"><strong>{{percent .CompletionRate}}</strong>completion rate</div>
+<div class="tile"><strong>{{.LongestStreak.Days}} days</strong>longest streak</div>
+{{- with .LongestSession}}
+<div class="tile"><strong>{{hours .Minutes}}</strong>longest session: {{.Title}}, {{date .StartTime}}</div>
+{{- end}}
+{{- with .BusiestDay}}
+<div class="tile"><strong>{{hours .Minutes}}</strong>busiest day: {{day .Date}}</div>
+{{- end}}
+<div class="tile"><strong>{{.Purchases.Games}}</strong>games bought{{with .Purchases.MoneySpent}}, {{money .}}{{with $.Purchases.Currency}} {{.}}{{end}} spent{{end}}</div>
+</div>
+{{- if .TopGames}}
+<h2>Top games</h2>
+<table>
+<tr><th>#</th><th>Game</th><th>Platform</th><th class="num">Time</th><th class="num">Share</th></tr>
+{{- range $i, $game := .TopGames}}
+<tr><td>{{inc $i}}</td><td>{{$game.Name}}</td><td>{{$game.Platform}}</td><td class="num">{{hours $game.Minutes}}</td><td class="num">{{percent $game.Share}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .TopPlatforms}}
+<h2>Top platforms</h2>
+<table>
+<tr><th>Platform</th><th class="num">Time</th><th class="num">Share</th></tr>
+{{- range .TopPlatforms}}
+<tr><td>{{.Name}}</td><td class="num">{{hours .Minutes}}</td><td class="num">{{percent .Share}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .TopGenres}}
+<h2>Top genres</h2>
+<table>
+<tr><th>Genre</th><th class="num">Time</th></tr>
+{{- range .TopGenres}}
+<tr><td>{{.Name}}</td><td class="num">{{hours .Minutes}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2>Month by month</h2>
+<table>
+<tr><th>Month</th><th class="num">Time</th><th class="num">Sessions</th></tr>
+{{- range .Months}}
+<tr><td>{{month .Start}}</td><td class="num">{{hours .Minutes}}</td><td class="num">{{.Sessions}}</td></tr>
+{{- end}}
+</table>
+{{- if .GamesFinished}}
+<h2>Finished</h2>
+<ul>
+{{- range .GamesFinished}}
+<li>{{.Title}} ({{.Platform}}), {{date .Date}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .GamesAbandoned}}
+<h2>Abandoned</h2>
+<ul>
+{{- range .GamesAbandoned}}
+<li>{{.Title}} ({{.Platform}}), {{date .Date}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`
+
+var (
+	yearReviewMarkdownTemplate = template.Must(template.New("review.md").Funcs(yearReviewFuncs).Funcs(template.FuncMap{"inc": inc}).Parse(yearReviewMarkdown))
+	yearReviewHTMLTemplate     = htmltemplate.Must(htmltemplate.New("review.html").Funcs(yearReviewFuncs).Funcs(htmltemplate.FuncMap{"inc": inc}).Parse(yearReviewHTML))
+)
+
+func inc(i int) int {
+	return i + 1
+}
+
+// RenderYearReviewMarkdown writes the review as a Markdown document
+func RenderYearReviewMarkdown(w io.Writer, review *YearReview) error {
+	return yearReviewMarkdownTemplate.Execute(w, review)
+}
+
+// RenderYearReviewHTML writes the review as a standalone HTML page
+func RenderYearReviewHTML(w io.Writer, review *YearReview) error {
+	return yearReviewHTMLTemplate.Execute(w, review)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"pelico/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYearReview(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+	utc := time.UTC
+
+	// Super Mario World was started the year before; the rest start in 2024
+	addPlayedSession(t, db, 1, time.Date(2023, 12, 30, 20, 0, 0, 0, utc), 60)
+	addPlayedSession(t, db, 1, time.Date(2024, 3, 1, 20, 0, 0, 0, utc), 30)
+	addPlayedSession(t, db, 2, time.Date(2024, 3, 2, 20, 0, 0, 0, utc), 180)
+	addPlayedSession(t, db, 2, time.Date(2024, 3, 3, 20, 0, 0, 0, utc), 90)
+	addPlayedSession(t, db, 3, time.Date(2024, 7, 14, 10, 0, 0, 0, utc), 45)
+	addPlayedSession(t, db, 4, time.Date(2025, 1, 2, 10, 0, 0, 0, utc), 20)
+
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 12, 0, 0, 0, utc)
+		return &d
+	}
+	require.NoError(t, db.Model(&models.Game{ID: 2}).Updates(map[string]interface{}{
+		"completion_status": "completed", "completion_date": date(2024, 3, 4), "purchase_date": date(2024, 2, 20),
+	}).Error)
+	require.NoError(t, db.Model(&models.Game{ID: 3}).Updates(map[string]interface{}{
+		"completion_status": "abandoned", "completion_date": date(2024, 8, 1), "purchase_date": date(2023, 11, 1),
+	}).Error)
+	require.NoError(t, db.Model(&models.Game{ID: 4}).Updates(map[string]interface{}{
+		"completion_status": "completed", "completion_date": date(2023, 5, 1),
+	}).Error)
+
+	review, err := NewPlaytimeStats(db).YearReview(2024, utc)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2024, review.Year)
+	assert.Equal(t, 345, review.TotalMinutes)
+	assert.Equal(t, 5.8, review.TotalHours)
+	assert.Equal(t, 4, review.Sessions)
+	assert.Equal(t, 4, review.ActiveDays)
+	assert.Equal(t, 3, review.GamesPlayed)
+
+	titles := func(games []YearReviewGame) []string {
+		var names []string
+		for _, game := range games {
+			names = append(names, game.Title)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"Chrono Trigger", "Hollow Knight"}, titles(review.GamesStarted))
+	assert.Equal(t, []string{"Chrono Trigger"}, titles(review.GamesFinished))
+	assert.Equal(t, []string{"Hollow Knight"}, titles(review.GamesAbandoned))
+	assert.Equal(t, 0.333, review.CompletionRate)
+
+	require.NotEmpty(t, review.TopGames)
+	assert.Equal(t, "Chrono Trigger", review.TopGames[0].Name)
+	assert.Equal(t, "SNES", review.TopPlatforms[0].Name)
+	require.Len(t, review.Months, 12)
+	assert.Equal(t, 300, review.Months[2].Minutes)
+
+	require.NotNil(t, review.LongestSession)
+	assert.Equal(t, "Chrono Trigger", review.LongestSession.Title)
+	assert.Equal(t, 180, review.LongestSession.Minutes)
+	assert.Equal(t, YearReviewStreak{Days: 3, From: "2024-03-01", To: "2024-03-03"}, review.LongestStreak)
+	require.NotNil(t, review.BusiestDay)
+	assert.Equal(t, "2024-03-02", review.BusiestDay.Date)
+
+	assert.Equal(t, 1, review.Purchases.Games)
+	assert.Nil(t, review.Purchases.MoneySpent)
+
+	var markdown bytes.Buffer
+	require.NoError(t, RenderYearReviewMarkdown(&markdown, review))
+	assert.Contains(t, markdown.String(), "# 2024 in review")
+	assert.Contains(t, markdown.String(), "| Longest streak | 3 days (Fri, Mar 1 to Sun, Mar 3) |")
+	assert.Contains(t, markdown.String(), "- Chrono Trigger (SNES), Mar 4")
+	assert.NotContains(t, markdown.String(), "spent")
+
+	var page bytes.Buffer
+	require.NoError(t, RenderYearReviewHTML(&page, review))
+	assert.Contains(t, page.String(), "<h1>2024 in review</h1>")
+	assert.Contains(t, page.String(), "<li>Hollow Knight (PC), Aug 1</li>")
+}
+
+func TestYearReview_EmptyYear(t *testing.T) {
+	db := newTestPlaytimeDB(t)
+
+	review, err := NewPlaytimeStats(db).YearReview(2020, time.UTC)
+	require.NoError(t, err)
+	assert.Zero(t, review.TotalMinutes)
+	assert.Nil(t, review.LongestSession)
+	assert.Nil(t, review.BusiestDay)
+	assert.Zero(t, review.CompletionRate)
+
+	var markdown bytes.Buffer
+	require.NoError(t, RenderYearReviewMarkdown(&markdown, review))
+	assert.Contains(t, markdown.String(), "**0.0 h** played")
+}